@@ -37,6 +37,10 @@ var (
 	imageServerPortNum = flag.Uint("imageServerPortNum",
 		constants.ImageServerPortNumber,
 		"Port number of image server")
+	maxBuildLogAge = flag.Duration("maxBuildLogAge", 0,
+		"Maximum age of build logs to search (0: no limit)")
+	maxSearchResults = flag.Uint("maxSearchResults", 0,
+		"Maximum number of build logs to report when searching (0: default)")
 	maxSourceAge = flag.Duration("maxSourceAge", time.Hour,
 		"Maximum age of a source image before it is rebuilt")
 	mtimesCopyFilterFile = flag.String("mtimesCopyFilterFile", "",
@@ -44,6 +48,8 @@ var (
 	rawSize      flagutil.Size
 	showFetchLog = flag.Bool("showFetchLog", false,
 		"If true, show fetch log when getting directed graph")
+	streamName = flag.String("streamName", "",
+		"Image stream (and sub-streams) to search build logs for")
 	variablesFilename = flag.String("variablesFilename", "",
 		"Name of file to read variables from for local builds")
 
@@ -84,6 +90,7 @@ var subcommands = []commands.Command{
 	{"process-manifest", "manifestDir rootDir", 2, 2,
 		processManifestSubcommand},
 	{"replace-idle-slaves", "", 0, 0, replaceIdleSlavesSubcommand},
+	{"search-build-logs", "pattern", 1, 1, searchBuildLogsSubcommand},
 }
 
 var imaginatorSrpcClient *srpc.Client
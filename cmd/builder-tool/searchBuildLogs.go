@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Cloud-Foundations/Dominator/imagebuilder/client"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
+)
+
+func searchBuildLogsSubcommand(args []string, logger log.DebugLogger) error {
+	if err := searchBuildLogs(args[0], logger); err != nil {
+		return fmt.Errorf("error searching build logs: %s", err)
+	}
+	return nil
+}
+
+func searchBuildLogs(pattern string, logger log.Logger) error {
+	srpcClient := getImaginatorClient()
+	results, err := client.SearchBuildLogs(srpcClient,
+		proto.SearchBuildLogsRequest{
+			MaxAge:     *maxBuildLogAge,
+			MaxResults: *maxSearchResults,
+			Pattern:    pattern,
+			StreamName: *streamName,
+		})
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		status := "OK"
+		if result.Error != "" {
+			status = "error: " + result.Error
+		}
+		fmt.Printf("%s (built %s, %s)\n", result.ImageName,
+			result.BuildTime.Local().Format("2006-01-02 15:04:05"), status)
+		for _, match := range result.Matches {
+			fmt.Printf("  %d: %s\n", match.LineNumber, match.Line)
+		}
+		if omitted := result.NumMatches - uint(len(result.Matches)); omitted > 0 {
+			fmt.Printf("  (%d more matches)\n", omitted)
+		}
+	}
+	if len(results) < 1 {
+		fmt.Fprintln(os.Stderr, "No matches")
+	}
+	return nil
+}
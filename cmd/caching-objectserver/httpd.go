@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/Cloud-Foundations/Dominator/lib/html"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+)
+
+type HtmlWriter interface {
+	WriteHtml(writer io.Writer)
+}
+
+type httpServer struct {
+	htmlWriters          []HtmlWriter
+	upstreamObjectServer string
+}
+
+func startHttpServer(upstreamObjectServer string) (*httpServer, error) {
+	s := &httpServer{upstreamObjectServer: upstreamObjectServer}
+	html.HandleFunc("/", s.statusHandler)
+	return s, nil
+}
+
+func (s *httpServer) AddHtmlWriter(htmlWriter HtmlWriter) {
+	s.htmlWriters = append(s.htmlWriters, htmlWriter)
+}
+
+func (s *httpServer) serve(portNum uint) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", portNum))
+	if err != nil {
+		return err
+	}
+	listener = srpc.NewTlsSniffingListener(listener)
+	return http.Serve(listener, nil)
+}
+
+func (s *httpServer) statusHandler(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/" {
+		http.NotFound(w, req)
+		return
+	}
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	fmt.Fprintln(writer, "<title>Caching Object Server status page</title>")
+	fmt.Fprintln(writer, "<body>")
+	fmt.Fprintln(writer, "<center>")
+	fmt.Fprintln(writer,
+		"<h1><b>Caching Object Server</b> status page</h1>")
+	fmt.Fprintln(writer, "</center>")
+	html.WriteHeaderWithRequestNoGC(writer, req)
+	fmt.Fprintln(writer, "<h3>")
+	fmt.Fprintf(writer, "Upstream object server: %s<br>\n",
+		s.upstreamObjectServer)
+	for _, htmlWriter := range s.htmlWriters {
+		htmlWriter.WriteHtml(writer)
+	}
+	fmt.Fprintln(writer, "</h3>")
+	fmt.Fprintln(writer, "<hr>")
+	html.WriteFooter(writer)
+	fmt.Fprintln(writer, "</body>")
+}
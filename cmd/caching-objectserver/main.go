@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Cloud-Foundations/Dominator/lib/constants"
+	"github.com/Cloud-Foundations/Dominator/lib/flags/loadflags"
+	"github.com/Cloud-Foundations/Dominator/lib/flagutil"
+	"github.com/Cloud-Foundations/Dominator/lib/log/serverlogger"
+	"github.com/Cloud-Foundations/Dominator/lib/objectserver/cachingreader"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc/setupserver"
+	"github.com/Cloud-Foundations/tricorder/go/healthserver"
+	"github.com/Cloud-Foundations/tricorder/go/tricorder"
+)
+
+var (
+	allowPublicCheckObjects = flag.Bool("allowPublicCheckObjects", false,
+		"If true, allow all users to call CheckObjects method")
+	allowPublicGetObjects = flag.Bool("allowPublicGetObjects", false,
+		"If true, allow all users to call GetObjects method")
+	maxCachedBytes = flagutil.Size(100 << 30)
+	objectDir      = flag.String("objectDir",
+		"/var/lib/caching-objectserver", "Name of object cache directory")
+	portNum = flag.Uint("portNum", constants.CachingObjectServerPortNumber,
+		"Port number to allocate and listen on for HTTP/RPC")
+	upstreamObjectServer = flag.String("upstreamObjectServer",
+		fmt.Sprintf(":%d", constants.ImageServerPortNumber),
+		"Address of object server to fill the cache from")
+)
+
+func init() {
+	flag.Var(&maxCachedBytes, "maxCachedBytes",
+		"Maximum size of the object cache")
+}
+
+func main() {
+	if os.Geteuid() == 0 {
+		fmt.Fprintln(os.Stderr, "Do not run the Caching Object Server as root")
+		os.Exit(1)
+	}
+	if err := loadflags.LoadForDaemon("caching-objectserver"); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	flag.Parse()
+	tricorder.RegisterFlags()
+	logger := serverlogger.New("")
+	srpc.SetDefaultLogger(logger)
+	err := setupserver.SetupTlsWithParams(setupserver.Params{Logger: logger})
+	if err != nil {
+		logger.Fatalln(err)
+	}
+	if err := os.MkdirAll(*objectDir, 0755); err != nil {
+		logger.Fatalln(err)
+	}
+	objSrv, err := cachingreader.NewObjectServer(*objectDir,
+		uint64(maxCachedBytes), *upstreamObjectServer, logger)
+	if err != nil {
+		logger.Fatalf("Cannot create ObjectServer: %s\n", err)
+	}
+	rpcHtmlWriter := startRpcServer(objSrv, logger)
+	webServer, err := startHttpServer(*upstreamObjectServer)
+	if err != nil {
+		logger.Fatalf("Unable to create http server: %s\n", err)
+	}
+	webServer.AddHtmlWriter(objSrv)
+	webServer.AddHtmlWriter(rpcHtmlWriter)
+	webServer.AddHtmlWriter(logger)
+	healthserver.SetReady()
+	logger.Printf("Service ready, opening listener on port: %d\n", *portNum)
+	if err := webServer.serve(*portNum); err != nil {
+		logger.Fatalf("Unable to start http server: %s\n", err)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/objectserver"
+	"github.com/Cloud-Foundations/Dominator/lib/objectserver/cachingreader"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/objectserver"
+	"github.com/Cloud-Foundations/tricorder/go/tricorder"
+	"github.com/Cloud-Foundations/tricorder/go/tricorder/units"
+)
+
+type rpcType struct {
+	getSemaphore chan struct{}
+	logger       log.DebugLogger
+	objSrv       *cachingreader.ObjectServer
+}
+
+type rpcHtmlWriter struct {
+	getSemaphore chan struct{}
+}
+
+func startRpcServer(objSrv *cachingreader.ObjectServer,
+	logger log.DebugLogger) *rpcHtmlWriter {
+	getSemaphore := make(chan struct{}, 100)
+	rpcObj := &rpcType{
+		getSemaphore: getSemaphore,
+		logger:       logger,
+		objSrv:       objSrv,
+	}
+	var publicMethods []string
+	if *allowPublicCheckObjects {
+		publicMethods = append(publicMethods, "CheckObjects")
+	}
+	if *allowPublicGetObjects {
+		publicMethods = append(publicMethods, "GetObjects")
+	}
+	srpc.RegisterNameWithOptions("ObjectServer", rpcObj,
+		srpc.ReceiverOptions{PublicMethods: publicMethods})
+	tricorder.RegisterMetric("/get-requests",
+		func() uint { return uint(len(getSemaphore)) },
+		units.None, "number of GetObjects() requests in progress")
+	return &rpcHtmlWriter{getSemaphore}
+}
+
+func (hw *rpcHtmlWriter) WriteHtml(writer io.Writer) {
+	fmt.Fprintf(writer, "GetObjects() RPC slots: %d out of %d<br>\n",
+		len(hw.getSemaphore), cap(hw.getSemaphore))
+}
+
+func (t *rpcType) CheckObjects(conn *srpc.Conn,
+	request proto.CheckObjectsRequest,
+	reply *proto.CheckObjectsResponse) error {
+	objectSizes, err := t.objSrv.CheckObjects(request.Hashes)
+	if err != nil {
+		return err
+	}
+	reply.ObjectSizes = objectSizes
+	return nil
+}
+
+// GetObjects streams the objects, filling the cache from the upstream object
+// server for objects which are not cached.
+func (t *rpcType) GetObjects(conn *srpc.Conn) error {
+	defer conn.Flush()
+	t.getSemaphore <- struct{}{}
+	defer func() { <-t.getSemaphore }()
+	var request proto.GetObjectsRequest
+	var response proto.GetObjectsResponse
+	if err := conn.Decode(&request); err != nil {
+		response.ResponseString = err.Error()
+		return conn.Encode(response)
+	}
+	objectsReader, err := t.objSrv.GetObjects(request.Hashes)
+	if err != nil {
+		response.ResponseString = err.Error()
+		return conn.Encode(response)
+	}
+	defer objectsReader.Close()
+	fullObjectsReader := objectsReader.(objectserver.FullObjectsReader)
+	response.ObjectSizes = fullObjectsReader.ObjectSizes()
+	if err := conn.Encode(response); err != nil {
+		return err
+	}
+	conn.Flush()
+	buffer := make([]byte, 32<<10)
+	for _, hashVal := range request.Hashes {
+		length, reader, err := objectsReader.NextObject()
+		if err != nil {
+			t.logger.Println(err)
+			return err
+		}
+		nCopied, err := io.CopyBuffer(conn, reader, buffer)
+		reader.Close()
+		if err != nil {
+			t.logger.Printf("Error copying: %s\n", err)
+			return err
+		}
+		if nCopied != int64(length) {
+			txt := fmt.Sprintf("Expected length: %d, got: %d for: %x",
+				length, nCopied, hashVal)
+			t.logger.Println(txt)
+			return errors.New(txt)
+		}
+	}
+	t.logger.Debugf(0, "GetObjects() sent: %d objects\n", len(request.Hashes))
+	return nil
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/mdb"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	dm_proto "github.com/Cloud-Foundations/Dominator/proto/disruptionmanager"
+)
+
+const readyCheckTimeout = 10 * time.Second
+
+type memberType struct {
+	Healthy     bool
+	LastError   string    `json:",omitempty"`
+	LastSeen    time.Time `json:",omitempty"`
+	ReadyChecks []string  `json:",omitempty"`
+}
+
+type memberInfoType struct {
+	Hostname string
+	memberType
+}
+
+type memberCheckType struct {
+	err             error
+	groupIdentifier string
+	hostname        string
+	readyChecks     []string
+}
+
+// checkReadyCheck returns an error if the readiness check is not supported.
+func checkReadyCheck(readyCheck string) error {
+	switch {
+	case strings.HasPrefix(readyCheck, "http://"),
+		strings.HasPrefix(readyCheck, "https://"),
+		strings.HasPrefix(readyCheck, "tcp://"):
+		return nil
+	case strings.HasPrefix(readyCheck, "srpc://"):
+		_, _, err := parseSrpcReadyCheck(readyCheck)
+		return err
+	}
+	return errors.New("unsupported ready check: " + readyCheck)
+}
+
+// expandTag applies template expansion to the value of the tag, using the MDB
+// data for the machine.
+func expandTag(machine mdb.Machine, tagName string) (string, error) {
+	tmpl, err := template.New("").Parse(machine.Tags[tagName])
+	if err != nil {
+		return "", err
+	}
+	builder := &strings.Builder{}
+	if err := tmpl.Execute(builder, machine); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
+// getReadyChecks returns the readiness checks for the machine.
+func getReadyChecks(machine mdb.Machine, logger log.Logger) []string {
+	var readyChecks []string
+	if _, ok := machine.Tags[tagDisruptionManagerReadyUrl]; ok {
+		value, err := expandTag(machine, tagDisruptionManagerReadyUrl)
+		if err != nil {
+			logger.Printf("%s: error expanding [%s]: %s\n",
+				machine.Hostname, tagDisruptionManagerReadyUrl, err)
+		} else if value != "" {
+			readyChecks = append(readyChecks, value)
+		}
+	}
+	if _, ok := machine.Tags[tagDisruptionManagerReadyChecks]; !ok {
+		return readyChecks
+	}
+	value, err := expandTag(machine, tagDisruptionManagerReadyChecks)
+	if err != nil {
+		logger.Printf("%s: error expanding [%s]: %s\n",
+			machine.Hostname, tagDisruptionManagerReadyChecks, err)
+		return readyChecks
+	}
+	return append(readyChecks,
+		splitReadyChecks(machine.Hostname, value, logger)...)
+}
+
+// parseMinimumAvailable returns the minimum number of members of a group
+// which must be available. The value is either a number or a percentage of
+// the members.
+func parseMinimumAvailable(value string, numMembers int) uint64 {
+	if value == "" {
+		return 0
+	}
+	if strings.HasSuffix(value, "%") {
+		percent, err := strconv.ParseUint(strings.TrimSuffix(value, "%"), 10,
+			64)
+		if err != nil {
+			return 0
+		}
+		return (percent*uint64(numMembers) + 99) / 100
+	}
+	minimum, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return minimum
+}
+
+// parseSrpcReadyCheck returns the address and the Health method for an SRPC
+// readiness check of the form srpc://host:port/Service.Health.
+func parseSrpcReadyCheck(readyCheck string) (string, string, error) {
+	address := strings.TrimPrefix(readyCheck, "srpc://")
+	var method string
+	if index := strings.IndexByte(address, '/'); index >= 0 {
+		address, method = address[:index], address[index+1:]
+	}
+	if !strings.HasSuffix(method, ".Health") || len(method) < 8 {
+		return "", "", errors.New("missing Service.Health method: " +
+			readyCheck)
+	}
+	return address, method, nil
+}
+
+// runReadyCheck runs a readiness check and returns nil if it passed. The
+// supported checks are:
+//
+//	http://... or https://...:       a GET request must return a 200 status
+//	tcp://host:port:                 a TCP connection must succeed
+//	srpc://host:port/Service.Health: the Health method must report healthy
+func runReadyCheck(readyCheck string) error {
+	switch {
+	case strings.HasPrefix(readyCheck, "http://"),
+		strings.HasPrefix(readyCheck, "https://"):
+		client := http.Client{Timeout: readyCheckTimeout}
+		resp, err := client.Get(readyCheck)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s: %s", readyCheck, resp.Status)
+		}
+		return nil
+	case strings.HasPrefix(readyCheck, "tcp://"):
+		conn, err := net.DialTimeout("tcp",
+			strings.TrimPrefix(readyCheck, "tcp://"), readyCheckTimeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case strings.HasPrefix(readyCheck, "srpc://"):
+		address, method, err := parseSrpcReadyCheck(readyCheck)
+		if err != nil {
+			return err
+		}
+		client, err := srpc.DialHTTP("tcp", address, readyCheckTimeout)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		var reply dm_proto.HealthResponse
+		err = client.RequestReply(method, dm_proto.HealthRequest{}, &reply)
+		if err != nil {
+			return err
+		}
+		if reply.Error != "" {
+			return fmt.Errorf("%s: %s", readyCheck, reply.Error)
+		}
+		if !reply.Healthy {
+			return fmt.Errorf("%s: not healthy", readyCheck)
+		}
+		return nil
+	}
+	return errors.New("unsupported ready check: " + readyCheck)
+}
+
+// splitReadyChecks splits a list of readiness checks separated by ";".
+// Unsupported checks are logged and skipped.
+func splitReadyChecks(hostname, value string, logger log.Logger) []string {
+	var readyChecks []string
+	for _, readyCheck := range strings.Split(value, ";") {
+		if readyCheck = strings.TrimSpace(readyCheck); readyCheck == "" {
+			continue
+		}
+		if err := checkReadyCheck(readyCheck); err != nil {
+			logger.Printf("%s: %s\n", hostname, err)
+			continue
+		}
+		readyChecks = append(readyChecks, readyCheck)
+	}
+	return readyChecks
+}
+
+// runReadyChecks runs all the readiness checks and returns the first error.
+func runReadyChecks(readyChecks []string) error {
+	for _, readyCheck := range readyChecks {
+		if err := runReadyCheck(readyCheck); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (dm *disruptionManager) healthCheckLoop(interval,
+	memberExpiry time.Duration) {
+	for ; ; time.Sleep(interval) {
+		if !dm.isLeader() {
+			continue
+		}
+		for _, logLine := range dm.healthCheckOnce(memberExpiry) {
+			dm.logger.Println(logLine)
+		}
+	}
+}
+
+// healthCheckOnce runs the readiness checks for all group members and
+// forgets members which have not been seen recently.
+func (dm *disruptionManager) healthCheckOnce(
+	memberExpiry time.Duration) []string {
+	var invalidate bool
+	var memberChecks []*memberCheckType
+	dm.mutex.Lock()
+	expireBefore := time.Now().Add(-memberExpiry)
+	for groupIdentifier, group := range dm.groups {
+		for hostname, member := range group.members {
+			if member.LastSeen.Before(expireBefore) {
+				invalidate = true
+				delete(group.members, hostname)
+				continue
+			}
+			if len(member.ReadyChecks) > 0 {
+				memberChecks = append(memberChecks, &memberCheckType{
+					groupIdentifier: groupIdentifier,
+					hostname:        hostname,
+					readyChecks:     member.ReadyChecks,
+				})
+			}
+		}
+	}
+	dm.unlockAndInvalidate(invalidate)
+	completion := make(chan struct{}, len(memberChecks))
+	for _, memberCheck := range memberChecks {
+		go func(memberCheck *memberCheckType) {
+			memberCheck.err = runReadyChecks(memberCheck.readyChecks)
+			completion <- struct{}{}
+		}(memberCheck)
+	}
+	for range memberChecks {
+		<-completion
+	}
+	invalidate = false
+	var logLines []string
+	var recalculate bool
+	dm.mutex.Lock()
+	defer func() {
+		dm.unlockAndInvalidate(invalidate)
+		if recalculate {
+			sendNotification(dm.recalculateNotifier)
+		}
+	}()
+	for _, memberCheck := range memberChecks {
+		group := dm.groups[memberCheck.groupIdentifier]
+		if group == nil {
+			continue
+		}
+		member := group.members[memberCheck.hostname]
+		if member == nil {
+			continue
+		}
+		groupText := makeGroupText(memberCheck.groupIdentifier)
+		healthy := memberCheck.err == nil
+		if healthy {
+			member.LastError = ""
+		} else {
+			member.LastError = memberCheck.err.Error()
+		}
+		if healthy == member.Healthy {
+			continue
+		}
+		invalidate = true
+		member.Healthy = healthy
+		if healthy {
+			recalculate = true
+			logLines = append(logLines, fmt.Sprintf("%s: healthy (%s)",
+				memberCheck.hostname, groupText))
+		} else {
+			logLines = append(logLines, fmt.Sprintf("%s: unhealthy (%s): %s",
+				memberCheck.hostname, groupText, member.LastError))
+		}
+	}
+	return logLines
+}
+
+func (dm *disruptionManager) startHealthChecks(interval,
+	memberExpiry time.Duration) {
+	go dm.healthCheckLoop(interval, memberExpiry)
+}
+
+// meetsMinimumAvailable returns true if the group would still have the
+// minimum number of available members if the host was disrupted. A member is
+// available if it is healthy and is not permitted to disrupt or waiting to
+// become ready.
+func (group *groupInfoType) meetsMinimumAvailable(hostname string) bool {
+	minimum := parseMinimumAvailable(group.minAvailable, len(group.members))
+	if minimum < 1 {
+		return true
+	}
+	var available uint64
+	for name, member := range group.members {
+		if !member.Healthy || name == hostname {
+			continue
+		}
+		if _, ok := group.permitted[name]; ok {
+			continue
+		}
+		if _, ok := group.waiting[name]; ok {
+			continue
+		}
+		available++
+	}
+	return available >= minimum
+}
+
+// updateMember records the machine as a member of the group. It returns true
+// if the group membership or the readiness checks for the member changed.
+func (group *groupInfoType) updateMember(machine mdb.Machine,
+	logger log.Logger) bool {
+	readyChecks := getReadyChecks(machine, logger)
+	member := group.members[machine.Hostname]
+	if member == nil {
+		group.members[machine.Hostname] = &memberType{
+			Healthy:     len(readyChecks) < 1,
+			LastSeen:    time.Now(),
+			ReadyChecks: readyChecks,
+		}
+		return true
+	}
+	member.LastSeen = time.Now()
+	if stringSlicesEqual(member.ReadyChecks, readyChecks) {
+		return false
+	}
+	member.ReadyChecks = readyChecks
+	if len(readyChecks) < 1 {
+		member.Healthy = true
+		member.LastError = ""
+	}
+	return true
+}
+
+func stringSlicesEqual(left, right []string) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for index, value := range left {
+		if value != right[index] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckReadyCheck(t *testing.T) {
+	tests := []struct {
+		readyCheck string
+		valid      bool
+	}{
+		{"http://host:80/ready", true},
+		{"https://host/ready", true},
+		{"tcp://host:22", true},
+		{"srpc://host:6969/Service.Health", true},
+		{"srpc://host:6969", false},
+		{"srpc://host:6969/Service.Ping", false},
+		{"srpc://host:6969/.Health", false},
+		{"command:true", false},
+		{"ftp://host", false},
+	}
+	for _, test := range tests {
+		err := checkReadyCheck(test.readyCheck)
+		if test.valid && err != nil {
+			t.Errorf("%s: %s", test.readyCheck, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("%s: accepted", test.readyCheck)
+		}
+	}
+}
+
+func TestParseMinimumAvailable(t *testing.T) {
+	tests := []struct {
+		value      string
+		numMembers int
+		want       uint64
+	}{
+		{"", 10, 0},
+		{"3", 10, 3},
+		{"50%", 10, 5},
+		{"50%", 5, 3},
+		{"100%", 4, 4},
+		{"bad", 10, 0},
+		{"bad%", 10, 0},
+	}
+	for _, test := range tests {
+		got := parseMinimumAvailable(test.value, test.numMembers)
+		if got != test.want {
+			t.Errorf("parseMinimumAvailable(%q, %d): %d != %d",
+				test.value, test.numMembers, got, test.want)
+		}
+	}
+}
+
+func TestMeetsMinimumAvailable(t *testing.T) {
+	group := newGroup()
+	group.minAvailable = "2"
+	for _, hostname := range []string{"host-0", "host-1", "host-2"} {
+		group.members[hostname] = &memberType{Healthy: true}
+	}
+	if !group.meetsMinimumAvailable("host-0") {
+		t.Fatal("host-0 should be permitted with 3 healthy members")
+	}
+	group.permitted["host-0"] = time.Now()
+	if group.meetsMinimumAvailable("host-1") {
+		t.Fatal("host-1 should not be permitted while host-0 is disrupted")
+	}
+	delete(group.permitted, "host-0")
+	group.members["host-2"].Healthy = false
+	if group.meetsMinimumAvailable("host-0") {
+		t.Fatal("host-0 should not be permitted while host-2 is unhealthy")
+	}
+	if !group.meetsMinimumAvailable("host-2") {
+		t.Fatal("unhealthy host-2 should be permitted")
+	}
+}
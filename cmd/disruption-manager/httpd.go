@@ -7,12 +7,14 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/format"
 	"github.com/Cloud-Foundations/Dominator/lib/html"
 	libjson "github.com/Cloud-Foundations/Dominator/lib/json"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	dm_proto "github.com/Cloud-Foundations/Dominator/proto/disruptionmanager"
 	sub_proto "github.com/Cloud-Foundations/Dominator/proto/sub"
 )
@@ -99,6 +101,7 @@ func (s *httpServer) serve(portNum uint) error {
 	if err != nil {
 		return err
 	}
+	listener = srpc.NewTlsSniffingListener(listener)
 	return http.Serve(listener, nil)
 }
 
@@ -150,10 +153,63 @@ func (s *httpServer) showStateHandler(w http.ResponseWriter,
 		}
 	}
 	tw.Close()
+	fmt.Fprintln(writer, "<br>")
+	writeGroupAvailability(writer, groupList)
 	fmt.Fprintln(writer, "</center>")
 	fmt.Fprintln(writer, "</body>")
 }
 
+func writeGroupAvailability(writer io.Writer, groupList *groupListType) {
+	fmt.Fprintln(writer, `<table border="1" style="width:100%">`)
+	tw, _ := html.NewTableWriter(writer, true,
+		"Group", "Members", "Healthy", "Available", "Minimum Available",
+		"Unhealthy Members")
+	for _, groupInfo := range groupList.groups {
+		if len(groupInfo.Members) < 1 {
+			continue
+		}
+		disrupting := make(map[string]struct{})
+		for _, hostInfo := range groupInfo.Permitted {
+			disrupting[hostInfo.Hostname] = struct{}{}
+		}
+		for _, waitInfo := range groupInfo.Waiting {
+			disrupting[waitInfo.Hostname] = struct{}{}
+		}
+		var numAvailable, numHealthy uint
+		var unhealthy []string
+		for _, member := range groupInfo.Members {
+			if !member.Healthy {
+				unhealthy = append(unhealthy, member.Hostname)
+				continue
+			}
+			numHealthy++
+			if _, ok := disrupting[member.Hostname]; !ok {
+				numAvailable++
+			}
+		}
+		var minimumAvailable string
+		if groupInfo.MinimumAvailable != "" {
+			minimumAvailable = fmt.Sprintf("%d (%s)",
+				parseMinimumAvailable(groupInfo.MinimumAvailable,
+					len(groupInfo.Members)),
+				groupInfo.MinimumAvailable)
+		}
+		var background string
+		if minimum := parseMinimumAvailable(groupInfo.MinimumAvailable,
+			len(groupInfo.Members)); uint64(numAvailable) < minimum {
+			background = "#ffb0b0"
+		}
+		tw.WriteRow("", background,
+			groupInfo.Identifier,
+			fmt.Sprintf("%d", len(groupInfo.Members)),
+			fmt.Sprintf("%d", numHealthy),
+			fmt.Sprintf("%d", numAvailable),
+			minimumAvailable,
+			strings.Join(unhealthy, " "))
+	}
+	tw.Close()
+}
+
 func (s *httpServer) statusHandler(w http.ResponseWriter, req *http.Request) {
 	if req.URL.Path != "/" {
 		http.NotFound(w, req)
@@ -184,6 +240,9 @@ func (s *httpServer) statusHandler(w http.ResponseWriter, req *http.Request) {
 			groupList.totalWaiting)
 		fmt.Fprintln(writer, `<a href="showState">dashboard</a><br>`)
 	}
+	if rm := s.disruptionManager.replicas; rm != nil {
+		rm.writeHtml(writer)
+	}
 	for _, htmlWriter := range s.htmlWriters {
 		htmlWriter.WriteHtml(writer)
 	}
@@ -9,21 +9,36 @@ import (
 
 	"github.com/Cloud-Foundations/Dominator/lib/constants"
 	"github.com/Cloud-Foundations/Dominator/lib/flags/loadflags"
+	"github.com/Cloud-Foundations/Dominator/lib/flagutil"
 	"github.com/Cloud-Foundations/Dominator/lib/log/serverlogger"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc/setupserver"
 	"github.com/Cloud-Foundations/tricorder/go/tricorder"
 )
 
 var (
+	healthCheckInterval = flag.Duration("healthCheckInterval",
+		30*time.Second, "Interval between readiness checks for group members")
+	leaseDuration = flag.Duration("leaseDuration", 15*time.Second,
+		"Duration of the leadership lease when replicated")
 	maximumPermittedDuration = flag.Duration("maximumPermittedDuration",
 		time.Hour,
 		"Maximum time disruption will be permitted after last request")
+	memberExpiry = flag.Duration("memberExpiry", 30*24*time.Hour,
+		"Time after which group members with no requests are forgotten")
 	portNum = flag.Uint("portNum", constants.DisruptionManagerPortNumber,
 		"Port number to allocate and listen on for HTTP/RPC")
+	replicaName = flag.String("replicaName", "",
+		"Address of this replica (default: hostname:portNum)")
+	replicas flagutil.StringList
 	stateDir = flag.String("stateDir", "/var/lib/disruption-manager",
 		"Name of state directory")
 )
 
+func init() {
+	flag.Var(&replicas, "replicas",
+		"Comma separated list of addresses (host:port) of all replicas")
+}
+
 func showErrorAndDie(err error) {
 	fmt.Fprintln(os.Stderr, err)
 	os.Exit(2)
@@ -46,6 +61,22 @@ func main() {
 	if err != nil {
 		logger.Fatalf("Unable to create Disruption Manager: %s\n", err)
 	}
+	dm.startHealthChecks(*healthCheckInterval, *memberExpiry)
+	if len(replicas) > 0 {
+		myName := *replicaName
+		if myName == "" {
+			hostname, err := os.Hostname()
+			if err != nil {
+				logger.Fatalln(err)
+			}
+			myName = fmt.Sprintf("%s:%d", hostname, *portNum)
+		}
+		_, err := newReplicaManager(dm, myName, replicas, *leaseDuration,
+			logger)
+		if err != nil {
+			logger.Fatalf("Unable to set up replication: %s\n", err)
+		}
+	}
 	err = setupserver.SetupTlsWithParams(setupserver.Params{Logger: logger})
 	if err != nil {
 		logger.Fatalln(err)
@@ -3,14 +3,11 @@ package main
 import (
 	"fmt"
 	"net"
-	"net/http"
 	"os"
 	"path"
 	"sort"
 	"strconv"
-	"strings"
 	"sync"
-	"text/template"
 	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/backoffdelay"
@@ -25,6 +22,8 @@ import (
 const (
 	tagGroupIdentifier               = "DisruptionManagerGroupIdentifier"
 	tagGroupMaximumDisrupting        = "DisruptionManagerGroupMaximumDisrupting"
+	tagGroupMinimumAvailable         = "DisruptionManagerGroupMinimumAvailable"
+	tagDisruptionManagerReadyChecks  = "DisruptionManagerReadyChecks"
 	tagDisruptionManagerReadyTimeout = "DisruptionManagerReadyTimeout"
 	tagDisruptionManagerReadyUrl     = "DisruptionManagerReadyUrl"
 )
@@ -32,6 +31,7 @@ const (
 type disruptionManager struct {
 	logger              log.DebugLogger
 	maxDuration         time.Duration
+	replicas            *replicaManager // nil if standalone.
 	stateFilename       string
 	recalculateNotifier chan<- struct{}
 	writeNotifier       chan<- struct{}
@@ -42,16 +42,20 @@ type disruptionManager struct {
 
 type groupInfoType struct {
 	maxPermitted uint64
+	minAvailable string                   // Number or percentage of members.
+	members      map[string]*memberType   // K: hostname.
 	permitted    map[string]time.Time     // K: hostname, V: last request time.
 	requested    map[string]time.Time     // K: hostname, V: last request time.
 	waiting      map[string]*waitDataType // K: hostname.
 }
 
 type groupStatsType struct {
-	Identifier string
-	Permitted  []hostInfoType `json:",omitempty"`
-	Requested  []hostInfoType `json:",omitempty"`
-	Waiting    []waitInfoType `json:",omitempty"`
+	Identifier       string
+	Members          []memberInfoType `json:",omitempty"`
+	MinimumAvailable string           `json:",omitempty"`
+	Permitted        []hostInfoType   `json:",omitempty"`
+	Requested        []hostInfoType   `json:",omitempty"`
+	Waiting          []waitInfoType   `json:",omitempty"`
 }
 
 type hostInfoType struct {
@@ -69,6 +73,8 @@ type groupListType struct {
 
 type waitDataType struct {
 	finished     bool
+	started      bool
+	ReadyChecks  []string  `json:",omitempty"`
 	ReadyTimeout time.Time `json:",omitempty"`
 	ReadyUrl     string    `json:",omitempty"`
 }
@@ -131,9 +137,7 @@ func newDisruptionManager(stateFilename string,
 	logger log.DebugLogger) (*disruptionManager, error) {
 	recalculateNotifier := make(chan struct{}, 1)
 	writeNotifier := make(chan struct{}, 1)
-	var groupList groupListType
 	dm := &disruptionManager{
-		exportable:          &groupList,
 		groups:              make(map[string]*groupInfoType),
 		logger:              logger,
 		maxDuration:         maximumPermittedDuration,
@@ -142,40 +146,15 @@ func newDisruptionManager(stateFilename string,
 		writeNotifier:       writeNotifier,
 	}
 	if stateFilename != "" {
-		err := json.ReadFromFile(stateFilename, &groupList.groups)
+		var groups []groupStatsType
+		err := json.ReadFromFile(stateFilename, &groups)
 		if err != nil {
 			if !os.IsNotExist(err) {
 				return nil, err
 			}
 		} else {
-			for _, groupStats := range groupList.groups {
-				group := newGroup()
-				dm.groups[groupStats.Identifier] = group
-				for _, host := range groupStats.Permitted {
-					if _, ok := group.permitted[host.Hostname]; !ok {
-						group.permitted[host.Hostname] = host.LastRequest
-						groupList.totalPermitted++
-					}
-				}
-				for _, host := range groupStats.Requested {
-					if _, ok := group.permitted[host.Hostname]; !ok {
-						group.requested[host.Hostname] = host.LastRequest
-						groupList.totalRequested++
-					}
-				}
-				for _, host := range groupStats.Waiting {
-					if _, ok := group.waiting[host.Hostname]; !ok {
-						if !host.ReadyTimeout.IsZero() &&
-							time.Until(host.ReadyTimeout) > 0 {
-							group.waiting[host.Hostname] = &host.waitDataType
-							go host.waitDataType.wait(recalculateNotifier,
-								host.Hostname,
-								makeGroupText(groupStats.Identifier), logger)
-							groupList.totalWaiting++
-						}
-					}
-				}
-			}
+			dm.loadGroups(groups)
+			dm.startWaiters()
 			go dm.writeLoop(writeNotifier)
 		}
 	}
@@ -185,6 +164,10 @@ func newDisruptionManager(stateFilename string,
 
 func (dm *disruptionManager) cancel(machine mdb.Machine) (
 	sub_proto.DisruptionState, string, error) {
+	if state, forwarded, err := dm.forwardToLeader(
+		sub_proto.DisruptionRequestCancel, machine); forwarded {
+		return state, "", err
+	}
 	waitData := makeWaitData(machine, dm.logger)
 	var invalidate bool
 	dm.mutex.Lock()
@@ -192,11 +175,15 @@ func (dm *disruptionManager) cancel(machine mdb.Machine) (
 		dm.unlockAndInvalidate(invalidate)
 	}()
 	group, groupText := dm.getGroup(machine)
+	if group.updateMember(machine, dm.logger) {
+		invalidate = true
+	}
 	var logMessage string
 	if _, ok := group.permitted[machine.Hostname]; ok {
 		invalidate = true
 		if waitData != nil {
 			group.waiting[machine.Hostname] = waitData
+			waitData.started = true
 			go waitData.wait(dm.recalculateNotifier, machine.Hostname,
 				groupText, dm.logger)
 			logMessage = fmt.Sprintf("%s: permitted->denied/waiting (%s)",
@@ -230,6 +217,15 @@ func (dm *disruptionManager) cancel(machine mdb.Machine) (
 }
 
 func (dm *disruptionManager) check(machine mdb.Machine) (
+	sub_proto.DisruptionState, string, error) {
+	if state, forwarded, err := dm.forwardToLeader(
+		sub_proto.DisruptionRequestCheck, machine); forwarded {
+		return state, "", err
+	}
+	return dm.replicatePermitted(dm.checkLocal(machine))
+}
+
+func (dm *disruptionManager) checkLocal(machine mdb.Machine) (
 	sub_proto.DisruptionState, string, error) {
 	var invalidate bool
 	dm.mutex.Lock()
@@ -237,6 +233,9 @@ func (dm *disruptionManager) check(machine mdb.Machine) (
 		dm.unlockAndInvalidate(invalidate)
 	}()
 	group, groupText := dm.getGroup(machine)
+	if group.updateMember(machine, dm.logger) {
+		invalidate = true
+	}
 	if _, ok := group.permitted[machine.Hostname]; ok {
 		return sub_proto.DisruptionStatePermitted, "", nil
 	}
@@ -244,7 +243,7 @@ func (dm *disruptionManager) check(machine mdb.Machine) (
 	if !previouslyRequested {
 		return sub_proto.DisruptionStateDenied, "", nil
 	}
-	if !group.canPermit(machine.Tags) {
+	if !group.canPermit(machine.Hostname, machine.Tags) {
 		return sub_proto.DisruptionStateRequested, "", nil
 	}
 	// Previously requested and now there is room. W00t!
@@ -281,14 +280,26 @@ func (dm *disruptionManager) getGroupList() *groupListType {
 	}
 	var groupList groupListType
 	for groupIdentifier, group := range dm.groups {
-		if len(group.permitted) < 1 &&
+		if len(group.members) < 1 &&
+			len(group.permitted) < 1 &&
 			len(group.requested) < 1 &&
 			len(group.waiting) < 1 {
 			continue
 		}
 		groupStats := groupStatsType{
-			Identifier: groupIdentifier,
+			Identifier:       groupIdentifier,
+			MinimumAvailable: group.minAvailable,
+		}
+		for hostname, member := range group.members {
+			groupStats.Members = append(groupStats.Members, memberInfoType{
+				Hostname:   hostname,
+				memberType: *member,
+			})
 		}
+		sort.SliceStable(groupStats.Members, func(left, right int) bool {
+			return groupStats.Members[left].Hostname <
+				groupStats.Members[right].Hostname
+		})
 		for hostname, lastRequest := range group.permitted {
 			groupStats.Permitted = append(groupStats.Permitted, hostInfoType{
 				Hostname:    hostname,
@@ -320,6 +331,40 @@ func (dm *disruptionManager) getGroupList() *groupListType {
 	return &groupList
 }
 
+// loadGroups replaces the state for all groups. The caller must hold the lock
+// if the manager is in use.
+func (dm *disruptionManager) loadGroups(groups []groupStatsType) {
+	dm.groups = make(map[string]*groupInfoType)
+	for _, groupStats := range groups {
+		group := newGroup()
+		group.minAvailable = groupStats.MinimumAvailable
+		dm.groups[groupStats.Identifier] = group
+		for _, host := range groupStats.Members {
+			member := host.memberType
+			group.members[host.Hostname] = &member
+		}
+		for _, host := range groupStats.Permitted {
+			group.permitted[host.Hostname] = host.LastRequest
+		}
+		for _, host := range groupStats.Requested {
+			if _, ok := group.permitted[host.Hostname]; !ok {
+				group.requested[host.Hostname] = host.LastRequest
+			}
+		}
+		for _, host := range groupStats.Waiting {
+			if _, ok := group.waiting[host.Hostname]; ok {
+				continue
+			}
+			if !host.ReadyTimeout.IsZero() &&
+				time.Until(host.ReadyTimeout) > 0 {
+				waitData := host.waitDataType
+				group.waiting[host.Hostname] = &waitData
+			}
+		}
+	}
+	dm.exportable = nil
+}
+
 func (dm *disruptionManager) recalculateLoop(notifier <-chan struct{}) {
 	for {
 		for _, logLine := range dm.recalculateOnce() {
@@ -340,6 +385,9 @@ func (dm *disruptionManager) recalculateLoop(notifier <-chan struct{}) {
 }
 
 func (dm *disruptionManager) recalculateOnce() []string {
+	if !dm.isLeader() {
+		return nil
+	}
 	var invalidate bool
 	dm.mutex.Lock()
 	defer func() {
@@ -370,7 +418,7 @@ func (dm *disruptionManager) recalculateOnce() []string {
 				delete(group.requested, hostname)
 				dm.logger.Printf("%s: requested/expired->denied (%s)\n",
 					hostname, groupText)
-			} else if group.canPermit(nil) {
+			} else if group.canPermit(hostname, nil) {
 				invalidate = true
 				group.permitted[hostname] = lastRequestTime
 				delete(group.requested, hostname)
@@ -384,16 +432,26 @@ func (dm *disruptionManager) recalculateOnce() []string {
 }
 
 func (dm *disruptionManager) request(machine mdb.Machine) (
+	sub_proto.DisruptionState, string, error) {
+	if state, forwarded, err := dm.forwardToLeader(
+		sub_proto.DisruptionRequestRequest, machine); forwarded {
+		return state, "", err
+	}
+	return dm.replicatePermitted(dm.requestLocal(machine))
+}
+
+func (dm *disruptionManager) requestLocal(machine mdb.Machine) (
 	sub_proto.DisruptionState, string, error) {
 	dm.mutex.Lock()
 	defer dm.unlockAndInvalidate(true)
 	group, groupText := dm.getGroup(machine)
+	group.updateMember(machine, dm.logger)
 	if _, ok := group.permitted[machine.Hostname]; ok {
 		group.permitted[machine.Hostname] = time.Now()
 		return sub_proto.DisruptionStatePermitted, "", nil
 	}
 	var logMessage string
-	if group.canPermit(machine.Tags) {
+	if group.canPermit(machine.Hostname, machine.Tags) {
 		group.permitted[machine.Hostname] = time.Now()
 		if _, ok := group.requested[machine.Hostname]; ok {
 			logMessage = fmt.Sprintf("%s: requested->permitted (%s)",
@@ -413,6 +471,23 @@ func (dm *disruptionManager) request(machine mdb.Machine) (
 	return sub_proto.DisruptionStateRequested, logMessage, nil
 }
 
+// startWaiters starts waiting for readiness for the waiting hosts which are
+// not yet being waited for.
+func (dm *disruptionManager) startWaiters() {
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+	for groupIdentifier, group := range dm.groups {
+		for hostname, waitData := range group.waiting {
+			if waitData.started {
+				continue
+			}
+			waitData.started = true
+			go waitData.wait(dm.recalculateNotifier, hostname,
+				makeGroupText(groupIdentifier), dm.logger)
+		}
+	}
+}
+
 func (dm *disruptionManager) unlockAndInvalidate(invalidate bool) {
 	if invalidate {
 		dm.exportable = nil
@@ -422,6 +497,9 @@ func (dm *disruptionManager) unlockAndInvalidate(invalidate bool) {
 		return
 	}
 	sendNotification(dm.writeNotifier)
+	if dm.replicas != nil {
+		dm.replicas.stateChanged()
+	}
 }
 
 func (dm *disruptionManager) writeLoop(notifier <-chan struct{}) {
@@ -443,19 +521,27 @@ func (dm *disruptionManager) writeOnce() error {
 	return nil
 }
 
-// canPermit returns true if the group can permit more disruption.
-func (group *groupInfoType) canPermit(tgs tags.Tags) bool {
+// canPermit returns true if the group can permit more disruption for the
+// host.
+func (group *groupInfoType) canPermit(hostname string, tgs tags.Tags) bool {
 	maximum, err := strconv.ParseUint(tgs[tagGroupMaximumDisrupting], 10, 64)
 	if err != nil || maximum < 1 {
 		maximum = 1
 	}
 	group.maxPermitted = maximum
-	return uint64(len(group.permitted)+len(group.waiting)) < maximum
+	if tgs != nil {
+		group.minAvailable = tgs[tagGroupMinimumAvailable]
+	}
+	if uint64(len(group.permitted)+len(group.waiting)) >= maximum {
+		return false
+	}
+	return group.meetsMinimumAvailable(hostname)
 }
 
 func newGroup() *groupInfoType {
 	return &groupInfoType{
 		maxPermitted: 1,
+		members:      make(map[string]*memberType),
 		permitted:    make(map[string]time.Time),
 		requested:    make(map[string]time.Time),
 		waiting:      make(map[string]*waitDataType),
@@ -483,22 +569,28 @@ func makeWaitData(machine mdb.Machine, logger log.Logger) *waitDataType {
 		}
 	}
 	if value, ok := machine.Tags[tagDisruptionManagerReadyUrl]; ok {
-		tmpl, err := template.New("").Parse(value)
+		readyUrl, err := expandTag(machine, tagDisruptionManagerReadyUrl)
 		if err != nil {
-			logger.Printf("%s: error parsing [%s]=%s\n",
-				machine.Hostname, tagDisruptionManagerReadyUrl, value)
+			logger.Printf("%s: error expanding [%s]=%s: %s\n",
+				machine.Hostname, tagDisruptionManagerReadyUrl, value, err)
 			return nil
 		}
-		builder := &strings.Builder{}
-		if err := tmpl.Execute(builder, machine); err != nil {
-			logger.Printf("%s: error executing [%s]=%s\n",
-				machine.Hostname, tagDisruptionManagerReadyUrl, value)
+		waitData.ReadyUrl = readyUrl
+	}
+	if value, ok := machine.Tags[tagDisruptionManagerReadyChecks]; ok {
+		readyChecks, err := expandTag(machine, tagDisruptionManagerReadyChecks)
+		if err != nil {
+			logger.Printf("%s: error expanding [%s]=%s: %s\n",
+				machine.Hostname, tagDisruptionManagerReadyChecks, value, err)
 			return nil
 		}
+		waitData.ReadyChecks = splitReadyChecks(machine.Hostname, readyChecks,
+			logger)
+	}
+	if waitData.ReadyUrl != "" || len(waitData.ReadyChecks) > 0 {
 		if waitData.ReadyTimeout.IsZero() {
 			waitData.ReadyTimeout = time.Now().Add(15 * time.Minute)
 		}
-		waitData.ReadyUrl = builder.String()
 		retval = &waitData
 	}
 	return retval
@@ -507,7 +599,12 @@ func makeWaitData(machine mdb.Machine, logger log.Logger) *waitDataType {
 func (wd *waitDataType) wait(recalculateNotifier chan<- struct{},
 	hostname, groupText string, logger log.DebugLogger) {
 	maxDelay := time.Until(wd.ReadyTimeout)
-	if wd.ReadyUrl == "" { // Simple delay.
+	var readyChecks []string
+	if wd.ReadyUrl != "" {
+		readyChecks = append(readyChecks, wd.ReadyUrl)
+	}
+	readyChecks = append(readyChecks, wd.ReadyChecks...)
+	if len(readyChecks) < 1 { // Simple delay.
 		time.Sleep(maxDelay)
 		wd.finished = true
 		logger.Printf("%s: ready delay completed (%s)\n", hostname, groupText)
@@ -520,14 +617,8 @@ func (wd *waitDataType) wait(recalculateNotifier chan<- struct{},
 	}
 	sleeper := backoffdelay.NewExponential(maxInterval>>4, maxInterval, 2)
 	for ; time.Until(wd.ReadyTimeout) > 0; sleeper.Sleep() {
-		resp, err := http.Get(wd.ReadyUrl)
-		if err != nil {
-			logger.Debugf(1, "%s: %s\n", wd.ReadyUrl, err)
-			continue
-		}
-		resp.Body.Close()
-		if resp.StatusCode != http.StatusOK {
-			logger.Debugf(1, "%s: %s\n", wd.ReadyUrl, resp.Status)
+		if err := runReadyChecks(readyChecks); err != nil {
+			logger.Debugf(1, "%s: %s\n", hostname, err)
 			continue
 		}
 		wd.finished = true
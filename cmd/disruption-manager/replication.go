@@ -0,0 +1,554 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/html"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/mdb"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	dm_proto "github.com/Cloud-Foundations/Dominator/proto/disruptionmanager"
+	sub_proto "github.com/Cloud-Foundations/Dominator/proto/sub"
+)
+
+// replicaManager implements leader election and state replication between
+// replicas. The leader holds a lease granted by a majority of the replicas and
+// renews it with periodic heartbeats, which also carry the state to replicas
+// which have not acknowledged the latest version. Only the leader changes the
+// state: the other replicas forward requests to the leader. The leader does
+// not permit disruption until a majority of the replicas have the state, so
+// that a new leader will not permit more disruption after a failover.
+type replicaManager struct {
+	dm                *disruptionManager
+	heartbeatMutex    sync.Mutex // Serialise heartbeats.
+	leaseDuration     time.Duration
+	logger            log.DebugLogger
+	myName            string
+	notifier          chan struct{}
+	peers             []*peerType
+	peersByName       map[string]*peerType
+	sendHeartbeatFunc func(*peerType, dm_proto.ReplicaHeartbeatRequest) (
+		dm_proto.ReplicaHeartbeatResponse, error)
+	mutex           sync.Mutex // Protect everything below.
+	isLeader        bool
+	leaderExpires   time.Time // Lease held by this replica.
+	leaseHolder     string    // Lease granted by this replica.
+	leaseExpires    time.Time // Lease granted by this replica.
+	lastLeaderTime  time.Time // Last time a leader was elected.
+	stateVersion    uint64
+	previousLeader  string
+	heartbeatFailed bool
+}
+
+type peerType struct {
+	clientResource *srpc.ClientResource
+	name           string
+	ackedVersion   uint64    // Protected by replicaManager.mutex.
+	lastContact    time.Time // Protected by replicaManager.mutex.
+	lastError      string    // Protected by replicaManager.mutex.
+}
+
+type heartbeatResult struct {
+	err   error
+	peer  *peerType
+	reply dm_proto.ReplicaHeartbeatResponse
+}
+
+func newReplicaManager(dm *disruptionManager, myName string,
+	replicas []string, leaseDuration time.Duration,
+	logger log.DebugLogger) (*replicaManager, error) {
+	rm := &replicaManager{
+		dm:            dm,
+		leaseDuration: leaseDuration,
+		logger:        logger,
+		myName:        myName,
+		notifier:      make(chan struct{}, 1),
+		peersByName:   make(map[string]*peerType),
+	}
+	rm.sendHeartbeatFunc = rm.sendHeartbeat
+	foundSelf := false
+	for _, replica := range replicas {
+		if replica == myName {
+			foundSelf = true
+			continue
+		}
+		if _, ok := rm.peersByName[replica]; ok {
+			return nil, fmt.Errorf("duplicate replica: %s", replica)
+		}
+		peer := &peerType{
+			clientResource: srpc.NewClientResource("tcp", replica),
+			name:           replica,
+		}
+		rm.peers = append(rm.peers, peer)
+		rm.peersByName[replica] = peer
+	}
+	if !foundSelf {
+		return nil, fmt.Errorf("replica: %s not in list of replicas", myName)
+	}
+	dm.replicas = rm
+	go rm.heartbeatLoop()
+	return rm, nil
+}
+
+func (dm *disruptionManager) encodeState() ([]byte, error) {
+	return json.Marshal(dm.getGroupList().groups)
+}
+
+// forwardToLeader forwards the request to the leader if replication is
+// enabled and this replica is not the leader. It returns true if the request
+// was forwarded.
+func (dm *disruptionManager) forwardToLeader(
+	requestType sub_proto.DisruptionRequest, machine mdb.Machine) (
+	sub_proto.DisruptionState, bool, error) {
+	if dm.replicas == nil || dm.replicas.isLeaderNow() {
+		return sub_proto.DisruptionStateAnytime, false, nil
+	}
+	state, err := dm.replicas.forward(requestType, machine)
+	return state, true, err
+}
+
+func (dm *disruptionManager) isLeader() bool {
+	if dm.replicas == nil {
+		return true
+	}
+	return dm.replicas.isLeaderNow()
+}
+
+func (dm *disruptionManager) loadState(groups []groupStatsType) {
+	dm.mutex.Lock()
+	dm.loadGroups(groups)
+	dm.mutex.Unlock()
+	sendNotification(dm.writeNotifier)
+}
+
+// replicatePermitted waits for the state to be replicated to a majority of
+// the replicas before returning a permitted state.
+func (dm *disruptionManager) replicatePermitted(
+	state sub_proto.DisruptionState, logMessage string, err error) (
+	sub_proto.DisruptionState, string, error) {
+	if err != nil || dm.replicas == nil ||
+		state != sub_proto.DisruptionStatePermitted {
+		return state, logMessage, err
+	}
+	if err := dm.replicas.waitForReplication(); err != nil {
+		return sub_proto.DisruptionStateDenied, logMessage, err
+	}
+	return state, logMessage, nil
+}
+
+func (rm *replicaManager) forward(requestType sub_proto.DisruptionRequest,
+	machine mdb.Machine) (sub_proto.DisruptionState, error) {
+	leader := rm.getLeader()
+	if leader == "" {
+		return sub_proto.DisruptionStateAnytime,
+			errors.New("no leader elected")
+	}
+	peer := rm.peersByName[leader]
+	if peer == nil {
+		return sub_proto.DisruptionStateAnytime,
+			fmt.Errorf("unknown leader: %s", leader)
+	}
+	client, err := peer.clientResource.GetHTTP(nil, rm.leaseDuration)
+	if err != nil {
+		return sub_proto.DisruptionStateAnytime, err
+	}
+	defer client.Put()
+	var errorString string
+	var state sub_proto.DisruptionState
+	switch requestType {
+	case sub_proto.DisruptionRequestCancel:
+		request := dm_proto.DisruptionCancelRequest{MDB: machine}
+		var reply dm_proto.DisruptionCancelResponse
+		err = client.RequestReply("DisruptionManager.Cancel", request, &reply)
+		errorString, state = reply.Error, reply.Response
+	case sub_proto.DisruptionRequestCheck:
+		request := dm_proto.DisruptionCheckRequest{MDB: machine}
+		var reply dm_proto.DisruptionCheckResponse
+		err = client.RequestReply("DisruptionManager.Check", request, &reply)
+		errorString, state = reply.Error, reply.Response
+	case sub_proto.DisruptionRequestRequest:
+		request := dm_proto.DisruptionRequestRequest{MDB: machine}
+		var reply dm_proto.DisruptionRequestResponse
+		err = client.RequestReply("DisruptionManager.Request", request,
+			&reply)
+		errorString, state = reply.Error, reply.Response
+	default:
+		return sub_proto.DisruptionStateAnytime,
+			fmt.Errorf("invalid request: %d", requestType)
+	}
+	if err != nil {
+		client.Close()
+		return sub_proto.DisruptionStateAnytime, err
+	}
+	if errorString != "" {
+		return sub_proto.DisruptionStateAnytime, errors.New(errorString)
+	}
+	return state, nil
+}
+
+// getLeader returns the name of the leader, or the empty string if no leader
+// is known.
+func (rm *replicaManager) getLeader() string {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	return rm.getLeaderWithLock()
+}
+
+func (rm *replicaManager) getLeaderWithLock() string {
+	if rm.isLeader && time.Until(rm.leaderExpires) > 0 {
+		return rm.myName
+	}
+	if rm.leaseHolder != "" && time.Until(rm.leaseExpires) > 0 {
+		return rm.leaseHolder
+	}
+	return ""
+}
+
+// grantLease handles a heartbeat from a candidate (possibly this replica). If
+// a lease is granted and the heartbeat contains state from another replica,
+// the state is returned so that it may be loaded.
+func (rm *replicaManager) grantLease(
+	request dm_proto.ReplicaHeartbeatRequest) (
+	dm_proto.ReplicaHeartbeatResponse, []byte) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	leaseHolder := rm.leaseHolder
+	if time.Until(rm.leaseExpires) <= 0 {
+		leaseHolder = ""
+	}
+	if leaseHolder != "" && leaseHolder != request.Candidate {
+		return dm_proto.ReplicaHeartbeatResponse{
+			Leader:       leaseHolder,
+			StateVersion: rm.stateVersion,
+		}, nil
+	}
+	// Do not elect a new leader which has older state.
+	if leaseHolder == "" && request.StateVersion < rm.stateVersion {
+		return dm_proto.ReplicaHeartbeatResponse{
+			StateVersion: rm.stateVersion,
+		}, nil
+	}
+	rm.leaseHolder = request.Candidate
+	rm.leaseExpires = time.Now().Add(request.LeaseDuration)
+	var state []byte
+	if peer := rm.peersByName[request.Candidate]; peer != nil {
+		peer.lastContact = time.Now()
+		peer.lastError = ""
+		if rm.isLeader {
+			rm.isLeader = false
+			rm.logger.Printf("lost leadership to: %s\n", request.Candidate)
+		}
+		if request.Candidate != rm.previousLeader {
+			rm.previousLeader = request.Candidate
+			rm.lastLeaderTime = time.Now()
+			rm.logger.Printf("following leader: %s\n", request.Candidate)
+		}
+		if len(request.State) > 0 {
+			state = request.State
+			rm.stateVersion = request.StateVersion
+		}
+	}
+	return dm_proto.ReplicaHeartbeatResponse{
+		Granted:      true,
+		StateVersion: rm.stateVersion,
+	}, state
+}
+
+// handleHeartbeat handles a heartbeat from a peer. The candidate must be one of
+// the configured peers and the heartbeat must come from its address.
+func (rm *replicaManager) handleHeartbeat(remoteAddr string,
+	request dm_proto.ReplicaHeartbeatRequest) (
+	dm_proto.ReplicaHeartbeatResponse, error) {
+	if request.Candidate == rm.myName {
+		return dm_proto.ReplicaHeartbeatResponse{},
+			errors.New("heartbeat from self")
+	}
+	peer, ok := rm.peersByName[request.Candidate]
+	if !ok {
+		return dm_proto.ReplicaHeartbeatResponse{},
+			fmt.Errorf("unknown replica: %s", request.Candidate)
+	}
+	peerHost, _, err := net.SplitHostPort(peer.name)
+	if err != nil {
+		return dm_proto.ReplicaHeartbeatResponse{}, err
+	}
+	if err := hostAccessCheck(remoteAddr, peerHost); err != nil {
+		return dm_proto.ReplicaHeartbeatResponse{},
+			fmt.Errorf("heartbeat from: %s: %s", remoteAddr, err)
+	}
+	var groups []groupStatsType
+	if len(request.State) > 0 {
+		if err := json.Unmarshal(request.State, &groups); err != nil {
+			return dm_proto.ReplicaHeartbeatResponse{}, err
+		}
+	}
+	reply, state := rm.grantLease(request)
+	if len(state) > 0 {
+		rm.dm.loadState(groups)
+	}
+	return reply, nil
+}
+
+func (rm *replicaManager) heartbeatLoop() {
+	interval := rm.leaseDuration >> 2
+	for {
+		if rm.shouldHeartbeat() {
+			rm.heartbeatOnce()
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-rm.notifier:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		case <-timer.C:
+		}
+	}
+}
+
+// heartbeatOnce sends a heartbeat to all replicas, to obtain or renew the
+// leadership lease.
+func (rm *replicaManager) heartbeatOnce() {
+	rm.heartbeatMutex.Lock()
+	defer rm.heartbeatMutex.Unlock()
+	startTime := time.Now()
+	rm.mutex.Lock()
+	wasLeader := rm.isLeader
+	request := dm_proto.ReplicaHeartbeatRequest{
+		Candidate:     rm.myName,
+		LeaseDuration: rm.leaseDuration,
+		StateVersion:  rm.stateVersion,
+	}
+	rm.mutex.Unlock()
+	var state []byte
+	if wasLeader {
+		var err error
+		if state, err = rm.dm.encodeState(); err != nil {
+			rm.logger.Printf("error encoding state: %s\n", err)
+		}
+	}
+	numGranted := 0
+	if reply, _ := rm.grantLease(request); reply.Granted {
+		numGranted++
+	}
+	results := make(chan heartbeatResult, len(rm.peers))
+	for _, peer := range rm.peers {
+		peerRequest := request
+		rm.mutex.Lock()
+		if peer.ackedVersion < request.StateVersion {
+			peerRequest.State = state
+		}
+		rm.mutex.Unlock()
+		go func(peer *peerType, request dm_proto.ReplicaHeartbeatRequest) {
+			reply, err := rm.sendHeartbeatFunc(peer, request)
+			results <- heartbeatResult{err: err, peer: peer, reply: reply}
+		}(peer, peerRequest)
+	}
+	resultList := make([]heartbeatResult, 0, len(rm.peers))
+	for range rm.peers {
+		resultList = append(resultList, <-results)
+	}
+	maxVersion := request.StateVersion
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	for _, result := range resultList {
+		if result.err != nil {
+			result.peer.lastError = result.err.Error()
+			continue
+		}
+		result.peer.lastContact = time.Now()
+		result.peer.lastError = ""
+		if result.reply.Granted {
+			numGranted++
+			result.peer.ackedVersion = result.reply.StateVersion
+		}
+		if result.reply.StateVersion > maxVersion {
+			maxVersion = result.reply.StateVersion
+		}
+	}
+	if numGranted > (len(rm.peers)+1)>>1 {
+		rm.leaderExpires = startTime.Add(rm.leaseDuration)
+		rm.heartbeatFailed = false
+		if !rm.isLeader {
+			rm.isLeader = true
+			rm.previousLeader = rm.myName
+			rm.lastLeaderTime = time.Now()
+			// Ensure the state from this replica replaces the state on all
+			// other replicas.
+			rm.stateVersion = maxVersion + 1
+			for _, peer := range rm.peers {
+				peer.ackedVersion = 0
+			}
+			rm.logger.Printf("elected leader with %d of %d votes\n",
+				numGranted, len(rm.peers)+1)
+			go rm.dm.startWaiters()
+			sendNotification(rm.dm.recalculateNotifier)
+			sendNotification(rm.notifier)
+		}
+		return
+	}
+	if rm.isLeader {
+		rm.isLeader = false
+		rm.logger.Printf("lost leadership: only %d of %d votes\n",
+			numGranted, len(rm.peers)+1)
+	}
+	rm.heartbeatFailed = true
+	if rm.leaseHolder == rm.myName {
+		// Release the lease so that another replica may be elected.
+		rm.leaseHolder = ""
+	}
+}
+
+func (rm *replicaManager) isLeaderNow() bool {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	return rm.isLeader && time.Until(rm.leaderExpires) > 0
+}
+
+// numAcknowledged returns the number of replicas (including this replica)
+// which have acknowledged the specified state version.
+func (rm *replicaManager) numAcknowledged(stateVersion uint64) int {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	numAcked := 1
+	for _, peer := range rm.peers {
+		if peer.ackedVersion >= stateVersion {
+			numAcked++
+		}
+	}
+	return numAcked
+}
+
+func (rm *replicaManager) sendHeartbeat(peer *peerType,
+	request dm_proto.ReplicaHeartbeatRequest) (
+	dm_proto.ReplicaHeartbeatResponse, error) {
+	var reply dm_proto.ReplicaHeartbeatResponse
+	client, err := peer.clientResource.GetHTTP(nil, rm.leaseDuration>>2)
+	if err != nil {
+		return reply, err
+	}
+	defer client.Put()
+	err = client.RequestReply("DisruptionManager.ReplicaHeartbeat", request,
+		&reply)
+	if err != nil {
+		client.Close()
+		return reply, err
+	}
+	if reply.Error != "" {
+		return reply, errors.New(reply.Error)
+	}
+	return reply, nil
+}
+
+// shouldHeartbeat returns true if this replica is the leader or if there is
+// no leader. Candidates wait a random time to reduce the chance of split
+// votes.
+func (rm *replicaManager) shouldHeartbeat() bool {
+	rm.mutex.Lock()
+	leader := rm.getLeaderWithLock()
+	isLeader := rm.isLeader
+	heartbeatFailed := rm.heartbeatFailed
+	rm.mutex.Unlock()
+	if isLeader || leader == rm.myName {
+		return true
+	}
+	if leader != "" {
+		return false
+	}
+	if heartbeatFailed {
+		time.Sleep(time.Duration(rand.Int63n(int64(rm.leaseDuration >> 1))))
+	}
+	return true
+}
+
+// stateChanged is called after the state was changed by this replica.
+func (rm *replicaManager) stateChanged() {
+	rm.mutex.Lock()
+	if !rm.isLeader {
+		rm.mutex.Unlock()
+		return
+	}
+	rm.stateVersion++
+	rm.mutex.Unlock()
+	sendNotification(rm.notifier)
+}
+
+// waitForReplication sends heartbeats until a majority of the replicas
+// (including this replica) have acknowledged the current state. It returns an
+// error if this replica is not the leader or if a majority have not
+// acknowledged the state within the lease duration.
+func (rm *replicaManager) waitForReplication() error {
+	rm.mutex.Lock()
+	stateVersion := rm.stateVersion
+	rm.mutex.Unlock()
+	stopTime := time.Now().Add(rm.leaseDuration)
+	for {
+		if !rm.isLeaderNow() {
+			return errors.New("not the leader")
+		}
+		if rm.numAcknowledged(stateVersion) > (len(rm.peers)+1)>>1 {
+			return nil
+		}
+		if time.Until(stopTime) <= 0 {
+			return errors.New("state not replicated to a majority")
+		}
+		rm.heartbeatOnce()
+		if rm.numAcknowledged(stateVersion) <= (len(rm.peers)+1)>>1 {
+			time.Sleep(rm.leaseDuration >> 4)
+		}
+	}
+}
+
+func (rm *replicaManager) writeHtml(writer io.Writer) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	leader := rm.getLeaderWithLock()
+	if leader == "" {
+		fmt.Fprintln(writer,
+			`<font color="red">No leader elected</font><br>`)
+	} else if leader == rm.myName {
+		fmt.Fprintf(writer, "This replica (%s) is the leader, since %s<br>\n",
+			rm.myName, rm.lastLeaderTime.Format(format.TimeFormatSeconds))
+	} else {
+		fmt.Fprintf(writer,
+			"This replica (%s) is following: %s, since %s<br>\n",
+			rm.myName, leader,
+			rm.lastLeaderTime.Format(format.TimeFormatSeconds))
+	}
+	fmt.Fprintf(writer, "State version: %d<br>\n", rm.stateVersion)
+	fmt.Fprintln(writer, `<table border="1">`)
+	tw, _ := html.NewTableWriter(writer, true, "Replica", "Role",
+		"Last Contact", "State Version", "Error")
+	now := time.Now()
+	for _, peer := range rm.peers {
+		role := "follower"
+		if peer.name == leader {
+			role = "leader"
+		}
+		var lastContact, ackedVersion string
+		if !peer.lastContact.IsZero() {
+			lastContact = format.Duration(now.Sub(peer.lastContact)) + " ago"
+		}
+		if rm.isLeader {
+			ackedVersion = fmt.Sprintf("%d", peer.ackedVersion)
+		}
+		var background string
+		if peer.lastError != "" {
+			background = "#ffb0b0"
+		}
+		tw.WriteRow("", background, peer.name, role, lastContact,
+			ackedVersion, peer.lastError)
+	}
+	tw.Close()
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log/nulllogger"
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+	dm_proto "github.com/Cloud-Foundations/Dominator/proto/disruptionmanager"
+	sub_proto "github.com/Cloud-Foundations/Dominator/proto/sub"
+)
+
+const testLeaseDuration = 200 * time.Millisecond
+
+var testReplicaNames = []string{
+	"127.0.0.1:6979",
+	"127.0.0.2:6979",
+	"127.0.0.3:6979",
+}
+
+// testCluster connects replicas by calling the heartbeat handlers directly.
+type testCluster struct {
+	mutex       sync.Mutex
+	partitioned map[string]bool
+	replicas    map[string]*replicaManager
+}
+
+func makeTestCluster(t *testing.T) *testCluster {
+	cluster := &testCluster{
+		partitioned: make(map[string]bool),
+		replicas:    make(map[string]*replicaManager),
+	}
+	for _, myName := range testReplicaNames {
+		dm := &disruptionManager{
+			groups:              make(map[string]*groupInfoType),
+			logger:              nulllogger.New(),
+			maxDuration:         time.Hour,
+			recalculateNotifier: make(chan struct{}, 1),
+			writeNotifier:       make(chan struct{}, 1),
+		}
+		rm := &replicaManager{
+			dm:            dm,
+			leaseDuration: testLeaseDuration,
+			logger:        testlogger.New(t),
+			myName:        myName,
+			notifier:      make(chan struct{}, 1),
+			peersByName:   make(map[string]*peerType),
+		}
+		sender := myName
+		rm.sendHeartbeatFunc = func(peer *peerType,
+			request dm_proto.ReplicaHeartbeatRequest) (
+			dm_proto.ReplicaHeartbeatResponse, error) {
+			return cluster.sendHeartbeat(sender, peer, request)
+		}
+		for _, name := range testReplicaNames {
+			if name != myName {
+				peer := &peerType{name: name}
+				rm.peers = append(rm.peers, peer)
+				rm.peersByName[name] = peer
+			}
+		}
+		dm.replicas = rm
+		cluster.replicas[myName] = rm
+	}
+	return cluster
+}
+
+func (cluster *testCluster) partition(name string) {
+	cluster.mutex.Lock()
+	defer cluster.mutex.Unlock()
+	cluster.partitioned[name] = true
+}
+
+func (cluster *testCluster) sendHeartbeat(sender string, peer *peerType,
+	request dm_proto.ReplicaHeartbeatRequest) (
+	dm_proto.ReplicaHeartbeatResponse, error) {
+	cluster.mutex.Lock()
+	partitioned := cluster.partitioned[sender] ||
+		cluster.partitioned[peer.name]
+	cluster.mutex.Unlock()
+	if partitioned {
+		return dm_proto.ReplicaHeartbeatResponse{},
+			errors.New("partitioned")
+	}
+	return cluster.replicas[peer.name].handleHeartbeat(sender, request)
+}
+
+func makeTestReplicaManager(t *testing.T) *replicaManager {
+	rm := &replicaManager{
+		logger:      testlogger.New(t),
+		myName:      "replica-0:6979",
+		peersByName: make(map[string]*peerType),
+	}
+	for _, name := range []string{"replica-1:6979", "replica-2:6979"} {
+		peer := &peerType{name: name}
+		rm.peers = append(rm.peers, peer)
+		rm.peersByName[name] = peer
+	}
+	return rm
+}
+
+func TestGrantLease(t *testing.T) {
+	rm := makeTestReplicaManager(t)
+	request := dm_proto.ReplicaHeartbeatRequest{
+		Candidate:     "replica-1:6979",
+		LeaseDuration: 50 * time.Millisecond,
+		State:         []byte("[]"),
+		StateVersion:  3,
+	}
+	reply, state := rm.grantLease(request)
+	if !reply.Granted {
+		t.Fatal("lease not granted")
+	}
+	if string(state) != "[]" {
+		t.Fatal("state not returned")
+	}
+	if reply.StateVersion != 3 {
+		t.Fatalf("state version: %d != 3", reply.StateVersion)
+	}
+	if leader := rm.getLeader(); leader != request.Candidate {
+		t.Fatalf("leader: %s != %s", leader, request.Candidate)
+	}
+	// Another candidate must be refused while the lease is held.
+	otherRequest := dm_proto.ReplicaHeartbeatRequest{
+		Candidate:     "replica-2:6979",
+		LeaseDuration: 50 * time.Millisecond,
+		StateVersion:  3,
+	}
+	if reply, _ := rm.grantLease(otherRequest); reply.Granted {
+		t.Fatal("lease granted while held by another replica")
+	} else if reply.Leader != request.Candidate {
+		t.Fatalf("leader: %s != %s", reply.Leader, request.Candidate)
+	}
+	// Once the lease has expired, a candidate with older state is refused.
+	time.Sleep(100 * time.Millisecond)
+	if leader := rm.getLeader(); leader != "" {
+		t.Fatalf("leader: %s after lease expired", leader)
+	}
+	otherRequest.StateVersion = 2
+	if reply, _ := rm.grantLease(otherRequest); reply.Granted {
+		t.Fatal("lease granted to replica with older state")
+	}
+	otherRequest.StateVersion = 3
+	if reply, _ := rm.grantLease(otherRequest); !reply.Granted {
+		t.Fatal("lease not granted after expiry")
+	}
+}
+
+func TestFailover(t *testing.T) {
+	cluster := makeTestCluster(t)
+	leader := cluster.replicas[testReplicaNames[0]]
+	leader.heartbeatOnce()
+	if !leader.isLeaderNow() {
+		t.Fatal("leader not elected")
+	}
+	state, _, err := leader.dm.request(machine0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != sub_proto.DisruptionStatePermitted {
+		t.Fatalf("machine0 state: %s", state)
+	}
+	follower := cluster.replicas[testReplicaNames[1]]
+	if state, _, _ := follower.dm.checkLocal(machine0); state !=
+		sub_proto.DisruptionStatePermitted {
+		t.Fatalf("machine0 state not replicated: %s", state)
+	}
+	// Fail the leader and elect a new leader once the lease has expired.
+	cluster.partition(leader.myName)
+	time.Sleep(testLeaseDuration + 50*time.Millisecond)
+	follower.heartbeatOnce()
+	if !follower.isLeaderNow() {
+		t.Fatal("new leader not elected")
+	}
+	// The new leader must not permit more disruption than the old leader.
+	state, _, err = follower.dm.request(machine1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != sub_proto.DisruptionStateRequested {
+		t.Fatalf("machine1 state: %s", state)
+	}
+}
+
+func TestPermitRequiresMajority(t *testing.T) {
+	cluster := makeTestCluster(t)
+	leader := cluster.replicas[testReplicaNames[0]]
+	leader.heartbeatOnce()
+	if !leader.isLeaderNow() {
+		t.Fatal("leader not elected")
+	}
+	cluster.partition(testReplicaNames[1])
+	cluster.partition(testReplicaNames[2])
+	state, _, err := leader.dm.request(machine0)
+	if err == nil {
+		t.Fatal("disruption permitted without replication to a majority")
+	}
+	if state == sub_proto.DisruptionStatePermitted {
+		t.Fatalf("machine0 state: %s", state)
+	}
+}
+
+func TestHeartbeatFromNonPeer(t *testing.T) {
+	cluster := makeTestCluster(t)
+	rm := cluster.replicas[testReplicaNames[0]]
+	request := dm_proto.ReplicaHeartbeatRequest{
+		Candidate:     "127.0.0.9:6979",
+		LeaseDuration: time.Minute,
+	}
+	if _, err := rm.handleHeartbeat(request.Candidate, request); err == nil {
+		t.Fatal("heartbeat from unknown replica accepted")
+	}
+	// A peer name claimed from a different address must be refused.
+	request.Candidate = testReplicaNames[1]
+	if _, err := rm.handleHeartbeat("127.0.0.9:1234", request); err == nil {
+		t.Fatal("heartbeat from impersonated replica accepted")
+	}
+	if leader := rm.getLeader(); leader != "" {
+		t.Fatalf("lease granted to: %s", leader)
+	}
+	reply, err := rm.handleHeartbeat("127.0.0.2:1234", request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reply.Granted {
+		t.Fatal("lease not granted to peer")
+	}
+}
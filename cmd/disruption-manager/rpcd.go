@@ -85,3 +85,17 @@ func (t *rpcType) Request(conn *srpc.Conn,
 	}
 	return nil
 }
+
+func (t *rpcType) ReplicaHeartbeat(conn *srpc.Conn,
+	request dm_proto.ReplicaHeartbeatRequest,
+	reply *dm_proto.ReplicaHeartbeatResponse) error {
+	if t.disruptionManager.replicas == nil {
+		reply.Error = "replication not enabled"
+		return nil
+	}
+	response, err := t.disruptionManager.replicas.handleHeartbeat(
+		conn.RemoteAddr(), request)
+	*reply = response
+	reply.Error = errors.ErrorToString(err)
+	return nil
+}
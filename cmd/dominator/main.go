@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/Cloud-Foundations/Dominator/dom/herd"
+	"github.com/Cloud-Foundations/Dominator/dom/journal"
 	"github.com/Cloud-Foundations/Dominator/dom/rpcd"
 	"github.com/Cloud-Foundations/Dominator/lib/constants"
 	"github.com/Cloud-Foundations/Dominator/lib/flags/loadflags"
@@ -19,12 +20,16 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/mdb/mdbd"
 	objectserver "github.com/Cloud-Foundations/Dominator/lib/objectserver/filesystem"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc/auditlog"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc/setupserver"
 	"github.com/Cloud-Foundations/Dominator/lib/wsyscall"
 	"github.com/Cloud-Foundations/tricorder/go/tricorder"
 )
 
-const dirPerms = syscall.S_IRWXU
+const (
+	dirPerms               = syscall.S_IRWXU
+	maintenanceWindowsFile = "maintenance-windows.json"
+)
 
 var (
 	debug = flag.Bool("debug", false,
@@ -48,6 +53,12 @@ var (
 		"Port number to allocate and listen on for HTTP/RPC")
 	stateDir = flag.String("stateDir", "/var/lib/Dominator",
 		"Name of dominator state directory.")
+	updateJournalDir = flag.String("updateJournalDir", "update-journal",
+		"Directory for the journal of updates sent to subs, relative to stateDir. If empty, no journal is kept")
+	updateJournalMaxAge = flag.Duration("updateJournalMaxAge",
+		90*24*time.Hour, "Maximum age of update journal entries")
+	updateJournalMaxEntries = flag.Uint("updateJournalMaxEntries", 1000,
+		"Maximum number of update journal entries per sub")
 )
 
 func showMdb(mdb *mdb.Mdb) {
@@ -105,6 +116,9 @@ func main() {
 			logger.Fatalln(err)
 		}
 	}
+	if err := auditlog.Setup(logger); err != nil {
+		logger.Fatalf("Cannot set up audit log: %s\n", err)
+	}
 	rlim := syscall.Rlimit{Cur: *fdLimit, Max: *fdLimit}
 	if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &rlim); err != nil {
 		fmt.Fprintf(os.Stderr, "Cannot set FD limit: %s\n", err)
@@ -135,6 +149,25 @@ func main() {
 	herd := herd.NewHerd(fmt.Sprintf("%s:%d", *imageServerHostname,
 		*imageServerPortNum), objectServer, metricsDir, logger)
 	herd.AddHtmlWriter(logger)
+	err = herd.LoadMaintenanceWindows(path.Join(*stateDir,
+		maintenanceWindowsFile))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot load maintenance windows: %s\n", err)
+		os.Exit(1)
+	}
+	if *updateJournalDir != "" {
+		updateJournal, err := journal.New(journal.Options{
+			Directory:        path.Join(*stateDir, *updateJournalDir),
+			MaxAge:           *updateJournalMaxAge,
+			MaxEntriesPerSub: *updateJournalMaxEntries,
+		},
+			journal.Params{Logger: logger})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot open update journal: %s\n", err)
+			os.Exit(1)
+		}
+		herd.SetUpdateJournal(updateJournal)
+	}
 	rpcd.Setup(herd, logger)
 	if err = herd.StartServer(*portNum, true); err != nil {
 		fmt.Fprintf(os.Stderr, "Unable to create http server: %s\n", err)
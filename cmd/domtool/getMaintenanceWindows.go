@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+func getMaintenanceWindowsSubcommand(args []string,
+	logger log.DebugLogger) error {
+	if err := getMaintenanceWindows(getClient()); err != nil {
+		return fmt.Errorf("error getting maintenance windows: %s", err)
+	}
+	return nil
+}
+
+func getMaintenanceWindows(client *srpc.Client) error {
+	var request dominator.GetMaintenanceWindowsRequest
+	var reply dominator.GetMaintenanceWindowsResponse
+	if err := client.RequestReply("Dominator.GetMaintenanceWindows", request,
+		&reply); err != nil {
+		return err
+	}
+	return json.WriteWithIndent(os.Stdout, "    ", reply)
+}
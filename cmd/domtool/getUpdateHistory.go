@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+func getUpdateHistorySubcommand(args []string, logger log.DebugLogger) error {
+	if err := getUpdateHistory(getClient()); err != nil {
+		return fmt.Errorf("error getting update history: %s", err)
+	}
+	return nil
+}
+
+func getUpdateHistory(client *srpc.Client) error {
+	request := dominator.GetUpdateHistoryRequest{
+		Hostname:   *host,
+		MaxAge:     *maxUpdateHistoryAge,
+		MaxEntries: *maxUpdateHistoryEntries,
+		PathName:   *pathName,
+	}
+	var reply dominator.GetUpdateHistoryResponse
+	if err := client.RequestReply("Dominator.GetUpdateHistory", request,
+		&reply); err != nil {
+		return err
+	}
+	if err := errors.New(reply.Error); err != nil {
+		return err
+	}
+	for _, entry := range reply.Entries {
+		fmt.Printf("%s %s %s\n", entry.Time.Format("2006-01-02 15:04:05 MST"),
+			entry.Hostname, entry.ImageName)
+		for _, pathName := range entry.PathsChanged {
+			fmt.Printf("    changed: %s\n", pathName)
+		}
+		for _, pathName := range entry.PathsDeleted {
+			fmt.Printf("    deleted: %s\n", pathName)
+		}
+		if len(entry.Triggers) > 0 {
+			fmt.Printf("    triggers: %s\n", strings.Join(entry.Triggers, " "))
+		}
+	}
+	return nil
+}
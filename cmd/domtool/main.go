@@ -25,7 +25,13 @@ var (
 		"Hostname of dominator")
 	domPortNum = flag.Uint("domPortNum", constants.DominatorPortNumber,
 		"Port number of dominator")
-	locationsToMatch  flagutil.StringList
+	host = flag.String("host", "",
+		"Hostname of sub to get update history for (default all)")
+	locationsToMatch    flagutil.StringList
+	maxUpdateHistoryAge = flag.Duration("maxUpdateHistoryAge", 0,
+		"Maximum age of update history entries to get (default all)")
+	maxUpdateHistoryEntries = flag.Uint("maxUpdateHistoryEntries", 0,
+		"Maximum number of update history entries to get (default all)")
 	mdbServerHostname = flag.String("mdbServerHostname", "",
 		"Hostname of MDB server (default same as domHostname)")
 	mdbServerPortNum = flag.Uint("mdbServerPortNum",
@@ -34,6 +40,8 @@ var (
 	networkSpeedPercent = flag.Uint("networkSpeedPercent",
 		constants.DefaultNetworkSpeedPercent,
 		"Network speed as percentage of capacity")
+	pathName = flag.String("path", "",
+		"Path (file or directory) to get update history for (default all)")
 	pauseDuration = flag.Duration("pauseDuration", time.Hour,
 		"Duration to pause updates for sub")
 	scanExcludeList  flagutil.StringList = constants.ScanExcludeList
@@ -79,13 +87,18 @@ var subcommands = []commands.Command{
 	{"get-default-image", "", 0, 0, getDefaultImageSubcommand},
 	{"get-info-for-subs", "", 0, 0, getInfoForSubsSubcommand},
 	{"get-machine-from-mdb", "sub", 1, 1, getMachineMdbSubcommand},
+	{"get-maintenance-windows", "", 0, 0, getMaintenanceWindowsSubcommand},
 	{"get-mdb", "", 0, 0, getMdbSubcommand},
 	{"get-mdb-updates", "", 0, 0, getMdbUpdatesSubcommand},
 	{"get-subs-configuration", "", 0, 0, getSubsConfigurationSubcommand},
+	{"get-update-history", "", 0, 0, getUpdateHistorySubcommand},
 	{"list-subs", "", 0, 0, listSubsSubcommand},
 	{"pause-sub-updates", "sub reason", 2, 2, pauseSubUpdatesSubcommand},
 	{"resume-sub-updates", "sub", 1, 1, resumeSubUpdatesSubcommand},
 	{"set-default-image", "", 1, 1, setDefaultImageSubcommand},
+	{"set-maintenance-windows", "policy [window...]", 1, -1,
+		setMaintenanceWindowsSubcommand},
+	{"show-drift", "sub", 1, 1, showDriftSubcommand},
 }
 
 func getClient() *srpc.Client {
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+func setMaintenanceWindowsSubcommand(args []string,
+	logger log.DebugLogger) error {
+	if err := setMaintenanceWindows(getClient(), args[0],
+		args[1:]); err != nil {
+		return fmt.Errorf("error setting maintenance windows: %s", err)
+	}
+	return nil
+}
+
+func setMaintenanceWindows(client *srpc.Client, policy string,
+	windows []string) error {
+	request := dominator.SetMaintenanceWindowsRequest{
+		Policy:  policy,
+		Windows: windows,
+	}
+	var reply dominator.SetMaintenanceWindowsResponse
+	if err := client.RequestReply("Dominator.SetMaintenanceWindows", request,
+		&reply); err != nil {
+		return err
+	}
+	return nil
+}
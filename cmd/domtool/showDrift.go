@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+func showDriftSubcommand(args []string, logger log.DebugLogger) error {
+	if err := showDrift(getClient(), args[0]); err != nil {
+		return fmt.Errorf("error showing drift: %s", err)
+	}
+	return nil
+}
+
+func showDrift(client *srpc.Client, subHostname string) error {
+	request := dominator.GetSubDriftRequest{Hostname: subHostname}
+	var reply dominator.GetSubDriftResponse
+	if err := client.RequestReply("Dominator.GetSubDrift", request,
+		&reply); err != nil {
+		return err
+	}
+	if err := errors.New(reply.Error); err != nil {
+		return err
+	}
+	if reply.Drift == nil {
+		fmt.Fprintln(os.Stderr, "No drift recorded")
+		return nil
+	}
+	return json.WriteWithIndent(os.Stdout, "    ", reply.Drift)
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/log/serverlogger"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc/auditlog"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc/proxy"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc/setupserver"
 	"github.com/Cloud-Foundations/tricorder/go/tricorder"
@@ -83,6 +84,9 @@ func main() {
 	if err := setupserver.SetupTlsWithParams(params); err != nil {
 		logger.Fatalln(err)
 	}
+	if err := auditlog.Setup(logger); err != nil {
+		logger.Fatalf("Cannot set up audit log: %s\n", err)
+	}
 	if err := proxy.New(logger); err != nil {
 		logger.Fatalln(err)
 	}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/blobstore"
+)
+
+// createBackupStore returns the store for VM backups, or nil if backups are
+// not configured.
+func createBackupStore() (blobstore.Store, error) {
+	if *backupS3Bucket != "" {
+		return blobstore.NewS3Store(blobstore.S3Options{
+			Bucket:   *backupS3Bucket,
+			Endpoint: *backupS3Endpoint,
+			Prefix:   *backupS3Prefix,
+			Region:   *backupS3Region,
+		})
+	}
+	if *backupDirectory != "" {
+		return blobstore.NewDirectoryStore(*backupDirectory)
+	}
+	return nil, nil
+}
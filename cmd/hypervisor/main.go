@@ -23,6 +23,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/log/serverlogger"
 	"github.com/Cloud-Foundations/Dominator/lib/net"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc/auditlog"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc/setupserver"
 	"github.com/Cloud-Foundations/tricorder/go/tricorder"
 )
@@ -33,6 +34,16 @@ const (
 )
 
 var (
+	backupDirectory = flag.String("backupDirectory", "",
+		"Directory to store VM backups in (local alternative to S3)")
+	backupS3Bucket = flag.String("backupS3Bucket", "",
+		"S3 bucket to store VM backups in")
+	backupS3Endpoint = flag.String("backupS3Endpoint", "",
+		"Optional endpoint for S3-compatible backup storage")
+	backupS3Prefix = flag.String("backupS3Prefix", "",
+		"Prefix for keys of VM backups in the S3 bucket")
+	backupS3Region = flag.String("backupS3Region", "",
+		"Region of the S3 bucket for VM backups")
 	dhcpServerOnBridgesOnly = flag.Bool("dhcpServerOnBridgesOnly", false,
 		"If true, run the DHCP server on bridge interfaces only")
 	imageServerHostname = flag.String("imageServerHostname", "localhost",
@@ -126,6 +137,9 @@ func run() {
 	if err := setupserver.SetupTlsWithParams(params); err != nil {
 		logger.Fatalln(err)
 	}
+	if err := auditlog.Setup(logger); err != nil {
+		logger.Fatalf("Cannot set up audit log: %s\n", err)
+	}
 	if err := os.MkdirAll(*stateDir, dirPerms); err != nil {
 		logger.Fatalf("Cannot create state directory: %s\n", err)
 	}
@@ -181,7 +195,12 @@ func run() {
 	if err != nil {
 		logger.Fatalf("Cannot start tftpboot server: %s\n", err)
 	}
+	backupStore, err := createBackupStore()
+	if err != nil {
+		logger.Fatalf("Cannot create backup store: %s\n", err)
+	}
 	managerObj, err := manager.New(manager.StartOptions{
+		BackupStore:          backupStore,
 		BridgeMap:            bridgeMap,
 		DhcpServer:           dhcpServer,
 		ImageServerAddress:   imageServerAddress,
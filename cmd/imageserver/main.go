@@ -14,6 +14,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/log/serverlogger"
 	"github.com/Cloud-Foundations/Dominator/lib/objectserver/filesystem"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc/auditlog"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc/setupserver"
 	objectserverRpcd "github.com/Cloud-Foundations/Dominator/objectserver/rpcd"
 	"github.com/Cloud-Foundations/tricorder/go/healthserver"
@@ -75,6 +76,9 @@ func main() {
 			logger.Fatalln(err)
 		}
 	}
+	if err := auditlog.Setup(logger); err != nil {
+		logger.Fatalf("Cannot set up audit log: %s\n", err)
+	}
 	objSrv, err := filesystem.NewObjectServerWithConfigAndParams(
 		filesystem.Config{
 			BaseDirectory:     *objectDir,
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/imageserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	objectclient "github.com/Cloud-Foundations/Dominator/lib/objectserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/oci"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+)
+
+func addImageOciSubcommand(args []string, logger log.DebugLogger) error {
+	imageSClient, objectClient := getClients()
+	err := addImageOci(imageSClient, objectClient, args[0], args[1], args[2],
+		args[3], logger)
+	if err != nil {
+		return fmt.Errorf("error adding image: \"%s\": %s", args[0], err)
+	}
+	return nil
+}
+
+func addImageOci(imageSClient *srpc.Client,
+	objectClient *objectclient.ObjectClient,
+	name, ociPathname, filterFilename, triggersFilename string,
+	logger log.DebugLogger) error {
+	imageExists, err := client.CheckImage(imageSClient, name)
+	if err != nil {
+		return errors.New("error checking for image existence: " + err.Error())
+	}
+	if imageExists {
+		return errors.New("image exists")
+	}
+	newImage := new(image.Image)
+	if err := loadImageFiles(newImage, objectClient, filterFilename,
+		triggersFilename); err != nil {
+		return err
+	}
+	ociImage, err := oci.Open(ociPathname, *ociReference)
+	if err != nil {
+		return err
+	}
+	defer ociImage.Close()
+	var h hasher
+	h.objQ, err = objectclient.NewObjectAdderQueue(imageSClient)
+	if err != nil {
+		return err
+	}
+	startTime := time.Now()
+	newImage.FileSystem, newImage.Packages, err = ociImage.FileSystem(&h,
+		newImage.Filter)
+	if err != nil {
+		h.objQ.Close()
+		return errors.New("error building image: " + err.Error())
+	}
+	if err := h.objQ.Close(); err != nil {
+		return err
+	}
+	logger.Debugf(0, "Merged %d layers and uploaded %s of file data in %s\n",
+		ociImage.NumLayers(),
+		format.FormatBytes(newImage.FileSystem.TotalDataBytes),
+		format.Duration(time.Since(startTime)))
+	if err := spliceComputedFiles(newImage.FileSystem); err != nil {
+		return err
+	}
+	if err := copyMtimes(imageSClient, newImage, *copyMtimesFrom); err != nil {
+		return err
+	}
+	return addImage(imageSClient, name, newImage, logger)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"github.com/Cloud-Foundations/Dominator/lib/image/sbom"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func diffImageSBOMsSubcommand(args []string, logger log.DebugLogger) error {
+	if err := diffImageSBOMs(args[0], args[1], args[2]); err != nil {
+		return fmt.Errorf("error diffing SBOMs: %s", err)
+	}
+	return nil
+}
+
+func diffImageSBOMs(tool, leftName, rightName string) error {
+	leftFile, err := writeSBOMListToTempfile(leftName)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(leftFile)
+	rightFile, err := writeSBOMListToTempfile(rightName)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(rightFile)
+	cmd := exec.Command(tool, leftFile, rightFile)
+	cmd.Stdout = os.Stdout
+	return cmd.Run()
+}
+
+func writeSBOMListToTempfile(typedName string) (string, error) {
+	reader, err := getTypedImageSBOMReader(typedName)
+	if err != nil {
+		return "", err
+	}
+	doc, err := sbom.Read(reader)
+	reader.Close()
+	if err != nil {
+		return "", err
+	}
+	file, err := ioutil.TempFile("", "imagetool-diff")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	if err := doc.List(writer); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	if err := writer.Flush(); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem/util"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/objectserver"
+	objectclient "github.com/Cloud-Foundations/Dominator/lib/objectserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/oci"
+)
+
+func exportOciSubcommand(args []string, logger log.DebugLogger) error {
+	_, objectClient := getClients()
+	if err := exportOci(objectClient, args[0], args[1], logger); err != nil {
+		return fmt.Errorf("error exporting image: %s", err)
+	}
+	return nil
+}
+
+func exportOci(objectClient *objectclient.ObjectClient, imageName,
+	output string, logger log.DebugLogger) error {
+	img, err := getTypedImage(imageName)
+	if err != nil {
+		return err
+	}
+	architecture := *ociArchitecture
+	if architecture == "" {
+		architecture = img.Tags["Architecture"]
+	}
+	if architecture == "" {
+		return errors.New(
+			"unknown architecture: specify -ociArchitecture or tag the image")
+	}
+	var objectsGetter objectserver.ObjectsGetter = objectClient
+	if *computedFilesRoot != "" {
+		objectsGetter, err = util.ReplaceComputedFiles(img.FileSystem,
+			&util.ComputedFilesData{RootDirectory: *computedFilesRoot},
+			objectClient)
+		if err != nil {
+			return err
+		}
+	}
+	var layerFileSystems []*filesystem.FileSystem
+	if *ociDiffLayer {
+		if img.SourceImage == "" {
+			return errors.New("image has no source image")
+		}
+		sourceImage, err := getTypedImage("i:" + img.SourceImage)
+		if err != nil {
+			return err
+		}
+		diffFs, err := oci.DiffFileSystems(sourceImage.FileSystem,
+			img.FileSystem)
+		if err != nil {
+			return err
+		}
+		layerFileSystems = []*filesystem.FileSystem{sourceImage.FileSystem,
+			diffFs}
+	} else {
+		layerFileSystems = []*filesystem.FileSystem{img.FileSystem}
+	}
+	isArchive := strings.HasSuffix(output, ".tar")
+	layoutDir := output
+	if isArchive {
+		layoutDir, err = ioutil.TempDir("", "imagetool.oci")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(layoutDir)
+	}
+	layoutWriter, err := oci.NewLayoutWriter(layoutDir)
+	if err != nil {
+		return err
+	}
+	layers := make([]oci.Layer, 0, len(layerFileSystems))
+	for _, fs := range layerFileSystems {
+		layer, err := layoutWriter.AddLayer(fs, objectsGetter)
+		if err != nil {
+			return err
+		}
+		logger.Debugf(0, "Wrote layer: %s (%d bytes)\n",
+			layer.Digest, layer.Size)
+		layers = append(layers, layer)
+	}
+	err = layoutWriter.AddImage(oci.NewConfig(img, architecture), layers,
+		*ociReference)
+	if err != nil {
+		return err
+	}
+	if err := layoutWriter.Close(); err != nil {
+		return err
+	}
+	if !isArchive {
+		return nil
+	}
+	return writeOciArchive(output, layoutDir)
+}
+
+func writeOciArchive(filename, layoutDir string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	if err := oci.WriteArchive(writer, layoutDir); err != nil {
+		file.Close()
+		os.Remove(filename)
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		os.Remove(filename)
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func getImageSBOMSubcommand(args []string, logger log.DebugLogger) error {
+	var outFileName string
+	if len(args) > 1 {
+		outFileName = args[1]
+	}
+	if err := getImageSBOM(args[0], outFileName); err != nil {
+		return fmt.Errorf("error getting image SBOM: %s", err)
+	}
+	return nil
+}
+
+func getImageSBOM(imageName, outFileName string) error {
+	reader, err := getTypedImageSBOMReader(imageName)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	if outFileName == "" {
+		_, err := io.Copy(os.Stdout, reader)
+		return err
+	} else {
+		return fsutil.CopyToFile(outFileName, filePerms, reader, 0)
+	}
+}
@@ -51,6 +51,7 @@ type typedImage struct {
 	image      *image.Image
 	imageName  string
 	imageType  uint
+	sbom       *image.Annotation
 	specifier  string
 	triggers   *triggers.Triggers
 }
@@ -155,29 +156,7 @@ func getTypedImageBuildLogReader(typedName string) (io.ReadCloser, error) {
 	if err != nil {
 		return nil, err
 	}
-	if hashPtr := buildLog.Object; hashPtr != nil {
-		_, objectClient := getClients()
-		_, r, err := objectClient.GetObject(*hashPtr)
-		if err != nil {
-			return nil, err
-		}
-		return r, nil
-	} else if buildLog.URL != "" {
-		resp, err := http.Get(buildLog.URL)
-		if err != nil {
-			return nil, err
-		}
-		if resp.StatusCode != http.StatusOK {
-			return nil, errors.New(resp.Status)
-		}
-		if resp.ContentLength > 0 {
-			return &readCloser{resp.Body,
-				&io.LimitedReader{resp.Body, resp.ContentLength}}, nil
-		}
-		return resp.Body, nil
-	} else {
-		return nil, errors.New("no build log data")
-	}
+	return openAnnotation(buildLog, "build log")
 }
 
 func getTypedImageFilter(typedName string) (*filter.Filter, error) {
@@ -210,6 +189,31 @@ func getTypedImageMetadata(typedName string) (*image.Image, error) {
 	return img, nil
 }
 
+func getTypedImageSBOM(typedName string) (*image.Annotation, error) {
+	ti, err := makeTypedImage(typedName)
+	if err != nil {
+		return nil, err
+	}
+	if err := ti.loadMetadata(); err != nil {
+		return nil, err
+	}
+	sbom, err := ti.getSBOM()
+	if err != nil {
+		return nil, err
+	}
+	return sbom, nil
+}
+
+// getTypedImageSBOMReader returns an SBOM reader. The reader must be closed
+// before the next call to getTypedImageSBOMReader.
+func getTypedImageSBOMReader(typedName string) (io.ReadCloser, error) {
+	sbom, err := getTypedImageSBOM(typedName)
+	if err != nil {
+		return nil, err
+	}
+	return openAnnotation(sbom, "SBOM")
+}
+
 func getTypedImageTriggers(typedName string) (*triggers.Triggers, error) {
 	ti, err := makeTypedImage(typedName)
 	if err != nil {
@@ -270,6 +274,33 @@ func makeTypedImage(typedName string) (*typedImage, error) {
 	return retval, nil
 }
 
+func openAnnotation(annotation *image.Annotation, name string) (
+	io.ReadCloser, error) {
+	if hashPtr := annotation.Object; hashPtr != nil {
+		_, objectClient := getClients()
+		_, r, err := objectClient.GetObject(*hashPtr)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	} else if annotation.URL != "" {
+		resp, err := http.Get(annotation.URL)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, errors.New(resp.Status)
+		}
+		if resp.ContentLength > 0 {
+			return &readCloser{resp.Body,
+				&io.LimitedReader{resp.Body, resp.ContentLength}}, nil
+		}
+		return resp.Body, nil
+	} else {
+		return nil, errors.New("no " + name + " data")
+	}
+}
+
 func (rc *readCloser) Close() error {
 	return rc.closer.Close()
 }
@@ -339,6 +370,7 @@ func (ti *typedImage) load() error {
 			return err
 		}
 		ti.buildLog = img.BuildLog
+		ti.sbom = img.SBOM
 		ti.fileSystem = img.FileSystem
 		ti.filter = img.Filter
 		ti.image = img
@@ -351,6 +383,7 @@ func (ti *typedImage) load() error {
 			return err
 		}
 		ti.buildLog = img.BuildLog
+		ti.sbom = img.SBOM
 		ti.fileSystem = img.FileSystem
 		ti.filter = img.Filter
 		ti.image = img
@@ -362,6 +395,7 @@ func (ti *typedImage) load() error {
 			return err
 		}
 		ti.buildLog = img.BuildLog
+		ti.sbom = img.SBOM
 		ti.fileSystem = img.FileSystem
 		ti.filter = img.Filter
 		ti.image = img
@@ -392,6 +426,7 @@ func (ti *typedImage) loadMetadata() error {
 			return err
 		}
 		ti.buildLog = img.BuildLog
+		ti.sbom = img.SBOM
 		ti.filter = img.Filter
 		ti.image = img
 		ti.triggers = img.Triggers
@@ -402,6 +437,7 @@ func (ti *typedImage) loadMetadata() error {
 			return err
 		}
 		ti.buildLog = img.BuildLog
+		ti.sbom = img.SBOM
 		ti.filter = img.Filter
 		ti.image = img
 		ti.imageName = name
@@ -412,6 +448,7 @@ func (ti *typedImage) loadMetadata() error {
 			return err
 		}
 		ti.buildLog = img.BuildLog
+		ti.sbom = img.SBOM
 		ti.filter = img.Filter
 		ti.image = img
 		ti.triggers = img.Triggers
@@ -477,6 +514,14 @@ func (ti *typedImage) openFile(filename string) (io.ReadCloser, error) {
 	}
 }
 
+func (ti *typedImage) getSBOM() (*image.Annotation, error) {
+	if sbom := ti.sbom; sbom == nil {
+		return nil, errors.New("SBOM data not available")
+	} else {
+		return sbom, nil
+	}
+}
+
 func (ti *typedImage) getTriggers() (*triggers.Triggers, error) {
 	if trig := ti.triggers; trig == nil {
 		return nil, errors.New("Triggers not available")
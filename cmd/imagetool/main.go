@@ -41,6 +41,8 @@ var (
 		"If true, show debugging output")
 	deleteFilter = flag.String("deleteFilter", "",
 		"Name of delete filter file for addi, adds and diff subcommands")
+	ecosystem = flag.String("ecosystem", "",
+		"OSV ecosystem (i.e. Debian:11) when scanning for vulnerabilities (default: determine from image)")
 	expiresIn = flag.Duration("expiresIn", 0,
 		"How long before the image expires (auto deletes). Default: never")
 	filterFile = flag.String("filterFile", "",
@@ -73,6 +75,12 @@ var (
 		"Port number of MDB server")
 	minFreeBytes = flag.Uint64("minFreeBytes", 4<<20,
 		"minimum number of free bytes in raw image")
+	ociArchitecture = flag.String("ociArchitecture", "",
+		"Architecture recorded by export-oci (default: Architecture image tag)")
+	ociDiffLayer = flag.Bool("ociDiffLayer", false,
+		"If true, export-oci writes the source image and the changes as separate layers")
+	ociReference = flag.String("ociReference", "",
+		"Image reference (name:tag) to select from or record in an OCI layout or Docker archive")
 	objectAddInterval = flag.Duration("objectAddInterval", 0,
 		"Interval between object uploads (for debugging)")
 	overlayDirectory = flag.String("overlayDirectory", "",
@@ -138,6 +146,8 @@ func printUsage() {
 var subcommands = []commands.Command{
 	{"add", "                    name imagefile filterfile triggerfile", 4, 4,
 		addImagefileSubcommand},
+	{"add-image-oci", "          name source filterfile triggerfile", 4, 4,
+		addImageOciSubcommand},
 	{"addi", "                   name imagename filterfile triggerfile", 4, 4,
 		addImageimageSubcommand},
 	{"addrep", "                 name baseimage layerimage...", 3, -1,
@@ -164,11 +174,14 @@ var subcommands = []commands.Command{
 		diffFileInImagesSubcommand},
 	{"diff-filters", "           tool left right", 3, 3,
 		diffFilterInImagesSubcommand},
+	{"diff-image-sboms", "       tool left right", 3, 3,
+		diffImageSBOMsSubcommand},
 	{"diff-package-lists", "     tool left right", 3, 3,
 		diffImagePackageListsSubcommand},
 	{"diff-triggers", "          tool left right", 3, 3,
 		diffTriggersInImagesSubcommand},
 	{"estimate-usage", "         name", 1, 1, estimateImageUsageSubcommand},
+	{"export-oci", "             name output", 2, 2, exportOciSubcommand},
 	{"find-latest-image", "      directory", 1, 1, findLatestImageSubcommand},
 	{"get", "                    name directory", 2, 2, getImageSubcommand},
 	{"get-archive-data", "       name outfile", 2, 2,
@@ -178,6 +191,8 @@ var subcommands = []commands.Command{
 	{"get-file-in-image", "      name imageFile [outfile]", 2, 3,
 		getFileInImageSubcommand},
 	{"get-image-expiration", "   name", 1, 1, getImageExpirationSubcommand},
+	{"get-image-sbom", "         name [outfile]", 1, 2,
+		getImageSBOMSubcommand},
 	{"get-image-updates", "", 0, 0, getImageUpdatesSubcommand},
 	{"get-package-list", "       name [outfile]", 1, 2,
 		getImagePackageListSubcommand},
@@ -198,6 +213,8 @@ var subcommands = []commands.Command{
 		patchDirectorySubcommand},
 	{"scan-filtered-files", "    name directory", 2, 2,
 		scanFilteredFilesSubcommand},
+	{"scan-image-vulnerabilities", "name", 1, 1,
+		scanImageVulnerabilitiesSubcommand},
 	{"show", "                   name", 1, 1, showImageSubcommand},
 	{"show-bad-computed-files", "", 0, 0, showBadComputedFilesSubcommand},
 	{"show-bad-image-subs", "", 0, 0, showBadImageSubsSubcommand},
@@ -23,7 +23,9 @@ func mergeTriggers(triggerFiles []string) error {
 		if err != nil {
 			return err
 		}
-		mergeableTriggers.Merge(trig)
+		if err := mergeableTriggers.Merge(trig); err != nil {
+			return fmt.Errorf("%s: %s", triggerFile, err)
+		}
 	}
 	trig := mergeableTriggers.ExportTriggers()
 	return json.WriteWithIndent(os.Stdout, "    ", trig.Triggers)
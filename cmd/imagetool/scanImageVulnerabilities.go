@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/imageserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+func scanImageVulnerabilitiesSubcommand(args []string,
+	logger log.DebugLogger) error {
+	imageSClient, _ := getClients()
+	if err := scanImageVulnerabilities(imageSClient, args[0]); err != nil {
+		return fmt.Errorf("error scanning image vulnerabilities: %s", err)
+	}
+	return nil
+}
+
+func scanImageVulnerabilities(imageSClient *srpc.Client, name string) error {
+	reply, err := client.ScanImageVulnerabilities(imageSClient,
+		proto.ScanImageVulnerabilitiesRequest{
+			Ecosystem: *ecosystem,
+			ImageName: name,
+		})
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(os.Stdout)
+	defer writer.Flush()
+	fmt.Fprintf(writer, "Ecosystem: %s, %d vulnerabilities\n",
+		reply.Ecosystem, len(reply.Vulnerabilities))
+	var idWidth, nameWidth, versionWidth int
+	for _, match := range reply.Vulnerabilities {
+		if len(match.Id) > idWidth {
+			idWidth = len(match.Id)
+		}
+		if len(match.PackageName) > nameWidth {
+			nameWidth = len(match.PackageName)
+		}
+		if len(match.PackageVersion) > versionWidth {
+			versionWidth = len(match.PackageVersion)
+		}
+	}
+	for _, match := range reply.Vulnerabilities {
+		fixedVersion := match.FixedVersion
+		if fixedVersion == "" {
+			fixedVersion = "(no fix)"
+		}
+		fmt.Fprintf(writer, "%-*s %-*s %-*s fixed: %s",
+			idWidth, match.Id,
+			nameWidth, match.PackageName,
+			versionWidth, match.PackageVersion,
+			fixedVersion)
+		if len(match.Aliases) > 0 {
+			fmt.Fprintf(writer, " aliases: %s",
+				strings.Join(match.Aliases, ","))
+		}
+		fmt.Fprintln(writer)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+//go:build linux
+
+package main
+
+import (
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/imagebuilder/logarchiver"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+type retentionPolicyConfiguration struct {
+	MaxAgeInSeconds uint64
+	MaxBuilds       uint
+}
+
+func createBuildLogArchiver(logger log.DebugLogger) (
+	logarchiver.BuildLogger, error) {
+	options := logarchiver.BuildLogArchiveOptions{Topdir: *buildLogDir}
+	params := logarchiver.BuildLogArchiveParams{Logger: logger}
+	// Logs in an object store are only deleted by the retention policies.
+	if *buildLogS3Bucket != "" {
+		objectStore, err := logarchiver.NewS3ObjectStore(
+			logarchiver.S3ObjectStoreOptions{
+				Bucket:   *buildLogS3Bucket,
+				Endpoint: *buildLogS3Endpoint,
+				Prefix:   *buildLogS3Prefix,
+				Region:   *buildLogS3Region,
+			})
+		if err != nil {
+			return nil, err
+		}
+		params.ObjectStore = objectStore
+	} else if *buildLogDir == "" || buildLogQuota <= 1<<20 {
+		return nil, nil
+	} else {
+		options.Quota = uint64(buildLogQuota)
+	}
+	if *buildLogRetentionFile != "" {
+		var configuration map[string]retentionPolicyConfiguration
+		err := json.ReadFromFile(*buildLogRetentionFile, &configuration)
+		if err != nil {
+			return nil, err
+		}
+		options.RetentionPolicies = make(
+			map[string]logarchiver.RetentionPolicy, len(configuration))
+		for prefix, policy := range configuration {
+			options.RetentionPolicies[prefix] = logarchiver.RetentionPolicy{
+				MaxAge:    time.Duration(policy.MaxAgeInSeconds) * time.Second,
+				MaxBuilds: policy.MaxBuilds,
+			}
+		}
+	}
+	return logarchiver.New(options, params)
+}
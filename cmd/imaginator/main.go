@@ -11,13 +11,13 @@ import (
 
 	"github.com/Cloud-Foundations/Dominator/imagebuilder/builder"
 	"github.com/Cloud-Foundations/Dominator/imagebuilder/httpd"
-	"github.com/Cloud-Foundations/Dominator/imagebuilder/logarchiver"
 	"github.com/Cloud-Foundations/Dominator/imagebuilder/rpcd"
 	"github.com/Cloud-Foundations/Dominator/lib/constants"
 	"github.com/Cloud-Foundations/Dominator/lib/flags/loadflags"
 	"github.com/Cloud-Foundations/Dominator/lib/flagutil"
 	"github.com/Cloud-Foundations/Dominator/lib/log/serverlogger"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc/auditlog"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc/setupserver"
 	"github.com/Cloud-Foundations/tricorder/go/tricorder"
 )
@@ -30,7 +30,17 @@ const (
 var (
 	buildLogDir = flag.String("buildLogDir", "/var/log/imaginator/builds",
 		"Name of directory to write build logs to")
-	buildLogQuota    = flagutil.Size(100 << 20)
+	buildLogQuota         = flagutil.Size(100 << 20)
+	buildLogRetentionFile = flag.String("buildLogRetentionFile", "",
+		"Name of JSON file containing build log retention policies")
+	buildLogS3Bucket = flag.String("buildLogS3Bucket", "",
+		"Name of S3 bucket to write build logs to instead of buildLogDir")
+	buildLogS3Endpoint = flag.String("buildLogS3Endpoint", "",
+		"Optional endpoint URL for an S3-compatible build log object store")
+	buildLogS3Prefix = flag.String("buildLogS3Prefix", "",
+		"Prefix for build log object keys in the S3 bucket")
+	buildLogS3Region = flag.String("buildLogS3Region", "",
+		"Region of the S3 bucket for build logs")
 	configurationUrl = flag.String("configurationUrl",
 		"file:///etc/imaginator/conf.json", "URL containing configuration")
 	imageServerHostname = flag.String("imageServerHostname", "localhost",
@@ -87,6 +97,9 @@ func main() {
 	if err := setupserver.SetupTlsWithParams(params); err != nil {
 		logger.Fatalln(err)
 	}
+	if err := auditlog.Setup(logger); err != nil {
+		logger.Fatalf("Cannot set up audit log: %s\n", err)
+	}
 	if err := os.MkdirAll(*stateDir, dirPerms); err != nil {
 		logger.Fatalf("Cannot create state directory: %s\n", err)
 	}
@@ -94,20 +107,9 @@ func main() {
 	if err != nil {
 		logger.Fatalf("Error starting slave driver: %s\n", err)
 	}
-	var buildLogArchiver logarchiver.BuildLogger
-	if *buildLogDir != "" && buildLogQuota > 1<<20 {
-		buildLogArchiver, err = logarchiver.New(
-			logarchiver.BuildLogArchiveOptions{
-				Quota:  uint64(buildLogQuota),
-				Topdir: *buildLogDir,
-			},
-			logarchiver.BuildLogArchiveParams{
-				Logger: logger,
-			},
-		)
-		if err != nil {
-			logger.Fatalf("Error starting build log archiver: %s\n", err)
-		}
+	buildLogArchiver, err := createBuildLogArchiver(logger)
+	if err != nil {
+		logger.Fatalf("Error starting build log archiver: %s\n", err)
 	}
 	var presentationImageServerAddress string
 	if *presentationImageServerHostname != "" {
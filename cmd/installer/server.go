@@ -112,6 +112,7 @@ func startServer(portNum uint, remoteShellWaitGroup *sync.WaitGroup,
 	}
 	sprayLogger := debuglogger.New(stdlog.New(&logWriter{srpcObj}, "", 0))
 	sprayLogger.SetLevel(int16(*logDebugLevel))
+	listener = srpc.NewTlsSniffingListener(listener)
 	go http.Serve(listener, nil)
 	return teelogger.New(logger, sprayLogger), nil
 }
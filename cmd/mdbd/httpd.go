@@ -13,6 +13,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/format"
 	"github.com/Cloud-Foundations/Dominator/lib/html"
 	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/lib/url"
 )
 
@@ -53,6 +54,7 @@ func startHttpServer(portNum uint, variables map[string]string,
 	html.HandleFunc("/getVariables", s.getVariablesHandler)
 	html.HandleFunc("/showMdb", s.showMdbHandler)
 	html.HandleFunc("/showPaused", s.showPausedHandler)
+	listener = srpc.NewTlsSniffingListener(listener)
 	go http.Serve(listener, nil)
 	return s, nil
 }
@@ -39,6 +39,7 @@ func doMain(logger log.DebugLogger) error {
 		return err
 	}
 	srpc.RegisterName("Test", &serverType{})
+	listener = srpc.NewTlsSniffingListener(listener)
 	return http.Serve(listener, nil)
 }
 
@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/constants"
 	"github.com/Cloud-Foundations/Dominator/lib/cpulimiter"
@@ -38,6 +39,8 @@ import (
 )
 
 var (
+	changeTracking = flag.Bool("changeTracking", false,
+		"If true, track file changes and only rehash changed files between full scans")
 	configDirectory = flag.String("configDirectory", "/etc/subd/conf.d",
 		"Directory of optional JSON configuration files")
 	defaultCpuPercent = flag.Uint("defaultCpuPercent", 0,
@@ -48,6 +51,8 @@ var (
 		"Scan speed as percentage of capacity (default 2)")
 	disruptionManager = flag.String("disruptionManager", "",
 		"Path to DisruptionManager tool")
+	fullScanInterval = flag.Duration("fullScanInterval", 24*time.Hour,
+		"Interval between full scans if changeTracking is enabled")
 	maxThreads = flag.Uint("maxThreads", 1,
 		"Maximum number of parallel OS threads to use")
 	noteGenerator = flag.String("noteGenerator", "",
@@ -331,7 +336,9 @@ func main() {
 		configParams.ScanSpeedPercent = *defaultScanSpeedPercent
 	}
 	var configuration scanner.Configuration
+	configuration.ChangeTracking = *changeTracking
 	configuration.CpuLimiter = cpulimiter.New(100)
+	configuration.FullScanInterval = *fullScanInterval
 	configuration.DefaultCpuPercent = configParams.CpuPercent
 	// Apply built-in defaults if nothing specified.
 	if configuration.DefaultCpuPercent < 1 {
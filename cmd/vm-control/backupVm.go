@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"net"
+
+	hyperclient "github.com/Cloud-Foundations/Dominator/hypervisor/client"
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func backupVmSubcommand(args []string, logger log.DebugLogger) error {
+	if err := backupVm(args[0], logger); err != nil {
+		return fmt.Errorf("error backing up VM: %s", err)
+	}
+	return nil
+}
+
+func backupVm(vmHostname string, logger log.DebugLogger) error {
+	if vmIP, hypervisor, err := lookupVmAndHypervisor(vmHostname); err != nil {
+		return err
+	} else {
+		return backupVmOnHypervisor(hypervisor, vmIP, logger)
+	}
+}
+
+func backupVmOnHypervisor(hypervisor string, ipAddr net.IP,
+	logger log.DebugLogger) error {
+	client, err := dialHypervisor(hypervisor)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	backup, err := hyperclient.BackupVm(client, ipAddr)
+	if err != nil {
+		return err
+	}
+	logger.Printf("created backup: %s, uploaded: %s\n",
+		backup.BackupId, format.FormatBytes(backup.UploadedBytes))
+	return nil
+}
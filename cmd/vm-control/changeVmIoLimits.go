@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"net"
+
+	hyperclient "github.com/Cloud-Foundations/Dominator/hypervisor/client"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func changeVmIoLimitsSubcommand(args []string, logger log.DebugLogger) error {
+	if err := changeVmIoLimits(args[0], logger); err != nil {
+		return fmt.Errorf("error changing VM IO limits: %s", err)
+	}
+	return nil
+}
+
+func changeVmIoLimits(vmHostname string, logger log.DebugLogger) error {
+	if vmIP, hypervisor, err := lookupVmAndHypervisor(vmHostname); err != nil {
+		return err
+	} else {
+		return changeVmIoLimitsOnHypervisor(hypervisor, vmIP, logger)
+	}
+}
+
+func changeVmIoLimitsOnHypervisor(hypervisor string, ipAddr net.IP,
+	logger log.DebugLogger) error {
+	request := proto.ChangeVmIoLimitsRequest{
+		IpAddress:     ipAddr,
+		NetworkLimits: getNetworkLimits(),
+	}
+	numVolumes := len(volumeBandwidthLimits)
+	if len(volumeIopsLimits) > numVolumes {
+		numVolumes = len(volumeIopsLimits)
+	}
+	for index := 0; index < numVolumes; index++ {
+		request.VolumeIoLimits = append(request.VolumeIoLimits,
+			getVolumeIoLimits(index))
+	}
+	client, err := dialHypervisor(hypervisor)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return hyperclient.ChangeVmIoLimits(client, request)
+}
+
+// getNetworkLimits returns the limits for the VM interfaces specified by the
+// -egressLimits and -ingressLimits flags.
+func getNetworkLimits() []proto.InterfaceIoLimits {
+	numInterfaces := len(egressLimits)
+	if len(ingressLimits) > numInterfaces {
+		numInterfaces = len(ingressLimits)
+	}
+	var networkLimits []proto.InterfaceIoLimits
+	for index := 0; index < numInterfaces; index++ {
+		var limits proto.InterfaceIoLimits
+		if index < len(egressLimits) {
+			limits.EgressBitsPerSecond = uint64(egressLimits[index])
+		}
+		if index < len(ingressLimits) {
+			limits.IngressBitsPerSecond = uint64(ingressLimits[index])
+		}
+		networkLimits = append(networkLimits, limits)
+	}
+	return networkLimits
+}
+
+// getVolumeIoLimits returns the limits for the specified volume from the
+// -volumeBandwidthLimits and -volumeIopsLimits flags.
+func getVolumeIoLimits(index int) proto.VolumeIoLimits {
+	var limits proto.VolumeIoLimits
+	if index < len(volumeBandwidthLimits) {
+		limits.BytesPerSecond = uint64(volumeBandwidthLimits[index])
+	}
+	if index < len(volumeIopsLimits) {
+		limits.IOPS = uint64(volumeIopsLimits[index])
+	}
+	return limits
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	hyperclient "github.com/Cloud-Foundations/Dominator/hypervisor/client"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func changeVmLifecyclePolicySubcommand(args []string,
+	logger log.DebugLogger) error {
+	if err := changeVmLifecyclePolicy(args[0], logger); err != nil {
+		return fmt.Errorf("error changing VM lifecycle policy: %s", err)
+	}
+	return nil
+}
+
+func changeVmLifecyclePolicy(vmHostname string, logger log.DebugLogger) error {
+	if vmIP, hypervisor, err := lookupVmAndHypervisor(vmHostname); err != nil {
+		return err
+	} else {
+		return changeVmLifecyclePolicyOnHypervisor(hypervisor, vmIP, logger)
+	}
+}
+
+func changeVmLifecyclePolicyOnHypervisor(hypervisor string, ipAddr net.IP,
+	logger log.DebugLogger) error {
+	client, err := dialHypervisor(hypervisor)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return hyperclient.ChangeVmLifecyclePolicy(client, ipAddr,
+		getLifecyclePolicy())
+}
+
+// getLifecyclePolicy returns the lifecycle policy specified by the
+// -backupBeforeDestroy, -destroyOnExpiry, -expiresIn, -expiryWarning and
+// -idleTimeout flags.
+func getLifecyclePolicy() proto.LifecyclePolicy {
+	policy := proto.LifecyclePolicy{
+		BackupBeforeDestroy: *backupBeforeDestroy,
+		DestroyOnExpiry:     *destroyOnExpiry,
+		IdleTimeout:         *idleTimeout,
+		WarningPeriod:       *expiryWarning,
+	}
+	if *expiresIn > 0 {
+		policy.ExpiresAt = time.Now().Add(*expiresIn)
+	}
+	return policy
+}
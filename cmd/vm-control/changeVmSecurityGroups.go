@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func changeVmSecurityGroupsSubcommand(args []string,
+	logger log.DebugLogger) error {
+	if err := changeVmSecurityGroups(args[0], logger); err != nil {
+		return fmt.Errorf("error changing VM security groups: %s", err)
+	}
+	return nil
+}
+
+func changeVmSecurityGroups(vmHostname string, logger log.DebugLogger) error {
+	if vmIP, hypervisor, err := lookupVmAndHypervisor(vmHostname); err != nil {
+		return err
+	} else {
+		return changeVmSecurityGroupsOnHypervisor(hypervisor, vmIP, logger)
+	}
+}
+
+func changeVmSecurityGroupsOnHypervisor(hypervisor string, ipAddr net.IP,
+	logger log.DebugLogger) error {
+	client, err := dialHypervisor(hypervisor)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	request := proto.ChangeVmSecurityGroupsRequest{
+		IpAddress:      ipAddr,
+		SecurityGroups: securityGroups,
+	}
+	var reply proto.ChangeVmSecurityGroupsResponse
+	err = client.RequestReply("Hypervisor.ChangeVmSecurityGroups", request,
+		&reply)
+	if err != nil {
+		return err
+	}
+	return errors.New(reply.Error)
+}
@@ -190,6 +190,8 @@ func createVm(logger log.DebugLogger) error {
 		}
 	}
 	tmpVmInfo := approximateVolumesForCreateRequest()
+	tmpVmInfo.Address = request.Address
+	tmpVmInfo.Tags = request.VmInfo.Tags
 	if hypervisor, err := getHypervisorAddress(tmpVmInfo); err != nil {
 		return err
 	} else {
@@ -208,31 +210,38 @@ func createVmInfoFromFlags() hyper_proto.VmInfo {
 	if len(volumeTypes) > 0 {
 		volumeType = volumeTypes[0]
 	}
+	ioLimits := getVolumeIoLimits(0)
 	if volumeFormat != hyper_proto.VolumeFormatRaw ||
 		volumeInterface != hyper_proto.VolumeInterfaceVirtIO ||
+		ioLimits != (hyper_proto.VolumeIoLimits{}) ||
 		volumeType != hyper_proto.VolumeTypePersistent {
 		// If any provided, set for root volume. Secondaries are done later.
 		volumes = append(volumes, hyper_proto.Volume{
 			Format:    volumeFormat,
 			Interface: volumeInterface,
+			IoLimits:  ioLimits,
 			Type:      volumeType,
 		})
 	}
 	return hyper_proto.VmInfo{
 		ConsoleType:        consoleType,
 		CpuPriority:        *cpuPriority,
+		DedicatedCPUs:      *dedicatedCPUs,
 		DestroyOnPowerdown: *destroyOnPowerdown,
 		DestroyProtection:  *destroyProtection,
 		DisableVirtIO:      *disableVirtIO,
 		ExtraKernelOptions: *extraKernelOptions,
 		Hostname:           *vmHostname,
+		LifecyclePolicy:    getLifecyclePolicy(),
 		MachineType:        machineType,
 		MemoryInMiB:        uint64(memory >> 20),
 		MilliCPUs:          *milliCPUs,
+		NetworkLimits:      getNetworkLimits(),
 		OwnerGroups:        ownerGroups,
 		OwnerUsers:         ownerUsers,
 		Tags:               vmTags,
 		SecondarySubnetIDs: secondarySubnetIDs,
+		SecurityGroups:     securityGroups,
 		SpreadVolumes:      *spreadVolumes,
 		SubnetId:           *subnetId,
 		VirtualCPUs:        *virtualCPUs,
@@ -259,6 +268,7 @@ func createVmOnHypervisor(hypervisor string,
 		if index+1 < len(volumeInterfaces) {
 			volume.Interface = volumeInterfaces[index+1]
 		}
+		volume.IoLimits = getVolumeIoLimits(index + 1)
 		if index+1 < len(volumeTypes) {
 			volume.Type = volumeTypes[index+1]
 		}
@@ -297,6 +307,7 @@ func createVmOnHypervisor(hypervisor string,
 		request.ImageName = *imageName
 		request.ImageTimeout = *imageTimeout
 		request.SkipBootloader = *skipBootloader
+		request.ThinRootVolume = *thinRootVolume
 		if overlayFiles, err := loadOverlayFiles(); err != nil {
 			return err
 		} else {
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func listSecurityGroupsSubcommand(args []string, logger log.DebugLogger) error {
+	if err := listSecurityGroups(logger); err != nil {
+		return fmt.Errorf("error listing security groups: %s", err)
+	}
+	return nil
+}
+
+func getSecurityGroups(client *srpc.Client) ([]proto.SecurityGroup, error) {
+	var request proto.ListSecurityGroupsRequest
+	var reply proto.ListSecurityGroupsResponse
+	err := client.RequestReply("Hypervisor.ListSecurityGroups", request,
+		&reply)
+	if err != nil {
+		return nil, err
+	}
+	if err := errors.New(reply.Error); err != nil {
+		return nil, err
+	}
+	return reply.SecurityGroups, nil
+}
+
+func getSecurityGroupsHypervisor() string {
+	if *hypervisorHostname != "" {
+		return fmt.Sprintf("%s:%d", *hypervisorHostname, *hypervisorPortNum)
+	}
+	return fmt.Sprintf("localhost:%d", *hypervisorPortNum)
+}
+
+func listSecurityGroups(logger log.DebugLogger) error {
+	client, err := dialHypervisor(getSecurityGroupsHypervisor())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	groups, err := getSecurityGroups(client)
+	if err != nil {
+		return err
+	}
+	return json.WriteWithIndent(os.Stdout, "    ", groups)
+}
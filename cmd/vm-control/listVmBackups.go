@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	hyperclient "github.com/Cloud-Foundations/Dominator/hypervisor/client"
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func listVmBackupsSubcommand(args []string, logger log.DebugLogger) error {
+	if err := listVmBackups(args[0], logger); err != nil {
+		return fmt.Errorf("error listing VM backups: %s", err)
+	}
+	return nil
+}
+
+func listVmBackups(vmHostname string, logger log.DebugLogger) error {
+	if vmIP, hypervisor, err := lookupVmAndHypervisor(vmHostname); err != nil {
+		return err
+	} else {
+		return listVmBackupsOnHypervisor(hypervisor, vmIP, logger)
+	}
+}
+
+func listVmBackupsOnHypervisor(hypervisor string, ipAddr net.IP,
+	logger log.DebugLogger) error {
+	client, err := dialHypervisor(hypervisor)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	backups, err := hyperclient.ListVmBackups(client, ipAddr)
+	if err != nil {
+		return err
+	}
+	for _, backup := range backups {
+		volumeSizes := make([]string, 0, len(backup.VolumeSizes))
+		for _, size := range backup.VolumeSizes {
+			volumeSizes = append(volumeSizes, format.FormatBytes(size))
+		}
+		fmt.Fprintf(os.Stdout, "%s  created: %s  volumes: %s  uploaded: %s\n",
+			backup.BackupId, backup.CreatedOn.Format(format.TimeFormatSeconds),
+			strings.Join(volumeSizes, ","),
+			format.FormatBytes(backup.UploadedBytes))
+	}
+	return nil
+}
@@ -23,9 +23,15 @@ import (
 var (
 	adjacentVM = flag.String("adjacentVM", "",
 		"IP address of VM adjacent (same Hypervisor) to VM being created")
+	backupBeforeDestroy = flag.Bool("backupBeforeDestroy", false,
+		"If true, back up VM before it is destroyed on expiry")
 	consoleType hyper_proto.ConsoleType
 	cpuPriority = flag.Int("cpuPriority", 0,
 		"CPU priority (-20:+19) for VM process on Hypervisor")
+	dedicatedCPUs = flag.Bool("dedicatedCPUs", false,
+		"If true, allocate dedicated whole cores from a single NUMA node")
+	destroyOnExpiry = flag.Bool("destroyOnExpiry", false,
+		"If true, destroy VM when it expires, else stop it")
 	destroyOnPowerdown = flag.Bool("destroyOnPowerdown", false,
 		"If true, destroy VM if it powers down internally")
 	destroyProtection = flag.Bool("destroyProtection", false,
@@ -36,8 +42,13 @@ var (
 		"Time to wait before timing out on DHCP request from VM")
 	doNotStart = flag.Bool("doNotStart", false,
 		"If true, do not start VM when creating")
+	egressLimits  flagutil.SizeList
 	enableNetboot = flag.Bool("enableNetboot", false,
 		"If true, enable boot from network for first boot")
+	expiresIn = flag.Duration("expiresIn", 0,
+		"Time until VM expires (default never)")
+	expiryWarning = flag.Duration("expiryWarning", 0,
+		"Time before expiry to email VM owners")
 	extraKernelOptions = flag.String("extraKernelOptions", "",
 		"Extra options to pass to kernel")
 	fleetManagerHostname = flag.String("fleetManagerHostname", "",
@@ -56,6 +67,9 @@ var (
 		"Filename of PEM-encoded cetificate availabe from metadata service ")
 	identityKeyFile = flag.String("identityKeyFile", "",
 		"Filename of PEM-encoded key available from metadata service ")
+	idleTimeout = flag.Duration("idleTimeout", 0,
+		"Stop VM when idle for this long (default never)")
+	ingressLimits    flagutil.SizeList
 	includeUnhealthy = flag.Bool("includeUnhealthy", false,
 		"If true, list connected but unhealthy hypervisors")
 	imageFile = flag.String("imageFile", "",
@@ -101,7 +115,8 @@ var (
 	secondaryVolumeSizes       flagutil.SizeList
 	secondaryVolumesInitParams = flag.String("secondaryVolumesInitParams", "",
 		"File containing initialisation parameters for secondary volumes")
-	serialPort = flag.Uint("serialPort", 0,
+	securityGroups flagutil.StringList
+	serialPort     = flag.Uint("serialPort", 0,
 		"Serial port number on VM")
 	skipBackup = flag.Bool("skipBackup", false,
 		"If true, do not make a backup when patching/replacing the VM image")
@@ -114,6 +129,8 @@ var (
 	storageIndices flagutil.UintList
 	subnetId       = flag.String("subnetId", "",
 		"Subnet ID to launch VM in")
+	thinRootVolume = flag.Bool("thinRootVolume", false,
+		"If true, create root volume as an overlay on a shared image volume")
 	requestIPs   flagutil.StringList
 	roundupPower = flag.Uint64("roundupPower", 28,
 		"power of 2 to round up root volume size")
@@ -132,13 +149,15 @@ var (
 	vmTagsToMatch tags.MatchTags
 	vncViewer     = flag.String("vncViewer", defaultVncViewer,
 		"Path to VNC viewer")
-	volumeFilename = flag.String("volumeFilename", "",
+	volumeBandwidthLimits flagutil.SizeList
+	volumeFilename        = flag.String("volumeFilename", "",
 		"Name of file to write volume data to")
 	volumeFormat hyper_proto.VolumeFormat
 	volumeIndex  = flag.Uint("volumeIndex", 0,
 		"Index of volume to get or delete")
 	volumeIndices    flagutil.UintList
 	volumeInterfaces volumeInterfaceList
+	volumeIopsLimits flagutil.UintList
 	volumeSize       flagutil.Size
 	volumeTypes      volumeTypeList
 	watchdogAction   hyper_proto.WatchdogAction
@@ -151,8 +170,12 @@ var (
 func init() {
 	flag.Var(&consoleType, "consoleType",
 		"type of graphical console (default none)")
+	flag.Var(&egressLimits, "egressLimits",
+		"Rate limits (bits/second) for traffic from VM interfaces")
 	flag.Var(&hypervisorTagsToMatch, "hypervisorTagsToMatch",
 		"Tags to match when getting/listing or creating/copying/moving VMs")
+	flag.Var(&ingressLimits, "ingressLimits",
+		"Rate limits (bits/second) for traffic to VM interfaces")
 	flag.Var(&machineType, "machineType",
 		"Type of machine to emulate (default generic PC)")
 	flag.Var(&memory, "memory", "memory (default 1GiB)")
@@ -166,15 +189,20 @@ func init() {
 	flag.Var(&secondarySubnetIDs, "secondarySubnetIDs", "Secondary Subnet IDs")
 	flag.Var(&secondaryVolumeSizes, "secondaryVolumeSizes",
 		"Sizes for secondary volumes")
+	flag.Var(&securityGroups, "securityGroups", "Security group IDs for VM")
 	flag.Var(&storageIndices, "storageIndices",
 		"Indices for volume backing stores")
 	flag.Var(&vmTags, "vmTags", "Tags to apply to VM")
 	flag.Var(&vmTagsToMatch, "vmTagsToMatch", "Tags to match when listing")
+	flag.Var(&volumeBandwidthLimits, "volumeBandwidthLimits",
+		"Bandwidth limits (bytes/second) for volumes")
 	flag.Var(&volumeFormat, "volumeFormat",
 		"Format of image provided by file or URL (default raw)")
 	flag.Var(&volumeIndices, "volumeIndices", "Index of volumes")
 	flag.Var(&volumeInterfaces, "volumeInterfaces",
 		"Interfaces (device type presented to VM) for volumes (default virtio)")
+	flag.Var(&volumeIopsLimits, "volumeIopsLimits",
+		"IO operations/second limits for volumes")
 	flag.Var(&volumeSize, "volumeSize", "New size of specified volume")
 	flag.Var(&volumeTypes, "volumeTypes",
 		"Types for volumes (default persistent)")
@@ -195,16 +223,22 @@ func printUsage() {
 
 var subcommands = []commands.Command{
 	{"add-vm-volumes", "IPaddr", 1, 1, addVmVolumesSubcommand},
+	{"backup-vm", "IPaddr", 1, 1, backupVmSubcommand},
 	{"become-primary-vm-owner", "IPaddr", 1, 1, becomePrimaryVmOwnerSubcommand},
 	{"change-vm-console-type", "IPaddr", 1, 1, changeVmConsoleTypeSubcommand},
 	{"change-vm-cpu-priority", "IPaddr", 1, 1, changeVmCpuPrioritySubcommand},
 	{"change-vm-cpus", "IPaddr", 1, 1, changeVmCPUsSubcommand},
 	{"change-vm-destroy-protection", "IPaddr", 1, 1,
 		changeVmDestroyProtectionSubcommand},
+	{"change-vm-io-limits", "IPaddr", 1, 1, changeVmIoLimitsSubcommand},
+	{"change-vm-lifecycle-policy", "IPaddr", 1, 1,
+		changeVmLifecyclePolicySubcommand},
 	{"change-vm-machine-type", "IPaddr", 1, 1, changeVmMachineTypeSubcommand},
 	{"change-vm-memory", "IPaddr", 1, 1, changeVmMemorySubcommand},
 	{"change-vm-owner-groups", "IPaddr", 1, 1, changeVmOwnerGroupsSubcommand},
 	{"change-vm-owner-users", "IPaddr", 1, 1, changeVmOwnerUsersSubcommand},
+	{"change-vm-security-groups", "IPaddr", 1, 1,
+		changeVmSecurityGroupsSubcommand},
 	{"change-vm-tags", "IPaddr", 1, 1, changeVmTagsSubcommand},
 	{"change-vm-vcpus", "IPaddr", 1, 1, changeVmVirtualCPUsSubcommand},
 	{"change-vm-volume-interfaces", "IPaddr", 1, 1,
@@ -216,6 +250,7 @@ var subcommands = []commands.Command{
 	{"copy-vm", "IPaddr", 1, 1, copyVmSubcommand},
 	{"create-vm", "", 0, 0, createVmSubcommand},
 	{"debug-vm-image", "IPaddr", 1, 1, debugVmImageSubcommand},
+	{"delete-security-group", "ID", 1, 1, deleteSecurityGroupSubcommand},
 	{"delete-vm-volume", "IPaddr", 1, 1, deleteVmVolumeSubcommand},
 	{"destroy-vm", "IPaddr", 1, 1, destroyVmSubcommand},
 	{"discard-vm-old-image", "IPaddr", 1, 1, discardVmOldImageSubcommand},
@@ -234,6 +269,8 @@ var subcommands = []commands.Command{
 		importVirshVmSubcommand},
 	{"list-hypervisors", "", 0, 0, listHypervisorsSubcommand},
 	{"list-locations", "[TopLocation]", 0, 1, listLocationsSubcommand},
+	{"list-security-groups", "", 0, 0, listSecurityGroupsSubcommand},
+	{"list-vm-backups", "IPaddr", 1, 1, listVmBackupsSubcommand},
 	{"list-vms", "", 0, 0, listVMsSubcommand},
 	{"migrate-vm", "IPaddr", 1, 1, migrateVmSubcommand},
 	{"parse-virsh-xml", "filename", 1, 1, parseVirshXmlSubcommand},
@@ -244,11 +281,14 @@ var subcommands = []commands.Command{
 	{"replace-vm-image", "IPaddr", 1, 1, replaceVmImageSubcommand},
 	{"replace-vm-user-data", "IPaddr", 1, 1, replaceVmUserDataSubcommand},
 	{"restore-vm", "source", 1, 1, restoreVmSubcommand},
+	{"restore-vm-from-backup", "IPaddr BackupId", 2, 2,
+		restoreVmFromBackupSubcommand},
 	{"restore-vm-from-snapshot", "IPaddr", 1, 1,
 		restoreVmFromSnapshotSubcommand},
 	{"restore-vm-image", "IPaddr", 1, 1, restoreVmImageSubcommand},
 	{"restore-vm-user-data", "IPaddr", 1, 1, restoreVmUserDataSubcommand},
 	{"reorder-vm-volumes", "IPaddr", 1, 1, reorderVmVolumesSubcommand},
+	{"set-security-group", "filename", 1, 1, setSecurityGroupSubcommand},
 	{"set-vm-migrating", "IPaddr", 1, 1, setVmMigratingSubcommand},
 	{"snapshot-vm", "IPaddr", 1, 1, snapshotVmSubcommand},
 	{"save-vm", "IPaddr destination", 2, 2, saveVmSubcommand},
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"net"
 	"os"
 	"os/exec"
 	"sort"
@@ -73,6 +74,21 @@ func compareStorage(hypervisors []fm_proto.Hypervisor, i, j int) bool {
 		hypervisors[j].TotalVolumeBytes-hypervisors[j].AllocatedVolumeBytes
 }
 
+// canDedicateCPUs returns true if a NUMA node on the Hypervisor has enough
+// free cores and memory for the VM.
+func canDedicateCPUs(h fm_proto.Hypervisor, vmInfo hyper_proto.VmInfo) bool {
+	numCPUs := (vmInfo.MilliCPUs + 999) / 1000
+	if vmInfo.VirtualCPUs > numCPUs {
+		numCPUs = vmInfo.VirtualCPUs
+	}
+	for _, node := range h.NumaNodes {
+		if node.CanDedicate(numCPUs, vmInfo.MemoryInMiB) {
+			return true
+		}
+	}
+	return false
+}
+
 func findHypervisorsWithCapacity(inputHypervisors []fm_proto.Hypervisor,
 	vmInfo hyper_proto.VmInfo) []fm_proto.Hypervisor {
 	outputHypervisors := make([]fm_proto.Hypervisor, 0, len(inputHypervisors))
@@ -91,15 +107,71 @@ func findHypervisorsWithCapacity(inputHypervisors []fm_proto.Hypervisor,
 		if totalVolumeSize+h.AllocatedVolumeBytes > h.TotalVolumeBytes {
 			continue
 		}
+		if vmInfo.DedicatedCPUs && !canDedicateCPUs(h, vmInfo) {
+			continue
+		}
 		outputHypervisors = append(outputHypervisors, h)
 	}
 	return outputHypervisors
 }
 
+// checkPlacement returns an error if placing the VM on the Hypervisor would
+// break anti-affinity with the other VMs in the placement group. Otherwise the
+// Fleet Manager reserves the failure domain for the VM for a few minutes, so
+// that concurrent placements are not given the same failure domain.
+func checkPlacement(client *srpc.Client, hypervisorAddress string,
+	placementGroup fm_proto.PlacementGroup, vmIpAddr net.IP) error {
+	if placementGroup.Name == "" {
+		return nil
+	}
+	hostname, _, err := net.SplitHostPort(hypervisorAddress)
+	if err != nil {
+		return err
+	}
+	request := fm_proto.CheckPlacementRequest{
+		HypervisorHostname: hostname,
+		PlacementGroup:     placementGroup,
+		VmToPlace:          vmIpAddr,
+	}
+	var reply fm_proto.CheckPlacementResponse
+	err = client.RequestReply("FleetManager.CheckPlacement", request, &reply)
+	if err != nil {
+		return err
+	}
+	if reply.Error != "" {
+		return errors.New(reply.Error)
+	}
+	return nil
+}
+
 func getHypervisorAddress(vmInfo hyper_proto.VmInfo) (string, error) {
+	placementGroup, err := fm_proto.GetPlacementGroup(vmInfo.Tags)
+	if err != nil {
+		return "", err
+	}
 	if *hypervisorHostname != "" {
-		return fmt.Sprintf("%s:%d", *hypervisorHostname, *hypervisorPortNum),
-			nil
+		hypervisor := fmt.Sprintf("%s:%d", *hypervisorHostname,
+			*hypervisorPortNum)
+		if placementGroup.Name == "" {
+			return hypervisor, nil
+		}
+		if *fleetManagerHostname == "" {
+			return "", fmt.Errorf(
+				"placement group: %s requires a fleetManagerHostname",
+				placementGroup.Name)
+		}
+		client, err := dialFleetManager(fmt.Sprintf("%s:%d",
+			*fleetManagerHostname, *fleetManagerPortNum))
+		if err != nil {
+			return "", err
+		}
+		defer client.Close()
+		err = checkPlacement(client, hypervisor, placementGroup,
+			vmInfo.Address.IpAddress)
+		if err != nil {
+			return "", err
+		}
+		return hypervisor, nil
 	}
 	client, err := dialFleetManager(fmt.Sprintf("%s:%d",
 		*fleetManagerHostname, *fleetManagerPortNum))
@@ -108,20 +180,42 @@ func getHypervisorAddress(vmInfo hyper_proto.VmInfo) (string, error) {
 	}
 	defer client.Close()
 	if *adjacentVM != "" {
-		if adjacentVmIpAddr, err := lookupIP(*adjacentVM); err != nil {
+		adjacentVmIpAddr, err := lookupIP(*adjacentVM)
+		if err != nil {
+			return "", err
+		}
+		hypervisor, err := findHypervisorClient(client, adjacentVmIpAddr)
+		if err != nil {
 			return "", err
-		} else {
-			return findHypervisorClient(client, adjacentVmIpAddr)
 		}
+		err = checkPlacement(client, hypervisor, placementGroup,
+			vmInfo.Address.IpAddress)
+		if err != nil {
+			return "", err
+		}
+		return hypervisor, nil
 	}
 	if placement == placementChoiceAny { // Really dumb placement.
-		return selectAnyHypervisor(client)
+		hypervisor, err := selectAnyHypervisor(client, placementGroup,
+			vmInfo.Address.IpAddress)
+		if err != nil {
+			return "", err
+		}
+		// Reserve the failure domain.
+		err = checkPlacement(client, hypervisor, placementGroup,
+			vmInfo.Address.IpAddress)
+		if err != nil {
+			return "", err
+		}
+		return hypervisor, nil
 	}
 	request := fm_proto.GetHypervisorsInLocationRequest{
 		HypervisorTagsToMatch: hypervisorTagsToMatch,
 		IncludeVMs:            placement == placementChoiceCommand,
 		Location:              *location,
+		PlacementGroup:        placementGroup,
 		SubnetId:              *subnetId,
+		VmToPlace:             vmInfo.Address.IpAddress,
 	}
 	var reply fm_proto.GetHypervisorsInLocationResponse
 	err = client.RequestReply("FleetManager.GetHypervisorsInLocation",
@@ -137,15 +231,26 @@ func getHypervisorAddress(vmInfo hyper_proto.VmInfo) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("%s:%d",
-		hypervisor.Hostname, constants.HypervisorPortNumber), nil
+	hypervisorAddress := fmt.Sprintf("%s:%d",
+		hypervisor.Hostname, constants.HypervisorPortNumber)
+	// Reserve the failure domain. The command may also ignore the list.
+	err = checkPlacement(client, hypervisorAddress, placementGroup,
+		vmInfo.Address.IpAddress)
+	if err != nil {
+		return "", err
+	}
+	return hypervisorAddress, nil
 }
 
-func selectAnyHypervisor(client *srpc.Client) (string, error) {
+func selectAnyHypervisor(client *srpc.Client,
+	placementGroup fm_proto.PlacementGroup,
+	vmIpAddr net.IP) (string, error) {
 	request := fm_proto.ListHypervisorsInLocationRequest{
 		HypervisorTagsToMatch: hypervisorTagsToMatch,
 		Location:              *location,
+		PlacementGroup:        placementGroup,
 		SubnetId:              *subnetId,
+		VmToPlace:             vmIpAddr,
 	}
 	var reply fm_proto.ListHypervisorsInLocationResponse
 	err := client.RequestReply("FleetManager.ListHypervisorsInLocation",
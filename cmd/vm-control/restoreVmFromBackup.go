@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"net"
+
+	hyperclient "github.com/Cloud-Foundations/Dominator/hypervisor/client"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func restoreVmFromBackupSubcommand(args []string,
+	logger log.DebugLogger) error {
+	if err := restoreVmFromBackup(args[0], args[1], logger); err != nil {
+		return fmt.Errorf("error restoring VM from backup: %s", err)
+	}
+	return nil
+}
+
+func restoreVmFromBackup(vmHostname, backupId string,
+	logger log.DebugLogger) error {
+	if vmIP, hypervisor, err := lookupVmAndHypervisor(vmHostname); err != nil {
+		return err
+	} else {
+		return restoreVmFromBackupOnHypervisor(hypervisor, vmIP, backupId,
+			logger)
+	}
+}
+
+func restoreVmFromBackupOnHypervisor(hypervisor string, ipAddr net.IP,
+	backupId string, logger log.DebugLogger) error {
+	client, err := dialHypervisor(hypervisor)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return hyperclient.RestoreVmFromBackup(client, ipAddr, backupId)
+}
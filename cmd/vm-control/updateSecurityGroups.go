@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func deleteSecurityGroupSubcommand(args []string,
+	logger log.DebugLogger) error {
+	if err := deleteSecurityGroup(args[0], logger); err != nil {
+		return fmt.Errorf("error deleting security group: %s", err)
+	}
+	return nil
+}
+
+func setSecurityGroupSubcommand(args []string, logger log.DebugLogger) error {
+	if err := setSecurityGroup(args[0], logger); err != nil {
+		return fmt.Errorf("error setting security group: %s", err)
+	}
+	return nil
+}
+
+func deleteSecurityGroup(groupId string, logger log.DebugLogger) error {
+	client, err := dialHypervisor(getSecurityGroupsHypervisor())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return updateSecurityGroups(client,
+		proto.UpdateSecurityGroupsRequest{Delete: []string{groupId}})
+}
+
+// setSecurityGroup adds the security group in the file, or replaces the
+// existing group with the same ID.
+func setSecurityGroup(filename string, logger log.DebugLogger) error {
+	var group proto.SecurityGroup
+	if err := json.ReadFromFile(filename, &group); err != nil {
+		return err
+	}
+	if err := group.CheckValid(); err != nil {
+		return err
+	}
+	for _, rules := range [][]proto.SecurityRule{
+		group.EgressRules, group.IngressRules} {
+		for _, rule := range rules {
+			if len(rule.PeerTags) > 0 {
+				return fmt.Errorf(
+					"security group: %s: PeerTags require the fleet-manager",
+					group.Id)
+			}
+		}
+	}
+	client, err := dialHypervisor(getSecurityGroupsHypervisor())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	groups, err := getSecurityGroups(client)
+	if err != nil {
+		return err
+	}
+	request := proto.UpdateSecurityGroupsRequest{
+		Add: []proto.SecurityGroup{group},
+	}
+	for _, existingGroup := range groups {
+		if existingGroup.Id == group.Id {
+			request = proto.UpdateSecurityGroupsRequest{
+				Change: []proto.SecurityGroup{group},
+			}
+			break
+		}
+	}
+	return updateSecurityGroups(client, request)
+}
+
+func updateSecurityGroups(client *srpc.Client,
+	request proto.UpdateSecurityGroupsRequest) error {
+	var reply proto.UpdateSecurityGroupsResponse
+	err := client.RequestReply("Hypervisor.UpdateSecurityGroups", request,
+		&reply)
+	if err != nil {
+		return err
+	}
+	return errors.New(reply.Error)
+}
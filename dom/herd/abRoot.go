@@ -0,0 +1,41 @@
+package herd
+
+import (
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	subproto "github.com/Cloud-Foundations/Dominator/proto/sub"
+)
+
+// getUpdateMode returns the update mode selected by the UpdateMode MDB tag.
+func (sub *Sub) getUpdateMode() subproto.UpdateMode {
+	if strings.EqualFold(sub.mdb.Tags["UpdateMode"], "ABRoot") {
+		return subproto.UpdateModeABRoot
+	}
+	return subproto.UpdateModeInPlace
+}
+
+// makeImageFileSystem returns a copy of the file-system for the required
+// image with the computed files replaced by their computed contents. It
+// returns nil if a computed file is missing.
+func (sub *Sub) makeImageFileSystem() *filesystem.FileSystem {
+	imageFs := sub.requiredImage.FileSystem
+	fs := *imageFs
+	fs.InodeTable = make(filesystem.InodeTable, len(imageFs.InodeTable))
+	inodeToFilenames := imageFs.InodeToFilenamesTable()
+	for inum, inode := range imageFs.InodeTable {
+		if _, ok := inode.(*filesystem.ComputedRegularInode); ok {
+			filenames := inodeToFilenames[inum]
+			if len(filenames) < 1 {
+				return nil
+			}
+			rInode, ok := sub.computedInodes[filenames[0]]
+			if !ok {
+				return nil
+			}
+			inode = rInode
+		}
+		fs.InodeTable[inum] = inode
+	}
+	return &fs
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Cloud-Foundations/Dominator/dom/images"
+	"github.com/Cloud-Foundations/Dominator/dom/journal"
 	"github.com/Cloud-Foundations/Dominator/lib/cpusharer"
 	filegenclient "github.com/Cloud-Foundations/Dominator/lib/filegen/client"
 	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
@@ -59,6 +60,8 @@ const (
 	statusSendingUpdate
 	statusMissingComputedFile
 	statusUpdatesDisabled
+	statusDrifted
+	statusWaitingForMaintenanceWindow
 	statusUnsafeUpdate
 	statusDisruptionRequested
 	statusDisruptionDenied
@@ -108,6 +111,7 @@ type Sub struct {
 	lastReachableTime            time.Time
 	lastConnectionSucceededTime  time.Time
 	lastConnectDuration          time.Duration
+	lastABRootStatus             *subproto.ABRootStatus
 	lastDisruptionState          subproto.DisruptionState
 	lastPollStartTime            time.Time
 	lastPollSucceededTime        time.Time
@@ -121,6 +125,13 @@ type Sub struct {
 	lastSuccessfulImageName      string
 	lastNote                     string
 	lastWriteError               string
+	lastDriftMutex               sync.Mutex
+	lastDrift                    *domproto.SubDrift // Protected by mutex.
+	lastFetchServer              string
+	lastFetchServerFailure       time.Time
+	maintenanceWindowsKey        string
+	maintenanceWindows           *maintenanceWindows
+	nextMaintenanceTime          time.Time
 	systemUptime                 *time.Duration
 }
 
@@ -155,6 +166,10 @@ type Herd struct {
 	subdInstallerQueueDelete chan<- string
 	subdInstallerQueueErase  chan<- string
 	totalScanDuration        time.Duration
+	updateJournal            *journal.Journal
+	maintenanceWindows       *maintenanceWindows
+	maintenanceWindowsFile   string
+	fetchServers             map[string]string // Key: location.
 }
 
 type subCounter struct {
@@ -197,15 +212,28 @@ func (herd *Herd) GetDefaultImage() string {
 	return herd.defaultImageName
 }
 
+func (herd *Herd) GetMaintenanceWindows() domproto.MaintenanceWindows {
+	return herd.getMaintenanceWindows()
+}
+
 func (herd *Herd) GetSubsConfiguration() subproto.Configuration {
 	return herd.getSubsConfiguration()
 }
 
+func (herd *Herd) GetSubDrift(hostname string) (*domproto.SubDrift, error) {
+	return herd.getSubDrift(hostname)
+}
+
 func (herd *Herd) GetInfoForSubs(request domproto.GetInfoForSubsRequest) (
 	[]domproto.SubInfo, error) {
 	return herd.getInfoForSubs(request)
 }
 
+func (herd *Herd) GetUpdateHistory(request domproto.GetUpdateHistoryRequest) (
+	[]domproto.UpdateHistoryEntry, error) {
+	return herd.getUpdateHistory(request)
+}
+
 func (herd *Herd) ListSubs(request domproto.ListSubsRequest) ([]string, error) {
 	return herd.listSubs(request)
 }
@@ -214,6 +242,13 @@ func (herd *Herd) LockWithTimeout(timeout time.Duration) {
 	herd.lockWithTimeout(timeout)
 }
 
+// LoadMaintenanceWindows loads the global maintenance windows from the
+// specified file (if it exists). Windows set later are saved to the file. It
+// must be called before polling starts.
+func (herd *Herd) LoadMaintenanceWindows(filename string) error {
+	return herd.loadMaintenanceWindows(filename)
+}
+
 func (herd *Herd) MdbUpdate(mdb *mdb.Mdb) {
 	herd.mdbUpdate(mdb)
 }
@@ -230,6 +265,17 @@ func (herd *Herd) SetDefaultImage(imageName string) error {
 	return herd.setDefaultImage(imageName)
 }
 
+func (herd *Herd) SetMaintenanceWindows(
+	config domproto.MaintenanceWindows) error {
+	return herd.setMaintenanceWindows(config)
+}
+
+// SetUpdateJournal sets the journal which records the updates sent to subs.
+// It must be called before polling starts.
+func (herd *Herd) SetUpdateJournal(updateJournal *journal.Journal) {
+	herd.updateJournal = updateJournal
+}
+
 func (herd *Herd) StartServer(portNum uint, daemon bool) error {
 	return herd.startServer(portNum, daemon)
 }
@@ -0,0 +1,202 @@
+package herd
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/html"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/triggers"
+	"github.com/Cloud-Foundations/Dominator/lib/url"
+	proto "github.com/Cloud-Foundations/Dominator/proto/dominator"
+	subproto "github.com/Cloud-Foundations/Dominator/proto/sub"
+)
+
+var (
+	auditMode = flag.Bool("auditMode", false,
+		"If true, compute updates for all subs but do not send them")
+)
+
+type driftedPath struct {
+	change    string
+	hostnames []string
+	pathname  string
+}
+
+func (sub *Sub) getLastDrift() *proto.SubDrift {
+	sub.lastDriftMutex.Lock()
+	defer sub.lastDriftMutex.Unlock()
+	return sub.lastDrift
+}
+
+// isAuditOnly returns true if updates for the sub should be computed and
+// recorded but not sent.
+func (sub *Sub) isAuditOnly() bool {
+	if *auditMode {
+		return true
+	}
+	if value, ok := sub.mdb.Tags["AuditOnly"]; ok {
+		return strings.EqualFold(value, "true")
+	}
+	return false
+}
+
+// computeDrift summarises the changes which the update request would make.
+func (sub *Sub) computeDrift(request subproto.UpdateRequest) *proto.SubDrift {
+	drift := &proto.SubDrift{
+		ComputeTime:   time.Now(),
+		ImageName:     request.ImageName,
+		PathsToDelete: request.PathsToDelete,
+	}
+	filenameToInode := sub.fileSystem.FilenameToInodeTable()
+	addPath := func(pathname string) {
+		if _, ok := filenameToInode[pathname]; ok {
+			drift.PathsToChange = append(drift.PathsToChange, pathname)
+		} else {
+			drift.PathsToAdd = append(drift.PathsToAdd, pathname)
+		}
+	}
+	for _, inode := range request.DirectoriesToMake {
+		addPath(inode.Name)
+	}
+	for _, inode := range request.InodesToMake {
+		addPath(inode.Name)
+	}
+	for _, hardlink := range request.HardlinksToMake {
+		addPath(hardlink.NewLink)
+	}
+	for _, inode := range request.InodesToChange {
+		drift.PathsToChange = append(drift.PathsToChange, inode.Name)
+	}
+	sort.Strings(drift.PathsToAdd)
+	sort.Strings(drift.PathsToChange)
+	sort.Strings(drift.PathsToDelete)
+	drift.Triggers = matchTriggers(request.Triggers, drift.PathsToAdd,
+		drift.PathsToChange, drift.PathsToDelete)
+	return drift
+}
+
+func (sub *Sub) setLastDrift(drift *proto.SubDrift) {
+	sub.lastDriftMutex.Lock()
+	defer sub.lastDriftMutex.Unlock()
+	sub.lastDrift = drift
+}
+
+// matchTriggers returns the triggers which would be run if the specified paths
+// were changed. The triggers from the image are shared between subs and record
+// match state, so a private copy is used.
+func matchTriggers(imageTriggers *triggers.Triggers,
+	pathnameLists ...[]string) []*triggers.Trigger {
+	if imageTriggers == nil || len(imageTriggers.Triggers) < 1 {
+		return nil
+	}
+	trigs := triggers.New()
+	for _, trigger := range imageTriggers.Triggers {
+		trigger := *trigger
+		trigs.Triggers = append(trigs.Triggers, &trigger)
+	}
+	for _, pathnames := range pathnameLists {
+		for _, pathname := range pathnames {
+			trigs.Match(pathname)
+		}
+	}
+	return trigs.GetMatchedTriggers()
+}
+
+func selectDriftedSub(sub *Sub) bool {
+	return sub.publishedStatus == statusDrifted && sub.getLastDrift() != nil
+}
+
+func (herd *Herd) getSubDrift(hostname string) (*proto.SubDrift, error) {
+	sub := herd.getSub(hostname)
+	if sub == nil {
+		return nil, errors.New("unknown sub: " + hostname)
+	}
+	return sub.getLastDrift(), nil
+}
+
+// getDriftedPaths returns the drift for all audited subs, grouped by path.
+func (herd *Herd) getDriftedPaths() []*driftedPath {
+	pathsByKey := make(map[string]*driftedPath)
+	addPaths := func(hostname, change string, pathnames []string) {
+		for _, pathname := range pathnames {
+			key := change + ":" + pathname
+			dPath := pathsByKey[key]
+			if dPath == nil {
+				dPath = &driftedPath{change: change, pathname: pathname}
+				pathsByKey[key] = dPath
+			}
+			dPath.hostnames = append(dPath.hostnames, hostname)
+		}
+	}
+	for _, sub := range herd.getSelectedSubs(selectDriftedSub) {
+		drift := sub.getLastDrift()
+		if drift == nil {
+			continue
+		}
+		addPaths(sub.mdb.Hostname, "add", drift.PathsToAdd)
+		addPaths(sub.mdb.Hostname, "change", drift.PathsToChange)
+		addPaths(sub.mdb.Hostname, "delete", drift.PathsToDelete)
+	}
+	driftedPaths := make([]*driftedPath, 0, len(pathsByKey))
+	for _, dPath := range pathsByKey {
+		driftedPaths = append(driftedPaths, dPath)
+	}
+	sort.Slice(driftedPaths, func(left, right int) bool {
+		if driftedPaths[left].pathname != driftedPaths[right].pathname {
+			return driftedPaths[left].pathname < driftedPaths[right].pathname
+		}
+		return driftedPaths[left].change < driftedPaths[right].change
+	})
+	return driftedPaths
+}
+
+func (herd *Herd) showDriftedSubsHandler(w http.ResponseWriter,
+	req *http.Request) {
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	parsedQuery := url.ParseQuery(req.URL)
+	if parsedQuery.OutputType() == url.OutputTypeJson {
+		drifts := make(map[string]*proto.SubDrift)
+		for _, sub := range herd.getSelectedSubs(selectDriftedSub) {
+			if drift := sub.getLastDrift(); drift != nil {
+				drifts[sub.mdb.Hostname] = drift
+			}
+		}
+		json.WriteWithIndent(writer, "    ", drifts)
+		return
+	}
+	fmt.Fprintf(writer, "<title>Dominator drifted subs</title>")
+	fmt.Fprintln(writer, `<style>
+                          table, th, td {
+                          border-collapse: collapse;
+                          }
+                          </style>`)
+	fmt.Fprintln(writer, "<body>")
+	fmt.Fprintln(writer, "<h3>Drift for audited subs, by path</h3>")
+	fmt.Fprintln(writer, `<table border="1" style="width:100%">`)
+	tw, _ := html.NewTableWriter(writer, true, "Path", "Change", "Num Subs",
+		"Subs")
+	for _, dPath := range herd.getDriftedPaths() {
+		hostLinks := make([]string, 0, len(dPath.hostnames))
+		for _, hostname := range dPath.hostnames {
+			hostLinks = append(hostLinks,
+				fmt.Sprintf("<a href=\"showSub?%s\">%s</a>",
+					hostname, hostname))
+		}
+		tw.WriteRow("", "",
+			dPath.pathname,
+			dPath.change,
+			fmt.Sprintf("%d", len(dPath.hostnames)),
+			strings.Join(hostLinks, " "),
+		)
+	}
+	tw.Close()
+	fmt.Fprintln(writer, "</body>")
+}
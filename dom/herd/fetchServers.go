@@ -0,0 +1,58 @@
+package herd
+
+import (
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+)
+
+const fetchServerFailureHoldoff = 15 * time.Minute
+
+var (
+	fetchServersFile = flag.String("fetchServersFile", "",
+		"Name of JSON file mapping locations to caching object servers")
+)
+
+// loadFetchServers loads the map of locations to object server addresses
+// which subs should fetch objects from.
+func (herd *Herd) loadFetchServers() {
+	if *fetchServersFile == "" {
+		return
+	}
+	var fetchServers map[string]string
+	if err := json.ReadFromFile(*fetchServersFile, &fetchServers); err != nil {
+		herd.logger.Printf("Error loading fetch servers: %s\n", err)
+		return
+	}
+	herd.fetchServers = fetchServers
+}
+
+// getFetchServer returns the address of the object server for the location.
+// The most specific matching location is used, where location components are
+// separated by "/". The image server address is returned if there is no match.
+func (herd *Herd) getFetchServer(location string) string {
+	for location != "" {
+		if address, ok := herd.fetchServers[location]; ok {
+			return address
+		}
+		if index := strings.LastIndex(location, "/"); index < 0 {
+			break
+		} else {
+			location = location[:index]
+		}
+	}
+	return herd.imageManager.String()
+}
+
+// getFetchServer returns the address of the object server the sub should fetch
+// objects from. If the last fetch from a caching object server failed, the
+// image server is used for a while.
+func (sub *Sub) getFetchServer() string {
+	imageServerAddress := sub.herd.imageManager.String()
+	if time.Since(sub.lastFetchServerFailure) < fetchServerFailureHoldoff {
+		return imageServerAddress
+	}
+	return sub.herd.getFetchServer(sub.mdb.Location)
+}
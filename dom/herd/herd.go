@@ -61,6 +61,7 @@ func newHerd(imageServerAddress string, objectServer objectserver.ObjectServer,
 		herd.cpuSharer)
 	herd.currentScanStartTime = time.Now()
 	herd.setupMetrics(metricsDir)
+	herd.loadFetchServers()
 	go herd.subdInstallerLoop()
 	return &herd
 }
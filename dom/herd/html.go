@@ -35,6 +35,7 @@ func (herd *Herd) writeHtml(writer io.Writer) {
 	}
 	var numAliveSubs, numCompliantSubs, numDeviantSubs uint64
 	var numLikelyCompliantSubs, numDisruptionWaitingSubs uint64
+	var numDriftedSubs, numMaintenanceWaitingSubs uint64
 	var reachableMinuteSubs, reachable10MinuteSubs, reachableHourSubs uint64
 	var reachableDaySubs, reachableWeekSubs, reachableMonthSubs uint64
 	var unreachableMinuteSubs, unreachable10MinuteSubs uint64
@@ -46,6 +47,8 @@ func (herd *Herd) writeHtml(writer io.Writer) {
 		{&numDeviantSubs, selectDeviantSub},
 		{&numLikelyCompliantSubs, selectLikelyCompliantSub},
 		{&numDisruptionWaitingSubs, selectDisruptionWaitingSub},
+		{&numDriftedSubs, selectDriftedSub},
+		{&numMaintenanceWaitingSubs, selectMaintenanceWaitingSub},
 		{&reachableMinuteSubs, rDuration(time.Minute).selector},
 		{&reachable10MinuteSubs, rDuration(10 * time.Minute).selector},
 		{&reachableHourSubs, rDuration(time.Hour).selector},
@@ -143,12 +146,33 @@ func (herd *Herd) writeHtml(writer io.Writer) {
 		fmt.Fprintf(writer,
 			", <a href=\"showAllSubs?status=disruption%%20requested&status=disruption%%20denied&output=csv\">CSV</a>)<br>\n")
 	}
+	if numDriftedSubs > 0 || *auditMode {
+		fmt.Fprintf(writer,
+			"Number of drifted (audited) subs: <a href=\"showAllSubs?status=drifted\">%d</a>",
+			numDriftedSubs)
+		fmt.Fprintf(writer,
+			" (<a href=\"showDriftedSubs\">by path</a>")
+		fmt.Fprintf(writer,
+			", <a href=\"showDriftedSubs?output=json\">JSON</a>)<br>\n")
+	}
+	if numMaintenanceWaitingSubs > 0 {
+		fmt.Fprintf(writer,
+			"Number of subs waiting for maintenance window: <a href=\"showAllSubs?status=waiting%%20for%%20maintenance%%20window\">%d</a>",
+			numMaintenanceWaitingSubs)
+		if nextStart := herd.getNextMaintenanceTime(); !nextStart.IsZero() {
+			fmt.Fprintf(writer, " (next window starts: %s)",
+				nextStart.Format(format.TimeFormatSeconds))
+		}
+		fmt.Fprintln(writer, "<br>")
+	}
 	fmt.Fprintf(writer,
 		"Image status for subs: <a href=\"showImagesForSubs\">dashboard</a>")
 	fmt.Fprintf(writer,
 		" (<a href=\"listImagesForSubs?output=json\">JSON</a>")
 	fmt.Fprintf(writer,
 		", <a href=\"listImagesForSubs?output=csv\">CSV</a>)<br>\n")
+	fmt.Fprintln(writer,
+		`Subs running vulnerable images: <a href="showVulnerableSubs">dashboard</a><br>`)
 	subs := herd.getSelectedSubs(nil)
 	connectDurations := getConnectDurations(subs)
 	shortPollDurations := getPollDurations(subs, false)
@@ -256,6 +280,10 @@ func selectDeviantSub(sub *Sub) bool {
 		return true
 	case statusUpdatesDisabled:
 		return true
+	case statusDrifted:
+		return true
+	case statusWaitingForMaintenanceWindow:
+		return true
 	case statusUpdating:
 		return true
 	case statusUpdateDenied:
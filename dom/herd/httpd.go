@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/Cloud-Foundations/Dominator/lib/html"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 )
 
 func (herd *Herd) startServer(portNum uint, daemon bool) error {
@@ -26,6 +27,7 @@ func (herd *Herd) startServer(portNum uint, daemon bool) error {
 		herd.makeShowSubsHandler(selectCompliantSub, "compliant "))
 	html.HandleFunc("/showLikelyCompliantSubs",
 		herd.makeShowSubsHandler(selectLikelyCompliantSub, "likely compliant "))
+	html.HandleFunc("/showDriftedSubs", herd.showDriftedSubsHandler)
 	html.HandleFunc("/showDeviantSubs",
 		herd.makeShowSubsHandler(selectDeviantSub, "deviant "))
 	html.HandleFunc("/showImagesForSubs",
@@ -33,6 +35,9 @@ func (herd *Herd) startServer(portNum uint, daemon bool) error {
 	html.HandleFunc("/showReachableSubs", herd.showReachableSubsHandler)
 	html.HandleFunc("/showUnreachableSubs", herd.showUnreachableSubsHandler)
 	html.HandleFunc("/showSub", herd.showSubHandler)
+	html.HandleFunc("/showVulnerableSubs",
+		html.BenchmarkedHandler(herd.showVulnerableSubsHandler))
+	listener = srpc.NewTlsSniffingListener(listener)
 	if daemon {
 		go http.Serve(listener, nil)
 	} else {
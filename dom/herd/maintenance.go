@@ -0,0 +1,234 @@
+package herd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/timewindow"
+	proto "github.com/Cloud-Foundations/Dominator/proto/dominator"
+	subproto "github.com/Cloud-Foundations/Dominator/proto/sub"
+)
+
+const filePerms = syscall.S_IRUSR | syscall.S_IWUSR | syscall.S_IRGRP
+
+type maintenanceWindows struct {
+	config            proto.MaintenanceWindows
+	disruptiveChanges bool
+	windows           []*timewindow.Window
+}
+
+func parseMaintenanceWindows(config proto.MaintenanceWindows) (
+	*maintenanceWindows, error) {
+	mw := &maintenanceWindows{config: config}
+	switch config.Policy {
+	case "", proto.MaintenancePolicyAllChanges:
+	case proto.MaintenancePolicyDisruptiveChanges:
+		mw.disruptiveChanges = true
+	default:
+		return nil, errors.New("unknown maintenance policy: " + config.Policy)
+	}
+	for _, specification := range config.Windows {
+		window, err := timewindow.Parse(specification)
+		if err != nil {
+			return nil, err
+		}
+		mw.windows = append(mw.windows, window)
+	}
+	return mw, nil
+}
+
+// isOpen returns true if updates may be sent at the specified time.
+func (mw *maintenanceWindows) isOpen(t time.Time) bool {
+	if mw == nil || len(mw.windows) < 1 {
+		return true
+	}
+	for _, window := range mw.windows {
+		if window.IsOpen(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextStart returns the earliest start of a window at or after the specified
+// time.
+func (mw *maintenanceWindows) nextStart(t time.Time) time.Time {
+	var earliest time.Time
+	for _, window := range mw.windows {
+		start := window.NextStart(t)
+		if start.IsZero() {
+			continue
+		}
+		if earliest.IsZero() || start.Before(earliest) {
+			earliest = start
+		}
+	}
+	return earliest
+}
+
+func selectMaintenanceWaitingSub(sub *Sub) bool {
+	return sub.publishedStatus == statusWaitingForMaintenanceWindow
+}
+
+func (herd *Herd) getGlobalMaintenanceWindows() *maintenanceWindows {
+	herd.RLock()
+	defer herd.RUnlock()
+	return herd.maintenanceWindows
+}
+
+func (herd *Herd) getMaintenanceWindows() proto.MaintenanceWindows {
+	if mw := herd.getGlobalMaintenanceWindows(); mw != nil {
+		return mw.config
+	}
+	return proto.MaintenanceWindows{}
+}
+
+// getNextMaintenanceTime returns the earliest time at which a maintenance
+// window opens for a waiting sub.
+func (herd *Herd) getNextMaintenanceTime() time.Time {
+	var earliest time.Time
+	for _, sub := range herd.getSelectedSubs(selectMaintenanceWaitingSub) {
+		nextStart := sub.nextMaintenanceTime
+		if nextStart.IsZero() {
+			continue
+		}
+		if earliest.IsZero() || nextStart.Before(earliest) {
+			earliest = nextStart
+		}
+	}
+	return earliest
+}
+
+// loadMaintenanceWindows loads the global maintenance windows from the
+// specified file (if it exists) and saves future changes to it.
+func (herd *Herd) loadMaintenanceWindows(filename string) error {
+	var config proto.MaintenanceWindows
+	if err := json.ReadFromFile(filename, &config); err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	mw, err := parseMaintenanceWindows(config)
+	if err != nil {
+		return fmt.Errorf("error parsing: %s: %s", filename, err)
+	}
+	if len(mw.windows) < 1 {
+		mw = nil
+	}
+	herd.Lock()
+	defer herd.Unlock()
+	herd.maintenanceWindows = mw
+	herd.maintenanceWindowsFile = filename
+	return nil
+}
+
+func (herd *Herd) setMaintenanceWindows(
+	config proto.MaintenanceWindows) error {
+	mw, err := parseMaintenanceWindows(config)
+	if err != nil {
+		return err
+	}
+	if len(mw.windows) < 1 {
+		mw = nil
+	}
+	herd.Lock()
+	defer herd.Unlock()
+	if herd.maintenanceWindowsFile != "" {
+		err := json.WriteToFile(herd.maintenanceWindowsFile,
+			filePerms, "    ", config)
+		if err != nil {
+			return err
+		}
+	}
+	herd.maintenanceWindows = mw
+	return nil
+}
+
+// getMaintenanceWindows returns the maintenance windows for the sub. Windows
+// specified with the MaintenanceWindows MDB tag override the global windows.
+func (sub *Sub) getMaintenanceWindows() *maintenanceWindows {
+	specifications := sub.mdb.Tags["MaintenanceWindows"]
+	if specifications == "" {
+		sub.maintenanceWindowsKey = ""
+		sub.maintenanceWindows = nil
+		return sub.herd.getGlobalMaintenanceWindows()
+	}
+	policy := sub.mdb.Tags["MaintenancePolicy"]
+	key := policy + "|" + specifications
+	if key == sub.maintenanceWindowsKey {
+		return sub.maintenanceWindows
+	}
+	config := proto.MaintenanceWindows{Policy: policy}
+	for _, specification := range strings.Split(specifications, ";") {
+		specification = strings.TrimSpace(specification)
+		if specification != "" {
+			config.Windows = append(config.Windows, specification)
+		}
+	}
+	mw, err := parseMaintenanceWindows(config)
+	if err != nil {
+		// Fall back to the global windows rather than sending updates at any
+		// time.
+		sub.herd.logger.Printf(
+			"Error parsing maintenance windows for: %s: %s\n", sub, err)
+		mw = sub.herd.getGlobalMaintenanceWindows()
+	}
+	sub.maintenanceWindowsKey = key
+	sub.maintenanceWindows = mw
+	return mw
+}
+
+// holdForMaintenanceWindow returns true if the update should be held until
+// the next maintenance window opens.
+func (sub *Sub) holdForMaintenanceWindow(request subproto.UpdateRequest) bool {
+	sub.nextMaintenanceTime = time.Time{}
+	mw := sub.getMaintenanceWindows()
+	timeNow := time.Now()
+	if mw.isOpen(timeNow) {
+		return false
+	}
+	if mw.disruptiveChanges && !isDisruptiveUpdate(request) {
+		return false
+	}
+	sub.nextMaintenanceTime = mw.nextStart(timeNow)
+	return true
+}
+
+// isDisruptiveUpdate returns true if the update would run high impact
+// triggers or reboot the sub.
+func isDisruptiveUpdate(request subproto.UpdateRequest) bool {
+	if request.UpdateMode == subproto.UpdateModeABRoot {
+		return true
+	}
+	for _, trigger := range matchTriggers(request.Triggers,
+		getChangedPaths(request), request.PathsToDelete) {
+		if trigger.HighImpact || trigger.DoReboot {
+			return true
+		}
+	}
+	return false
+}
+
+// getNextMaintenanceTime returns the start of the next maintenance window if
+// the sub is waiting for one, else the zero time.
+func (sub *Sub) getNextMaintenanceTime() time.Time {
+	if sub.publishedStatus != statusWaitingForMaintenanceWindow {
+		return time.Time{}
+	}
+	return sub.nextMaintenanceTime
+}
+
+func (sub *Sub) maintenanceWindowHTML() string {
+	nextStart := sub.getNextMaintenanceTime()
+	if nextStart.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf(" (until %s)",
+		nextStart.Format(format.TimeFormatSeconds))
+}
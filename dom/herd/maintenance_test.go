@@ -0,0 +1,38 @@
+package herd
+
+import (
+	"path/filepath"
+	"testing"
+
+	proto "github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+func TestMaintenanceWindowsPersistence(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "maintenance-windows.json")
+	herd := &Herd{}
+	if err := herd.loadMaintenanceWindows(filename); err != nil {
+		t.Fatal(err)
+	}
+	if config := herd.getMaintenanceWindows(); len(config.Windows) > 0 {
+		t.Fatalf("unexpected windows: %v", config.Windows)
+	}
+	config := proto.MaintenanceWindows{
+		Policy:  proto.MaintenancePolicyDisruptiveChanges,
+		Windows: []string{"0 2 * * Sat,Sun 4h"},
+	}
+	if err := herd.setMaintenanceWindows(config); err != nil {
+		t.Fatal(err)
+	}
+	restarted := &Herd{}
+	if err := restarted.loadMaintenanceWindows(filename); err != nil {
+		t.Fatal(err)
+	}
+	loaded := restarted.getMaintenanceWindows()
+	if loaded.Policy != config.Policy || len(loaded.Windows) != 1 ||
+		loaded.Windows[0] != config.Windows[0] {
+		t.Fatalf("got: %v, want: %v", loaded, config)
+	}
+	if !restarted.getGlobalMaintenanceWindows().disruptiveChanges {
+		t.Error("policy not restored")
+	}
+}
@@ -201,12 +201,14 @@ func (herd *Herd) showSubsJSON(writer io.Writer,
 func (sub *Sub) makeInfo() proto.SubInfo {
 	return proto.SubInfo{
 		Machine:             sub.mdb,
+		ABRoot:              sub.lastABRootStatus,
 		LastDisruptionState: sub.lastDisruptionState,
 		LastNote:            sub.lastNote,
 		LastScanDuration:    sub.lastScanDuration,
 		LastSuccessfulImage: sub.lastSuccessfulImageName,
 		LastSyncTime:        sub.lastSyncTime,
 		LastUpdateTime:      sub.lastUpdateTime,
+		NextMaintenanceTime: sub.getNextMaintenanceTime(),
 		StartTime:           sub.startTime,
 		Status:              sub.publishedStatus.String(),
 		SystemUptime:        sub.systemUptime,
@@ -228,8 +230,9 @@ func showSub(tw *html.TableWriter, sub *Sub) {
 	sub.herd.showImage(tw, sub.mdb.PlannedImage, false)
 	sub.showBusy(tw)
 	tw.WriteData("",
-		fmt.Sprintf("<a href=\"showSub?%s\">%s</a>",
-			sub.mdb.Hostname, sub.publishedStatus.html()))
+		fmt.Sprintf("<a href=\"showSub?%s\">%s</a>%s",
+			sub.mdb.Hostname, sub.publishedStatus.html(),
+			sub.maintenanceWindowHTML()))
 	showSince(tw, sub.pollTime, sub.startTime)
 	showDuration(tw, sub.lastScanDuration, false)
 	showSince(tw, timeNow, sub.lastPollSucceededTime)
@@ -314,7 +317,14 @@ func (herd *Herd) showSubHandler(writer http.ResponseWriter,
 	newRow(w, "Busy time", false)
 	sub.showBusy(tw)
 	newRow(w, "Status", false)
-	tw.WriteData("", sub.publishedStatus.html())
+	tw.WriteData("", sub.publishedStatus.html()+sub.maintenanceWindowHTML())
+	if drift := sub.getLastDrift(); drift != nil {
+		newRow(w, "Drift", false)
+		tw.WriteData("", fmt.Sprintf(
+			"%d to add, %d to change, %d to delete, %d triggers",
+			len(drift.PathsToAdd), len(drift.PathsToChange),
+			len(drift.PathsToDelete), len(drift.Triggers)))
+	}
 	if sub.lastWriteError != "" {
 		newRow(w, "Last write error", false)
 		tw.WriteData("", sub.lastWriteError)
@@ -327,6 +337,10 @@ func (herd *Herd) showSubHandler(writer http.ResponseWriter,
 		newRow(w, "Location", false)
 		tw.WriteData("", sub.mdb.Location)
 	}
+	if len(sub.herd.fetchServers) > 0 {
+		newRow(w, "Fetch server", false)
+		tw.WriteData("", sub.getFetchServer())
+	}
 	newRow(w, "Time since last successful poll", false)
 	showSince(tw, timeNow, sub.lastPollSucceededTime)
 	newRow(w, "Time since last update", false)
@@ -343,6 +357,18 @@ func (herd *Herd) showSubHandler(writer http.ResponseWriter,
 	showDuration(tw, sub.lastComputeUpdateCpuDuration, false)
 	newRow(w, "Last disruption state", false)
 	tw.WriteData("", sub.lastDisruptionState.String())
+	if abRoot := sub.lastABRootStatus; abRoot != nil {
+		newRow(w, "A/B root", false)
+		text := fmt.Sprintf("%s (active: %s, inactive: %s)",
+			abRoot.State, abRoot.ActiveLabel, abRoot.InactiveLabel)
+		if abRoot.StagedImage != "" {
+			text += ", staged: " + abRoot.StagedImage
+		}
+		if abRoot.LastError != "" {
+			text += ", error: " + abRoot.LastError
+		}
+		tw.WriteData("", text)
+	}
 	if sub.systemUptime != nil {
 		newRow(w, "System uptime", false)
 		showDuration(tw, *sub.systemUptime, false)
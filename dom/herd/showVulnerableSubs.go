@@ -0,0 +1,116 @@
+package herd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	imageclient "github.com/Cloud-Foundations/Dominator/imageserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/html"
+	"github.com/Cloud-Foundations/Dominator/lib/osv"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/dominator"
+	imageproto "github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+type vulnerableImage struct {
+	hostnames []string
+	imageName string
+	matches   []osv.Match
+}
+
+func (herd *Herd) showVulnerableSubsHandler(w io.Writer, req *http.Request) {
+	fmt.Fprintf(w, "<title>Dominator vulnerable subs</title>")
+	fmt.Fprintln(w, `<style>
+                          table, th, td {
+                          border-collapse: collapse;
+                          }
+                          </style>`)
+	fmt.Fprintln(w, "<body>")
+	fmt.Fprintln(w, "<h3>Subs running images with known vulnerabilities</h3>")
+	vulnerableImages, err := herd.getVulnerableImages()
+	if err != nil {
+		fmt.Fprintf(w, "<font color=\"red\">%s</font><br>\n", err)
+	}
+	fmt.Fprintln(w, `<table border="1" style="width:100%">`)
+	tw, _ := html.NewTableWriter(w, true, "Vulnerability", "Package",
+		"Version", "Fixed Version", "Severity", "Image", "Subs")
+	for _, vulnImage := range vulnerableImages {
+		hostLinks := make([]string, 0, len(vulnImage.hostnames))
+		for _, hostname := range vulnImage.hostnames {
+			hostLinks = append(hostLinks,
+				fmt.Sprintf("<a href=\"showSub?%s\">%s</a>",
+					hostname, hostname))
+		}
+		subs := strings.Join(hostLinks, " ")
+		for _, match := range vulnImage.matches {
+			tw.WriteRow("", "",
+				match.Id,
+				match.PackageName,
+				match.PackageVersion,
+				match.FixedVersion,
+				match.Severity,
+				fmt.Sprintf("<a href=\"http://%s/showImage?%s\">%s</a>",
+					herd.imageManager, vulnImage.imageName,
+					vulnImage.imageName),
+				subs,
+			)
+		}
+	}
+	tw.Close()
+	fmt.Fprintln(w, "</body>")
+}
+
+// getVulnerableImages will scan the required images for all subs, joining the
+// results with the sub information.
+func (herd *Herd) getVulnerableImages() ([]*vulnerableImage, error) {
+	subInfos, err := herd.getInfoForSubs(proto.GetInfoForSubsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	imagesByName := make(map[string]*vulnerableImage)
+	for _, subInfo := range subInfos {
+		if subInfo.RequiredImage == "" {
+			continue
+		}
+		vulnImage := imagesByName[subInfo.RequiredImage]
+		if vulnImage == nil {
+			vulnImage = &vulnerableImage{imageName: subInfo.RequiredImage}
+			imagesByName[subInfo.RequiredImage] = vulnImage
+		}
+		vulnImage.hostnames = append(vulnImage.hostnames, subInfo.Hostname)
+	}
+	if len(imagesByName) < 1 {
+		return nil, nil
+	}
+	client, err := srpc.DialHTTP("tcp", herd.imageManager.String(), 0)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	vulnerableImages := make([]*vulnerableImage, 0, len(imagesByName))
+	var firstError error
+	for imageName, vulnImage := range imagesByName {
+		reply, err := imageclient.ScanImageVulnerabilities(client,
+			imageproto.ScanImageVulnerabilitiesRequest{ImageName: imageName})
+		if err != nil {
+			if firstError == nil {
+				firstError = fmt.Errorf("error scanning: %s: %s",
+					imageName, err)
+			}
+			continue
+		}
+		if len(reply.Vulnerabilities) < 1 {
+			continue
+		}
+		vulnImage.matches = reply.Vulnerabilities
+		vulnerableImages = append(vulnerableImages, vulnImage)
+	}
+	sort.Slice(vulnerableImages, func(left, right int) bool {
+		return vulnerableImages[left].imageName <
+			vulnerableImages[right].imageName
+	})
+	return vulnerableImages, firstError
+}
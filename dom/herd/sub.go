@@ -331,6 +331,16 @@ func (sub *Sub) poll(srpcClient *srpc.Client, previousStatus subStatus) {
 		sub.herd.updatesDisabledReason == "" && !sub.mdb.DisableUpdates {
 		sub.generationCount = 0 // Force a full poll.
 	}
+	// If the sub was audited and is no longer in audit mode, force a full poll.
+	if previousStatus == statusDrifted && !sub.isAuditOnly() {
+		sub.generationCount = 0 // Force a full poll.
+	}
+	// If the last update was held and a maintenance window is now open, force a
+	// full poll.
+	if previousStatus == statusWaitingForMaintenanceWindow &&
+		sub.getMaintenanceWindows().isOpen(time.Now()) {
+		sub.generationCount = 0 // Force a full poll.
+	}
 	// If the last update was disabled due to a safety check and there is a
 	// pending SafetyClear, force a full poll to re-compute the update.
 	if previousStatus == statusUnsafeUpdate && sub.pendingSafetyClear {
@@ -373,6 +383,7 @@ func (sub *Sub) poll(srpcClient *srpc.Client, previousStatus subStatus) {
 		logger.Printf("Error calling %s.Poll(): %s\n", sub, err)
 		return
 	}
+	sub.lastABRootStatus = reply.ABRoot
 	sub.lastDisruptionState = reply.DisruptionState
 	sub.lastPollSucceededTime = time.Now()
 	sub.lastSuccessfulImageName = reply.LastSuccessfulImageName
@@ -447,6 +458,9 @@ func (sub *Sub) poll(srpcClient *srpc.Client, previousStatus subStatus) {
 	if previousStatus == statusFetching && reply.LastFetchError != "" {
 		logger.Printf("Fetch failure for: %s: %s\n", sub, reply.LastFetchError)
 		sub.status = statusFailedToFetch
+		if sub.lastFetchServer != sub.herd.imageManager.String() {
+			sub.lastFetchServerFailure = time.Now()
+		}
 		if sub.fileSystem == nil {
 			sub.generationCount = 0 // Force a full poll next cycle.
 			return
@@ -518,11 +532,14 @@ func (sub *Sub) poll(srpcClient *srpc.Client, previousStatus subStatus) {
 		sub.status = previousStatus
 		return
 	}
-	if idle, status := sub.fetchMissingObjects(srpcClient, sub.requiredImage,
-		reply.FreeSpace, true); !idle {
-		sub.status = status
-		sub.reclaim()
-		return
+	auditOnly := sub.isAuditOnly()
+	if !auditOnly {
+		if idle, status := sub.fetchMissingObjects(srpcClient,
+			sub.requiredImage, reply.FreeSpace, true); !idle {
+			sub.status = status
+			sub.reclaim()
+			return
+		}
 	}
 	sub.status = statusComputingUpdate
 	if idle, status := sub.sendUpdate(srpcClient); !idle {
@@ -530,6 +547,11 @@ func (sub *Sub) poll(srpcClient *srpc.Client, previousStatus subStatus) {
 		sub.reclaim()
 		return
 	}
+	if auditOnly { // Nothing may be written to the sub.
+		sub.status = statusSynced
+		sub.reclaim()
+		return
+	}
 	if idle, status := sub.fetchMissingObjects(srpcClient, sub.plannedImage,
 		reply.FreeSpace, false); !idle {
 		if status != statusImageNotReady && status != statusNotEnoughFreeSpace {
@@ -633,9 +655,10 @@ func (sub *Sub) fetchMissingObjects(srpcClient *srpc.Client, img *image.Image,
 		if !sub.checkForEnoughSpace(freeSpace, objectsToFetch) {
 			return false, statusNotEnoughFreeSpace
 		}
-		logger.Printf("Calling %s:Subd.Fetch() for: %d objects\n",
-			sub, len(objectsToFetch))
-		err := client.Fetch(srpcClient, sub.herd.imageManager.String(),
+		fetchServer := sub.getFetchServer()
+		logger.Printf("Calling %s:Subd.Fetch() for: %d objects from: %s\n",
+			sub, len(objectsToFetch), fetchServer)
+		err := client.Fetch(srpcClient, fetchServer,
 			objectcache.ObjectMapToCache(objectsToFetch))
 		if err != nil {
 			srpcClient.Close()
@@ -645,6 +668,7 @@ func (sub *Sub) fetchMissingObjects(srpcClient *srpc.Client, img *image.Image,
 			}
 			return false, statusFailedToFetch
 		}
+		sub.lastFetchServer = fetchServer
 		returnAvailable = false
 		returnStatus = statusFetching
 	}
@@ -681,11 +705,21 @@ func (sub *Sub) sendUpdate(srpcClient *srpc.Client) (bool, subStatus) {
 	if idle, missing := sub.buildUpdateRequest(&request); missing {
 		return false, statusMissingComputedFile
 	} else if idle {
+		sub.setLastDrift(nil)
 		return true, statusSynced
 	}
+	if sub.isAuditOnly() {
+		sub.setLastDrift(sub.computeDrift(request))
+		return false, statusDrifted
+	}
+	sub.setLastDrift(nil)
 	if sub.mdb.DisableUpdates || sub.herd.updatesDisabledReason != "" {
 		return false, statusUpdatesDisabled
 	}
+	request.UpdateMode = sub.getUpdateMode()
+	if sub.holdForMaintenanceWindow(request) {
+		return false, statusWaitingForMaintenanceWindow
+	}
 	if !sub.pendingSafetyClear {
 		// Perform a cheap safety check: if over half the inodes will be deleted
 		// then mark the update as unsafe.
@@ -701,6 +735,12 @@ func (sub *Sub) sendUpdate(srpcClient *srpc.Client) (bool, subStatus) {
 	if sub.pendingForceDisruptiveUpdate {
 		request.ForceDisruption = true
 	}
+	if request.UpdateMode == subproto.UpdateModeABRoot {
+		request.ImageFileSystem = sub.makeImageFileSystem()
+		if request.ImageFileSystem == nil {
+			return false, statusMissingComputedFile
+		}
+	}
 	sub.status = statusSendingUpdate
 	sub.lastUpdateTime = time.Now()
 	logger.Printf("Calling %s:Subd.Update() for image: %s\n",
@@ -715,6 +755,7 @@ func (sub *Sub) sendUpdate(srpcClient *srpc.Client) (bool, subStatus) {
 	}
 	sub.pendingSafetyClear = false
 	sub.pendingForceDisruptiveUpdate = false
+	sub.recordUpdate(request)
 	return false, statusUpdating
 }
 
@@ -66,6 +66,10 @@ func (status subStatus) string() string {
 		return "missing computed file"
 	case statusUpdatesDisabled:
 		return "updates disabled"
+	case statusDrifted:
+		return "drifted"
+	case statusWaitingForMaintenanceWindow:
+		return "waiting for maintenance window"
 	case statusUnsafeUpdate:
 		return "unsafe update"
 	case statusDisruptionRequested:
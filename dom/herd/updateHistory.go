@@ -0,0 +1,60 @@
+package herd
+
+import (
+	"errors"
+	"sort"
+
+	proto "github.com/Cloud-Foundations/Dominator/proto/dominator"
+	subproto "github.com/Cloud-Foundations/Dominator/proto/sub"
+)
+
+func (herd *Herd) getUpdateHistory(request proto.GetUpdateHistoryRequest) (
+	[]proto.UpdateHistoryEntry, error) {
+	if herd.updateJournal == nil {
+		return nil, errors.New("update journal not enabled")
+	}
+	return herd.updateJournal.GetHistory(request)
+}
+
+// recordUpdate appends a summary of an update which was sent to the sub to
+// the update journal.
+func (sub *Sub) recordUpdate(request subproto.UpdateRequest) {
+	if sub.herd.updateJournal == nil {
+		return
+	}
+	entry := proto.UpdateHistoryEntry{
+		Hostname:     sub.mdb.Hostname,
+		ImageName:    request.ImageName,
+		PathsChanged: getChangedPaths(request),
+		PathsDeleted: request.PathsToDelete,
+		Time:         sub.lastUpdateTime,
+	}
+	for _, trigger := range matchTriggers(request.Triggers,
+		entry.PathsChanged, entry.PathsDeleted) {
+		entry.Triggers = append(entry.Triggers, trigger.Service)
+	}
+	if err := sub.herd.updateJournal.Append(entry); err != nil {
+		sub.herd.logger.Printf("Error recording update for: %s: %s\n",
+			sub, err)
+	}
+}
+
+// getChangedPaths returns the sorted list of paths which the update request
+// would create or change.
+func getChangedPaths(request subproto.UpdateRequest) []string {
+	var pathnames []string
+	for _, inode := range request.DirectoriesToMake {
+		pathnames = append(pathnames, inode.Name)
+	}
+	for _, inode := range request.InodesToMake {
+		pathnames = append(pathnames, inode.Name)
+	}
+	for _, hardlink := range request.HardlinksToMake {
+		pathnames = append(pathnames, hardlink.NewLink)
+	}
+	for _, inode := range request.InodesToChange {
+		pathnames = append(pathnames, inode.Name)
+	}
+	sort.Strings(pathnames)
+	return pathnames
+}
@@ -0,0 +1,44 @@
+package journal
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	proto "github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+// Journal records the updates which have been sent to subs. There is one
+// append-only file per sub in the journal directory.
+type Journal struct {
+	options Options
+	params  Params
+	mutex   sync.Mutex // Serialise file writes.
+}
+
+type Options struct {
+	Directory        string
+	MaxAge           time.Duration // Zero: no limit.
+	MaxEntriesPerSub uint          // Zero: no limit.
+}
+
+type Params struct {
+	Logger log.DebugLogger
+}
+
+// New opens the journal in the specified directory, creating it if needed,
+// and starts a goroutine to periodically enforce the retention limits.
+func New(options Options, params Params) (*Journal, error) {
+	return newJournal(options, params)
+}
+
+// Append adds an entry to the journal for a sub.
+func (j *Journal) Append(entry proto.UpdateHistoryEntry) error {
+	return j.append(entry)
+}
+
+// GetHistory returns the journal entries matching the request, newest first.
+func (j *Journal) GetHistory(request proto.GetUpdateHistoryRequest) (
+	[]proto.UpdateHistoryEntry, error) {
+	return j.getHistory(request)
+}
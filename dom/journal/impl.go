@@ -0,0 +1,177 @@
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	proto "github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+const retentionInterval = time.Hour
+
+func newJournal(options Options, params Params) (*Journal, error) {
+	if options.Directory == "" {
+		return nil, errors.New("no journal directory specified")
+	}
+	err := os.MkdirAll(options.Directory, fsutil.DirPerms)
+	if err != nil {
+		return nil, err
+	}
+	j := &Journal{options: options, params: params}
+	if options.MaxAge > 0 || options.MaxEntriesPerSub > 0 {
+		go j.retentionLoop()
+	}
+	return j, nil
+}
+
+func (j *Journal) append(entry proto.UpdateHistoryEntry) error {
+	filename, err := j.getFilename(entry.Hostname)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	file, err := os.OpenFile(filename, os.O_RDWR|os.O_APPEND|os.O_CREATE,
+		fsutil.PublicFilePerms)
+	if err != nil {
+		return err
+	}
+	// Terminate a torn entry so that it does not corrupt this one.
+	buffer := make([]byte, 1)
+	if fi, err := file.Stat(); err == nil && fi.Size() > 0 {
+		_, err := file.ReadAt(buffer, fi.Size()-1)
+		if err == nil && buffer[0] != '\n' {
+			data = append([]byte{'\n'}, data...)
+		}
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (j *Journal) getFilename(hostname string) (string, error) {
+	if hostname == "" || hostname[0] == '.' ||
+		strings.ContainsRune(hostname, '/') {
+		return "", errors.New("bad hostname: " + hostname)
+	}
+	return filepath.Join(j.options.Directory, hostname), nil
+}
+
+func (j *Journal) getHistory(request proto.GetUpdateHistoryRequest) (
+	[]proto.UpdateHistoryEntry, error) {
+	var hostnames []string
+	if request.Hostname != "" {
+		hostnames = []string{request.Hostname}
+	} else {
+		var err error
+		hostnames, err = fsutil.ReadDirnames(j.options.Directory, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var minTime time.Time
+	if request.MaxAge > 0 {
+		minTime = time.Now().Add(-request.MaxAge)
+	}
+	var entries []proto.UpdateHistoryEntry
+	for _, hostname := range hostnames {
+		if strings.HasPrefix(hostname, ".") {
+			continue
+		}
+		subEntries, err := j.readEntries(hostname)
+		if err != nil {
+			if os.IsNotExist(err) && request.Hostname != "" {
+				return nil, nil
+			}
+			return nil, err
+		}
+		for _, entry := range subEntries {
+			if entry.Time.Before(minTime) {
+				continue
+			}
+			if request.PathName != "" {
+				if !filterEntry(&entry, request.PathName) {
+					continue
+				}
+			}
+			entries = append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(left, right int) bool {
+		return entries[left].Time.After(entries[right].Time)
+	})
+	if request.MaxEntries > 0 && uint(len(entries)) > request.MaxEntries {
+		entries = entries[:request.MaxEntries]
+	}
+	return entries, nil
+}
+
+// filterEntry will remove the paths from the entry which are not the specified
+// path or below it. It returns false if no paths remain.
+func filterEntry(entry *proto.UpdateHistoryEntry, pathName string) bool {
+	entry.PathsChanged = filterPaths(entry.PathsChanged, pathName)
+	entry.PathsDeleted = filterPaths(entry.PathsDeleted, pathName)
+	return len(entry.PathsChanged) > 0 || len(entry.PathsDeleted) > 0
+}
+
+func filterPaths(pathNames []string, pathName string) []string {
+	var matched []string
+	for _, name := range pathNames {
+		if name == pathName || pathName == "/" ||
+			strings.HasPrefix(name, pathName+"/") {
+			matched = append(matched, name)
+		}
+	}
+	return matched
+}
+
+func (j *Journal) readEntries(hostname string) (
+	[]proto.UpdateHistoryEntry, error) {
+	filename, err := j.getFilename(hostname)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var entries []proto.UpdateHistoryEntry
+	reader := bufio.NewReader(file)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			// A torn entry may follow a crash: skip it and keep reading.
+			var entry proto.UpdateHistoryEntry
+			if err := json.Unmarshal(line, &entry); err != nil {
+				j.params.Logger.Printf(
+					"skipping bad journal entry for: %s at line %d: %s\n",
+					hostname, lineNumber, err)
+			} else {
+				entries = append(entries, entry)
+			}
+		}
+		if err == io.EOF {
+			return entries, nil
+		}
+	}
+}
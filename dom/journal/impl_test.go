@@ -0,0 +1,91 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+	proto "github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+func TestAppendAndGetHistory(t *testing.T) {
+	j, err := New(Options{Directory: t.TempDir(), MaxEntriesPerSub: 2},
+		Params{Logger: testlogger.New(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for index, hostname := range []string{"h0", "h1", "h0", "h0"} {
+		err := j.Append(proto.UpdateHistoryEntry{
+			Hostname:     hostname,
+			ImageName:    "image",
+			PathsChanged: []string{"/etc/foo", "/etc/foo.d/bar", "/etc/food"},
+			Time:         now.Add(time.Duration(index) * time.Second),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := j.GetHistory(proto.GetUpdateHistoryRequest{
+		PathName: "/etc/foo.d",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("got %d entries, want 4", len(entries))
+	}
+	if entries[0].Hostname != "h0" ||
+		!entries[0].Time.Equal(now.Add(3*time.Second)) {
+		t.Errorf("newest entry not first: %v", entries[0])
+	}
+	if len(entries[0].PathsChanged) != 1 {
+		t.Errorf("paths not filtered: %v", entries[0].PathsChanged)
+	}
+	if err := j.enforceRetentionForSub("h0"); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = j.GetHistory(proto.GetUpdateHistoryRequest{Hostname: "h0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries after retention, want 2", len(entries))
+	}
+}
+
+func TestReadEntriesSkipsBadLines(t *testing.T) {
+	directory := t.TempDir()
+	j, err := New(Options{Directory: directory},
+		Params{Logger: testlogger.New(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = j.Append(proto.UpdateHistoryEntry{Hostname: "h0", ImageName: "i0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.OpenFile(filepath.Join(directory, "h0"),
+		os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString("garbage\n{\"Hostname\":"); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	err = j.Append(proto.UpdateHistoryEntry{Hostname: "h0", ImageName: "i1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := j.readEntries("h0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].ImageName != "i0" ||
+		entries[1].ImageName != "i1" {
+		t.Errorf("entries: %v", entries)
+	}
+}
@@ -0,0 +1,86 @@
+package journal
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	proto "github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+func (j *Journal) retentionLoop() {
+	for ; ; time.Sleep(retentionInterval) {
+		j.enforceRetention()
+	}
+}
+
+func (j *Journal) enforceRetention() {
+	hostnames, err := fsutil.ReadDirnames(j.options.Directory, false)
+	if err != nil {
+		j.params.Logger.Println(err)
+		return
+	}
+	for _, hostname := range hostnames {
+		if strings.HasPrefix(hostname, ".") {
+			continue
+		}
+		if err := j.enforceRetentionForSub(hostname); err != nil {
+			j.params.Logger.Printf("error trimming journal for: %s: %s\n",
+				hostname, err)
+		}
+	}
+}
+
+// enforceRetentionForSub rewrites the journal for a sub, discarding entries
+// which are too old or exceed the maximum number of entries. The journal file
+// is removed if no entries remain.
+func (j *Journal) enforceRetentionForSub(hostname string) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	entries, err := j.readEntries(hostname)
+	if err != nil {
+		return err
+	}
+	keep := entries
+	if j.options.MaxAge > 0 {
+		minTime := time.Now().Add(-j.options.MaxAge)
+		for len(keep) > 0 && keep[0].Time.Before(minTime) {
+			keep = keep[1:]
+		}
+	}
+	if max := j.options.MaxEntriesPerSub; max > 0 && uint(len(keep)) > max {
+		keep = keep[uint(len(keep))-max:]
+	}
+	if len(keep) == len(entries) {
+		return nil
+	}
+	filename, err := j.getFilename(hostname)
+	if err != nil {
+		return err
+	}
+	if len(keep) < 1 {
+		return os.Remove(filename)
+	}
+	j.params.Logger.Debugf(0, "trimming %d entries from journal for: %s\n",
+		len(entries)-len(keep), hostname)
+	return writeEntries(filename, keep)
+}
+
+func writeEntries(filename string, entries []proto.UpdateHistoryEntry) error {
+	writer, err := fsutil.CreateRenamingWriter(filename,
+		fsutil.PublicFilePerms)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(writer)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			writer.Abort()
+			writer.Close()
+			return err
+		}
+	}
+	return writer.Close()
+}
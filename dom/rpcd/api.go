@@ -22,15 +22,28 @@ func Setup(herd *herd.Herd, logger log.Logger) {
 				"ClearSafetyShutoff":    1,
 				"ForceDisruptiveUpdate": 1,
 				"GetInfoForSubs":        1,
+				"GetSubDrift":           1,
+				"GetUpdateHistory":      1,
 				"ListSubs":              1,
 			}),
 	}
 	srpc.RegisterNameWithOptions("Dominator", rpcObj,
 		srpc.ReceiverOptions{
+			AuditedMethods: []string{
+				"ClearSafetyShutoff",
+				"ConfigureSubs",
+				"DisableUpdates",
+				"EnableUpdates",
+				"ForceDisruptiveUpdate",
+				"SetDefaultImage",
+				"SetMaintenanceWindows",
+			},
 			PublicMethods: []string{
 				"ClearSafetyShutoff",
 				"ForceDisruptiveUpdate",
 				"GetInfoForSubs",
+				"GetSubDrift",
+				"GetUpdateHistory",
 				"ListSubs",
 			}})
 }
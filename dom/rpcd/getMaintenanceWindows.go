@@ -0,0 +1,14 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+func (t *rpcType) GetMaintenanceWindows(conn *srpc.Conn,
+	request dominator.GetMaintenanceWindowsRequest,
+	reply *dominator.GetMaintenanceWindowsResponse) error {
+	*reply = dominator.GetMaintenanceWindowsResponse(
+		t.herd.GetMaintenanceWindows())
+	return nil
+}
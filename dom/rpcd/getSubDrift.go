@@ -0,0 +1,19 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+func (t *rpcType) GetSubDrift(conn *srpc.Conn,
+	request dominator.GetSubDriftRequest,
+	reply *dominator.GetSubDriftResponse) error {
+	drift, err := t.herd.GetSubDrift(request.Hostname)
+	response := dominator.GetSubDriftResponse{
+		Error: errors.ErrorToString(err),
+		Drift: drift,
+	}
+	*reply = response
+	return nil
+}
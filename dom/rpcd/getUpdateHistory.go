@@ -0,0 +1,19 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+func (t *rpcType) GetUpdateHistory(conn *srpc.Conn,
+	request dominator.GetUpdateHistoryRequest,
+	reply *dominator.GetUpdateHistoryResponse) error {
+	entries, err := t.herd.GetUpdateHistory(request)
+	response := dominator.GetUpdateHistoryResponse{
+		Error:   errors.ErrorToString(err),
+		Entries: entries,
+	}
+	*reply = response
+	return nil
+}
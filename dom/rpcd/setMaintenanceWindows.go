@@ -0,0 +1,23 @@
+package rpcd
+
+import (
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/dominator"
+)
+
+func (t *rpcType) SetMaintenanceWindows(conn *srpc.Conn,
+	request dominator.SetMaintenanceWindowsRequest,
+	reply *dominator.SetMaintenanceWindowsResponse) error {
+	windows := strings.Join(request.Windows, "; ")
+	if conn.Username() == "" {
+		t.logger.Printf("SetMaintenanceWindows(%s, %s)\n",
+			request.Policy, windows)
+	} else {
+		t.logger.Printf("SetMaintenanceWindows(%s, %s): by %s\n",
+			request.Policy, windows, conn.Username())
+	}
+	return t.herd.SetMaintenanceWindows(
+		dominator.MaintenanceWindows(request))
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/fleetmanager/topology"
 	"github.com/Cloud-Foundations/Dominator/lib/html"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 )
 
 type HtmlWriter interface {
@@ -30,6 +31,7 @@ func StartServer(portNum uint, logger log.DebugLogger) (*Server, error) {
 	}
 	server := &Server{logger: logger}
 	html.HandleFunc("/", server.statusHandler)
+	listener = srpc.NewTlsSniffingListener(listener)
 	go http.Serve(listener, nil)
 	return server, nil
 }
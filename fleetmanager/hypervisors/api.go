@@ -40,6 +40,7 @@ type hypervisorType struct {
 	closeClientChannel   chan<- struct{}
 	deleteScheduled      bool
 	disabled             bool
+	haveSecurityGroups   bool
 	healthStatus         string
 	lastIpmiProbe        time.Time
 	localTags            tags.Tags
@@ -48,8 +49,10 @@ type hypervisorType struct {
 	memoryInMiB          uint64
 	migratingVms         map[string]*vmInfoType // Key: VM IP address.
 	numCPUs              uint
+	numaNodes            []hyper_proto.NumaNode
 	ownerUsers           map[string]struct{}
 	probeStatus          probeStatus
+	securityGroups       []hyper_proto.SecurityGroup
 	serialNumber         string
 	subnets              []hyper_proto.Subnet
 	totalVolumeBytes     uint64
@@ -74,6 +77,7 @@ type Manager struct {
 	ipmiPasswordFile string
 	ipmiUsername     string
 	logger           log.DebugLogger
+	securityNotifier chan struct{}
 	storer           Storer
 	mutex            sync.RWMutex               // Protect everything below.
 	allocatingIPs    map[string]struct{}        // Key: VM IP address.
@@ -81,11 +85,20 @@ type Manager struct {
 	locations        map[string]*locationType   // Key: location.
 	migratingIPs     map[string]struct{}        // Key: VM IP address.
 	notifiers        map[<-chan fm_proto.Update]*locationType
+	reservations     []placementReservationType // Pending placements.
 	topology         *topology.Topology
 	subnets          map[string]*subnetType // Key: Gateway IP.
 	vms              map[string]*vmInfoType // Key: VM IP address.
 }
 
+type placementReservationType struct {
+	expires   time.Time
+	group     string
+	hostname  string // Hypervisor.
+	location  string // Hypervisor.
+	vmToPlace string // IP address, may be empty.
+}
+
 type probeStatus uint
 
 type serialStorer interface {
@@ -142,6 +155,10 @@ func (m *Manager) ChangeMachineTags(hostname string,
 	return m.changeMachineTags(hostname, authInfo, tgs)
 }
 
+func (m *Manager) CheckPlacement(request fm_proto.CheckPlacementRequest) error {
+	return m.checkPlacement(request)
+}
+
 func (m *Manager) CloseUpdateChannel(channel <-chan fm_proto.Update) {
 	m.closeUpdateChannel(channel)
 }
@@ -37,7 +37,20 @@ func (m *Manager) writeHtml(writer io.Writer) {
 		}
 	}
 	numVMs := uint(len(m.vms))
+	var numExpiringVMs uint
+	for _, vm := range m.vms {
+		if !vm.LifecyclePolicy.ExpiresAt.IsZero() {
+			numExpiringVMs++
+		}
+	}
 	m.mutex.RUnlock()
+	placementGroups := m.getPlacementGroups()
+	var numViolatedGroups uint
+	for _, group := range placementGroups {
+		if group.Violations > 0 {
+			numViolatedGroups++
+		}
+	}
 	writeCountLinksHT(writer, "Number of hypervisors known",
 		"listHypervisors", numMachines)
 	writeCountLinksHT(writer, "Number of hypervisors powered off",
@@ -52,6 +65,12 @@ func (m *Manager) writeHtml(writer io.Writer) {
 		"listVMs", numVMs)
 	writeLinksHTJ(writer, "VMs by primary owner",
 		"listVMsByPrimaryOwner", numVMs)
+	writeCountLinksHTJ(writer, "Number of VMs with an expiry time",
+		"listExpiringVMs", numExpiringVMs)
+	writeCountLinksHTJ(writer, "Number of placement groups",
+		"listPlacementGroups", uint(len(placementGroups)))
+	writeCountLinksHT(writer, "Number of placement groups with violations",
+		"listPlacementGroups?state=violated", numViolatedGroups)
 	fmt.Fprint(writer,
 		`Hypervisor locations: <a href="listLocations?status=all">all</a>`)
 	fmt.Fprint(writer,
@@ -23,6 +23,14 @@ func (h *hypervisorType) makeProtoHypervisor(
 	protoHypervisor.Machine.MemoryInMiB = h.memoryInMiB
 	protoHypervisor.NumCPUs = h.numCPUs
 	protoHypervisor.TotalVolumeBytes = h.totalVolumeBytes
+	if len(h.numaNodes) > 0 {
+		vms := make([]*hyper_proto.VmInfo, 0, len(h.vms))
+		for _, vm := range h.vms {
+			vms = append(vms, &vm.VmInfo)
+		}
+		protoHypervisor.NumaNodes = hyper_proto.CountNumaNodeAllocations(
+			h.numaNodes, vms)
+	}
 	if includeVMs {
 		protoHypervisor.VMs = make([]hyper_proto.VmInfo, 0, len(h.vms))
 		for _, vm := range h.vms {
@@ -77,6 +85,8 @@ func (m *Manager) getHypervisorsInLocation(
 	if err != nil {
 		return fm_proto.GetHypervisorsInLocationResponse{}, err
 	}
+	hypervisors = m.filterHypervisorsForPlacement(hypervisors,
+		request.PlacementGroup, request.VmToPlace)
 	protoHypervisors := make([]fm_proto.Hypervisor, 0, len(hypervisors))
 	for _, hypervisor := range hypervisors {
 		protoHypervisors = append(protoHypervisors,
@@ -0,0 +1,104 @@
+package hypervisors
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/constants"
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/html"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/url"
+)
+
+// getExpiringVMs returns the VMs which have an expiry time in their lifecycle
+// policy, soonest first.
+func (m *Manager) getExpiringVMs() []*vmInfoType {
+	var vms []*vmInfoType
+	for _, vm := range m.getVMs(true) {
+		if !vm.LifecyclePolicy.ExpiresAt.IsZero() {
+			vms = append(vms, vm)
+		}
+	}
+	sort.SliceStable(vms, func(i, j int) bool {
+		return vms[i].LifecyclePolicy.ExpiresAt.Before(
+			vms[j].LifecyclePolicy.ExpiresAt)
+	})
+	return vms
+}
+
+func (m *Manager) listExpiringVMs(writer io.Writer, vms []*vmInfoType,
+	outputType uint) {
+	now := time.Now()
+	switch outputType {
+	case url.OutputTypeHtml:
+		fmt.Fprintln(writer, `<table border="1" style="width:100%">`)
+		tw, _ := html.NewTableWriter(writer, true, "IP Addr", "Name(tag)",
+			"State", "Expires At", "Expires In", "On Expiry", "Primary Owner",
+			"Hypervisor")
+		for _, vm := range vms {
+			policy := vm.LifecyclePolicy
+			var foreground, expiresIn string
+			if policy.ExpiresAt.After(now) {
+				expiresIn = format.Duration(policy.ExpiresAt.Sub(now))
+				if policy.ExpiresAt.Sub(now) < policy.WarningPeriod {
+					foreground = "#c00000"
+				}
+			} else {
+				expiresIn = "expired"
+				foreground = "grey"
+			}
+			onExpiry := "stop"
+			if policy.DestroyOnExpiry && !vm.DestroyProtection {
+				onExpiry = "destroy"
+				if policy.BackupBeforeDestroy {
+					onExpiry = "backup and destroy"
+				}
+			}
+			tw.WriteRow(foreground, "",
+				fmt.Sprintf("<a href=\"showVM?%s\">%s</a>",
+					vm.ipAddr, vm.ipAddr),
+				vm.Tags["Name"],
+				vm.State.String(),
+				policy.ExpiresAt.Format(format.TimeFormatSeconds),
+				expiresIn,
+				onExpiry,
+				vm.OwnerUsers[0],
+				fmt.Sprintf("<a href=\"http://%s:%d/\">%s</a>",
+					vm.hypervisor.machine.Hostname,
+					constants.HypervisorPortNumber,
+					vm.hypervisor.machine.Hostname),
+			)
+		}
+		tw.Close()
+	case url.OutputTypeJson:
+		json.WriteWithIndent(writer, "   ", vms)
+	case url.OutputTypeText:
+		for _, vm := range vms {
+			fmt.Fprintf(writer, "%s %s\n", vm.ipAddr,
+				vm.LifecyclePolicy.ExpiresAt.Format(time.RFC3339))
+		}
+	}
+}
+
+func (m *Manager) listExpiringVMsHandler(w http.ResponseWriter,
+	req *http.Request) {
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	parsedQuery := url.ParseQuery(req.URL)
+	switch parsedQuery.OutputType() {
+	case url.OutputTypeHtml:
+		fmt.Fprintf(writer, "<title>List of VMs with an expiry time</title>\n")
+		writer.WriteString(commonStyleSheet)
+		fmt.Fprintln(writer, "<body>")
+	}
+	m.listExpiringVMs(writer, m.getExpiringVMs(), parsedQuery.OutputType())
+	switch parsedQuery.OutputType() {
+	case url.OutputTypeHtml:
+		fmt.Fprintln(writer, "</body>")
+	}
+}
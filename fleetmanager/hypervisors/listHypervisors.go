@@ -269,6 +269,8 @@ func (m *Manager) listHypervisorsInLocation(
 	if err != nil {
 		return proto.ListHypervisorsInLocationResponse{}, err
 	}
+	hypervisors = m.filterHypervisorsForPlacement(hypervisors,
+		request.PlacementGroup, request.VmToPlace)
 	addresses := make([]string, 0, len(hypervisors))
 	var tagsForHypervisors []tags.Tags
 	for _, hypervisor := range hypervisors {
@@ -0,0 +1,280 @@
+package hypervisors
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/html"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/url"
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+)
+
+const placementReservationTimeout = 5 * time.Minute
+
+type placementGroupType struct {
+	Name       string
+	NumVMs     uint
+	Violations uint // Number of VMs sharing a failure domain.
+	VMs        []placementGroupVmType
+}
+
+type placementGroupVmType struct {
+	ConflictsWith []string `json:",omitempty"` // IP addresses.
+	Error         string   `json:",omitempty"` // Invalid PlacementSpread.
+	FailureDomain string
+	Hypervisor    string
+	IpAddress     string
+	Location      string `json:",omitempty"`
+	Name          string `json:",omitempty"`
+	Spread        uint   `json:",omitempty"`
+}
+
+// checkPlacement returns an error if placing the VM on the Hypervisor would
+// break anti-affinity with the other VMs in the placement group. If the
+// placement is permitted, the failure domain is reserved for the VM for
+// placementReservationTimeout, so that concurrent placements of other VMs in
+// the group are not given the same failure domain before the VM is created.
+func (m *Manager) checkPlacement(
+	request fm_proto.CheckPlacementRequest) error {
+	if request.PlacementGroup.Name == "" {
+		return nil
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	hypervisor, ok := m.hypervisors[request.HypervisorHostname]
+	if !ok {
+		return fmt.Errorf("Hypervisor: %s not found",
+			request.HypervisorHostname)
+	}
+	group := request.PlacementGroup
+	domains := m.getFailureDomainsLocked(group, request.VmToPlace)
+	domain := group.FailureDomain(hypervisor.machine.Hostname,
+		hypervisor.location)
+	if user, ok := domains[domain]; ok {
+		return fmt.Errorf(
+			"%s in placement group: %s is already in failure domain: %s",
+			user, group.Name, domain)
+	}
+	reservations := make([]placementReservationType, 0,
+		len(m.reservations)+1)
+	now := time.Now()
+	for _, reservation := range m.reservations {
+		if reservation.expires.After(now) {
+			reservations = append(reservations, reservation)
+		}
+	}
+	var vmToPlace string
+	if len(request.VmToPlace) > 0 {
+		vmToPlace = request.VmToPlace.String()
+	}
+	m.reservations = append(reservations, placementReservationType{
+		expires:   now.Add(placementReservationTimeout),
+		group:     group.Name,
+		hostname:  hypervisor.machine.Hostname,
+		location:  hypervisor.location,
+		vmToPlace: vmToPlace,
+	})
+	return nil
+}
+
+// filterHypervisorsForPlacement returns the Hypervisors where the VM may be
+// placed without breaking anti-affinity with the placement group.
+func (m *Manager) filterHypervisorsForPlacement(hypervisors []*hypervisorType,
+	group fm_proto.PlacementGroup, vmToPlace net.IP) []*hypervisorType {
+	if group.Name == "" {
+		return hypervisors
+	}
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	domains := m.getFailureDomainsLocked(group, vmToPlace)
+	filtered := make([]*hypervisorType, 0, len(hypervisors))
+	for _, hypervisor := range hypervisors {
+		domain := group.FailureDomain(hypervisor.machine.Hostname,
+			hypervisor.location)
+		if _, ok := domains[domain]; !ok {
+			filtered = append(filtered, hypervisor)
+		}
+	}
+	return filtered
+}
+
+// getFailureDomainsLocked returns the failure domains used by the VMs in the
+// placement group or reserved for pending placements, ignoring vmToPlace. The
+// value describes a VM in the failure domain.
+func (m *Manager) getFailureDomainsLocked(group fm_proto.PlacementGroup,
+	vmToPlace net.IP) map[string]string {
+	var vmToPlaceString string
+	if len(vmToPlace) > 0 {
+		vmToPlaceString = vmToPlace.String()
+	}
+	domains := make(map[string]string)
+	for ipAddr, vm := range m.vms {
+		if ipAddr == vmToPlaceString ||
+			vm.Tags[fm_proto.PlacementGroupTag] != group.Name {
+			continue
+		}
+		domains[group.FailureDomain(vm.hypervisor.machine.Hostname,
+			vm.Location)] = "VM: " + ipAddr
+	}
+	now := time.Now()
+	for _, reservation := range m.reservations {
+		if reservation.group != group.Name ||
+			!reservation.expires.After(now) {
+			continue
+		}
+		if reservation.vmToPlace != "" &&
+			reservation.vmToPlace == vmToPlaceString {
+			continue
+		}
+		domain := group.FailureDomain(reservation.hostname,
+			reservation.location)
+		if _, ok := domains[domain]; ok {
+			continue
+		}
+		if reservation.vmToPlace == "" {
+			domains[domain] = "pending VM"
+		} else {
+			domains[domain] = "pending VM: " + reservation.vmToPlace
+		}
+	}
+	return domains
+}
+
+// getPlacementGroups returns the placement groups, sorted by name. Each VM is
+// checked for conflicts using its own spread. VMs with an invalid spread are
+// violations and are checked using the default spread.
+func (m *Manager) getPlacementGroups() []*placementGroupType {
+	groups := make(map[string]*placementGroupType)
+	for _, vm := range m.getVMs(true) {
+		name := vm.Tags[fm_proto.PlacementGroupTag]
+		if name == "" {
+			continue
+		}
+		var errorString string
+		pg, err := fm_proto.GetPlacementGroup(vm.Tags)
+		if err != nil {
+			errorString = err.Error()
+			pg = fm_proto.PlacementGroup{Name: name}
+		}
+		group := groups[pg.Name]
+		if group == nil {
+			group = &placementGroupType{Name: pg.Name}
+			groups[pg.Name] = group
+		}
+		group.VMs = append(group.VMs, placementGroupVmType{
+			Error: errorString,
+			FailureDomain: pg.FailureDomain(vm.hypervisor.machine.Hostname,
+				vm.Location),
+			Hypervisor: vm.hypervisor.machine.Hostname,
+			IpAddress:  vm.ipAddr,
+			Location:   vm.Location,
+			Name:       vm.Tags["Name"],
+			Spread:     pg.Spread,
+		})
+	}
+	list := make([]*placementGroupType, 0, len(groups))
+	for _, group := range groups {
+		for index := range group.VMs {
+			vm := &group.VMs[index]
+			pg := fm_proto.PlacementGroup{Name: group.Name, Spread: vm.Spread}
+			for _, otherVm := range group.VMs {
+				if otherVm.IpAddress == vm.IpAddress {
+					continue
+				}
+				if pg.FailureDomain(otherVm.Hypervisor, otherVm.Location) ==
+					vm.FailureDomain {
+					vm.ConflictsWith = append(vm.ConflictsWith,
+						otherVm.IpAddress)
+				}
+			}
+			if len(vm.ConflictsWith) > 0 || vm.Error != "" {
+				group.Violations++
+			}
+		}
+		group.NumVMs = uint(len(group.VMs))
+		list = append(list, group)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+func (m *Manager) listPlacementGroups(writer io.Writer,
+	groups []*placementGroupType, outputType uint) {
+	switch outputType {
+	case url.OutputTypeHtml:
+		fmt.Fprintln(writer, `<table border="1" style="width:100%">`)
+		tw, _ := html.NewTableWriter(writer, true, "Group", "IP Addr",
+			"Name(tag)", "Spread", "Hypervisor", "Failure Domain",
+			"Conflicts With")
+		for _, group := range groups {
+			for _, vm := range group.VMs {
+				var foreground string
+				if len(vm.ConflictsWith) > 0 || vm.Error != "" {
+					foreground = "#c00000"
+				}
+				spread := "hypervisor"
+				if vm.Error != "" {
+					spread = vm.Error
+				} else if vm.Spread > 0 {
+					spread = fmt.Sprintf("depth %d", vm.Spread)
+				}
+				tw.WriteRow(foreground, "",
+					group.Name,
+					fmt.Sprintf("<a href=\"showVM?%s\">%s</a>",
+						vm.IpAddress, vm.IpAddress),
+					vm.Name,
+					spread,
+					fmt.Sprintf("<a href=\"showHypervisor?%s\">%s</a>",
+						vm.Hypervisor, vm.Hypervisor),
+					vm.FailureDomain,
+					strings.Join(vm.ConflictsWith, " "),
+				)
+			}
+		}
+		tw.Close()
+	case url.OutputTypeJson:
+		json.WriteWithIndent(writer, "   ", groups)
+	case url.OutputTypeText:
+		for _, group := range groups {
+			fmt.Fprintf(writer, "%s %d %d\n",
+				group.Name, group.NumVMs, group.Violations)
+		}
+	}
+}
+
+func (m *Manager) listPlacementGroupsHandler(w http.ResponseWriter,
+	req *http.Request) {
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	parsedQuery := url.ParseQuery(req.URL)
+	groups := m.getPlacementGroups()
+	if parsedQuery.Table["state"] == "violated" {
+		var violatedGroups []*placementGroupType
+		for _, group := range groups {
+			if group.Violations > 0 {
+				violatedGroups = append(violatedGroups, group)
+			}
+		}
+		groups = violatedGroups
+	}
+	switch parsedQuery.OutputType() {
+	case url.OutputTypeHtml:
+		fmt.Fprintf(writer, "<title>List of placement groups</title>\n")
+		writer.WriteString(commonStyleSheet)
+		fmt.Fprintln(writer, "<body>")
+	}
+	m.listPlacementGroups(writer, groups, parsedQuery.OutputType())
+	switch parsedQuery.OutputType() {
+	case url.OutputTypeHtml:
+		fmt.Fprintln(writer, "</body>")
+	}
+}
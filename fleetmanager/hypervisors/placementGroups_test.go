@@ -0,0 +1,179 @@
+package hypervisors
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/tags"
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func makeTestManager() *Manager {
+	m := &Manager{
+		hypervisors: make(map[string]*hypervisorType),
+		vms:         make(map[string]*vmInfoType),
+	}
+	for _, h := range []struct{ hostname, location string }{
+		{"h1", "dc1/rack1"},
+		{"h2", "dc1/rack1"},
+		{"h3", "dc1/rack2"},
+		{"h4", "dc2/rack1"},
+	} {
+		m.hypervisors[h.hostname] = &hypervisorType{
+			location: h.location,
+			machine: &fm_proto.Machine{
+				NetworkEntry: fm_proto.NetworkEntry{Hostname: h.hostname},
+			},
+		}
+	}
+	return m
+}
+
+func (m *Manager) addTestVm(ipAddr, hypervisor string, vmTags tags.Tags) {
+	h := m.hypervisors[hypervisor]
+	m.vms[ipAddr] = &vmInfoType{
+		ipAddr:     ipAddr,
+		VmInfo:     hyper_proto.VmInfo{Tags: vmTags},
+		Location:   h.location,
+		hypervisor: h,
+	}
+}
+
+func getHostnames(hypervisors []*hypervisorType) []string {
+	var hostnames []string
+	for _, hypervisor := range hypervisors {
+		hostnames = append(hostnames, hypervisor.machine.Hostname)
+	}
+	return hostnames
+}
+
+func TestCheckPlacement(t *testing.T) {
+	m := makeTestManager()
+	m.addTestVm("10.0.0.1", "h1", tags.Tags{fm_proto.PlacementGroupTag: "db"})
+	group := fm_proto.PlacementGroup{Name: "db", Spread: 2}
+	tests := []struct {
+		hypervisor string
+		group      fm_proto.PlacementGroup
+		vmToPlace  string
+		wantError  bool
+	}{
+		{"h2", fm_proto.PlacementGroup{}, "", false},
+		{"h1", fm_proto.PlacementGroup{Name: "web"}, "", false},
+		{"h1", fm_proto.PlacementGroup{Name: "db"}, "", true},
+		{"h4", fm_proto.PlacementGroup{Name: "db"}, "10.0.0.2", false},
+		{"h2", group, "", true},
+		{"h3", group, "10.0.0.3", false},
+		{"h1", group, "10.0.0.1", false},
+		{"h5", group, "", true},
+	}
+	for _, test := range tests {
+		err := m.checkPlacement(fm_proto.CheckPlacementRequest{
+			HypervisorHostname: test.hypervisor,
+			PlacementGroup:     test.group,
+			VmToPlace:          net.ParseIP(test.vmToPlace),
+		})
+		if test.wantError && err == nil {
+			t.Errorf("%s on %s: no error", test.group, test.hypervisor)
+		} else if !test.wantError && err != nil {
+			t.Errorf("%s on %s: %s", test.group, test.hypervisor, err)
+		}
+	}
+	// The placement of 10.0.0.3 in dc1/rack2 is reserved.
+	err := m.checkPlacement(fm_proto.CheckPlacementRequest{
+		HypervisorHostname: "h3",
+		PlacementGroup:     group,
+		VmToPlace:          net.ParseIP("10.0.0.4"),
+	})
+	if err == nil {
+		t.Error("reserved failure domain was given to another VM")
+	}
+	err = m.checkPlacement(fm_proto.CheckPlacementRequest{
+		HypervisorHostname: "h3",
+		PlacementGroup:     group,
+		VmToPlace:          net.ParseIP("10.0.0.3"),
+	})
+	if err != nil {
+		t.Errorf("reserved failure domain refused to same VM: %s", err)
+	}
+}
+
+func TestFilterHypervisorsForPlacement(t *testing.T) {
+	m := makeTestManager()
+	m.addTestVm("10.0.0.1", "h1", tags.Tags{fm_proto.PlacementGroupTag: "db"})
+	m.addTestVm("10.0.0.2", "h3", tags.Tags{fm_proto.PlacementGroupTag: "web"})
+	hypervisors := []*hypervisorType{m.hypervisors["h1"],
+		m.hypervisors["h2"], m.hypervisors["h3"], m.hypervisors["h4"]}
+	tests := []struct {
+		group     fm_proto.PlacementGroup
+		vmToPlace string
+		want      []string
+	}{
+		{fm_proto.PlacementGroup{}, "", []string{"h1", "h2", "h3", "h4"}},
+		{fm_proto.PlacementGroup{Name: "db"}, "", []string{"h2", "h3", "h4"}},
+		{fm_proto.PlacementGroup{Name: "db", Spread: 1}, "",
+			[]string{"h4"}},
+		{fm_proto.PlacementGroup{Name: "db", Spread: 2}, "",
+			[]string{"h3", "h4"}},
+		{fm_proto.PlacementGroup{Name: "db", Spread: 1}, "10.0.0.1",
+			[]string{"h1", "h2", "h3", "h4"}},
+	}
+	for _, test := range tests {
+		filtered := getHostnames(m.filterHypervisorsForPlacement(hypervisors,
+			test.group, net.ParseIP(test.vmToPlace)))
+		if len(filtered) != len(test.want) {
+			t.Errorf("%s: got: %v, want: %v", test.group, filtered, test.want)
+			continue
+		}
+		for index, hostname := range filtered {
+			if hostname != test.want[index] {
+				t.Errorf("%s: got: %v, want: %v",
+					test.group, filtered, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestGetPlacementGroups(t *testing.T) {
+	m := makeTestManager()
+	m.addTestVm("10.0.0.1", "h1", tags.Tags{fm_proto.PlacementGroupTag: "db"})
+	m.addTestVm("10.0.0.2", "h2", tags.Tags{
+		fm_proto.PlacementGroupTag:  "db",
+		fm_proto.PlacementSpreadTag: "1",
+	})
+	m.addTestVm("10.0.0.3", "h3", tags.Tags{
+		fm_proto.PlacementGroupTag:  "web",
+		fm_proto.PlacementSpreadTag: "bogus",
+	})
+	m.addTestVm("10.0.0.4", "h4", tags.Tags{fm_proto.PlacementGroupTag: "web"})
+	m.addTestVm("10.0.0.5", "h4", nil)
+	groups := m.getPlacementGroups()
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+	db := groups[0]
+	if db.Name != "db" || db.NumVMs != 2 || db.Violations != 1 {
+		t.Errorf("db: got: %s %d %d, want: db 2 1",
+			db.Name, db.NumVMs, db.Violations)
+	}
+	for _, vm := range db.VMs {
+		if vm.IpAddress == "10.0.0.2" && len(vm.ConflictsWith) != 1 {
+			t.Errorf("10.0.0.2: conflicts: %v", vm.ConflictsWith)
+		} else if vm.IpAddress == "10.0.0.1" && len(vm.ConflictsWith) > 0 {
+			t.Errorf("10.0.0.1: conflicts: %v", vm.ConflictsWith)
+		}
+	}
+	web := groups[1]
+	if web.Name != "web" || web.NumVMs != 2 || web.Violations != 1 {
+		t.Errorf("web: got: %s %d %d, want: web 2 1",
+			web.Name, web.NumVMs, web.Violations)
+	}
+	for _, vm := range web.VMs {
+		if vm.IpAddress == "10.0.0.3" && vm.Error == "" {
+			t.Error("10.0.0.3: invalid spread not reported")
+		} else if vm.IpAddress == "10.0.0.4" && vm.Error != "" {
+			t.Errorf("10.0.0.4: %s", vm.Error)
+		}
+	}
+}
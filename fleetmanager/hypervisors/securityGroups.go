@@ -0,0 +1,179 @@
+package hypervisors
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/tags/tagmatcher"
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+const securityGroupsCheckInterval = 10 * time.Second
+
+// notifySecurityGroupsUpdater requests that the security groups for all
+// Hypervisors be checked. It does not block.
+func (m *Manager) notifySecurityGroupsUpdater() {
+	select {
+	case m.securityNotifier <- struct{}{}:
+	default:
+	}
+}
+
+func (m *Manager) processSecurityGroupsUpdates(h *hypervisorType) {
+	t, err := m.getTopology()
+	if err != nil {
+		h.logger.Println(err)
+		return
+	}
+	h.mutex.RLock()
+	haveGroups := h.securityGroups
+	haveSecurityGroups := h.haveSecurityGroups
+	hostname := h.machine.Hostname
+	h.mutex.RUnlock()
+	if !haveSecurityGroups { // Hypervisor does not support security groups.
+		return
+	}
+	needGroups, err := t.GetSecurityGroupsForMachine(hostname)
+	if err != nil {
+		h.logger.Println(err)
+		return
+	}
+	needGroups = m.resolveSecurityGroupPeers(needGroups)
+	haveGroupsMap := make(map[string]int, len(haveGroups))
+	for index, group := range haveGroups {
+		haveGroupsMap[group.Id] = index
+	}
+	groupsToDelete := make(map[string]struct{}, len(haveGroups))
+	for _, group := range haveGroups {
+		groupsToDelete[group.Id] = struct{}{}
+	}
+	var request hyper_proto.UpdateSecurityGroupsRequest
+	for _, needGroup := range needGroups {
+		if index, ok := haveGroupsMap[needGroup.Id]; ok {
+			haveGroup := haveGroups[index]
+			delete(groupsToDelete, haveGroup.Id)
+			if !needGroup.Equal(&haveGroup) {
+				request.Change = append(request.Change, needGroup)
+			}
+		} else {
+			request.Add = append(request.Add, needGroup)
+		}
+	}
+	for groupId := range groupsToDelete {
+		request.Delete = append(request.Delete, groupId)
+	}
+	if len(request.Add) < 1 && len(request.Change) < 1 &&
+		len(request.Delete) < 1 {
+		return
+	}
+	client, err := srpc.DialHTTP("tcp", h.address(), time.Minute)
+	if err != nil {
+		h.logger.Println(err)
+		return
+	}
+	defer client.Close()
+	var reply hyper_proto.UpdateSecurityGroupsResponse
+	err = client.RequestReply("Hypervisor.UpdateSecurityGroups", request,
+		&reply)
+	if err == nil {
+		err = errors.New(reply.Error)
+	}
+	if err != nil {
+		h.logger.Println(err)
+		return
+	}
+	// Record the new groups now so that they are not sent again before the
+	// Hypervisor sends an update.
+	h.mutex.Lock()
+	h.securityGroups = needGroups
+	h.mutex.Unlock()
+	h.logger.Debugf(0,
+		"Added %d, changed %d and deleted %d security groups\n",
+		len(request.Add), len(request.Change), len(request.Delete))
+}
+
+// resolveSecurityGroupPeers returns a copy of the security groups where the
+// rules which reference VMs by tag also list the addresses of all matching VMs
+// in the fleet, since a Hypervisor only knows about its own VMs.
+func (m *Manager) resolveSecurityGroupPeers(
+	groups []hyper_proto.SecurityGroup) []hyper_proto.SecurityGroup {
+	newGroups := make([]hyper_proto.SecurityGroup, 0, len(groups))
+	for _, group := range groups {
+		group.EgressRules = m.resolveSecurityRules(group.EgressRules)
+		group.IngressRules = m.resolveSecurityRules(group.IngressRules)
+		newGroups = append(newGroups, group)
+	}
+	return newGroups
+}
+
+func (m *Manager) resolveSecurityRules(
+	rules []hyper_proto.SecurityRule) []hyper_proto.SecurityRule {
+	if len(rules) < 1 {
+		return nil
+	}
+	newRules := make([]hyper_proto.SecurityRule, 0, len(rules))
+	for _, rule := range rules {
+		if len(rule.PeerTags) > 0 {
+			rule.PeerAddresses = m.resolveSecurityRulePeers(rule)
+		}
+		newRules = append(newRules, rule)
+	}
+	return newRules
+}
+
+func (m *Manager) resolveSecurityRulePeers(
+	rule hyper_proto.SecurityRule) []net.IP {
+	ipAddrs := make(map[string]net.IP, len(rule.PeerAddresses))
+	for _, ipAddr := range rule.PeerAddresses {
+		ipAddrs[ipAddr.String()] = ipAddr
+	}
+	tagMatcher := tagmatcher.New(rule.PeerTags, false)
+	m.mutex.RLock()
+	for _, vm := range m.vms {
+		if !tagMatcher.MatchEach(vm.Tags) {
+			continue
+		}
+		ipAddrs[vm.ipAddr] = vm.Address.IpAddress
+		for _, address := range vm.SecondaryAddresses {
+			ipAddrs[address.IpAddress.String()] = address.IpAddress
+		}
+	}
+	m.mutex.RUnlock()
+	peerAddresses := make([]net.IP, 0, len(ipAddrs))
+	for _, ipAddr := range ipAddrs {
+		peerAddresses = append(peerAddresses, ipAddr)
+	}
+	sort.Slice(peerAddresses, func(left, right int) bool {
+		return bytes.Compare(peerAddresses[left].To4(),
+			peerAddresses[right].To4()) < 0
+	})
+	return peerAddresses
+}
+
+// securityGroupsUpdater re-checks the security groups for all Hypervisors when
+// notified, since changes to VMs change the peers for the rules. Notifications
+// are coalesced so that the fleet is checked at most once per interval.
+func (m *Manager) securityGroupsUpdater() {
+	for range m.securityNotifier {
+		sleepUntil := time.Now().Add(securityGroupsCheckInterval)
+		m.mutex.RLock()
+		hypervisors := make([]*hypervisorType, 0, len(m.hypervisors))
+		for _, h := range m.hypervisors {
+			hypervisors = append(hypervisors, h)
+		}
+		m.mutex.RUnlock()
+		for _, h := range hypervisors {
+			h.mutex.RLock()
+			connected := h.probeStatus == probeStatusConnected
+			h.mutex.RUnlock()
+			if connected {
+				m.processSecurityGroupsUpdates(h)
+			}
+		}
+		time.Sleep(time.Until(sleepUntil))
+	}
+}
@@ -23,6 +23,7 @@ func newManager(startOptions StartOptions) (*Manager, error) {
 		ipmiPasswordFile: startOptions.IpmiPasswordFile,
 		ipmiUsername:     startOptions.IpmiUsername,
 		logger:           startOptions.Logger,
+		securityNotifier: make(chan struct{}, 1),
 		storer:           startOptions.Storer,
 		allocatingIPs:    make(map[string]struct{}),
 		hypervisors:      make(map[string]*hypervisorType),
@@ -30,13 +31,17 @@ func newManager(startOptions StartOptions) (*Manager, error) {
 		subnets:          make(map[string]*subnetType),
 		vms:              make(map[string]*vmInfoType),
 	}
+	html.HandleFunc("/listExpiringVMs", manager.listExpiringVMsHandler)
 	html.HandleFunc("/listHypervisors", manager.listHypervisorsHandler)
 	html.HandleFunc("/listLocations", manager.listLocationsHandler)
+	html.HandleFunc("/listPlacementGroups",
+		manager.listPlacementGroupsHandler)
 	html.HandleFunc("/listVMs", manager.listVMsHandler)
 	html.HandleFunc("/listVMsByPrimaryOwner",
 		manager.listVMsByPrimaryOwnerHandler)
 	html.HandleFunc("/showHypervisor", manager.showHypervisorHandler)
 	html.HandleFunc("/showVM", manager.showVmHandler)
 	go manager.notifierLoop()
+	go manager.securityGroupsUpdater()
 	return manager, nil
 }
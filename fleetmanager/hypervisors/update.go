@@ -265,6 +265,7 @@ func (m *Manager) updateHypervisor(h *hypervisorType, machine *fm_proto.Machine,
 			go h.changeOwners(nil)
 		}
 		go m.processSubnetsUpdates(h, subnets)
+		go m.processSecurityGroupsUpdates(h)
 	}
 }
 
@@ -618,6 +619,9 @@ func (m *Manager) processHypervisorUpdate(h *hypervisorType,
 	if update.NumCPUs != nil {
 		h.numCPUs = *update.NumCPUs
 	}
+	if update.NumaNodes != nil {
+		h.numaNodes = update.NumaNodes
+	}
 	if update.TotalVolumeBytes != nil {
 		h.totalVolumeBytes = *update.TotalVolumeBytes
 	}
@@ -640,6 +644,13 @@ func (m *Manager) processHypervisorUpdate(h *hypervisorType,
 			m.processSubnetsUpdates(h, update.Subnets)
 		}
 		m.processAddressPoolUpdates(h, update)
+		if update.HaveSecurityGroups {
+			h.mutex.Lock()
+			h.haveSecurityGroups = true
+			h.securityGroups = update.SecurityGroups
+			h.mutex.Unlock()
+			m.processSecurityGroupsUpdates(h)
+		}
 	}
 	if update.HaveSerialNumber && update.SerialNumber != "" &&
 		update.SerialNumber != oldSerialNumber {
@@ -659,6 +670,9 @@ func (m *Manager) processHypervisorUpdate(h *hypervisorType,
 		} else {
 			m.processVmUpdates(h, update.VMs)
 		}
+		if *manageHypervisors {
+			m.notifySecurityGroupsUpdater()
+		}
 	}
 }
 
@@ -34,8 +34,14 @@ func Setup(hypervisorsManager *hypervisors.Manager, logger log.DebugLogger) (
 	}
 	srpc.RegisterNameWithOptions("FleetManager", srpcObj,
 		srpc.ReceiverOptions{
+			AuditedMethods: []string{
+				"ChangeMachineTags",
+				"MoveIpAddresses",
+				"PowerOnMachine",
+			},
 			PublicMethods: []string{
 				"ChangeMachineTags",
+				"CheckPlacement",
 				"GetHypervisorForVM",
 				"GetHypervisorsInLocation",
 				"GetMachineInfo",
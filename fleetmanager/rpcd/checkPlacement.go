@@ -0,0 +1,15 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+)
+
+func (t *srpcType) CheckPlacement(conn *srpc.Conn,
+	request proto.CheckPlacementRequest,
+	reply *proto.CheckPlacementResponse) error {
+	*reply = proto.CheckPlacementResponse{
+		errors.ErrorToString(t.hypervisorsManager.CheckPlacement(request))}
+	return nil
+}
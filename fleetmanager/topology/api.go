@@ -12,10 +12,11 @@ import (
 
 type Directory struct {
 	Name             string
-	Directories      []*Directory        `json:",omitempty"`
-	Machines         []*fm_proto.Machine `json:",omitempty"`
-	Subnets          []*Subnet           `json:",omitempty"`
-	Tags             tags.Tags           `json:",omitempty"`
+	Directories      []*Directory                `json:",omitempty"`
+	Machines         []*fm_proto.Machine         `json:",omitempty"`
+	SecurityGroups   []hyper_proto.SecurityGroup `json:",omitempty"`
+	Subnets          []*Subnet                   `json:",omitempty"`
+	Tags             tags.Tags                   `json:",omitempty"`
 	logger           log.DebugLogger
 	nameToDirectory  map[string]*Directory // Key: directory name.
 	owners           *ownersType
@@ -130,6 +131,13 @@ func (t *Topology) GetNumMachines() uint {
 	return uint(len(t.machineParents))
 }
 
+// GetSecurityGroupsForMachine returns the security groups defined in the
+// directory containing the machine and all its parent directories.
+func (t *Topology) GetSecurityGroupsForMachine(name string) (
+	[]hyper_proto.SecurityGroup, error) {
+	return t.getSecurityGroupsForMachine(name)
+}
+
 func (t *Topology) GetSubnetsForMachine(name string) ([]*Subnet, error) {
 	return t.getSubnetsForMachine(name)
 }
@@ -42,6 +42,9 @@ func (left *Directory) equal(right *Directory) bool {
 	if len(left.Machines) != len(right.Machines) {
 		return false
 	}
+	if len(left.SecurityGroups) != len(right.SecurityGroups) {
+		return false
+	}
 	if len(left.Subnets) != len(right.Subnets) {
 		return false
 	}
@@ -55,6 +58,11 @@ func (left *Directory) equal(right *Directory) bool {
 			return false
 		}
 	}
+	for index, leftGroup := range left.SecurityGroups {
+		if !leftGroup.Equal(&right.SecurityGroups[index]) {
+			return false
+		}
+	}
 	for index, leftSubnet := range left.Subnets {
 		if !leftSubnet.equal(right.Subnets[index]) {
 			return false
@@ -2,6 +2,8 @@ package topology
 
 import (
 	"fmt"
+
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
 )
 
 func (t *Topology) getLocationOfMachine(name string) (string, error) {
@@ -12,6 +14,19 @@ func (t *Topology) getLocationOfMachine(name string) (string, error) {
 	}
 }
 
+func (t *Topology) getSecurityGroupsForMachine(name string) (
+	[]hyper_proto.SecurityGroup, error) {
+	if directory, ok := t.machineParents[name]; !ok {
+		return nil, fmt.Errorf("unknown machine: %s", name)
+	} else {
+		var groups []hyper_proto.SecurityGroup
+		for ; directory != nil; directory = directory.parent {
+			groups = append(groups, directory.SecurityGroups...)
+		}
+		return groups, nil
+	}
+}
+
 func (t *Topology) getSubnetsForMachine(name string) ([]*Subnet, error) {
 	if directory, ok := t.machineParents[name]; !ok {
 		return nil, fmt.Errorf("unknown machine: %s", name)
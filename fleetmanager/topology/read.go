@@ -15,6 +15,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/stringutil"
 	"github.com/Cloud-Foundations/Dominator/lib/tags"
 	proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
 )
 
 type commonStateType struct {
@@ -24,9 +25,10 @@ type commonStateType struct {
 }
 
 type inheritingState struct {
-	owners    *ownersType
-	subnetIds map[string]struct{}
-	tags      tags.Tags
+	owners           *ownersType
+	securityGroupIds map[string]struct{}
+	subnetIds        map[string]struct{}
+	tags             tags.Tags
 }
 
 func checkMacAddressIsZero(macAddr proto.HardwareAddr) bool {
@@ -121,6 +123,23 @@ func loadOwners(filename string) (*ownersType, error) {
 	return &owners, nil
 }
 
+func loadSecurityGroups(filename string) (
+	[]hyper_proto.SecurityGroup, error) {
+	var groups []hyper_proto.SecurityGroup
+	if err := json.ReadFromFile(filename, &groups); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading: %s: %s", filename, err)
+	}
+	for _, group := range groups {
+		if err := group.CheckValid(); err != nil {
+			return nil, fmt.Errorf("error checking: %s: %s", filename, err)
+		}
+	}
+	return groups, nil
+}
+
 func loadSubnets(filename string) ([]*Subnet, error) {
 	var subnets []*Subnet
 	if err := json.ReadFromFile(filename, &subnets); err != nil {
@@ -256,17 +275,19 @@ func (cState *commonStateType) addNetworkEntry(entry proto.NetworkEntry,
 
 func newInheritingState() *inheritingState {
 	return &inheritingState{
-		owners:    &ownersType{},
-		subnetIds: cloneSet(nil),
-		tags:      make(tags.Tags),
+		owners:           &ownersType{},
+		securityGroupIds: cloneSet(nil),
+		subnetIds:        cloneSet(nil),
+		tags:             make(tags.Tags),
 	}
 }
 
 func (iState *inheritingState) copy() *inheritingState {
 	return &inheritingState{
-		owners:    iState.owners.copy(),
-		subnetIds: cloneSet(iState.subnetIds),
-		tags:      iState.tags.Copy(),
+		owners:           iState.owners.copy(),
+		securityGroupIds: cloneSet(iState.securityGroupIds),
+		subnetIds:        cloneSet(iState.subnetIds),
+		tags:             iState.tags.Copy(),
 	}
 }
 
@@ -300,6 +321,10 @@ func (t *Topology) readDirectory(topDir, dirname string,
 	if err := t.loadSubnets(directory, dirpath, iState.subnetIds); err != nil {
 		return nil, err
 	}
+	err := directory.loadSecurityGroups(dirpath, iState.securityGroupIds)
+	if err != nil {
+		return nil, err
+	}
 	if err := directory.loadTags(dirpath, iState.tags); err != nil {
 		return nil, err
 	}
@@ -375,6 +400,23 @@ func (directory *Directory) loadOwners(dirname string,
 	return nil
 }
 
+func (directory *Directory) loadSecurityGroups(dirname string,
+	securityGroupIds map[string]struct{}) error {
+	var err error
+	directory.SecurityGroups, err = loadSecurityGroups(
+		filepath.Join(dirname, "security-groups.json"))
+	if err != nil {
+		return err
+	}
+	for _, group := range directory.SecurityGroups {
+		if _, ok := securityGroupIds[group.Id]; ok {
+			return fmt.Errorf("duplicate security group ID: %s", group.Id)
+		}
+		securityGroupIds[group.Id] = struct{}{}
+	}
+	return nil
+}
+
 func (directory *Directory) loadSubnets(dirname string,
 	subnetIds map[string]struct{}) error {
 	var err error
@@ -19,6 +19,11 @@ func AddVmVolumes(client *srpc.Client, ipAddress net.IP, sizes []uint64) error {
 	return addVmVolumes(client, ipAddress, sizes)
 }
 
+func BackupVm(client *srpc.Client, ipAddress net.IP) (
+	proto.VmBackupInfo, error) {
+	return backupVm(client, ipAddress)
+}
+
 func ChangeVmConsoleType(client *srpc.Client, ipAddress net.IP,
 	consoleType proto.ConsoleType) error {
 	return changeVmConsoleType(client, ipAddress, consoleType)
@@ -29,6 +34,16 @@ func ChangeVmCpuPriority(client *srpc.Client, ipAddress net.IP,
 	return changeVmCpuPriority(client, ipAddress, request)
 }
 
+func ChangeVmIoLimits(client *srpc.Client,
+	request proto.ChangeVmIoLimitsRequest) error {
+	return changeVmIoLimits(client, request)
+}
+
+func ChangeVmLifecyclePolicy(client *srpc.Client, ipAddress net.IP,
+	policy proto.LifecyclePolicy) error {
+	return changeVmLifecyclePolicy(client, ipAddress, policy)
+}
+
 func ChangeVmMachineType(client *srpc.Client, ipAddress net.IP,
 	machineType proto.MachineType) error {
 	return changeVmMachineType(client, ipAddress, machineType)
@@ -114,6 +129,11 @@ func ListVMs(client *srpc.Client,
 	return listVMs(client, request)
 }
 
+func ListVmBackups(client *srpc.Client, ipAddress net.IP) (
+	[]proto.VmBackupInfo, error) {
+	return listVmBackups(client, ipAddress)
+}
+
 func ListVolumeDirectories(client *srpc.Client, doSort bool) ([]string, error) {
 	return listVolumeDirectories(client, doSort)
 }
@@ -137,6 +157,11 @@ func ReorderVmVolumes(client *srpc.Client, ipAddr net.IP, accessToken []byte,
 	return reorderVmVolumes(client, ipAddr, accessToken, volumeIndices)
 }
 
+func RestoreVmFromBackup(client *srpc.Client, ipAddress net.IP,
+	backupId string) error {
+	return restoreVmFromBackup(client, ipAddress, backupId)
+}
+
 func ScanVmRoot(client *srpc.Client, ipAddr net.IP,
 	scanFilter *filter.Filter) (*filesystem.FileSystem, error) {
 	return scanVmRoot(client, ipAddr, scanFilter)
@@ -38,6 +38,20 @@ func addVmVolumes(client *srpc.Client, ipAddress net.IP, sizes []uint64) error {
 	return errors.New(reply.Error)
 }
 
+func backupVm(client *srpc.Client, ipAddress net.IP) (
+	proto.VmBackupInfo, error) {
+	request := proto.BackupVmRequest{IpAddress: ipAddress}
+	var reply proto.BackupVmResponse
+	err := client.RequestReply("Hypervisor.BackupVm", request, &reply)
+	if err != nil {
+		return proto.VmBackupInfo{}, err
+	}
+	if err := errors.New(reply.Error); err != nil {
+		return proto.VmBackupInfo{}, err
+	}
+	return reply.Backup, nil
+}
+
 func changeVmConsoleType(client *srpc.Client, ipAddress net.IP,
 	consoleType proto.ConsoleType) error {
 	request := proto.ChangeVmConsoleTypeRequest{
@@ -64,6 +78,31 @@ func changeVmCpuPriority(client *srpc.Client, ipAddress net.IP,
 	return errors.New(reply.Error)
 }
 
+func changeVmIoLimits(client *srpc.Client,
+	request proto.ChangeVmIoLimitsRequest) error {
+	var reply proto.ChangeVmIoLimitsResponse
+	err := client.RequestReply("Hypervisor.ChangeVmIoLimits", request, &reply)
+	if err != nil {
+		return err
+	}
+	return errors.New(reply.Error)
+}
+
+func changeVmLifecyclePolicy(client *srpc.Client, ipAddress net.IP,
+	policy proto.LifecyclePolicy) error {
+	request := proto.ChangeVmLifecyclePolicyRequest{
+		IpAddress:       ipAddress,
+		LifecyclePolicy: policy,
+	}
+	var reply proto.ChangeVmLifecyclePolicyResponse
+	err := client.RequestReply("Hypervisor.ChangeVmLifecyclePolicy", request,
+		&reply)
+	if err != nil {
+		return err
+	}
+	return errors.New(reply.Error)
+}
+
 func changeVmMachineType(client *srpc.Client, ipAddress net.IP,
 	consoleType proto.MachineType) error {
 	request := proto.ChangeVmMachineTypeRequest{
@@ -376,6 +415,20 @@ func listVMs(client *srpc.Client,
 	return reply.IpAddresses, nil
 }
 
+func listVmBackups(client *srpc.Client, ipAddress net.IP) (
+	[]proto.VmBackupInfo, error) {
+	request := proto.ListVmBackupsRequest{IpAddress: ipAddress}
+	var reply proto.ListVmBackupsResponse
+	err := client.RequestReply("Hypervisor.ListVmBackups", request, &reply)
+	if err != nil {
+		return nil, err
+	}
+	if err := errors.New(reply.Error); err != nil {
+		return nil, err
+	}
+	return reply.Backups, nil
+}
+
 func listVolumeDirectories(client *srpc.Client, doSort bool) ([]string, error) {
 	var request proto.ListVolumeDirectoriesRequest
 	var reply proto.ListVolumeDirectoriesResponse
@@ -448,6 +501,21 @@ func reorderVmVolumes(client *srpc.Client, ipAddr net.IP, accessToken []byte,
 	return errors.New(reply.Error)
 }
 
+func restoreVmFromBackup(client *srpc.Client, ipAddress net.IP,
+	backupId string) error {
+	request := proto.RestoreVmFromBackupRequest{
+		BackupId:  backupId,
+		IpAddress: ipAddress,
+	}
+	var reply proto.RestoreVmFromBackupResponse
+	err := client.RequestReply("Hypervisor.RestoreVmFromBackup", request,
+		&reply)
+	if err != nil {
+		return err
+	}
+	return errors.New(reply.Error)
+}
+
 func scanVmRoot(client *srpc.Client, ipAddr net.IP,
 	scanFilter *filter.Filter) (*filesystem.FileSystem, error) {
 	request := proto.ScanVmRootRequest{IpAddress: ipAddr, Filter: scanFilter}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/Cloud-Foundations/Dominator/hypervisor/manager"
 	"github.com/Cloud-Foundations/Dominator/lib/html"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"golang.org/x/net/websocket"
 )
 
 type HtmlWriter interface {
@@ -36,10 +38,16 @@ func StartServer(portNum uint, managerObj *manager.Manager, daemon bool) error {
 	html.HandleFunc("/listSubnets", myState.listSubnetsHandler)
 	html.HandleFunc("/listVMs", myState.listVMsHandler)
 	html.HandleFunc("/showVmBootLog", myState.showBootLogHandler)
+	html.HandleFunc("/showVmSerialConsole", myState.showSerialConsoleHandler)
 	html.HandleFunc("/showVmLastPatchLog", myState.showLastPatchLogHandler)
 	html.HandleFunc("/showVM", myState.showVMHandler)
 	html.HandleFunc("/showVolumeDirectories",
 		myState.showVolumeDirectoriesHandler)
+	http.Handle("/vmSerialConsole", websocket.Server{
+		Handler:   myState.serialConsoleHandler,
+		Handshake: checkSameOrigin,
+	})
+	listener = srpc.NewTlsSniffingListener(listener)
 	if daemon {
 		go http.Serve(listener, nil)
 	} else {
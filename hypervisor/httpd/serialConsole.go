@@ -0,0 +1,239 @@
+package httpd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/url"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	bootLogPollInterval = 500 * time.Millisecond
+	serialPortMethod    = "Hypervisor.ConnectToVmSerialPort"
+)
+
+// The page uses a plain <pre> element as the terminal: output is appended
+// (with escape sequences removed) and key presses are sent to the VM.
+const serialConsoleScript = `
+var ipAddr = document.currentScript.dataset.ip;
+var screen = document.getElementById("console");
+var statusLine = document.getElementById("status");
+var decoder = new TextDecoder();
+var protocol = location.protocol == "https:" ? "wss:" : "ws:";
+var ws = new WebSocket(protocol + "//" + location.host +
+	"/vmSerialConsole?" + ipAddr);
+ws.binaryType = "arraybuffer";
+ws.onmessage = function(event) {
+	if (typeof event.data == "string") {
+		statusLine.textContent = event.data;
+		return;
+	}
+	var text = decoder.decode(new Uint8Array(event.data), {stream: true});
+	text = text.replace(/\x1b\[[0-9;?]*[A-Za-z]/g, "").replace(/\r/g, "");
+	screen.textContent += text;
+	window.scrollTo(0, document.body.scrollHeight);
+};
+ws.onclose = function() {
+	statusLine.textContent += " (disconnected)";
+};
+var keys = {
+	"Enter": "\r", "Backspace": "\x7f", "Tab": "\t", "Escape": "\x1b",
+	"ArrowUp": "\x1b[A", "ArrowDown": "\x1b[B", "ArrowRight": "\x1b[C",
+	"ArrowLeft": "\x1b[D",
+};
+document.addEventListener("keydown", function(event) {
+	var data = keys[event.key];
+	if (event.ctrlKey && event.key.length == 1) {
+		data = String.fromCharCode(event.key.toUpperCase().charCodeAt(0) - 64);
+	} else if (!data && event.key.length == 1) {
+		data = event.key;
+	}
+	if (data && ws.readyState == WebSocket.OPEN) {
+		ws.send(data);
+		event.preventDefault();
+	}
+});
+`
+
+// checkSameOrigin rejects WebSocket requests which were not made by a page
+// served by this Hypervisor. Browsers do not apply the same-origin policy to
+// WebSockets, so without this check any site could connect to a serial console.
+func checkSameOrigin(config *websocket.Config, req *http.Request) error {
+	origin, err := websocket.Origin(config, req)
+	if err != nil {
+		return err
+	}
+	if origin == nil {
+		return errors.New("missing Origin header")
+	}
+	if origin.Host != req.Host {
+		return fmt.Errorf("cross-origin request from: %s", origin)
+	}
+	config.Origin = origin
+	return nil
+}
+
+// copyBootLog copies the boot log for the VM to the WebSocket, returning the
+// boot log reader so that it may be followed.
+func (s state) copyBootLog(ws *websocket.Conn,
+	ipAddr net.IP) (io.ReadCloser, error) {
+	reader, err := s.manager.GetVmBootLog(ipAddr)
+	if err != nil {
+		return nil, err
+	}
+	if err := sendBootLogData(ws, reader); err != nil {
+		reader.Close()
+		return nil, err
+	}
+	return reader, nil
+}
+
+// followBootLog sends data appended to the boot log to the WebSocket until the
+// WebSocket is closed. This provides a read-only view of the serial console
+// without taking the (exclusive) serial port connection.
+func followBootLog(ws *websocket.Conn, reader io.Reader) {
+	closed := make(chan struct{})
+	go func() {
+		var data []byte
+		for websocket.Message.Receive(ws, &data) == nil {
+		}
+		close(closed)
+	}()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-time.After(bootLogPollInterval):
+		}
+		if err := sendBootLogData(ws, reader); err != nil {
+			return
+		}
+	}
+}
+
+func sendBootLogData(ws *websocket.Conn, reader io.Reader) error {
+	buffer := make([]byte, 4096)
+	for {
+		nRead, err := reader.Read(buffer)
+		if nRead > 0 {
+			if err := websocket.Message.Send(ws, buffer[:nRead]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// sendSerialOutput sends the output from the serial port to the WebSocket,
+// batching the bytes which are ready.
+func sendSerialOutput(ws *websocket.Conn, output <-chan byte) {
+	for char := range output {
+		buffer := []byte{char}
+		for moreData := true; moreData; {
+			select {
+			case char, ok := <-output:
+				if ok {
+					buffer = append(buffer, char)
+				} else {
+					moreData = false
+				}
+			default:
+				moreData = false
+			}
+		}
+		if err := websocket.Message.Send(ws, buffer); err != nil {
+			break
+		}
+	}
+	ws.Close()
+}
+
+func (s state) serialConsoleHandler(ws *websocket.Conn) {
+	defer ws.Close()
+	req := ws.Request()
+	parsedQuery := url.ParseQuery(req.URL)
+	if len(parsedQuery.Flags) != 1 {
+		return
+	}
+	var ipAddr net.IP
+	for name := range parsedQuery.Flags {
+		ipAddr = net.ParseIP(name)
+	}
+	// Owners of the VM (authenticated with a client certificate) get an
+	// interactive console, everyone else may only watch.
+	var input chan<- byte
+	var output <-chan byte
+	authInfo, err := srpc.GetAuthInformationForRequest(req, serialPortMethod)
+	if err == nil {
+		input, output, err = s.manager.ConnectToVmSerialPort(ipAddr, authInfo,
+			0)
+	}
+	if err != nil {
+		websocket.Message.Send(ws, "Read-only console: "+err.Error())
+	} else {
+		defer close(input)
+		websocket.Message.Send(ws, "Interactive console")
+	}
+	bootLog, err := s.copyBootLog(ws, ipAddr)
+	if err != nil {
+		websocket.Message.Send(ws, err.Error())
+		return
+	}
+	defer bootLog.Close()
+	if input == nil {
+		followBootLog(ws, bootLog)
+		return
+	}
+	go sendSerialOutput(ws, output)
+	for {
+		var data []byte
+		if err := websocket.Message.Receive(ws, &data); err != nil {
+			return
+		}
+		for _, char := range data {
+			input <- char
+		}
+	}
+}
+
+func (s state) showSerialConsoleHandler(w http.ResponseWriter,
+	req *http.Request) {
+	parsedQuery := url.ParseQuery(req.URL)
+	if len(parsedQuery.Flags) != 1 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var ipAddr net.IP
+	for name := range parsedQuery.Flags {
+		ipAddr = net.ParseIP(name)
+	}
+	if _, err := s.manager.GetVmInfo(ipAddr); err != nil {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	fmt.Fprintf(writer, "<title>Serial console for VM %s</title>\n", ipAddr)
+	fmt.Fprintln(writer, "<body>")
+	fmt.Fprintf(writer, "<h1>Serial console for VM %s</h1>\n", ipAddr)
+	fmt.Fprintln(writer, `<p id="status">Connecting...</p>`)
+	fmt.Fprintln(writer, `<pre id="console"></pre>`)
+	fmt.Fprintf(writer, "<script data-ip=\"%s\">", ipAddr)
+	fmt.Fprint(writer, serialConsoleScript)
+	fmt.Fprintln(writer, "</script>")
+	fmt.Fprintln(writer, "</body>")
+}
@@ -0,0 +1,37 @@
+package httpd
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestCheckSameOrigin(t *testing.T) {
+	tests := []struct {
+		origin string
+		valid  bool
+	}{
+		{"http://hypervisor:6976", true},
+		{"https://hypervisor:6976", true},
+		{"", false},
+		{"http://evil.example.com", false},
+		{"http://hypervisor:8080", false},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET",
+			"http://hypervisor:6976/vmSerialConsole?10.0.0.1", nil)
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+		config := &websocket.Config{
+			Version: websocket.ProtocolVersionHybi13,
+		}
+		err := checkSameOrigin(config, req)
+		if test.valid && err != nil {
+			t.Errorf("origin: %q: %s", test.origin, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("origin: %q: accepted", test.origin)
+		}
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/html"
 	"github.com/Cloud-Foundations/Dominator/lib/json"
 	"github.com/Cloud-Foundations/Dominator/lib/url"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
 )
 
 var timeFormat string = "02 Jan 2006 15:04:05.99 MST"
@@ -102,6 +103,11 @@ func (s state) showVMHandler(w http.ResponseWriter, req *http.Request) {
 		writeBool(writer, "Spread volumes", vm.SpreadVolumes)
 		writeString(writer, "Latest boot",
 			fmt.Sprintf("<a href=\"showVmBootLog?%s\">log</a>", ipAddr))
+		if vm.State == proto.StateRunning {
+			writeString(writer, "Serial console",
+				fmt.Sprintf("<a href=\"showVmSerialConsole?%s\">connect</a>",
+					ipAddr))
+		}
 		rc, size, lastPatchTime, err := s.manager.GetVmLastPatchLog(netIpAddr)
 		if err == nil {
 			rc.Close()
@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Cloud-Foundations/Dominator/lib/blobstore"
 	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
 	"github.com/Cloud-Foundations/Dominator/lib/filter"
 	"github.com/Cloud-Foundations/Dominator/lib/lockwatcher"
@@ -39,6 +40,9 @@ type DhcpServer interface {
 
 type Manager struct {
 	StartOptions
+	affinityNotifier  chan struct{}
+	baseVolumesMutex  sync.Mutex                     // Protect baseVolumeLocks.
+	baseVolumeLocks   map[string]*baseVolumeLockType // Key: directory.
 	healthStatusMutex sync.RWMutex
 	healthStatus      string
 	lockWatcher       *lockwatcher.LockWatcher
@@ -46,9 +50,11 @@ type Manager struct {
 	notifiersMutex    sync.Mutex
 	notifiers         map[<-chan proto.Update]chan<- proto.Update
 	numCPUs           uint
+	numaNodes         []proto.NumaNode
 	objectCache       *cachingreader.ObjectServer
 	objectVolumeIndex int // -1: not on a volume mount, else index of mount.
 	rootCookie        []byte
+	securityNotifier  chan struct{}
 	serialNumber      string
 	shuttingDown      bool
 	summaryMutex      sync.RWMutex
@@ -60,7 +66,8 @@ type Manager struct {
 	disabled          bool
 	ownerGroups       map[string]struct{}
 	ownerUsers        map[string]struct{}
-	subnets           map[string]proto.Subnet // Key: Subnet ID.
+	securityGroups    map[string]proto.SecurityGroup // Key: group ID.
+	subnets           map[string]proto.Subnet        // Key: Subnet ID.
 	subnetChannels    []chan<- proto.Subnet
 	totalVolumeBytes  uint64
 	vms               map[string]*vmInfoType // Key: IP address.
@@ -69,6 +76,7 @@ type Manager struct {
 }
 
 type StartOptions struct {
+	BackupStore          blobstore.Store          // If nil, backups are disabled.
 	BridgeMap            map[string]net.Interface // Key: interface name.
 	DhcpServer           DhcpServer
 	ImageServerAddress   string
@@ -115,6 +123,7 @@ type vmInfoType struct {
 	monitorSockname            string
 	blockMutations             bool
 	ownerUsers                 map[string]struct{}
+	pausedNotifier             chan<- struct{}
 	serialInput                io.Writer
 	serialOutput               chan<- byte
 	stoppedNotifier            chan<- struct{}
@@ -144,6 +153,11 @@ func (m *Manager) AddVmVolumes(ipAddr net.IP,
 	return m.addVmVolumes(ipAddr, authInfo, volumeSizes)
 }
 
+func (m *Manager) BackupVm(ipAddr net.IP,
+	authInfo *srpc.AuthInformation) (proto.VmBackupInfo, error) {
+	return m.backupVm(ipAddr, authInfo)
+}
+
 func (m *Manager) BecomePrimaryVmOwner(ipAddr net.IP,
 	authInfo *srpc.AuthInformation) error {
 	return m.becomePrimaryVmOwner(ipAddr, authInfo)
@@ -168,6 +182,17 @@ func (m *Manager) ChangeVmDestroyProtection(ipAddr net.IP,
 	return m.changeVmDestroyProtection(ipAddr, authInfo, destroyProtection)
 }
 
+func (m *Manager) ChangeVmIoLimits(ipAddr net.IP,
+	authInfo *srpc.AuthInformation, networkLimits []proto.InterfaceIoLimits,
+	volumeLimits []proto.VolumeIoLimits) error {
+	return m.changeVmIoLimits(ipAddr, authInfo, networkLimits, volumeLimits)
+}
+
+func (m *Manager) ChangeVmLifecyclePolicy(ipAddr net.IP,
+	authInfo *srpc.AuthInformation, policy proto.LifecyclePolicy) error {
+	return m.changeVmLifecyclePolicy(ipAddr, authInfo, policy)
+}
+
 func (m *Manager) ChangeVmMachineType(ipAddr net.IP,
 	authInfo *srpc.AuthInformation, machineType proto.MachineType) error {
 	return m.changeVmMachineType(ipAddr, authInfo, machineType)
@@ -183,6 +208,11 @@ func (m *Manager) ChangeVmOwnerUsers(ipAddr net.IP,
 	return m.changeVmOwnerUsers(ipAddr, authInfo, extraUsers)
 }
 
+func (m *Manager) ChangeVmSecurityGroups(ipAddr net.IP,
+	authInfo *srpc.AuthInformation, securityGroups []string) error {
+	return m.changeVmSecurityGroups(ipAddr, authInfo, securityGroups)
+}
+
 func (m *Manager) ChangeVmSize(authInfo *srpc.AuthInformation,
 	req proto.ChangeVmSizeRequest) error {
 	return m.changeVmSize(authInfo, req)
@@ -290,11 +320,7 @@ func (m *Manager) ExportLocalVm(authInfo *srpc.AuthInformation,
 }
 
 func (m *Manager) GetCapacity() proto.GetCapacityResponse {
-	return proto.GetCapacityResponse{
-		MemoryInMiB:      m.memTotalInMiB,
-		NumCPUs:          m.numCPUs,
-		TotalVolumeBytes: m.totalVolumeBytes,
-	}
+	return m.getCapacity()
 }
 
 func (m *Manager) GetHealthStatus() string {
@@ -400,6 +426,10 @@ func (m *Manager) ListRegisteredAddresses() []proto.Address {
 	return m.listRegisteredAddresses()
 }
 
+func (m *Manager) ListSecurityGroups() []proto.SecurityGroup {
+	return m.listSecurityGroups()
+}
+
 func (m *Manager) ListSubnets(doSort bool) []proto.Subnet {
 	return m.listSubnets(doSort)
 }
@@ -408,6 +438,11 @@ func (m *Manager) ListVMs(request proto.ListVMsRequest) []string {
 	return m.listVMs(request)
 }
 
+func (m *Manager) ListVmBackups(ipAddr net.IP,
+	authInfo *srpc.AuthInformation) ([]proto.VmBackupInfo, error) {
+	return m.listVmBackups(ipAddr, authInfo)
+}
+
 func (m *Manager) ListVolumeDirectories() []string {
 	return m.volumeDirectories
 }
@@ -476,6 +511,11 @@ func (m *Manager) ReplaceVmUserData(ipAddr net.IP, reader io.Reader,
 	return m.replaceVmUserData(ipAddr, reader, size, authInfo)
 }
 
+func (m *Manager) RestoreVmFromBackup(ipAddr net.IP,
+	authInfo *srpc.AuthInformation, backupId string) error {
+	return m.restoreVmFromBackup(ipAddr, authInfo, backupId)
+}
+
 func (m *Manager) RestoreVmFromSnapshot(ipAddr net.IP,
 	authInfo *srpc.AuthInformation, forceIfNotStopped bool) error {
 	return m.restoreVmFromSnapshot(ipAddr, authInfo, forceIfNotStopped)
@@ -526,6 +566,11 @@ func (m *Manager) StopVm(ipAddr net.IP, authInfo *srpc.AuthInformation,
 	return m.stopVm(ipAddr, authInfo, accessToken)
 }
 
+func (m *Manager) UpdateSecurityGroups(
+	request proto.UpdateSecurityGroupsRequest) error {
+	return m.updateSecurityGroups(request)
+}
+
 func (m *Manager) UpdateSubnets(request proto.UpdateSubnetsRequest) error {
 	return m.updateSubnets(request)
 }
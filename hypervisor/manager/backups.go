@@ -0,0 +1,568 @@
+package manager
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/blobstore"
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+const (
+	backupChunkSize     = 4 << 20
+	backupCheckInterval = 5 * time.Minute
+	backupIdFormat      = "20060102-150405"
+	backupIntervalTag   = "BackupInterval"
+	backupPauseTimeout  = 10 * time.Second
+	backupRetentionTag  = "BackupRetention"
+)
+
+var errorNoBackupStore = errors.New("no backup store configured")
+
+// backupManifest records the chunks for each volume of a VM backup. Chunks are
+// keyed by their SHA-512 hash and are stored per VM, so each hypervisor can
+// garbage collect the chunks for the VMs it manages.
+type backupManifest struct {
+	BackupId      string
+	CreatedOn     time.Time
+	UploadedBytes uint64
+	VmInfo        proto.VmInfo
+	Volumes       []backupVolume
+}
+
+type backupVolume struct {
+	Chunks []string // Hex hash for each chunk. Empty string: all zeros.
+	Size   uint64
+}
+
+type backupRetention struct {
+	maxAge     time.Duration // Zero: no limit.
+	maxBackups uint          // Zero: no limit.
+}
+
+func getBackupChunksStore(store blobstore.Store,
+	ipAddr string) blobstore.Store {
+	return blobstore.NewSubStore(store, ipAddr+"/chunks/")
+}
+
+func getBackupManifestsStore(store blobstore.Store,
+	ipAddr string) blobstore.Store {
+	return blobstore.NewSubStore(store, ipAddr+"/manifests/")
+}
+
+func isZero(data []byte) bool {
+	for _, value := range data {
+		if value != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (manifest *backupManifest) getInfo() proto.VmBackupInfo {
+	volumeSizes := make([]uint64, 0, len(manifest.Volumes))
+	for _, volume := range manifest.Volumes {
+		volumeSizes = append(volumeSizes, volume.Size)
+	}
+	return proto.VmBackupInfo{
+		BackupId:      manifest.BackupId,
+		CreatedOn:     manifest.CreatedOn,
+		UploadedBytes: manifest.UploadedBytes,
+		VolumeSizes:   volumeSizes,
+	}
+}
+
+// parseBackupRetention parses the value of the BackupRetention tag, which is
+// either the number of backups to keep or the maximum age of backups to keep.
+func parseBackupRetention(value string) (backupRetention, error) {
+	if maxBackups, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return backupRetention{maxBackups: uint(maxBackups)}, nil
+	}
+	maxAge, err := time.ParseDuration(value)
+	if err != nil {
+		return backupRetention{}, fmt.Errorf("invalid %s: %s",
+			backupRetentionTag, value)
+	}
+	return backupRetention{maxAge: maxAge}, nil
+}
+
+func readBackupManifest(store blobstore.Store,
+	backupId string) (*backupManifest, error) {
+	reader, err := store.GetObject(backupId)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	var manifest backupManifest
+	if err := json.NewDecoder(reader).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("error decoding manifest: %s: %s", backupId, err)
+	}
+	return &manifest, nil
+}
+
+// readBackupManifests returns the manifests for the VM, oldest first.
+func readBackupManifests(store blobstore.Store) ([]*backupManifest, error) {
+	var backupIds []string
+	err := store.ListObjects(func(object blobstore.ObjectInfo) error {
+		backupIds = append(backupIds, object.Key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(backupIds) // The format of the IDs sorts by time.
+	manifests := make([]*backupManifest, 0, len(backupIds))
+	for _, backupId := range backupIds {
+		manifest, err := readBackupManifest(store, backupId)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest)
+	}
+	return manifests, nil
+}
+
+// snapshotVolume makes a copy-on-write (reflink) copy of the volume file, which
+// is fast and uses no extra space until the volume is changed. On platforms
+// other than Linux a plain copy is made. It returns the name of the copy.
+func snapshotVolume(filename string) (string, error) {
+	source, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer source.Close()
+	snapshotFilename := filename + ".backup"
+	snapshot, err := os.OpenFile(snapshotFilename,
+		os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fsutil.PrivateFilePerms)
+	if err != nil {
+		return "", err
+	}
+	if err := cloneFile(snapshot, source); err != nil {
+		snapshot.Close()
+		os.Remove(snapshotFilename)
+		return "", fmt.Errorf("error cloning: %s: %s", filename, err)
+	}
+	if err := snapshot.Close(); err != nil {
+		os.Remove(snapshotFilename)
+		return "", err
+	}
+	return snapshotFilename, nil
+}
+
+// snapshotVolumes makes copy-on-write copies of the volumes. On failure, no
+// copies are left behind.
+func snapshotVolumes(volumeLocations []proto.LocalVolume) (
+	[]proto.LocalVolume, error) {
+	snapshots := make([]proto.LocalVolume, 0, len(volumeLocations))
+	for _, volumeLocation := range volumeLocations {
+		filename, err := snapshotVolume(volumeLocation.Filename)
+		if err != nil {
+			for _, snapshot := range snapshots {
+				os.Remove(snapshot.Filename)
+			}
+			return nil, err
+		}
+		volumeLocation.Filename = filename
+		snapshots = append(snapshots, volumeLocation)
+	}
+	return snapshots, nil
+}
+
+// writeBackupVolume reads the volume file and writes the chunks which are not
+// already stored. It returns the number of bytes written.
+func writeBackupVolume(store blobstore.Store, filename string,
+	knownChunks map[string]struct{}) (backupVolume, uint64, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return backupVolume{}, 0, err
+	}
+	defer file.Close()
+	var volume backupVolume
+	var uploadedBytes uint64
+	buffer := make([]byte, backupChunkSize)
+	for {
+		nRead, err := io.ReadFull(file, buffer)
+		if nRead > 0 {
+			chunk := buffer[:nRead]
+			volume.Size += uint64(nRead)
+			if isZero(chunk) {
+				volume.Chunks = append(volume.Chunks, "")
+			} else {
+				hashVal := sha512.Sum512(chunk)
+				key := hex.EncodeToString(hashVal[:])
+				if _, ok := knownChunks[key]; !ok {
+					if err := store.PutObject(key, chunk); err != nil {
+						return backupVolume{}, 0, err
+					}
+					knownChunks[key] = struct{}{}
+					uploadedBytes += uint64(nRead)
+				}
+				volume.Chunks = append(volume.Chunks, key)
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return volume, uploadedBytes, nil
+		}
+		if err != nil {
+			return backupVolume{}, 0, err
+		}
+	}
+}
+
+// restoreBackupVolume writes the chunks to the volume file. Chunks of zeros are
+// not written, leaving holes in the file.
+func restoreBackupVolume(store blobstore.Store, filename string,
+	volume backupVolume) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := file.Truncate(int64(volume.Size)); err != nil {
+		return err
+	}
+	for index, key := range volume.Chunks {
+		if key == "" {
+			continue
+		}
+		reader, err := store.GetObject(key)
+		if err != nil {
+			return fmt.Errorf("error reading chunk: %s: %s", key, err)
+		}
+		data, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("error reading chunk: %s: %s", key, err)
+		}
+		hashVal := sha512.Sum512(data)
+		if hex.EncodeToString(hashVal[:]) != key {
+			return fmt.Errorf("corrupt chunk: %s", key)
+		}
+		_, err = file.WriteAt(data, int64(index)*backupChunkSize)
+		if err != nil {
+			return err
+		}
+	}
+	return file.Close()
+}
+
+func (m *Manager) backupScheduler() {
+	lastBackups := make(map[string]time.Time) // Key: IP address.
+	for ; ; time.Sleep(backupCheckInterval) {
+		intervals := make(map[string]string) // Key: IP address.
+		m.mutex.RLock()
+		for ipAddr, vm := range m.vms {
+			if value := vm.Tags[backupIntervalTag]; value != "" {
+				intervals[ipAddr] = value
+			}
+		}
+		m.mutex.RUnlock()
+		for ipAddr, value := range intervals {
+			interval, err := time.ParseDuration(value)
+			if err != nil || interval < backupCheckInterval {
+				m.Logger.Printf("%s: invalid %s: %s\n",
+					ipAddr, backupIntervalTag, value)
+				continue
+			}
+			lastBackup, ok := lastBackups[ipAddr]
+			if !ok {
+				manifestsStore := getBackupManifestsStore(m.BackupStore, ipAddr)
+				manifests, err := readBackupManifests(manifestsStore)
+				if err != nil {
+					m.Logger.Printf("%s: error reading backups: %s\n",
+						ipAddr, err)
+					continue
+				}
+				if len(manifests) > 0 {
+					lastBackup = manifests[len(manifests)-1].CreatedOn
+				}
+			}
+			if time.Since(lastBackup) < interval {
+				lastBackups[ipAddr] = lastBackup
+				continue
+			}
+			backup, err := m.backupVm(net.ParseIP(ipAddr),
+				&srpc.AuthInformation{HaveMethodAccess: true})
+			if err != nil {
+				m.Logger.Printf("%s: error backing up: %s\n", ipAddr, err)
+				continue
+			}
+			lastBackups[ipAddr] = backup.CreatedOn
+		}
+		for ipAddr := range lastBackups {
+			if _, ok := intervals[ipAddr]; !ok {
+				delete(lastBackups, ipAddr)
+			}
+		}
+	}
+}
+
+// backupVm writes a backup of the volumes of the VM to the backup store. A
+// running VM is paused while copy-on-write snapshots of the volumes are made
+// and is resumed before the snapshots are read, so that the backup is
+// consistent. If the file-system does not support snapshots, the VM remains
+// paused while the volumes are read.
+func (m *Manager) backupVm(ipAddr net.IP,
+	authInfo *srpc.AuthInformation) (proto.VmBackupInfo, error) {
+	if m.BackupStore == nil {
+		return proto.VmBackupInfo{}, errorNoBackupStore
+	}
+	vm, err := m.getVmLockAndAuth(ipAddr, true, authInfo, nil)
+	if err != nil {
+		return proto.VmBackupInfo{}, err
+	}
+	var paused bool
+	var pausedChannel chan struct{}
+	switch vm.State {
+	case proto.StateStopped:
+	case proto.StateRunning:
+		if vm.commandInput != nil {
+			pausedChannel = make(chan struct{}, 1)
+			vm.pausedNotifier = pausedChannel
+			vm.commandInput <- "stop"
+			paused = true
+		}
+	default:
+		vm.mutex.Unlock()
+		return proto.VmBackupInfo{}, errors.New("VM is not stopped or running")
+	}
+	retentionValue := vm.Tags[backupRetentionTag]
+	vmInfo := vm.VmInfo
+	volumeLocations := vm.VolumeLocations
+	vm.blockMutations = true
+	vm.mutex.Unlock()
+	var snapshots []proto.LocalVolume
+	defer func() {
+		for _, snapshot := range snapshots {
+			os.Remove(snapshot.Filename)
+		}
+		vm.mutex.Lock()
+		vm.pausedNotifier = nil
+		if paused && vm.commandInput != nil {
+			vm.commandInput <- "cont"
+		}
+		vm.allowMutationsAndUnlock(true)
+	}()
+	if paused {
+		timer := time.NewTimer(backupPauseTimeout)
+		select {
+		case <-pausedChannel:
+			timer.Stop()
+		case <-timer.C:
+			return proto.VmBackupInfo{}, errors.New("timed out pausing VM")
+		}
+		snapshots, err = snapshotVolumes(volumeLocations)
+		if err != nil {
+			vm.logger.Printf("%s, VM will remain paused during backup\n", err)
+		} else {
+			volumeLocations = snapshots
+			vm.mutex.Lock()
+			if vm.commandInput != nil {
+				vm.commandInput <- "cont"
+			}
+			paused = false
+			vm.mutex.Unlock()
+		}
+	}
+	vm.logger.Debugln(0, "starting backup")
+	startTime := time.Now()
+	chunksStore := getBackupChunksStore(m.BackupStore, vm.ipAddress)
+	knownChunks := make(map[string]struct{})
+	err = chunksStore.ListObjects(func(object blobstore.ObjectInfo) error {
+		knownChunks[object.Key] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return proto.VmBackupInfo{}, err
+	}
+	manifest := backupManifest{
+		BackupId:  startTime.UTC().Format(backupIdFormat),
+		CreatedOn: startTime,
+		VmInfo:    vmInfo,
+	}
+	for _, volumeLocation := range volumeLocations {
+		filename := volumeLocation.Filename
+		if volumeLocation.BaseVolume != "" {
+			filename, err = flattenVolume(volumeLocation)
+			if err != nil {
+				return proto.VmBackupInfo{}, err
+			}
+		}
+		volume, uploadedBytes, err := writeBackupVolume(chunksStore,
+			filename, knownChunks)
+		if volumeLocation.BaseVolume != "" {
+			os.Remove(filename)
+		}
+		if err != nil {
+			return proto.VmBackupInfo{}, err
+		}
+		manifest.UploadedBytes += uploadedBytes
+		manifest.Volumes = append(manifest.Volumes, volume)
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return proto.VmBackupInfo{}, err
+	}
+	manifestsStore := getBackupManifestsStore(m.BackupStore, vm.ipAddress)
+	if err := manifestsStore.PutObject(manifest.BackupId, data); err != nil {
+		return proto.VmBackupInfo{}, err
+	}
+	vm.logger.Printf("backup: %s completed in %s, uploaded %d bytes\n",
+		manifest.BackupId, time.Since(startTime), manifest.UploadedBytes)
+	if retentionValue != "" {
+		if retention, err := parseBackupRetention(retentionValue); err != nil {
+			vm.logger.Println(err)
+		} else {
+			err := m.applyBackupRetention(vm.ipAddress, retention)
+			if err != nil {
+				vm.logger.Printf("error applying backup retention: %s\n", err)
+			}
+		}
+	}
+	return manifest.getInfo(), nil
+}
+
+// applyBackupRetention deletes the old backups for the VM which are not
+// covered by the retention policy and then deletes the chunks which are no
+// longer referenced. The newest backup is always kept.
+func (m *Manager) applyBackupRetention(ipAddr string,
+	retention backupRetention) error {
+	manifestsStore := getBackupManifestsStore(m.BackupStore, ipAddr)
+	manifests, err := readBackupManifests(manifestsStore)
+	if err != nil {
+		return err
+	}
+	numToDelete := 0
+	for index, manifest := range manifests[:len(manifests)-1] {
+		if retention.maxBackups > 0 &&
+			uint(len(manifests)-index) > retention.maxBackups {
+			numToDelete = index + 1
+		} else if retention.maxAge > 0 &&
+			time.Since(manifest.CreatedOn) > retention.maxAge {
+			numToDelete = index + 1
+		}
+	}
+	if numToDelete < 1 {
+		return nil
+	}
+	for _, manifest := range manifests[:numToDelete] {
+		if err := manifestsStore.DeleteObject(manifest.BackupId); err != nil {
+			return err
+		}
+	}
+	referencedChunks := make(map[string]struct{})
+	for _, manifest := range manifests[numToDelete:] {
+		for _, volume := range manifest.Volumes {
+			for _, key := range volume.Chunks {
+				referencedChunks[key] = struct{}{}
+			}
+		}
+	}
+	chunksStore := getBackupChunksStore(m.BackupStore, ipAddr)
+	var keysToDelete []string
+	err = chunksStore.ListObjects(func(object blobstore.ObjectInfo) error {
+		if _, ok := referencedChunks[object.Key]; !ok {
+			keysToDelete = append(keysToDelete, object.Key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range keysToDelete {
+		if err := chunksStore.DeleteObject(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) listVmBackups(ipAddr net.IP,
+	authInfo *srpc.AuthInformation) ([]proto.VmBackupInfo, error) {
+	if m.BackupStore == nil {
+		return nil, errorNoBackupStore
+	}
+	vm, err := m.getVmLockAndAuth(ipAddr, false, authInfo, nil)
+	if err != nil {
+		return nil, err
+	}
+	vm.mutex.RUnlock()
+	manifests, err := readBackupManifests(
+		getBackupManifestsStore(m.BackupStore, vm.ipAddress))
+	if err != nil {
+		return nil, err
+	}
+	backups := make([]proto.VmBackupInfo, 0, len(manifests))
+	for _, manifest := range manifests {
+		backups = append(backups, manifest.getInfo())
+	}
+	return backups, nil
+}
+
+// restoreVmFromBackup replaces the contents of the volumes of a stopped VM with
+// the contents from a backup. The volumes are resized to the sizes in the
+// backup.
+func (m *Manager) restoreVmFromBackup(ipAddr net.IP,
+	authInfo *srpc.AuthInformation, backupId string) error {
+	if m.BackupStore == nil {
+		return errorNoBackupStore
+	}
+	if backupId == "" || strings.ContainsRune(backupId, '/') {
+		return errors.New("invalid backup ID")
+	}
+	vm, err := m.getVmLockAndAuth(ipAddr, true, authInfo, nil)
+	if err != nil {
+		return err
+	}
+	if vm.State != proto.StateStopped {
+		vm.mutex.Unlock()
+		return errors.New("VM is not stopped")
+	}
+	manifest, err := readBackupManifest(
+		getBackupManifestsStore(m.BackupStore, vm.ipAddress), backupId)
+	if err != nil {
+		vm.mutex.Unlock()
+		return err
+	}
+	if len(manifest.Volumes) != len(vm.VolumeLocations) {
+		vm.mutex.Unlock()
+		return fmt.Errorf("backup has %d volumes, VM has %d volumes",
+			len(manifest.Volumes), len(vm.VolumeLocations))
+	}
+	volumeLocations := vm.VolumeLocations
+	vm.blockMutations = true
+	vm.mutex.Unlock()
+	chunksStore := getBackupChunksStore(m.BackupStore, vm.ipAddress)
+	vm.logger.Printf("restoring from backup: %s\n", backupId)
+	for index, volume := range manifest.Volumes {
+		err := restoreBackupVolume(chunksStore,
+			volumeLocations[index].Filename, volume)
+		if err != nil {
+			vm.allowMutationsAndUnlock(false)
+			return fmt.Errorf("error restoring volume: %d: %s", index, err)
+		}
+	}
+	vm.mutex.Lock()
+	for index, volume := range manifest.Volumes {
+		vm.Volumes[index].Size = volume.Size
+		// The volume is now RAW, so it no longer needs the base volume.
+		m.releaseBaseVolume(vm.VolumeLocations[index].BaseVolume)
+		vm.VolumeLocations[index].BaseVolume = ""
+	}
+	vm.writeAndSendInfo()
+	vm.allowMutationsAndUnlock(true)
+	return nil
+}
@@ -0,0 +1,46 @@
+package manager
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func TestSnapshotVolumes(t *testing.T) {
+	dirname := t.TempDir()
+	var volumeLocations []proto.LocalVolume
+	for _, name := range []string{"root", "secondary-volume.0"} {
+		filename := filepath.Join(dirname, name)
+		err := ioutil.WriteFile(filename, []byte(name), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		volumeLocations = append(volumeLocations,
+			proto.LocalVolume{Filename: filename})
+	}
+	snapshots, err := snapshotVolumes(volumeLocations)
+	if err != nil {
+		// Reflinks are not supported: no copies must be left behind.
+		t.Log(err)
+		matches, _ := filepath.Glob(filepath.Join(dirname, "*.backup"))
+		if len(matches) > 0 {
+			t.Fatalf("snapshots left behind: %v", matches)
+		}
+		return
+	}
+	for index, snapshot := range snapshots {
+		data, err := ioutil.ReadFile(snapshot.Filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := ioutil.ReadFile(volumeLocations[index].Filename)
+		if !bytes.Equal(data, want) {
+			t.Errorf("%s: %q != %q", snapshot.Filename, data, want)
+		}
+		os.Remove(snapshot.Filename)
+	}
+}
@@ -0,0 +1,254 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem/util"
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+const (
+	baseVolumesDirectory       = ".base-volumes"
+	baseVolumeFilename         = "volume"
+	baseVolumeImageFilename    = "image-name"
+	baseVolumeRefcountFilename = "refcount"
+)
+
+// baseVolumeLockType serialises changes to a cached base volume, so that
+// unpacking an image does not block changes to other base volumes.
+type baseVolumeLockType struct {
+	mutex sync.Mutex
+	users uint // Protected by Manager.baseVolumesMutex.
+}
+
+var errorThinRootVolume = errors.New(
+	"not supported for thin-provisioned root volume")
+
+// baseVolumeKey returns the key for the cached base volume for an image. The
+// key includes the options which change the contents of the volume.
+func baseVolumeKey(imageName string,
+	writeRawOptions util.WriteRawOptions) string {
+	hasher := sha256.New()
+	fmt.Fprintf(hasher, "%s\n%s\n%d\n%d\n", imageName,
+		writeRawOptions.ExtraKernelOptions, writeRawOptions.MinimumFreeBytes,
+		writeRawOptions.RoundupPower)
+	return fmt.Sprintf("%x", hasher.Sum(nil))[:16]
+}
+
+// changeBaseVolumeRefcount adds delta to the reference count for the base
+// volume in dirname and returns the new count.
+func changeBaseVolumeRefcount(dirname string, delta int) (int, error) {
+	filename := filepath.Join(dirname, baseVolumeRefcountFilename)
+	var refcount int
+	if data, err := ioutil.ReadFile(filename); err != nil {
+		if !os.IsNotExist(err) {
+			return 0, err
+		}
+	} else {
+		refcount, err = strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil {
+			return 0, fmt.Errorf("error parsing: %s: %s", filename, err)
+		}
+	}
+	refcount += delta
+	tmpFilename := filename + "~"
+	err := ioutil.WriteFile(tmpFilename, []byte(strconv.Itoa(refcount)+"\n"),
+		fsutil.PublicFilePerms)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmpFilename, filename); err != nil {
+		os.Remove(tmpFilename)
+		return 0, err
+	}
+	return refcount, nil
+}
+
+// checkThinRootVolume returns an error if the root volume for the request
+// cannot be an overlay on a shared base volume.
+func checkThinRootVolume(request proto.CreateVmRequest) error {
+	if request.ImageName == "" {
+		return errors.New("thin root volume requires an image name")
+	}
+	if len(request.OverlayDirectories) > 0 || len(request.OverlayFiles) > 0 {
+		return errors.New("cannot use overlays with thin root volume")
+	}
+	if request.SkipBootloader {
+		return errors.New("cannot skip bootloader with thin root volume")
+	}
+	return nil
+}
+
+// flattenVolume writes the contents of a QCOW2 overlay (including the backing
+// file) to a temporary RAW file. The caller must remove the file.
+func flattenVolume(volume proto.LocalVolume) (string, error) {
+	tmpFilename := volume.Filename + ".flat"
+	cmd := exec.Command("qemu-img", "convert", "-U", "-f", "qcow2",
+		"-O", "raw", volume.Filename, tmpFilename)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(tmpFilename)
+		return "", fmt.Errorf("error flattening volume: %s: %s",
+			err, string(output))
+	}
+	return tmpFilename, nil
+}
+
+// getBaseVolumeSize returns the size of the base volume, which is also the
+// size of the overlays backed by it.
+func getBaseVolumeSize(baseFilename string) (uint64, error) {
+	if fi, err := os.Stat(baseFilename); err != nil {
+		return 0, err
+	} else {
+		return uint64(fi.Size()), nil
+	}
+}
+
+// createBaseVolume unpacks the image into a new, read-only base volume in
+// dirname.
+func (m *Manager) createBaseVolume(dirname, imageName string,
+	client *srpc.Client, fs *filesystem.FileSystem,
+	writeRawOptions util.WriteRawOptions) error {
+	if err := os.MkdirAll(dirname, fsutil.DirPerms); err != nil {
+		return err
+	}
+	tmpFilename := filepath.Join(dirname, baseVolumeFilename+"~")
+	err := m.writeRaw(proto.LocalVolume{Filename: tmpFilename}, "", client, fs,
+		writeRawOptions, false)
+	if err != nil {
+		os.Remove(tmpFilename)
+		return err
+	}
+	err = os.Chmod(tmpFilename, fsutil.PrivateFilePerms&^0222) // Read-only.
+	if err != nil {
+		os.Remove(tmpFilename)
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(dirname, baseVolumeImageFilename),
+		[]byte(imageName+"\n"), fsutil.PublicFilePerms)
+	if err != nil {
+		os.Remove(tmpFilename)
+		return err
+	}
+	err = os.Rename(tmpFilename, filepath.Join(dirname, baseVolumeFilename))
+	if err != nil {
+		os.Remove(tmpFilename)
+		return err
+	}
+	m.Logger.Printf("created base volume: %s for image: %s\n",
+		dirname, imageName)
+	return nil
+}
+
+// createThinRootVolume creates the root volume for the VM as a QCOW2 overlay
+// on the cached base volume for the image, unpacking the image if there is no
+// cached base volume. The size of the volume is returned.
+func (m *Manager) createThinRootVolume(vm *vmInfoType, imageName string,
+	client *srpc.Client, fs *filesystem.FileSystem,
+	writeRawOptions util.WriteRawOptions) (uint64, error) {
+	key := baseVolumeKey(imageName, writeRawOptions)
+	// The root label must be the same for every VM sharing the base volume.
+	writeRawOptions.RootLabel = "rootfs@" + key[:8]
+	dirname := filepath.Join(
+		filepath.Dir(vm.VolumeLocations[0].DirectoryToCleanup),
+		baseVolumesDirectory, key)
+	baseFilename := filepath.Join(dirname, baseVolumeFilename)
+	defer m.lockBaseVolume(dirname)()
+	if _, err := os.Stat(baseFilename); err != nil {
+		if !os.IsNotExist(err) {
+			return 0, err
+		}
+		err := m.createBaseVolume(dirname, imageName, client, fs,
+			writeRawOptions)
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		vm.logger.Debugf(0, "using cached base volume: %s\n", dirname)
+	}
+	size, err := getBaseVolumeSize(baseFilename)
+	if err != nil {
+		return 0, err
+	}
+	filename := vm.VolumeLocations[0].Filename
+	cmd := exec.Command("qemu-img", "create", "-q", "-f", "qcow2",
+		"-F", "raw", "-b", baseFilename, filename)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return 0, fmt.Errorf("error creating overlay: %s: %s",
+			err, string(output))
+	}
+	if _, err := changeBaseVolumeRefcount(dirname, 1); err != nil {
+		os.Remove(filename)
+		return 0, err
+	}
+	vm.VolumeLocations[0].BaseVolume = baseFilename
+	return size, nil
+}
+
+// lockBaseVolume locks the base volume in the specified directory and returns
+// a function which unlocks it.
+func (m *Manager) lockBaseVolume(dirname string) func() {
+	m.baseVolumesMutex.Lock()
+	lock := m.baseVolumeLocks[dirname]
+	if lock == nil {
+		lock = &baseVolumeLockType{}
+		m.baseVolumeLocks[dirname] = lock
+	}
+	lock.users++
+	m.baseVolumesMutex.Unlock()
+	lock.mutex.Lock()
+	return func() {
+		lock.mutex.Unlock()
+		m.baseVolumesMutex.Lock()
+		lock.users--
+		if lock.users < 1 {
+			delete(m.baseVolumeLocks, dirname)
+		}
+		m.baseVolumesMutex.Unlock()
+	}
+}
+
+// releaseBaseVolume drops a reference to the base volume. The base volume is
+// removed once it has no more references.
+func (m *Manager) releaseBaseVolume(baseFilename string) {
+	if baseFilename == "" {
+		return
+	}
+	dirname := filepath.Dir(baseFilename)
+	defer m.lockBaseVolume(dirname)()
+	refcount, err := changeBaseVolumeRefcount(dirname, -1)
+	if err != nil {
+		m.Logger.Printf("error releasing base volume: %s: %s\n", dirname, err)
+		return
+	}
+	if refcount > 0 {
+		return
+	}
+	if err := os.RemoveAll(dirname); err != nil {
+		m.Logger.Println(err)
+	} else {
+		m.Logger.Printf("removed unused base volume: %s\n", dirname)
+	}
+}
+
+// releaseBaseVolumes drops the references to base volumes held by the VM
+// volumes.
+func (vm *vmInfoType) releaseBaseVolumes() {
+	for index, volume := range vm.VolumeLocations {
+		vm.manager.releaseBaseVolume(volume.BaseVolume)
+		vm.manager.releaseBaseVolume(volume.OldBaseVolume)
+		vm.VolumeLocations[index].BaseVolume = ""
+		vm.VolumeLocations[index].OldBaseVolume = ""
+	}
+}
@@ -0,0 +1,28 @@
+package manager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockBaseVolume(t *testing.T) {
+	m := &Manager{baseVolumeLocks: make(map[string]*baseVolumeLockType)}
+	unlockA := m.lockBaseVolume("a")
+	unlockB := m.lockBaseVolume("b") // Must not block on "a".
+	locked := make(chan struct{})
+	go func() {
+		m.lockBaseVolume("a")()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("base volume locked twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlockA()
+	<-locked
+	unlockB()
+	if len(m.baseVolumeLocks) > 0 {
+		t.Errorf("%d locks not removed", len(m.baseVolumeLocks))
+	}
+}
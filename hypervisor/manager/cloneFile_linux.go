@@ -0,0 +1,12 @@
+package manager
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// cloneFile makes a copy-on-write (reflink) copy of source in dest.
+func cloneFile(dest, source *os.File) error {
+	return unix.IoctlFileClone(int(dest.Fd()), int(source.Fd()))
+}
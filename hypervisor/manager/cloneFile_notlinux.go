@@ -0,0 +1,14 @@
+//go:build !linux
+
+package manager
+
+import (
+	"io"
+	"os"
+)
+
+// cloneFile copies source to dest, since reflinks are not supported.
+func cloneFile(dest, source *os.File) error {
+	_, err := io.Copy(dest, source)
+	return err
+}
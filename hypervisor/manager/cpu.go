@@ -18,7 +18,11 @@ func (m *Manager) checkSufficientCPUWithLock(milliCPU uint) error {
 func (m *Manager) getAvailableMilliCPUWithLock() uint {
 	available := int(m.numCPUs) * 1000
 	for _, vm := range m.vms {
-		available -= int(vm.MilliCPUs)
+		if vm.DedicatedCPUs {
+			available -= len(vm.PinnedCPUs) * 1000
+		} else {
+			available -= int(vm.MilliCPUs)
+		}
 	}
 	if available < 0 {
 		return 0
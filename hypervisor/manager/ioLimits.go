@@ -0,0 +1,199 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+const minimumNetworkBurst = 16 << 10
+
+// getNetworkBurst returns the burst size in bytes for the rate limit: 100 ms
+// worth of traffic.
+func getNetworkBurst(bitsPerSecond uint64) string {
+	burst := bitsPerSecond / 80
+	if burst < minimumNetworkBurst {
+		burst = minimumNetworkBurst
+	}
+	return strconv.FormatUint(burst, 10)
+}
+
+func getVolumeId(index int) string {
+	return fmt.Sprintf("volume%d", index)
+}
+
+func runTc(args ...string) error {
+	cmd := exec.Command("tc", args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error running tc %s: %s: %s",
+			strings.Join(args, " "), err, output)
+	}
+	return nil
+}
+
+// setInterfaceIoLimits sets the rate limits for the tap device of a VM.
+// Traffic to the VM leaves the host through the tap device and is shaped.
+// Traffic from the VM enters the host through the tap device and can only be
+// policed.
+func setInterfaceIoLimits(tapDevice string,
+	limits proto.InterfaceIoLimits) error {
+	if rate := limits.IngressBitsPerSecond; rate > 0 {
+		err := runTc("qdisc", "replace", "dev", tapDevice, "root", "tbf",
+			"rate", strconv.FormatUint(rate, 10)+"bit",
+			"burst", getNetworkBurst(rate), "latency", "50ms")
+		if err != nil {
+			return err
+		}
+	} else {
+		runTc("qdisc", "del", "dev", tapDevice, "root") // May not exist.
+	}
+	runTc("qdisc", "del", "dev", tapDevice, "ingress") // May not exist.
+	if rate := limits.EgressBitsPerSecond; rate > 0 {
+		err := runTc("qdisc", "add", "dev", tapDevice, "handle", "ffff:",
+			"ingress")
+		if err != nil {
+			return err
+		}
+		return runTc("filter", "add", "dev", tapDevice, "parent", "ffff:",
+			"protocol", "all", "u32", "match", "u32", "0", "0",
+			"police", "rate", strconv.FormatUint(rate, 10)+"bit",
+			"burst", getNetworkBurst(rate), "drop", "flowid", ":1")
+	}
+	return nil
+}
+
+func (m *Manager) changeVmIoLimits(ipAddr net.IP,
+	authInfo *srpc.AuthInformation, networkLimits []proto.InterfaceIoLimits,
+	volumeLimits []proto.VolumeIoLimits) error {
+	vm, err := m.getVmLockAndAuth(ipAddr, true, authInfo, nil)
+	if err != nil {
+		return err
+	}
+	defer vm.mutex.Unlock()
+	if len(networkLimits) > len(vm.SecondaryAddresses)+1 {
+		return errors.New("more network limits specified than VM interfaces")
+	}
+	if len(volumeLimits) > len(vm.Volumes) {
+		return errors.New("more volume limits specified than VM volumes")
+	}
+	var modifyProcess bool
+	switch vm.State {
+	case proto.StateStarting:
+		return errors.New("VM is starting")
+	case proto.StateRunning, proto.StateDebugging:
+		modifyProcess = true
+	case proto.StateStopping:
+		return errors.New("VM is stopping")
+	case proto.StateStopped, proto.StateFailedToStart, proto.StateMigrating,
+		proto.StateExporting, proto.StateCrashed:
+	case proto.StateDestroying:
+		return errors.New("VM is already destroying")
+	default:
+		return errors.New("unknown state: " + vm.State.String())
+	}
+	if len(networkLimits) < 1 {
+		networkLimits = nil
+	}
+	volumes := make([]proto.Volume, 0, len(vm.Volumes))
+	for index, volume := range vm.Volumes {
+		if index < len(volumeLimits) {
+			volume.IoLimits = volumeLimits[index]
+		} else {
+			volume.IoLimits = proto.VolumeIoLimits{}
+		}
+		volumes = append(volumes, volume)
+	}
+	if modifyProcess {
+		err := vm.setIoLimits(vm.NetworkLimits, networkLimits, vm.Volumes,
+			volumes)
+		if err != nil {
+			return err
+		}
+	}
+	vm.NetworkLimits = networkLimits
+	vm.Volumes = volumes
+	vm.writeAndSendInfo()
+	return nil
+}
+
+// getVolumeInterface returns the interface for the specified volume index.
+func (vm *vmInfoType) getVolumeInterface(index int) proto.VolumeInterface {
+	if index < len(vm.Volumes) {
+		return vm.Volumes[index].Interface
+	}
+	if vm.DisableVirtIO {
+		return proto.VolumeInterfaceIDE
+	}
+	return proto.VolumeInterfaceVirtIO
+}
+
+// setIoLimits does not take any locks. It changes the limits which differ
+// between the old and new limits for the running VM.
+func (vm *vmInfoType) setIoLimits(oldNetworkLimits,
+	newNetworkLimits []proto.InterfaceIoLimits,
+	oldVolumes, newVolumes []proto.Volume) error {
+	for index, volume := range newVolumes {
+		var oldLimits proto.VolumeIoLimits
+		if index < len(oldVolumes) {
+			oldLimits = oldVolumes[index].IoLimits
+		}
+		if volume.IoLimits != oldLimits {
+			vm.setVolumeIoLimits(index, volume.IoLimits)
+		}
+	}
+	var tapDevices []string
+	for index := 0; index < len(oldNetworkLimits) ||
+		index < len(newNetworkLimits); index++ {
+		var oldLimits, newLimits proto.InterfaceIoLimits
+		if index < len(oldNetworkLimits) {
+			oldLimits = oldNetworkLimits[index]
+		}
+		if index < len(newNetworkLimits) {
+			newLimits = newNetworkLimits[index]
+		}
+		if newLimits == oldLimits {
+			continue
+		}
+		if tapDevices == nil {
+			pid, err := vm.readPid()
+			if err != nil {
+				return fmt.Errorf("unable to read virtualiser PID: %w", err)
+			}
+			if tapDevices, err = getTapDevices(pid); err != nil {
+				return err
+			}
+		}
+		if index >= len(tapDevices) {
+			return fmt.Errorf("no tap device for interface: %d", index)
+		}
+		err := setInterfaceIoLimits(tapDevices[index], newLimits)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setVolumeIoLimits sends a command to the QMP monitor to throttle the volume.
+// It does not wait for the response.
+func (vm *vmInfoType) setVolumeIoLimits(index int,
+	limits proto.VolumeIoLimits) {
+	// Volumes attached with the old-style -drive flag are identified by the
+	// drive name, others are identified by the device name.
+	idKey := "id"
+	switch vm.getVolumeInterface(index) {
+	case proto.VolumeInterfaceVirtIO, proto.VolumeInterfaceIDE:
+		idKey = "device"
+	}
+	arguments := fmt.Sprintf(`"%s":"%s","bps":%d,"bps_rd":0,"bps_wr":0,`+
+		`"iops":%d,"iops_rd":0,"iops_wr":0`,
+		idKey, getVolumeId(index), limits.BytesPerSecond, limits.IOPS)
+	vm.commandInput <- `\{"execute":"block_set_io_throttle","arguments":{` +
+		arguments + "}}"
+}
@@ -0,0 +1,306 @@
+package manager
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/net/smtp"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+const (
+	clockTicksPerSecond    = 100  // USER_HZ on Linux.
+	idleCpuFraction        = 0.01 // A VM using less CPU than this is idle.
+	lifecycleCheckInterval = time.Minute
+	lifecycleRetryInterval = time.Hour
+)
+
+var (
+	emailDomain = flag.String("emailDomain", "",
+		"Email domain to send VM lifecycle notifications to")
+	smtpServer = flag.String("smtpServer", "", "Address of SMTP server")
+)
+
+// lifecycleStateType records the progress of the lifecycle policy for a VM.
+// It is not persisted, so idle VMs get a fresh timeout when the Hypervisor
+// restarts. The expiry time the owners were warned about is saved with the VM
+// so that they are not warned again after a restart.
+type lifecycleStateType struct {
+	cpuTicks   uint64
+	expiredAt  time.Time // Last attempt to handle expiry.
+	lastActive time.Time
+	sampledAt  time.Time
+}
+
+type lifecycleVmType struct {
+	destroyProtection bool
+	ipAddr            net.IP
+	name              string
+	ownerUsers        []string
+	policy            proto.LifecyclePolicy
+	state             proto.State
+	vm                *vmInfoType
+	warnedExpiry      time.Time
+}
+
+// readProcessCpuTicks returns the user+system CPU time consumed by the
+// process, in clock ticks.
+func readProcessCpuTicks(pid int) (uint64, error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+	// The command name may contain spaces, so skip past it.
+	index := bytes.LastIndexByte(data, ')')
+	if index < 0 {
+		return 0, errors.New("malformed stat file")
+	}
+	fields := strings.Fields(string(data[index+1:]))
+	if len(fields) < 13 {
+		return 0, errors.New("short stat file")
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return utime + stime, nil
+}
+
+func (m *Manager) changeVmLifecyclePolicy(ipAddr net.IP,
+	authInfo *srpc.AuthInformation, policy proto.LifecyclePolicy) error {
+	if err := policy.CheckValid(); err != nil {
+		return err
+	}
+	vm, err := m.getVmLockAndAuth(ipAddr, true, authInfo, nil)
+	if err != nil {
+		return err
+	}
+	defer vm.mutex.Unlock()
+	vm.LifecyclePolicy = policy
+	vm.writeAndSendInfo()
+	return nil
+}
+
+// checkIdle stops the VM if it has used little CPU time for longer than the
+// idle timeout.
+func (m *Manager) checkIdle(vm lifecycleVmType, state *lifecycleStateType,
+	now time.Time) {
+	if vm.state != proto.StateRunning {
+		state.cpuTicks = 0
+		return
+	}
+	pid, err := vm.vm.readPid()
+	if err != nil {
+		vm.vm.logger.Println(err)
+		return
+	}
+	cpuTicks, err := readProcessCpuTicks(pid)
+	if err != nil {
+		vm.vm.logger.Println(err)
+		return
+	}
+	lastTicks := state.cpuTicks
+	interval := now.Sub(state.sampledAt)
+	state.cpuTicks = cpuTicks
+	state.sampledAt = now
+	if lastTicks < 1 || cpuTicks < lastTicks { // First sample or restarted.
+		state.lastActive = now
+		return
+	}
+	activeTicks := interval.Seconds() * clockTicksPerSecond * idleCpuFraction
+	if float64(cpuTicks-lastTicks) > activeTicks {
+		state.lastActive = now
+		return
+	}
+	idleTime := now.Sub(state.lastActive)
+	if idleTime < vm.policy.IdleTimeout {
+		return
+	}
+	vm.vm.logger.Printf("stopping VM which was idle for %s\n",
+		format.Duration(idleTime))
+	err = m.stopVm(vm.ipAddr, &srpc.AuthInformation{HaveMethodAccess: true},
+		nil)
+	if err != nil {
+		vm.vm.logger.Printf("error stopping idle VM: %s\n", err)
+		state.lastActive = now // Try again after another timeout.
+		return
+	}
+	state.cpuTicks = 0
+	m.sendLifecycleEmail(vm, "Your VM was stopped because it was idle",
+		fmt.Sprintf("Your VM was idle for %s and was stopped.",
+			format.Duration(idleTime)))
+}
+
+// expireVm stops or destroys an expired VM. VMs with destroy protection are
+// only stopped.
+func (m *Manager) expireVm(vm lifecycleVmType, state *lifecycleStateType,
+	now time.Time) {
+	if now.Sub(state.expiredAt) < lifecycleRetryInterval {
+		return
+	}
+	authInfo := &srpc.AuthInformation{HaveMethodAccess: true}
+	destroy := vm.policy.DestroyOnExpiry && !vm.destroyProtection
+	if destroy && vm.policy.BackupBeforeDestroy {
+		if _, err := m.backupVm(vm.ipAddr, authInfo); err != nil {
+			vm.vm.logger.Printf(
+				"not destroying expired VM, backup failed: %s\n", err)
+			state.expiredAt = now
+			destroy = false
+		}
+	}
+	if destroy {
+		vm.vm.logger.Println("destroying expired VM")
+		if err := m.destroyVm(vm.ipAddr, authInfo, nil); err != nil {
+			vm.vm.logger.Printf("error destroying expired VM: %s\n", err)
+			state.expiredAt = now
+			return
+		}
+		m.sendLifecycleEmail(vm, "Your VM was destroyed because it expired",
+			fmt.Sprintf("Your VM expired at %s and was destroyed.",
+				vm.policy.ExpiresAt.Format(format.TimeFormatSeconds)))
+		return
+	}
+	if vm.state != proto.StateRunning {
+		return
+	}
+	vm.vm.logger.Println("stopping expired VM")
+	if err := m.stopVm(vm.ipAddr, authInfo, nil); err != nil {
+		vm.vm.logger.Printf("error stopping expired VM: %s\n", err)
+		state.expiredAt = now
+		return
+	}
+	m.sendLifecycleEmail(vm, "Your VM was stopped because it expired",
+		fmt.Sprintf("Your VM expired at %s and was stopped.",
+			vm.policy.ExpiresAt.Format(format.TimeFormatSeconds)))
+}
+
+// getLifecycleVMs returns the VMs which have an active lifecycle policy.
+func (m *Manager) getLifecycleVMs() map[string]lifecycleVmType {
+	vms := make(map[string]lifecycleVmType)
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	for ipAddr, vm := range m.vms {
+		vm.mutex.RLock()
+		if vm.LifecyclePolicy.IsActive() {
+			vms[ipAddr] = lifecycleVmType{
+				destroyProtection: vm.DestroyProtection,
+				ipAddr:            vm.Address.IpAddress,
+				name:              vm.Tags["Name"],
+				ownerUsers:        vm.OwnerUsers,
+				policy:            vm.LifecyclePolicy,
+				state:             vm.State,
+				vm:                vm,
+				warnedExpiry:      vm.LifecycleWarnedExpiry,
+			}
+		}
+		vm.mutex.RUnlock()
+	}
+	return vms
+}
+
+func (m *Manager) lifecycleManager() {
+	states := make(map[string]*lifecycleStateType) // Key: IP address.
+	for ; ; time.Sleep(lifecycleCheckInterval) {
+		vms := m.getLifecycleVMs()
+		now := time.Now()
+		for ipAddr, vm := range vms {
+			state := states[ipAddr]
+			if state == nil {
+				state = &lifecycleStateType{lastActive: now, sampledAt: now}
+				states[ipAddr] = state
+			}
+			m.processLifecyclePolicy(vm, state, now)
+		}
+		for ipAddr := range states {
+			if _, ok := vms[ipAddr]; !ok {
+				delete(states, ipAddr)
+			}
+		}
+	}
+}
+
+func (m *Manager) processLifecyclePolicy(vm lifecycleVmType,
+	state *lifecycleStateType, now time.Time) {
+	switch vm.state {
+	case proto.StateRunning, proto.StateStopped:
+	default: // Leave VMs which are busy alone.
+		return
+	}
+	policy := vm.policy
+	if !policy.ExpiresAt.IsZero() {
+		if !now.Before(policy.ExpiresAt) {
+			m.expireVm(vm, state, now)
+			return
+		}
+		if policy.WarningPeriod > 0 &&
+			!vm.warnedExpiry.Equal(policy.ExpiresAt) &&
+			now.After(policy.ExpiresAt.Add(-policy.WarningPeriod)) {
+			action := "stopped"
+			if policy.DestroyOnExpiry && !vm.destroyProtection {
+				action = "destroyed"
+			}
+			m.sendLifecycleEmail(vm, "Your VM will expire soon",
+				fmt.Sprintf("Your VM will expire at %s and will then be %s.",
+					policy.ExpiresAt.Format(format.TimeFormatSeconds),
+					action))
+			vm.vm.setLifecycleWarnedExpiry(policy.ExpiresAt)
+		}
+	}
+	if policy.IdleTimeout > 0 {
+		m.checkIdle(vm, state, now)
+	}
+}
+
+// sendLifecycleEmail sends a notification to the owners of the VM, if email
+// notifications are configured.
+func (m *Manager) sendLifecycleEmail(vm lifecycleVmType, subject,
+	text string) {
+	if *emailDomain == "" || *smtpServer == "" || len(vm.ownerUsers) < 1 {
+		return
+	}
+	fromAddress := "DoNotReply@" + *emailDomain
+	toAddresses := make([]string, 0, len(vm.ownerUsers))
+	for _, user := range vm.ownerUsers {
+		toAddresses = append(toAddresses, user+"@"+*emailDomain)
+	}
+	buffer := &bytes.Buffer{}
+	fmt.Fprintf(buffer, "From: %s\n", fromAddress)
+	fmt.Fprintf(buffer, "To: %s\n", strings.Join(toAddresses, ", "))
+	fmt.Fprintf(buffer, "Subject: %s\n", subject)
+	fmt.Fprintln(buffer)
+	fmt.Fprintln(buffer, text)
+	fmt.Fprintln(buffer)
+	fmt.Fprintf(buffer, "IP: %s  name: %s\n", vm.ipAddr, vm.name)
+	fmt.Fprintln(buffer)
+	fmt.Fprintln(buffer,
+		"Use vm-control change-vm-lifecycle-policy to change the policy.")
+	err := smtp.SendMailPlain(*smtpServer, *emailDomain, fromAddress,
+		toAddresses, buffer.Bytes())
+	if err != nil {
+		vm.vm.logger.Printf("error sending email: %s\n", err)
+	}
+}
+
+// setLifecycleWarnedExpiry records the expiry time the owners were warned about
+// and saves it with the VM.
+func (vm *vmInfoType) setLifecycleWarnedExpiry(expiresAt time.Time) {
+	vm.mutex.Lock()
+	defer vm.mutex.Unlock()
+	vm.LifecycleWarnedExpiry = expiresAt
+	if err := vm.writeInfo(); err != nil {
+		vm.logger.Println(err)
+	}
+}
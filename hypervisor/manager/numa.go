@@ -0,0 +1,455 @@
+package manager
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/wsyscall"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+const (
+	sysCpuDirectory  = "/sys/devices/system/cpu"
+	sysNodeDirectory = "/sys/devices/system/node"
+)
+
+var errorInsufficientDedicatedCPUs = errors.New(
+	"insufficient free cores on any NUMA node")
+
+// parseCpuList parses a list of CPUs in the Linux sysfs format, such as
+// "0-3,8,10-11".
+func parseCpuList(value string) ([]uint, error) {
+	var cpus []uint
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	for _, field := range strings.Split(value, ",") {
+		first, last := field, field
+		if index := strings.IndexByte(field, '-'); index >= 0 {
+			first, last = field[:index], field[index+1:]
+		}
+		firstCpu, err := strconv.ParseUint(first, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		lastCpu, err := strconv.ParseUint(last, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		for cpu := firstCpu; cpu <= lastCpu; cpu++ {
+			cpus = append(cpus, uint(cpu))
+		}
+	}
+	return cpus, nil
+}
+
+func readCpuList(filename string) ([]uint, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return parseCpuList(string(data))
+}
+
+// readCores groups the CPUs into cores, using the hyperthread siblings.
+func readCores(cpus []uint) ([][]uint, error) {
+	coresMap := make(map[uint][]uint)
+	for _, cpu := range cpus {
+		siblings, err := readCpuList(filepath.Join(sysCpuDirectory,
+			fmt.Sprintf("cpu%d", cpu), "topology", "thread_siblings_list"))
+		if err != nil {
+			return nil, err
+		}
+		if len(siblings) < 1 {
+			siblings = []uint{cpu}
+		}
+		coresMap[siblings[0]] = append(coresMap[siblings[0]], cpu)
+	}
+	cores := make([][]uint, 0, len(coresMap))
+	for _, core := range coresMap {
+		cores = append(cores, core)
+	}
+	sort.Slice(cores, func(left, right int) bool {
+		return cores[left][0] < cores[right][0]
+	})
+	return cores, nil
+}
+
+// readNodeMemory returns the total memory in MiB for a NUMA node.
+func readNodeMemory(dirname string) (uint64, error) {
+	file, err := os.Open(filepath.Join(dirname, "meminfo"))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Format: "Node 0 MemTotal:       65842080 kB".
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[2] != "MemTotal:" {
+			continue
+		}
+		kiB, err := strconv.ParseUint(fields[3], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return kiB >> 10, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, errors.New("no MemTotal in: " + dirname)
+}
+
+// readNumaNodes reads the NUMA nodes and the cores for each node from sysfs.
+// If the kernel does not support NUMA, a single node with all the online CPUs
+// and the specified memory is returned.
+func readNumaNodes(memTotalInMiB uint64) ([]proto.NumaNode, error) {
+	names, err := fsutil.ReadDirnames(sysNodeDirectory, false)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		cpus, err := readCpuList(filepath.Join(sysCpuDirectory, "online"))
+		if err != nil {
+			return nil, err
+		}
+		cores, err := readCores(cpus)
+		if err != nil {
+			return nil, err
+		}
+		return []proto.NumaNode{{Cores: cores, MemoryInMiB: memTotalInMiB}},
+			nil
+	}
+	var nodes []proto.NumaNode
+	for _, name := range names {
+		if !strings.HasPrefix(name, "node") {
+			continue
+		}
+		id, err := strconv.ParseUint(name[4:], 10, 32)
+		if err != nil {
+			continue
+		}
+		dirname := filepath.Join(sysNodeDirectory, name)
+		cpus, err := readCpuList(filepath.Join(dirname, "cpulist"))
+		if err != nil {
+			return nil, err
+		}
+		if len(cpus) < 1 { // Memory-only node.
+			continue
+		}
+		cores, err := readCores(cpus)
+		if err != nil {
+			return nil, err
+		}
+		memoryInMiB, err := readNodeMemory(dirname)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, proto.NumaNode{
+			Cores:       cores,
+			Id:          uint(id),
+			MemoryInMiB: memoryInMiB,
+		})
+	}
+	sort.Slice(nodes, func(left, right int) bool {
+		return nodes[left].Id < nodes[right].Id
+	})
+	return nodes, nil
+}
+
+// setProcessCpuAffinity sets the CPU affinity for all the threads of the
+// process.
+func setProcessCpuAffinity(pid int, cpus []uint) error {
+	dirname := fmt.Sprintf("/proc/%d/task", pid)
+	names, err := fsutil.ReadDirnames(dirname, false)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		tid, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+		if err := wsyscall.SetCpuAffinity(tid, cpus); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func uintSlicesEqual(left, right []uint) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for index, value := range left {
+		if value != right[index] {
+			return false
+		}
+	}
+	return true
+}
+
+// allocateDedicatedCPUsWithLock allocates whole cores from a single NUMA node
+// for the VM if it requires dedicated CPUs.
+func (m *Manager) allocateDedicatedCPUsWithLock(vm *vmInfoType) error {
+	vm.PinnedCPUs = nil
+	if !vm.DedicatedCPUs {
+		return nil
+	}
+	cpus, err := m.findDedicatedCPUsWithLock(
+		numSpecifiedVirtualCPUs(vm.MilliCPUs, vm.VirtualCPUs), vm.MemoryInMiB)
+	if err != nil {
+		return err
+	}
+	vm.PinnedCPUs = cpus
+	return nil
+}
+
+// changeVmDedicatedCPUs changes the number of CPUs for a stopped VM with
+// dedicated CPUs, re-allocating the cores.
+func (m *Manager) changeVmDedicatedCPUs(vm *vmInfoType,
+	req proto.ChangeVmSizeRequest) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	oldPinnedCPUs := vm.PinnedCPUs
+	vm.PinnedCPUs = nil // Make the cores available for re-allocation.
+	cpus, err := m.findDedicatedCPUsWithLock(
+		numSpecifiedVirtualCPUs(req.MilliCPUs, req.VirtualCPUs), vm.MemoryInMiB)
+	if err != nil {
+		vm.PinnedCPUs = oldPinnedCPUs
+		return err
+	}
+	vm.MilliCPUs = req.MilliCPUs
+	vm.PinnedCPUs = cpus
+	vm.VirtualCPUs = req.VirtualCPUs
+	return nil
+}
+
+// checkSufficientDedicatedCPUsWithLock checks if there are sufficient free
+// cores for a VM which requires dedicated CPUs.
+func (m *Manager) checkSufficientDedicatedCPUsWithLock(
+	vmInfo proto.VmInfo) error {
+	if !vmInfo.DedicatedCPUs {
+		return nil
+	}
+	_, err := m.findDedicatedCPUsWithLock(
+		numSpecifiedVirtualCPUs(vmInfo.MilliCPUs, vmInfo.VirtualCPUs),
+		vmInfo.MemoryInMiB)
+	return err
+}
+
+// cpuAffinityUpdater sets the CPU affinity of the virtualiser processes for
+// VMs which do not have dedicated CPUs, so that they do not run on the CPUs
+// dedicated to other VMs.
+func (m *Manager) cpuAffinityUpdater() {
+	var lastSharedCPUs []uint
+	updatedPids := make(map[int]struct{})
+	for range m.affinityNotifier {
+		sharedCPUs, vms := m.getSharedCPUsAndVMs()
+		if len(sharedCPUs) < 1 {
+			continue
+		}
+		if !uintSlicesEqual(sharedCPUs, lastSharedCPUs) {
+			lastSharedCPUs = sharedCPUs
+			updatedPids = make(map[int]struct{})
+		}
+		newUpdatedPids := make(map[int]struct{}, len(vms))
+		for _, vm := range vms {
+			pid, err := vm.readPid()
+			if err != nil {
+				continue
+			}
+			if _, ok := updatedPids[pid]; !ok {
+				if err := setProcessCpuAffinity(pid, sharedCPUs); err != nil {
+					vm.logger.Printf("error setting CPU affinity: %s\n", err)
+					continue
+				}
+			}
+			newUpdatedPids[pid] = struct{}{}
+		}
+		updatedPids = newUpdatedPids
+	}
+}
+
+// findDedicatedCPUsWithLock finds whole cores from a single NUMA node which
+// are not dedicated to other VMs. The node with the fewest free cores which is
+// large enough is chosen, to reduce fragmentation.
+func (m *Manager) findDedicatedCPUsWithLock(numCPUs uint,
+	memoryInMiB uint64) ([]uint, error) {
+	if len(m.numaNodes) < 1 {
+		return nil, errors.New("CPU topology unknown: cannot dedicate CPUs")
+	}
+	nodes := m.getNumaNodesWithLock()
+	bestIndex := -1
+	for index, node := range nodes {
+		if !node.CanDedicate(numCPUs, memoryInMiB) {
+			continue
+		}
+		if bestIndex < 0 || node.FreeCores() < nodes[bestIndex].FreeCores() {
+			bestIndex = index
+		}
+	}
+	if bestIndex < 0 {
+		return nil, errorInsufficientDedicatedCPUs
+	}
+	pinnedCPUs := m.getPinnedCPUsWithLock()
+	node := nodes[bestIndex]
+	numCores := node.NumCoresForCPUs(numCPUs)
+	var cpus []uint
+	for _, core := range node.Cores {
+		if numCores < 1 {
+			break
+		}
+		if _, ok := pinnedCPUs[core[0]]; ok {
+			continue
+		}
+		cpus = append(cpus, core...)
+		numCores--
+	}
+	if numCores > 0 {
+		return nil, errorInsufficientDedicatedCPUs
+	}
+	err := m.checkSufficientCPUWithLock(uint(len(cpus)) * 1000)
+	if err != nil {
+		return nil, err
+	}
+	return cpus, nil
+}
+
+func (m *Manager) getCapacity() proto.GetCapacityResponse {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return proto.GetCapacityResponse{
+		MemoryInMiB:      m.memTotalInMiB,
+		NumaNodes:        m.getNumaNodesWithLock(),
+		NumCPUs:          m.numCPUs,
+		TotalVolumeBytes: m.totalVolumeBytes,
+	}
+}
+
+// getNumaNodeForCPU returns the NUMA node containing the specified CPU.
+func (m *Manager) getNumaNodeForCPU(cpu uint) (*proto.NumaNode, error) {
+	for index := range m.numaNodes {
+		node := &m.numaNodes[index]
+		for _, core := range node.Cores {
+			for _, coreCpu := range core {
+				if coreCpu == cpu {
+					return node, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("CPU: %d not found in any NUMA node", cpu)
+}
+
+func (m *Manager) getNumaNodesWithLock() []proto.NumaNode {
+	vms := make([]*proto.VmInfo, 0, len(m.vms))
+	for _, vm := range m.vms {
+		vms = append(vms, &vm.VmInfo)
+	}
+	return proto.CountNumaNodeAllocations(m.numaNodes, vms)
+}
+
+func (m *Manager) getPinnedCPUsWithLock() map[uint]struct{} {
+	pinnedCPUs := make(map[uint]struct{})
+	for _, vm := range m.vms {
+		for _, cpu := range vm.PinnedCPUs {
+			pinnedCPUs[cpu] = struct{}{}
+		}
+	}
+	return pinnedCPUs
+}
+
+// getSharedCPUsAndVMs returns the CPUs which are not dedicated to VMs and the
+// running VMs which do not have dedicated CPUs.
+func (m *Manager) getSharedCPUsAndVMs() ([]uint, []*vmInfoType) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	pinnedCPUs := m.getPinnedCPUsWithLock()
+	var sharedCPUs []uint
+	for _, node := range m.numaNodes {
+		for _, core := range node.Cores {
+			for _, cpu := range core {
+				if _, ok := pinnedCPUs[cpu]; !ok {
+					sharedCPUs = append(sharedCPUs, cpu)
+				}
+			}
+		}
+	}
+	sort.Slice(sharedCPUs, func(left, right int) bool {
+		return sharedCPUs[left] < sharedCPUs[right]
+	})
+	var vms []*vmInfoType
+	for _, vm := range m.vms {
+		if len(vm.PinnedCPUs) > 0 {
+			continue
+		}
+		switch vm.State {
+		case proto.StateRunning, proto.StateDebugging:
+			vms = append(vms, vm)
+		}
+	}
+	return sharedCPUs, vms
+}
+
+// notifyCpuAffinityUpdater requests that the CPU affinity of the VMs which do
+// not have dedicated CPUs be checked. It does not block.
+func (m *Manager) notifyCpuAffinityUpdater() {
+	select {
+	case m.affinityNotifier <- struct{}{}:
+	default:
+	}
+}
+
+func (m *Manager) startCpuAffinityUpdater() {
+	if len(m.numaNodes) < 1 {
+		return
+	}
+	go m.cpuAffinityUpdater()
+	m.notifyCpuAffinityUpdater()
+}
+
+// pinDedicatedCPUs does not take any locks. It pins each virtual CPU thread of
+// the virtualiser process to one of the dedicated CPUs and the other threads
+// to all of the dedicated CPUs.
+func (vm *vmInfoType) pinDedicatedCPUs() error {
+	pid, err := vm.readPid()
+	if err != nil {
+		return fmt.Errorf("unable to read virtualiser PID: %w", err)
+	}
+	dirname := fmt.Sprintf("/proc/%d/task", pid)
+	names, err := fsutil.ReadDirnames(dirname, false)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		tid, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+		cpus := vm.PinnedCPUs
+		comm, err := ioutil.ReadFile(filepath.Join(dirname, name, "comm"))
+		if err != nil {
+			return err
+		}
+		// QEMU names the threads for the virtual CPUs "CPU N/KVM".
+		var vcpu uint
+		if n, _ := fmt.Sscanf(string(comm), "CPU %d/KVM", &vcpu); n == 1 {
+			cpus = []uint{vm.PinnedCPUs[vcpu%uint(len(vm.PinnedCPUs))]}
+		}
+		if err := wsyscall.SetCpuAffinity(tid, cpus); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -91,6 +91,16 @@ func (vm *vmInfoType) processMonitorResponses(monitorSock net.Conn,
 			} else if shutdownData.Reason == "host-qmp-quit" {
 				hostQuit = true // Not currently used but may be useful later.
 			}
+		case "STOP":
+			vm.mutex.RLock()
+			pausedNotifier := vm.pausedNotifier
+			vm.mutex.RUnlock()
+			if pausedNotifier != nil {
+				select {
+				case pausedNotifier <- struct{}{}:
+				default:
+				}
+			}
 		case "WATCHDOG":
 			var watchdogData watchdogDataType
 			if err := json.Unmarshal(message.Data, &watchdogData); err != nil {
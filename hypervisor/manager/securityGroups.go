@@ -0,0 +1,391 @@
+package manager
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/stringutil"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+const securityGroupsTable = "bridge dominator_vm_security"
+
+type vmFilterType struct {
+	ipAddress      string
+	securityGroups []string
+	tapDevices     []string
+}
+
+// getTapDevices returns the names of the tap devices opened by the process,
+// in the order of the file descriptors.
+func getTapDevices(pid int) ([]string, error) {
+	dirname := fmt.Sprintf("/proc/%d/fd", pid)
+	names, err := fsutil.ReadDirnames(dirname, false)
+	if err != nil {
+		return nil, err
+	}
+	fds := make([]int, 0, len(names))
+	for _, name := range names {
+		if fd, err := strconv.Atoi(name); err == nil {
+			fds = append(fds, fd)
+		}
+	}
+	sort.Ints(fds)
+	var tapDevices []string
+	for _, fd := range fds {
+		link, err := os.Readlink(filepath.Join(dirname, strconv.Itoa(fd)))
+		if err != nil || link != "/dev/net/tun" {
+			continue
+		}
+		tapDevice, err := readTapDeviceName(
+			fmt.Sprintf("/proc/%d/fdinfo/%d", pid, fd))
+		if err != nil {
+			return nil, err
+		}
+		if tapDevice != "" {
+			tapDevices = append(tapDevices, tapDevice)
+		}
+	}
+	return tapDevices, nil
+}
+
+// makeSecurityGroupsRuleset returns an nftables script which replaces the
+// security groups table. VMs with no security groups are not filtered.
+func makeSecurityGroupsRuleset(groups map[string]proto.SecurityGroup,
+	vms []*vmFilterType) string {
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "table %s\n", securityGroupsTable)
+	fmt.Fprintf(builder, "delete table %s\n", securityGroupsTable)
+	var forwardRules, chains []string
+	for _, vm := range vms {
+		if len(vm.securityGroups) < 1 || len(vm.tapDevices) < 1 {
+			continue
+		}
+		var egressRules, ingressRules []proto.SecurityRule
+		for _, groupId := range vm.securityGroups {
+			// Unknown groups permit nothing.
+			group := groups[groupId]
+			egressRules = append(egressRules, group.EgressRules...)
+			ingressRules = append(ingressRules, group.IngressRules...)
+		}
+		chainSuffix := strings.Replace(vm.ipAddress, ".", "_", -1)
+		ingressChain := "ingress_" + chainSuffix
+		chains = append(chains,
+			makeSecurityChain(ingressChain, "saddr", ingressRules))
+		var egressChain string
+		if len(egressRules) > 0 {
+			egressChain = "egress_" + chainSuffix
+			chains = append(chains,
+				makeSecurityChain(egressChain, "daddr", egressRules))
+		}
+		for _, tapDevice := range vm.tapDevices {
+			forwardRules = append(forwardRules,
+				fmt.Sprintf("oifname %q jump %s", tapDevice, ingressChain))
+			if egressChain != "" {
+				forwardRules = append(forwardRules,
+					fmt.Sprintf("iifname %q jump %s", tapDevice, egressChain))
+			}
+		}
+	}
+	if len(forwardRules) < 1 {
+		return builder.String()
+	}
+	fmt.Fprintf(builder, "table %s {\n", securityGroupsTable)
+	fmt.Fprintln(builder, "\tchain forward {")
+	fmt.Fprintln(builder,
+		"\t\ttype filter hook forward priority 0; policy accept;")
+	for _, rule := range forwardRules {
+		fmt.Fprintf(builder, "\t\t%s\n", rule)
+	}
+	fmt.Fprintln(builder, "\t}")
+	for _, chain := range chains {
+		builder.WriteString(chain)
+	}
+	fmt.Fprintln(builder, "}")
+	return builder.String()
+}
+
+func makeSecurityChain(name, peerDirection string,
+	rules []proto.SecurityRule) string {
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "\tchain %s {\n", name)
+	fmt.Fprintln(builder, "\t\tct state established,related accept")
+	fmt.Fprintln(builder, "\t\tether type arp accept")
+	// IPv6 neighbour discovery, the equivalent of ARP.
+	fmt.Fprintln(builder, "\t\ticmpv6 type { nd-neighbor-solicit,"+
+		" nd-neighbor-advert, nd-router-solicit, nd-router-advert } accept")
+	for _, rule := range rules {
+		if text := makeSecurityRule(rule, peerDirection); text != "" {
+			fmt.Fprintf(builder, "\t\t%s\n", text)
+		}
+	}
+	fmt.Fprintln(builder, "\t\tdrop")
+	fmt.Fprintln(builder, "\t}")
+	return builder.String()
+}
+
+// makeSecurityRule returns the nftables rule for the security rule, or an empty
+// string if the rule specifies peers but none could be found. Peers specified
+// with PeerTags are resolved by the fleet-manager (which knows about the VMs on
+// all Hypervisors) into PeerAddresses, so only PeerAddresses are used here.
+func makeSecurityRule(rule proto.SecurityRule, peerDirection string) string {
+	var peers []string
+	if rule.CIDR != "" {
+		peers = append(peers, rule.CIDR)
+	}
+	for _, ipAddr := range rule.PeerAddresses {
+		peers = append(peers, ipAddr.String())
+	}
+	var words []string
+	if len(peers) > 0 {
+		peers, _ = stringutil.DeduplicateList(peers, false)
+		words = append(words, fmt.Sprintf("ip %s { %s }",
+			peerDirection, strings.Join(peers, ", ")))
+	} else if len(rule.PeerAddresses) > 0 || len(rule.PeerTags) > 0 {
+		return ""
+	}
+	switch {
+	case rule.Protocol == "":
+	case rule.FromPort < 1:
+		words = append(words, "meta l4proto "+rule.Protocol)
+	case rule.ToPort <= rule.FromPort:
+		words = append(words,
+			fmt.Sprintf("%s dport %d", rule.Protocol, rule.FromPort))
+	default:
+		words = append(words, fmt.Sprintf("%s dport %d-%d",
+			rule.Protocol, rule.FromPort, rule.ToPort))
+	}
+	return strings.Join(append(words, "accept"), " ")
+}
+
+func readTapDeviceName(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "iff:" {
+			return fields[1], nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+func (m *Manager) changeVmSecurityGroups(ipAddr net.IP,
+	authInfo *srpc.AuthInformation, securityGroups []string) error {
+	securityGroups, _ = stringutil.DeduplicateList(securityGroups, false)
+	if err := m.checkSecurityGroupsExist(securityGroups); err != nil {
+		return err
+	}
+	vm, err := m.getVmLockAndAuth(ipAddr, true, authInfo, nil)
+	if err != nil {
+		return err
+	}
+	defer vm.mutex.Unlock()
+	vm.SecurityGroups = securityGroups
+	vm.writeAndSendInfo()
+	return nil
+}
+
+// checkSecurityGroupsExist returns an error if any of the specified security
+// groups are not known.
+func (m *Manager) checkSecurityGroupsExist(securityGroups []string) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	for _, groupId := range securityGroups {
+		if _, ok := m.securityGroups[groupId]; !ok {
+			return fmt.Errorf("security group: %s does not exist", groupId)
+		}
+	}
+	return nil
+}
+
+// getVmFilters returns the filtering information for all VMs. This grabs and
+// releases the Manager lock and the VM locks.
+func (m *Manager) getVmFilters() (
+	map[string]proto.SecurityGroup, []*vmFilterType) {
+	m.mutex.RLock()
+	groups := make(map[string]proto.SecurityGroup, len(m.securityGroups))
+	for groupId, group := range m.securityGroups {
+		groups[groupId] = group
+	}
+	vms := make([]*vmInfoType, 0, len(m.vms))
+	for _, vm := range m.vms {
+		vms = append(vms, vm)
+	}
+	m.mutex.RUnlock()
+	vmFilters := make([]*vmFilterType, 0, len(vms))
+	for _, vm := range vms {
+		vm.mutex.RLock()
+		vmFilter := &vmFilterType{
+			ipAddress:      vm.ipAddress,
+			securityGroups: vm.SecurityGroups,
+		}
+		var pid int
+		var err error
+		switch vm.State {
+		case proto.StateRunning, proto.StateStopping, proto.StateDebugging:
+			if len(vm.SecurityGroups) > 0 {
+				pid, err = vm.readPid()
+			}
+		}
+		vm.mutex.RUnlock()
+		if err != nil {
+			m.Logger.Printf("%s: error reading PID: %s\n", vm.ipAddress, err)
+		} else if pid > 0 {
+			vmFilter.tapDevices, err = getTapDevices(pid)
+			if err != nil {
+				m.Logger.Printf("%s: error finding tap devices: %s\n",
+					vm.ipAddress, err)
+			}
+		}
+		vmFilters = append(vmFilters, vmFilter)
+	}
+	sort.Slice(vmFilters, func(left, right int) bool {
+		return vmFilters[left].ipAddress < vmFilters[right].ipAddress
+	})
+	return groups, vmFilters
+}
+
+func (m *Manager) listSecurityGroups() []proto.SecurityGroup {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	groupIds := make([]string, 0, len(m.securityGroups))
+	for groupId := range m.securityGroups {
+		groupIds = append(groupIds, groupId)
+	}
+	sort.Strings(groupIds)
+	groups := make([]proto.SecurityGroup, 0, len(groupIds))
+	for _, groupId := range groupIds {
+		groups = append(groups, m.securityGroups[groupId])
+	}
+	return groups
+}
+
+func (m *Manager) loadSecurityGroups() error {
+	var groups []proto.SecurityGroup
+	err := json.ReadFromFile(path.Join(m.StateDir, "security-groups.json"),
+		&groups)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	m.securityGroups = make(map[string]proto.SecurityGroup, len(groups))
+	for _, group := range groups {
+		m.securityGroups[group.Id] = group
+	}
+	m.securityNotifier = make(chan struct{}, 1)
+	return nil
+}
+
+// notifySecurityGroupsUpdater requests that the security group rules be
+// regenerated. It does not block.
+func (m *Manager) notifySecurityGroupsUpdater() {
+	select {
+	case m.securityNotifier <- struct{}{}:
+	default:
+	}
+}
+
+func (m *Manager) securityGroupsUpdater() {
+	var lastRuleset string
+	for range m.securityNotifier {
+		ruleset := makeSecurityGroupsRuleset(m.getVmFilters())
+		if ruleset == lastRuleset {
+			continue
+		}
+		// Do not retry until the ruleset changes, to avoid log spam.
+		lastRuleset = ruleset
+		cmd := exec.Command("nft", "-f", "-")
+		cmd.Stdin = strings.NewReader(ruleset)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			m.Logger.Printf("error applying security groups: %s: %s\n",
+				err, output)
+		}
+	}
+}
+
+func (m *Manager) startSecurityGroupsUpdater() {
+	go m.securityGroupsUpdater()
+	m.notifySecurityGroupsUpdater()
+}
+
+func (m *Manager) updateSecurityGroups(
+	request proto.UpdateSecurityGroupsRequest) error {
+	for _, group := range request.Add {
+		if err := group.CheckValid(); err != nil {
+			return err
+		}
+	}
+	for _, group := range request.Change {
+		if err := group.CheckValid(); err != nil {
+			return err
+		}
+	}
+	if err := m.updateSecurityGroupsLocked(request); err != nil {
+		return err
+	}
+	m.notifySecurityGroupsUpdater()
+	return nil
+}
+
+func (m *Manager) updateSecurityGroupsLocked(
+	request proto.UpdateSecurityGroupsRequest) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, group := range request.Add {
+		if _, ok := m.securityGroups[group.Id]; ok {
+			return fmt.Errorf("security group: %s already exists", group.Id)
+		}
+	}
+	for _, group := range request.Change {
+		if _, ok := m.securityGroups[group.Id]; !ok {
+			return fmt.Errorf("security group: %s does not exist", group.Id)
+		}
+	}
+	for _, groupId := range request.Delete {
+		if _, ok := m.securityGroups[groupId]; !ok {
+			return fmt.Errorf("security group: %s does not exist", groupId)
+		}
+	}
+	for _, group := range request.Add {
+		m.securityGroups[group.Id] = group
+	}
+	for _, group := range request.Change {
+		m.securityGroups[group.Id] = group
+	}
+	for _, groupId := range request.Delete {
+		delete(m.securityGroups, groupId)
+	}
+	groupsToWrite := make([]proto.SecurityGroup, 0, len(m.securityGroups))
+	for _, group := range m.securityGroups {
+		groupsToWrite = append(groupsToWrite, group)
+	}
+	sort.Slice(groupsToWrite, func(left, right int) bool {
+		return groupsToWrite[left].Id < groupsToWrite[right].Id
+	})
+	err := json.WriteToFile(path.Join(m.StateDir, "security-groups.json"),
+		fsutil.PublicFilePerms, "    ", groupsToWrite)
+	if err != nil {
+		return err
+	}
+	m.sendUpdate(
+		proto.Update{
+			HaveSecurityGroups: true,
+			SecurityGroups:     groupsToWrite,
+		})
+	return nil
+}
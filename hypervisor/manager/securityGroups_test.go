@@ -0,0 +1,54 @@
+package manager
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/tags"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func TestMakeSecurityChain(t *testing.T) {
+	chain := makeSecurityChain("ingress_10_0_0_1", "saddr",
+		[]proto.SecurityRule{{FromPort: 22, Protocol: "tcp"}})
+	for _, want := range []string{
+		"ether type arp accept",
+		"icmpv6 type { nd-neighbor-solicit,",
+		"tcp dport 22 accept",
+		"drop",
+	} {
+		if !strings.Contains(chain, want) {
+			t.Errorf("%q not in chain:\n%s", want, chain)
+		}
+	}
+}
+
+func TestMakeSecurityRule(t *testing.T) {
+	tests := []struct {
+		rule proto.SecurityRule
+		want string
+	}{
+		{proto.SecurityRule{}, "accept"},
+		{proto.SecurityRule{FromPort: 22, Protocol: "tcp"},
+			"tcp dport 22 accept"},
+		{proto.SecurityRule{FromPort: 8000, Protocol: "udp", ToPort: 8100},
+			"udp dport 8000-8100 accept"},
+		{proto.SecurityRule{CIDR: "10.0.0.0/8", Protocol: "icmp"},
+			"ip saddr { 10.0.0.0/8 } meta l4proto icmp accept"},
+		{proto.SecurityRule{
+			PeerAddresses: []net.IP{net.ParseIP("10.1.2.3")},
+			PeerTags:      tags.MatchTags{"Role": {"bastion"}},
+		}, "ip saddr { 10.1.2.3 } accept"},
+		// Peers matched by tags which were not resolved permit nothing.
+		{proto.SecurityRule{
+			PeerTags: tags.MatchTags{"Role": {"bastion"}},
+		}, ""},
+	}
+	for _, test := range tests {
+		if got := makeSecurityRule(test.rule, "saddr"); got != test.want {
+			t.Errorf("makeSecurityRule(%v): %q != %q", test.rule, got,
+				test.want)
+		}
+	}
+}
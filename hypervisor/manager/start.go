@@ -73,14 +73,21 @@ func newManager(startOptions StartOptions) (*Manager, error) {
 		return nil, err
 	}
 	manager := &Manager{
-		StartOptions:  startOptions,
-		rootCookie:    rootCookie,
-		memTotalInMiB: memInfo.Total >> 20,
-		notifiers:     make(map[<-chan proto.Update]chan<- proto.Update),
-		numCPUs:       uint(runtime.NumCPU()),
-		serialNumber:  readSystemSerial(),
-		vms:           make(map[string]*vmInfoType),
-		uuid:          uuid,
+		StartOptions:     startOptions,
+		affinityNotifier: make(chan struct{}, 1),
+		baseVolumeLocks:  make(map[string]*baseVolumeLockType),
+		rootCookie:       rootCookie,
+		memTotalInMiB:    memInfo.Total >> 20,
+		notifiers:        make(map[<-chan proto.Update]chan<- proto.Update),
+		numCPUs:          uint(runtime.NumCPU()),
+		serialNumber:     readSystemSerial(),
+		vms:              make(map[string]*vmInfoType),
+		uuid:             uuid,
+	}
+	if numaNodes, err := readNumaNodes(manager.memTotalInMiB); err != nil {
+		startOptions.Logger.Printf("error reading NUMA nodes: %s\n", err)
+	} else {
+		manager.numaNodes = numaNodes
 	}
 	err = fsutil.CopyToFile(manager.GetRootCookiePath(),
 		fsutil.PrivateFilePerms, bytes.NewReader(rootCookie), 0)
@@ -100,6 +107,9 @@ func newManager(startOptions StartOptions) (*Manager, error) {
 	if err := manager.loadSubnets(); err != nil {
 		return nil, err
 	}
+	if err := manager.loadSecurityGroups(); err != nil {
+		return nil, err
+	}
 	if err := manager.loadAddressPool(); err != nil {
 		return nil, err
 	}
@@ -210,6 +220,12 @@ func newManager(startOptions StartOptions) (*Manager, error) {
 		manager.writeAddressPoolWithLock(manager.addressPool, false)
 	}
 	go manager.loopCheckHealthStatus()
+	manager.startSecurityGroupsUpdater()
+	manager.startCpuAffinityUpdater()
+	if manager.BackupStore != nil {
+		go manager.backupScheduler()
+	}
+	go manager.lifecycleManager()
 	lockCheckInterval := startOptions.LockCheckInterval
 	if lockCheckInterval > time.Second {
 		// Leveraged for dashboard, so keep it fresh.
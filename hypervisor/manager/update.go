@@ -25,6 +25,10 @@ func (m *Manager) makeUpdateChannel() <-chan proto.Update {
 			subnets = append(subnets, subnet)
 		}
 	}
+	securityGroups := make([]proto.SecurityGroup, 0, len(m.securityGroups))
+	for _, group := range m.securityGroups {
+		securityGroups = append(securityGroups, group)
+	}
 	vms := make(map[string]*proto.VmInfo, len(m.vms))
 	for addr, vm := range m.vms {
 		vms[addr] = &vm.VmInfo
@@ -39,21 +43,24 @@ func (m *Manager) makeUpdateChannel() <-chan proto.Update {
 	m.notifiers[channel] = channel
 	// Initial update: give everything.
 	channel <- proto.Update{
-		HaveAddressPool:  true,
-		AddressPool:      m.addressPool.Registered,
-		HaveDisabled:     true,
-		Disabled:         m.disabled,
-		MemoryInMiB:      &m.memTotalInMiB,
-		NumCPUs:          &m.numCPUs,
-		NumFreeAddresses: numFreeAddresses,
-		HealthStatus:     m.healthStatus,
-		HaveSerialNumber: true,
-		SerialNumber:     m.serialNumber,
-		HaveSubnets:      true,
-		Subnets:          subnets,
-		TotalVolumeBytes: &m.totalVolumeBytes,
-		HaveVMs:          true,
-		VMs:              vms,
+		HaveAddressPool:    true,
+		AddressPool:        m.addressPool.Registered,
+		HaveDisabled:       true,
+		Disabled:           m.disabled,
+		MemoryInMiB:        &m.memTotalInMiB,
+		NumCPUs:            &m.numCPUs,
+		NumaNodes:          m.numaNodes,
+		NumFreeAddresses:   numFreeAddresses,
+		HealthStatus:       m.healthStatus,
+		HaveSecurityGroups: true,
+		SecurityGroups:     securityGroups,
+		HaveSerialNumber:   true,
+		SerialNumber:       m.serialNumber,
+		HaveSubnets:        true,
+		Subnets:            subnets,
+		TotalVolumeBytes:   &m.totalVolumeBytes,
+		HaveVMs:            true,
+		VMs:                vms,
 	}
 	return channel
 }
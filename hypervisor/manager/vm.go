@@ -397,6 +397,9 @@ func (m *Manager) allocateVm(req proto.CreateVmRequest,
 	if err := checkCpuPriority(authInfo, req.CpuPriority); err != nil {
 		return nil, err
 	}
+	if err := req.LifecyclePolicy.CheckValid(); err != nil {
+		return nil, err
+	}
 	if err := req.MachineType.CheckValid(); err != nil {
 		return nil, err
 	}
@@ -416,6 +419,13 @@ func (m *Manager) allocateVm(req proto.CreateVmRequest,
 	if err := req.WatchdogModel.CheckValid(); err != nil {
 		return nil, err
 	}
+	if err := m.checkSecurityGroupsExist(req.SecurityGroups); err != nil {
+		return nil, err
+	}
+	if len(req.NetworkLimits) > len(req.SecondarySubnetIDs)+1 {
+		return nil,
+			errors.New("more network limits specified than VM interfaces")
+	}
 	subnetIDs := map[string]struct{}{req.SubnetId: {}}
 	for _, subnetId := range req.SecondarySubnetIDs {
 		if subnetId == "" {
@@ -479,6 +489,7 @@ func (m *Manager) allocateVm(req proto.CreateVmRequest,
 				CreatedOn:          time.Now(),
 				ConsoleType:        req.ConsoleType,
 				CpuPriority:        req.CpuPriority,
+				DedicatedCPUs:      req.DedicatedCPUs,
 				DestroyOnPowerdown: req.DestroyOnPowerdown,
 				DestroyProtection:  req.DestroyProtection,
 				DisableVirtIO:      req.DisableVirtIO,
@@ -486,13 +497,16 @@ func (m *Manager) allocateVm(req proto.CreateVmRequest,
 				Hostname:           req.Hostname,
 				ImageName:          req.ImageName,
 				ImageURL:           req.ImageURL,
+				LifecyclePolicy:    req.LifecyclePolicy,
 				MachineType:        req.MachineType,
 				MemoryInMiB:        req.MemoryInMiB,
 				MilliCPUs:          req.MilliCPUs,
+				NetworkLimits:      req.NetworkLimits,
 				OwnerGroups:        req.OwnerGroups,
 				SpreadVolumes:      req.SpreadVolumes,
 				SecondaryAddresses: secondaryAddresses,
 				SecondarySubnetIDs: req.SecondarySubnetIDs,
+				SecurityGroups:     req.SecurityGroups,
 				State:              proto.StateStarting,
 				SubnetId:           subnetId,
 				Tags:               req.Tags,
@@ -507,6 +521,9 @@ func (m *Manager) allocateVm(req proto.CreateVmRequest,
 		logger:           prefixlogger.New(ipAddress+": ", m.Logger),
 		metadataChannels: make(map[chan<- string]struct{}),
 	}
+	if err := m.allocateDedicatedCPUsWithLock(vm); err != nil {
+		return nil, err
+	}
 	m.vms[ipAddress] = vm
 	addressesToFree = nil
 	return vm, nil
@@ -612,6 +629,12 @@ func (m *Manager) changeVmCPUs(vm *vmInfoType, req proto.ChangeVmSizeRequest) (
 	if vm.State != proto.StateStopped {
 		return false, errors.New("VM is not stopped")
 	}
+	if vm.DedicatedCPUs {
+		if err := m.changeVmDedicatedCPUs(vm, req); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
 	if newCPUs <= oldCPUs {
 		vm.MilliCPUs = req.MilliCPUs
 		vm.VirtualCPUs = req.VirtualCPUs
@@ -800,6 +823,9 @@ func (m *Manager) changeVmVolumeSize(ipAddr net.IP,
 		return errors.New("cannot resize non-RAW volumes")
 	}
 	localVolume := vm.VolumeLocations[index]
+	if localVolume.BaseVolume != "" {
+		return errorThinRootVolume
+	}
 	if size == volume.Size {
 		return nil
 	}
@@ -1218,6 +1244,14 @@ func (m *Manager) createVm(conn *srpc.Conn) error {
 	if len(request.Volumes) > 0 {
 		rootVolumeType = request.Volumes[0].Type
 	}
+	if request.ThinRootVolume {
+		if err := checkThinRootVolume(request); err != nil {
+			if err := maybeDrainAll(conn, request); err != nil {
+				return err
+			}
+			return sendError(conn, err)
+		}
+	}
 	if request.ImageName != "" {
 		if err := maybeDrainImage(conn, request.ImageDataSize); err != nil {
 			return err
@@ -1240,9 +1274,6 @@ func (m *Manager) createVm(conn *srpc.Conn) error {
 		if err != nil {
 			return sendError(conn, err)
 		}
-		if err := sendUpdate(conn, "unpacking image: "+imageName); err != nil {
-			return err
-		}
 		writeRawOptions := util.WriteRawOptions{
 			ExtraKernelOptions: request.ExtraKernelOptions,
 			InitialImageName:   imageName,
@@ -1252,14 +1283,31 @@ func (m *Manager) createVm(conn *srpc.Conn) error {
 			RootLabel:          vm.rootLabel(false),
 			RoundupPower:       request.RoundupPower,
 		}
-		err = m.writeRaw(vm.VolumeLocations[0], "", client, fs, writeRawOptions,
-			request.SkipBootloader)
-		if err != nil {
-			return sendError(conn, err)
-		}
-		if fi, err := os.Stat(vm.VolumeLocations[0].Filename); err != nil {
-			return sendError(conn, err)
+		if request.ThinRootVolume {
+			err := sendUpdate(conn, "creating thin root volume: "+imageName)
+			if err != nil {
+				return err
+			}
+			size, err := m.createThinRootVolume(vm, imageName, client, fs,
+				writeRawOptions)
+			if err != nil {
+				return sendError(conn, err)
+			}
+			vm.Volumes = []proto.Volume{{Size: size}}
 		} else {
+			err := sendUpdate(conn, "unpacking image: "+imageName)
+			if err != nil {
+				return err
+			}
+			err = m.writeRaw(vm.VolumeLocations[0], "", client, fs,
+				writeRawOptions, request.SkipBootloader)
+			if err != nil {
+				return sendError(conn, err)
+			}
+			fi, err := os.Stat(vm.VolumeLocations[0].Filename)
+			if err != nil {
+				return sendError(conn, err)
+			}
 			vm.Volumes = []proto.Volume{{Size: uint64(fi.Size())}}
 		}
 	} else if request.ImageDataSize > 0 {
@@ -1300,6 +1348,7 @@ func (m *Manager) createVm(conn *srpc.Conn) error {
 	}
 	if len(request.Volumes) > 0 {
 		vm.Volumes[0].Interface = request.Volumes[0].Interface
+		vm.Volumes[0].IoLimits = request.Volumes[0].IoLimits
 	}
 	vm.Volumes[0].Type = rootVolumeType
 	if request.UserDataSize > 0 {
@@ -1638,7 +1687,18 @@ func (m *Manager) discardVmOldImage(ipAddr net.IP,
 	if err := removeFile(vm.getKernelPath() + extension); err != nil {
 		return err
 	}
-	return removeFile(vm.VolumeLocations[0].Filename + extension)
+	err = removeFile(vm.VolumeLocations[0].Filename + extension)
+	if err != nil {
+		return err
+	}
+	if baseVolume := vm.VolumeLocations[0].OldBaseVolume; baseVolume != "" {
+		m.releaseBaseVolume(baseVolume)
+		vm.mutex.Lock()
+		vm.VolumeLocations[0].OldBaseVolume = ""
+		vm.writeInfo()
+		vm.mutex.Unlock()
+	}
+	return nil
 }
 
 func (m *Manager) discardVmOldUserData(ipAddr net.IP,
@@ -1676,6 +1736,11 @@ func (m *Manager) exportLocalVm(authInfo *srpc.AuthInformation,
 	if vm.State != proto.StateStopped {
 		return nil, errors.New("VM is not stopped")
 	}
+	for _, volume := range vm.VolumeLocations {
+		if volume.BaseVolume != "" || volume.OldBaseVolume != "" {
+			return nil, errorThinRootVolume
+		}
+	}
 	bridges, _, err := vm.getBridgesAndOptions(false)
 	if err != nil {
 		return nil, err
@@ -1963,7 +2028,17 @@ func (m *Manager) getVmVolume(conn *srpc.Conn) error {
 		return conn.Encode(proto.GetVmVolumeResponse{
 			Error: "index too large"})
 	}
-	file, err := os.Open(vm.VolumeLocations[request.VolumeIndex].Filename)
+	// Thin-provisioned volumes are flattened so that they are self-contained.
+	volume := vm.VolumeLocations[request.VolumeIndex]
+	filename := volume.Filename
+	if volume.BaseVolume != "" {
+		filename, err = flattenVolume(volume)
+		if err != nil {
+			return conn.Encode(proto.GetVmVolumeResponse{Error: err.Error()})
+		}
+		defer os.Remove(filename)
+	}
+	file, err := os.Open(filename)
 	if err != nil {
 		return conn.Encode(proto.GetVmVolumeResponse{Error: err.Error()})
 	}
@@ -2105,6 +2180,9 @@ func (m *Manager) importLocalVm(authInfo *srpc.AuthInformation,
 	if subnetId == "" {
 		return fmt.Errorf("no matching subnet for: %s\n", ipAddress)
 	}
+	if err := m.allocateDedicatedCPUsWithLock(vm); err != nil {
+		return err
+	}
 	vm.VmInfo.SubnetId = subnetId
 	vm.VmInfo.SecondarySubnetIDs = nil
 	for _, addr := range request.SecondaryAddresses {
@@ -2139,7 +2217,9 @@ func (m *Manager) importLocalVm(authInfo *srpc.AuthInformation,
 			return err
 		}
 		vm.VolumeLocations = append(vm.VolumeLocations, proto.LocalVolume{
-			dirname, destFilename})
+			DirectoryToCleanup: dirname,
+			Filename:           destFilename,
+		})
 	}
 	m.vms[ipAddress] = vm
 	if _, err := vm.startManaging(0, false, true); err != nil {
@@ -2347,6 +2427,10 @@ func (m *Manager) migrateVm(conn *srpc.Conn) error {
 	}
 	vm.State = proto.StateStarting
 	m.mutex.Lock()
+	if err := m.allocateDedicatedCPUsWithLock(vm); err != nil {
+		m.mutex.Unlock()
+		return err
+	}
 	m.vms[ipAddress] = vm
 	m.mutex.Unlock()
 	dhcpTimedOut, err := vm.startManaging(request.DhcpTimeout, false, false)
@@ -2437,6 +2521,9 @@ func (m *Manager) migrateVmChecks(vmInfo proto.VmInfo,
 	if err := m.checkSufficientCPUWithLock(vmInfo.MilliCPUs); err != nil {
 		return err
 	}
+	if err := m.checkSufficientDedicatedCPUsWithLock(vmInfo); err != nil {
+		return err
+	}
 	err := m.checkSufficientMemoryWithLock(vmInfo.MemoryInMiB, nil)
 	if err != nil {
 		return err
@@ -2646,6 +2733,9 @@ func (m *Manager) patchVmImage(conn *srpc.Conn,
 	default:
 		return errors.New("VM is not running or stopped")
 	}
+	if vm.VolumeLocations[0].BaseVolume != "" {
+		return errorThinRootVolume
+	}
 	vm.mutex.Unlock()
 	haveLock = false
 	if m.objectCache == nil {
@@ -3154,6 +3244,7 @@ func (m *Manager) replaceVmImage(conn *srpc.Conn,
 		if err := os.Rename(tmpRootFilename, rootFilename); err != nil {
 			return sendError(conn, err)
 		}
+		m.releaseBaseVolume(vm.VolumeLocations[0].BaseVolume)
 	} else {
 		oldRootFilename := vm.VolumeLocations[0].Filename + ".old"
 		if err := os.Rename(rootFilename, oldRootFilename); err != nil {
@@ -3165,7 +3256,11 @@ func (m *Manager) replaceVmImage(conn *srpc.Conn,
 		}
 		os.Rename(initrdFilename, initrdFilename+".old")
 		os.Rename(kernelFilename, kernelFilename+".old")
+		// The old root volume (and its base volume) replaces the old backup.
+		m.releaseBaseVolume(vm.VolumeLocations[0].OldBaseVolume)
+		vm.VolumeLocations[0].OldBaseVolume = vm.VolumeLocations[0].BaseVolume
 	}
+	vm.VolumeLocations[0].BaseVolume = ""
 	os.Rename(tmpInitrdFilename, initrdFilename)
 	os.Rename(tmpKernelFilename, kernelFilename)
 	if request.ImageName != "" {
@@ -3252,7 +3347,10 @@ func (m *Manager) restoreVmImage(ipAddr net.IP,
 	}
 	vm.blockMutations = true
 	vm.mutex.Unlock()
-	defer vm.allowMutationsAndUnlock(false)
+	var haveLock bool
+	defer func() {
+		vm.allowMutationsAndUnlock(haveLock)
+	}()
 	if vm.State != proto.StateStopped {
 		return errors.New("VM is not stopped")
 	}
@@ -3262,6 +3360,13 @@ func (m *Manager) restoreVmImage(ipAddr net.IP,
 	if err != nil {
 		return err
 	}
+	size := uint64(fi.Size())
+	oldBaseVolume := vm.VolumeLocations[0].OldBaseVolume
+	if oldBaseVolume != "" {
+		if size, err = getBaseVolumeSize(oldBaseVolume); err != nil {
+			return err
+		}
+	}
 	if err := os.Rename(oldRootFilename, rootFilename); err != nil {
 		return err
 	}
@@ -3269,7 +3374,12 @@ func (m *Manager) restoreVmImage(ipAddr net.IP,
 	os.Rename(initrdFilename+".old", initrdFilename)
 	kernelFilename := vm.getKernelPath()
 	os.Rename(kernelFilename+".old", kernelFilename)
-	vm.Volumes[0].Size = uint64(fi.Size())
+	vm.mutex.Lock()
+	haveLock = true
+	m.releaseBaseVolume(vm.VolumeLocations[0].BaseVolume)
+	vm.VolumeLocations[0].BaseVolume = oldBaseVolume
+	vm.VolumeLocations[0].OldBaseVolume = ""
+	vm.Volumes[0].Size = size
 	vm.writeAndSendInfo()
 	return nil
 }
@@ -3377,6 +3487,9 @@ func (vm *vmInfoType) scanVmRoot(scanFilter *filter.Filter) (
 	if vm.State != proto.StateStopped {
 		return nil, errors.New("VM is not stopped")
 	}
+	if vm.VolumeLocations[0].BaseVolume != "" {
+		return nil, errorThinRootVolume
+	}
 	rootDir, err := ioutil.TempDir(vm.dirname, "root")
 	if err != nil {
 		return nil, err
@@ -3405,6 +3518,8 @@ func (vm *vmInfoType) scanVmRoot(scanFilter *filter.Filter) (
 }
 
 func (m *Manager) sendVmInfo(ipAddress string, vm *proto.VmInfo) {
+	m.notifyCpuAffinityUpdater()
+	m.notifySecurityGroupsUpdater()
 	if ipAddress != "0.0.0.0" {
 		if vm == nil { // GOB cannot encode a nil value in a map.
 			vm = new(proto.VmInfo)
@@ -3646,11 +3761,9 @@ func (vm *vmInfoType) changeIpAddress(ipAddress string) error {
 		if err := os.Rename(volume.DirectoryToCleanup, dirname); err != nil {
 			return err
 		}
-		vm.VolumeLocations[index] = proto.LocalVolume{
-			DirectoryToCleanup: dirname,
-			Filename: filepath.Join(dirname,
-				filepath.Base(volume.Filename)),
-		}
+		vm.VolumeLocations[index].DirectoryToCleanup = dirname
+		vm.VolumeLocations[index].Filename = filepath.Join(dirname,
+			filepath.Base(volume.Filename))
 	}
 	vm.logger.Printf("changing to new address: %s\n", ipAddress)
 	vm.logger = prefixlogger.New(ipAddress+": ", vm.manager.Logger)
@@ -3715,6 +3828,7 @@ func (vm *vmInfoType) cleanup() {
 		os.RemoveAll(volume.DirectoryToCleanup)
 	}
 	m.mutex.Unlock()
+	vm.releaseBaseVolumes()
 }
 
 func (vm *vmInfoType) copyRootVolume(request proto.CreateVmRequest,
@@ -3756,6 +3870,7 @@ func (vm *vmInfoType) delete() {
 			os.RemoveAll(volume.DirectoryToCleanup)
 		}
 	}
+	vm.releaseBaseVolumes()
 	os.RemoveAll(vm.dirname)
 	vm.manager.DhcpServer.RemoveLease(vm.Address.IpAddress)
 	for _, address := range vm.SecondaryAddresses {
@@ -4058,6 +4173,10 @@ func (vm *vmInfoType) startManaging(dhcpTimeout time.Duration,
 	vm.commandOutput = commandOutput
 	go vm.monitor(monitorSock, commandInput, commandOutput)
 	commandInput <- "qmp_capabilities"
+	err = vm.setIoLimits(nil, vm.NetworkLimits, nil, vm.Volumes)
+	if err != nil {
+		vm.logger.Println(err)
+	}
 	if vm.getDebugRoot() == "" {
 		vm.setState(proto.StateRunning)
 	} else {
@@ -4202,6 +4321,20 @@ func (vm *vmInfoType) startVm(enableNetboot, haveManagerLock bool) error {
 	if nCpus > uint(runtime.NumCPU()) && runtime.NumCPU() > 0 {
 		nCpus = uint(runtime.NumCPU())
 	}
+	name := vm.ipAddress
+	var numaNode *proto.NumaNode
+	if vm.DedicatedCPUs {
+		if len(vm.PinnedCPUs) < 1 {
+			return errors.New("no CPUs allocated for dedicated CPU VM")
+		}
+		node, err := vm.manager.getNumaNodeForCPU(vm.PinnedCPUs[0])
+		if err != nil {
+			return err
+		}
+		numaNode = node
+		// Name the threads so that the virtual CPU threads can be pinned.
+		name += ",debug-threads=on"
+	}
 	bridges, netOptions, err := vm.getBridgesAndOptions(haveManagerLock)
 	if err != nil {
 		return err
@@ -4220,7 +4353,7 @@ func (vm *vmInfoType) startVm(enableNetboot, haveManagerLock bool) error {
 		"-machine", fmt.Sprintf("%s,accel=kvm", vm.MachineType),
 		"-cpu", "host", // Allow the VM to take full advantage of host CPU.
 		"-nodefaults",
-		"-name", vm.ipAddress,
+		"-name", name,
 		"-m", fmt.Sprintf("%dM", vm.MemoryInMiB),
 		"-smp", fmt.Sprintf("cpus=%d", nCpus),
 		"-serial",
@@ -4230,6 +4363,14 @@ func (vm *vmInfoType) startVm(enableNetboot, haveManagerLock bool) error {
 		"-qmp", "unix:"+vm.monitorSockname+",server,nowait",
 		"-pidfile", pidfile,
 		"-daemonize")
+	if numaNode != nil {
+		// Bind the memory to the NUMA node of the dedicated CPUs.
+		cmd.Args = append(cmd.Args,
+			"-object", fmt.Sprintf(
+				"memory-backend-ram,id=ram0,size=%dM,host-nodes=%d,policy=bind",
+				vm.MemoryInMiB, numaNode.Id),
+			"-numa", "node,memdev=ram0")
+	}
 	var interfaceDriver string
 	if !vm.DisableVirtIO {
 		interfaceDriver = ",if=virtio"
@@ -4279,22 +4420,22 @@ func (vm *vmInfoType) startVm(enableNetboot, haveManagerLock bool) error {
 	}
 	for index, volume := range vm.VolumeLocations {
 		var volumeFormat proto.VolumeFormat
-		var volumeInterface proto.VolumeInterface
-		if vm.DisableVirtIO {
-			volumeInterface = proto.VolumeInterfaceIDE
-		}
 		if index < len(vm.Volumes) {
 			volumeFormat = vm.Volumes[index].Format
-			volumeInterface = vm.Volumes[index].Interface
 		}
+		if volume.BaseVolume != "" {
+			volumeFormat = proto.VolumeFormatQCOW2
+		}
+		volumeInterface := vm.getVolumeInterface(index)
 		// For the simple cases (VirtIO and IDE), use old-style flags to
 		// maintain compatibility with old versions of QEMU (like 2.0.0).
 		switch volumeInterface {
 		case proto.VolumeInterfaceVirtIO, proto.VolumeInterfaceIDE:
 			cmd.Args = append(cmd.Args,
 				"-drive", fmt.Sprintf(
-					"file=%s,format=%s,discard=off,if=%s",
-					volume.Filename, volumeFormat, volumeInterface))
+					"file=%s,format=%s,discard=off,if=%s,id=%s",
+					volume.Filename, volumeFormat, volumeInterface,
+					getVolumeId(index)))
 			continue
 		}
 		cmd.Args = append(cmd.Args,
@@ -4305,16 +4446,17 @@ func (vm *vmInfoType) startVm(enableNetboot, haveManagerLock bool) error {
 		case proto.VolumeInterfaceVirtIO:
 			cmd.Args = append(cmd.Args,
 				"-device", fmt.Sprintf(
-					"virtio-blk,drive=blk%d", index))
+					"virtio-blk,drive=blk%d,id=%s",
+					index, getVolumeId(index)))
 		case proto.VolumeInterfaceIDE:
 			cmd.Args = append(cmd.Args,
 				"-device", fmt.Sprintf(
-					"ide-hd,drive=blk%d", index))
+					"ide-hd,drive=blk%d,id=%s", index, getVolumeId(index)))
 		case proto.VolumeInterfaceNVMe:
 			cmd.Args = append(cmd.Args,
 				"-device", fmt.Sprintf(
-					"nvme,serial=fu%s-%d,drive=blk%d",
-					vm.Address.IpAddress, index, index))
+					"nvme,serial=fu%s-%d,drive=blk%d,id=%s",
+					vm.Address.IpAddress, index, index, getVolumeId(index)))
 		default:
 			return fmt.Errorf("invalid volume interface: %v", volumeInterface)
 		}
@@ -4346,6 +4488,11 @@ func (vm *vmInfoType) startVm(enableNetboot, haveManagerLock bool) error {
 			return err
 		}
 	}
+	if len(vm.PinnedCPUs) > 0 {
+		if err := vm.pinDedicatedCPUs(); err != nil {
+			return fmt.Errorf("error pinning CPUs: %s", err)
+		}
+	}
 	return nil
 }
 
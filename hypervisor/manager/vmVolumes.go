@@ -20,6 +20,12 @@ func (vm *vmInfoType) checkVolumes(grabLock bool) error {
 	}
 	for index, volume := range vm.VolumeLocations {
 		expectedSize := vm.Volumes[index].Size
+		if volume.BaseVolume != "" { // QCOW2 overlay: size is from the base.
+			if _, err := os.Stat(volume.Filename); err != nil {
+				return fmt.Errorf("error stating volume[%d]: %s", index, err)
+			}
+			continue
+		}
 		if fi, err := os.Stat(volume.Filename); err != nil {
 			return fmt.Errorf("error stating volume[%d]: %s", index, err)
 		} else if foundSize := uint64(fi.Size()); foundSize != expectedSize {
@@ -50,7 +56,10 @@ func (vm *vmInfoType) setupVolumes(rootSize uint64,
 	}
 	filename := filepath.Join(volumeDirectory, "root")
 	vm.VolumeLocations = append(vm.VolumeLocations,
-		proto.LocalVolume{volumeDirectory, filename})
+		proto.LocalVolume{
+			DirectoryToCleanup: volumeDirectory,
+			Filename:           filename,
+		})
 	for index := range secondaryVolumes {
 		volumeDirectory := filepath.Join(volumeDirectories[index+1],
 			vm.ipAddress)
@@ -60,7 +69,10 @@ func (vm *vmInfoType) setupVolumes(rootSize uint64,
 		}
 		filename := filepath.Join(volumeDirectory, indexToName(index+1))
 		vm.VolumeLocations = append(vm.VolumeLocations,
-			proto.LocalVolume{volumeDirectory, filename})
+			proto.LocalVolume{
+				DirectoryToCleanup: volumeDirectory,
+				Filename:           filename,
+			})
 	}
 	return nil
 }
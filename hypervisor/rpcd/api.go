@@ -59,16 +59,74 @@ func Setup(manager *manager.Manager, dhcpServer DhcpServer,
 			return manager.CheckOwnership(authInfo)
 		})
 	srpc.RegisterNameWithOptions("Hypervisor", srpcObj, srpc.ReceiverOptions{
+		AuditedMethods: []string{
+			"AcknowledgeVm",
+			"AddVmVolumes",
+			"BackupVm",
+			"BecomePrimaryVmOwner",
+			"ChangeAddressPool",
+			"ChangeOwners",
+			"ChangeVmConsoleType",
+			"ChangeVmCpuPriority",
+			"ChangeVmDestroyProtection",
+			"ChangeVmIoLimits",
+			"ChangeVmLifecyclePolicy",
+			"ChangeVmMachineType",
+			"ChangeVmOwnerGroups",
+			"ChangeVmOwnerUsers",
+			"ChangeVmSecurityGroups",
+			"ChangeVmSize",
+			"ChangeVmTags",
+			"ChangeVmVolumeInterfaces",
+			"ChangeVmVolumeSize",
+			"CommitImportedVm",
+			"CopyVm",
+			"CreateVm",
+			"DebugVmImage",
+			"DeleteVmVolume",
+			"DestroyVm",
+			"DiscardVmAccessToken",
+			"DiscardVmOldImage",
+			"DiscardVmOldUserData",
+			"DiscardVmSnapshot",
+			"ExportLocalVm",
+			"GetVmAccessToken",
+			"ImportLocalVm",
+			"MigrateVm",
+			"NetbootMachine",
+			"PatchVmImage",
+			"PowerOff",
+			"PrepareVmForMigration",
+			"RebootVm",
+			"RegisterExternalLeases",
+			"ReorderVmVolumes",
+			"ReplaceVmCredentials",
+			"ReplaceVmImage",
+			"ReplaceVmUserData",
+			"RestoreVmFromBackup",
+			"RestoreVmFromSnapshot",
+			"RestoreVmImage",
+			"RestoreVmUserData",
+			"SetDisabledState",
+			"SnapshotVm",
+			"StartVm",
+			"StopVm",
+			"UpdateSecurityGroups",
+			"UpdateSubnets",
+		},
 		PublicMethods: []string{
 			"AcknowledgeVm",
 			"AddVmVolumes",
+			"BackupVm",
 			"BecomePrimaryVmOwner",
 			"ChangeVmConsoleType",
 			"ChangeVmCpuPriority",
 			"ChangeVmDestroyProtection",
+			"ChangeVmLifecyclePolicy",
 			"ChangeVmMachineType",
 			"ChangeVmOwnerGroups",
 			"ChangeVmOwnerUsers",
+			"ChangeVmSecurityGroups",
 			"ChangeVmSize",
 			"ChangeVmTags",
 			"ChangeVmVolumeInterfaces",
@@ -96,8 +154,10 @@ func Setup(manager *manager.Manager, dhcpServer DhcpServer,
 			"GetVmUserData",
 			"GetVmVolume",
 			"ImportLocalVm",
+			"ListSecurityGroups",
 			"ListSubnets",
 			"ListVMs",
+			"ListVmBackups",
 			"ListVolumeDirectories",
 			"MigrateVm",
 			"PatchVmImage",
@@ -106,6 +166,7 @@ func Setup(manager *manager.Manager, dhcpServer DhcpServer,
 			"ReplaceVmCredentials",
 			"ReplaceVmImage",
 			"ReplaceVmUserData",
+			"RestoreVmFromBackup",
 			"RestoreVmFromSnapshot",
 			"RestoreVmImage",
 			"RestoreVmUserData",
@@ -0,0 +1,19 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func (t *srpcType) BackupVm(conn *srpc.Conn,
+	request hypervisor.BackupVmRequest,
+	reply *hypervisor.BackupVmResponse) error {
+	backup, err := t.manager.BackupVm(request.IpAddress,
+		conn.GetAuthInformation())
+	*reply = hypervisor.BackupVmResponse{
+		Backup: backup,
+		Error:  errors.ErrorToString(err),
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func (t *srpcType) ChangeVmIoLimits(conn *srpc.Conn,
+	request hypervisor.ChangeVmIoLimitsRequest,
+	reply *hypervisor.ChangeVmIoLimitsResponse) error {
+	*reply = hypervisor.ChangeVmIoLimitsResponse{
+		errors.ErrorToString(
+			t.manager.ChangeVmIoLimits(request.IpAddress,
+				conn.GetAuthInformation(),
+				request.NetworkLimits, request.VolumeIoLimits))}
+	return nil
+}
@@ -0,0 +1,17 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func (t *srpcType) ChangeVmLifecyclePolicy(conn *srpc.Conn,
+	request hypervisor.ChangeVmLifecyclePolicyRequest,
+	reply *hypervisor.ChangeVmLifecyclePolicyResponse) error {
+	*reply = hypervisor.ChangeVmLifecyclePolicyResponse{
+		errors.ErrorToString(
+			t.manager.ChangeVmLifecyclePolicy(request.IpAddress,
+				conn.GetAuthInformation(), request.LifecyclePolicy))}
+	return nil
+}
@@ -0,0 +1,17 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func (t *srpcType) ChangeVmSecurityGroups(conn *srpc.Conn,
+	request hypervisor.ChangeVmSecurityGroupsRequest,
+	reply *hypervisor.ChangeVmSecurityGroupsResponse) error {
+	*reply = hypervisor.ChangeVmSecurityGroupsResponse{
+		errors.ErrorToString(
+			t.manager.ChangeVmSecurityGroups(request.IpAddress,
+				conn.GetAuthInformation(), request.SecurityGroups))}
+	return nil
+}
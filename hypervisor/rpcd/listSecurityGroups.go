@@ -0,0 +1,14 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func (t *srpcType) ListSecurityGroups(conn *srpc.Conn,
+	request hypervisor.ListSecurityGroupsRequest,
+	reply *hypervisor.ListSecurityGroupsResponse) error {
+	*reply = hypervisor.ListSecurityGroupsResponse{
+		SecurityGroups: t.manager.ListSecurityGroups()}
+	return nil
+}
@@ -0,0 +1,19 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func (t *srpcType) ListVmBackups(conn *srpc.Conn,
+	request hypervisor.ListVmBackupsRequest,
+	reply *hypervisor.ListVmBackupsResponse) error {
+	backups, err := t.manager.ListVmBackups(request.IpAddress,
+		conn.GetAuthInformation())
+	*reply = hypervisor.ListVmBackupsResponse{
+		Backups: backups,
+		Error:   errors.ErrorToString(err),
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func (t *srpcType) RestoreVmFromBackup(conn *srpc.Conn,
+	request hypervisor.RestoreVmFromBackupRequest,
+	reply *hypervisor.RestoreVmFromBackupResponse) error {
+	*reply = hypervisor.RestoreVmFromBackupResponse{
+		errors.ErrorToString(t.manager.RestoreVmFromBackup(request.IpAddress,
+			conn.GetAuthInformation(), request.BackupId))}
+	return nil
+}
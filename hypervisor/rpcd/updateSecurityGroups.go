@@ -0,0 +1,15 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func (t *srpcType) UpdateSecurityGroups(conn *srpc.Conn,
+	request hypervisor.UpdateSecurityGroupsRequest,
+	reply *hypervisor.UpdateSecurityGroupsResponse) error {
+	*reply = hypervisor.UpdateSecurityGroupsResponse{
+		errors.ErrorToString(t.manager.UpdateSecurityGroups(request))}
+	return nil
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/hash"
 	"github.com/Cloud-Foundations/Dominator/lib/image"
 	"github.com/Cloud-Foundations/Dominator/lib/image/packageutil"
+	"github.com/Cloud-Foundations/Dominator/lib/image/sbom"
 	objectclient "github.com/Cloud-Foundations/Dominator/lib/objectserver/client"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/lib/tags"
@@ -105,6 +106,29 @@ func addImage(client srpc.ClientI, request proto.BuildImageRequest,
 	return name, nil
 }
 
+func addSBOM(objClient *objectclient.ObjectClient, streamName string,
+	fs *filesystem.FileSystem, packages []image.Package,
+	buildLog buildLogger) (hash.Hash, error) {
+	startTime := time.Now()
+	doc, err := sbom.New(&image.Image{FileSystem: fs, Packages: packages},
+		streamName, startTime)
+	if err != nil {
+		return hash.Hash{}, err
+	}
+	buffer := &bytes.Buffer{}
+	if err := doc.Write(buffer); err != nil {
+		return hash.Hash{}, err
+	}
+	length := uint64(buffer.Len())
+	hashVal, _, err := objClient.AddObject(buffer, length, nil)
+	if err != nil {
+		return hash.Hash{}, err
+	}
+	fmt.Fprintf(buildLog, "Generated SBOM (%s) in %s\n",
+		format.FormatBytes(length), format.Duration(time.Since(startTime)))
+	return hashVal, nil
+}
+
 func buildFileSystem(client srpc.ClientI, dirname string,
 	scanFilter *filter.Filter, cache *treeCache) (
 	*filesystem.FileSystem, error) {
@@ -205,6 +229,11 @@ func packImage(g *goroutine.Goroutine, client srpc.ClientI,
 		return nil, err
 	}
 	objClient := objectclient.AttachObjectClient(client)
+	sbomHash, err := addSBOM(objClient, request.StreamName, fs, packages,
+		buildLog)
+	if err != nil {
+		return nil, fmt.Errorf("error adding SBOM: %s", err)
+	}
 	// Make a copy of the build log because AddObject() drains the buffer.
 	logReader := bytes.NewBuffer(buildLog.Bytes())
 	hashVal, _, err := objClient.AddObject(logReader, uint64(logReader.Len()),
@@ -228,6 +257,7 @@ func packImage(g *goroutine.Goroutine, client srpc.ClientI,
 		Filter:     imageFilter,
 		Triggers:   trig,
 		Packages:   packages,
+		SBOM:       &image.Annotation{Object: &sbomHash},
 		Tags:       tgs,
 	}
 	if err := img.Verify(); err != nil {
@@ -51,6 +51,8 @@ type bootstrapStream struct {
 	imageTags        tags.Tags
 	imageTriggers    *triggers.Triggers
 	ImageTriggersUrl string
+	OciImage         string
+	OciReference     string
 	PackagerType     string
 }
 
@@ -314,6 +316,11 @@ func (b *Builder) ReplaceIdleSlaves(immediateGetNew bool) error {
 	return b.replaceIdleSlaves(immediateGetNew)
 }
 
+func (b *Builder) SearchBuildLogs(request proto.SearchBuildLogsRequest) (
+	[]proto.BuildLogSearchResult, error) {
+	return b.searchBuildLogs(request)
+}
+
 func (b *Builder) ShowImageStream(writer io.Writer, streamName string) {
 	b.showImageStream(writer, streamName)
 }
@@ -16,6 +16,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/format"
 	"github.com/Cloud-Foundations/Dominator/lib/goroutine"
 	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/oci"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
 )
@@ -76,7 +77,6 @@ func (stream *bootstrapStream) build(b *Builder, client srpc.ClientI,
 	request proto.BuildImageRequest,
 	buildLog buildLogger) (*image.Image, error) {
 	startTime := time.Now()
-	args := make([]string, 0, len(stream.BootstrapCommand))
 	rootDir, err := makeTempDirectory("",
 		strings.Replace(request.StreamName, "/", "_", -1))
 	if err != nil {
@@ -87,42 +87,81 @@ func (stream *bootstrapStream) build(b *Builder, client srpc.ClientI,
 	vg := variablesGetter(request.Variables).copy()
 	vg.add("dir", rootDir)
 	request.Variables = vg
+	g, err := newNamespaceTarget()
+	if err != nil {
+		return nil, err
+	}
+	defer g.Quit()
+	if stream.OciImage != "" {
+		err = stream.unpackOciImage(vg, rootDir, buildLog)
+	} else {
+		err = stream.runBootstrapCommand(g, vg, buildLog)
+	}
+	if err != nil {
+		return nil, err
+	}
+	packager := b.packagerTypes[stream.PackagerType]
+	if err := packager.writePackageInstaller(rootDir); err != nil {
+		return nil, err
+	}
+	if err := clearResolvConf(g, buildLog, rootDir); err != nil {
+		return nil, err
+	}
+	buildDuration := time.Since(startTime)
+	fmt.Fprintf(buildLog, "\nBuild time: %s\n",
+		format.Duration(buildDuration))
+	if err := cleanPackages(g, rootDir, buildLog); err != nil {
+		return nil, err
+	}
+	return packImage(g, client, request, rootDir,
+		stream.Filter, nil, nil, stream.imageFilter, stream.imageTags,
+		stream.imageTriggers, b.mtimesCopyFilter, buildLog)
+}
+
+func (stream *bootstrapStream) runBootstrapCommand(g *goroutine.Goroutine,
+	vg variablesGetter, buildLog io.Writer) error {
+	args := make([]string, 0, len(stream.BootstrapCommand))
 	for _, exp := range stream.BootstrapCommand {
 		arg := expand.Expression(exp, func(name string) string {
 			return vg[name]
 		})
 		args = append(args, arg)
 	}
+	if len(args) < 1 {
+		return errors.New("no bootstrap command")
+	}
 	fmt.Fprintf(buildLog, "Running command: %s with args:\n", args[0])
 	for _, arg := range args[1:] {
 		fmt.Fprintf(buildLog, "    %s\n", arg)
 	}
-	g, err := newNamespaceTarget()
-	if err != nil {
-		return nil, err
+	return runInTarget(g, nil, buildLog, "", nil, args[0], args[1:]...)
+}
+
+func (stream *bootstrapStream) unpackOciImage(vg variablesGetter,
+	rootDir string, buildLog io.Writer) error {
+	expandFunc := func(name string) string {
+		return vg[name]
 	}
-	defer g.Quit()
-	err = runInTarget(g, nil, buildLog, "", nil, args[0], args[1:]...)
-	if err != nil {
-		return nil, err
+	pathname := expand.Expression(stream.OciImage, expandFunc)
+	reference := expand.Expression(stream.OciReference, expandFunc)
+	if reference == "" {
+		fmt.Fprintf(buildLog, "Unpacking OCI image: %s\n", pathname)
 	} else {
-		packager := b.packagerTypes[stream.PackagerType]
-		if err := packager.writePackageInstaller(rootDir); err != nil {
-			return nil, err
-		}
-		if err := clearResolvConf(g, buildLog, rootDir); err != nil {
-			return nil, err
-		}
-		buildDuration := time.Since(startTime)
-		fmt.Fprintf(buildLog, "\nBuild time: %s\n",
-			format.Duration(buildDuration))
-		if err := cleanPackages(g, rootDir, buildLog); err != nil {
-			return nil, err
-		}
-		return packImage(g, client, request, rootDir,
-			stream.Filter, nil, nil, stream.imageFilter, stream.imageTags,
-			stream.imageTriggers, b.mtimesCopyFilter, buildLog)
+		fmt.Fprintf(buildLog, "Unpacking OCI image: %s from: %s\n",
+			reference, pathname)
+	}
+	ociImage, err := oci.Open(pathname, reference)
+	if err != nil {
+		return err
 	}
+	defer ociImage.Close()
+	startTime := time.Now()
+	if err := ociImage.Unpack(rootDir, nil); err != nil {
+		return fmt.Errorf("error unpacking OCI image: %s", err)
+	}
+	fmt.Fprintf(buildLog, "Unpacked %d layers in %s\n", ociImage.NumLayers(),
+		format.Duration(time.Since(startTime)))
+	return nil
 }
 
 func (packager *packagerType) writePackageInstaller(rootDir string) error {
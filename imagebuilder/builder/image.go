@@ -313,8 +313,13 @@ func buildImageFromManifest(client srpc.ClientI, manifestDir string,
 	}
 	if addTriggers {
 		mergeableTriggers := &triggers.MergeableTriggers{}
-		mergeableTriggers.Merge(manifest.sourceImageInfo.triggers)
-		mergeableTriggers.Merge(imageTriggers)
+		err := mergeableTriggers.Merge(manifest.sourceImageInfo.triggers)
+		if err != nil {
+			return nil, err
+		}
+		if err := mergeableTriggers.Merge(imageTriggers); err != nil {
+			return nil, err
+		}
 		imageTriggers = mergeableTriggers.ExportTriggers()
 	}
 	if manifest.mtimesCopyFilter != nil {
@@ -0,0 +1,17 @@
+package builder
+
+import (
+	"errors"
+
+	"github.com/Cloud-Foundations/Dominator/imagebuilder/logarchiver"
+	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
+)
+
+func (b *Builder) searchBuildLogs(request proto.SearchBuildLogsRequest) (
+	[]proto.BuildLogSearchResult, error) {
+	reporter, ok := b.buildLogArchiver.(logarchiver.BuildLogReporter)
+	if !ok {
+		return nil, errors.New("no build log archive")
+	}
+	return reporter.SearchBuildLogs(request)
+}
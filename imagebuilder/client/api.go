@@ -38,3 +38,9 @@ func GetDirectedGraph(client *srpc.Client,
 func ReplaceIdleSlaves(client *srpc.Client, immediateGetNew bool) error {
 	return replaceIdleSlaves(client, immediateGetNew)
 }
+
+func SearchBuildLogs(client *srpc.Client,
+	request proto.SearchBuildLogsRequest) (
+	[]proto.BuildLogSearchResult, error) {
+	return searchBuildLogs(client, request)
+}
@@ -123,3 +123,17 @@ func replaceIdleSlaves(client *srpc.Client, immediateGetNew bool) error {
 	}
 	return errors.New(reply.Error)
 }
+
+func searchBuildLogs(client *srpc.Client,
+	request proto.SearchBuildLogsRequest) (
+	[]proto.BuildLogSearchResult, error) {
+	var reply proto.SearchBuildLogsResponse
+	err := client.RequestReply("Imaginator.SearchBuildLogs", request, &reply)
+	if err != nil {
+		return nil, err
+	}
+	if reply.Error != "" {
+		return nil, errors.New(reply.Error)
+	}
+	return reply.Results, nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/imagebuilder/logarchiver"
 	"github.com/Cloud-Foundations/Dominator/lib/html"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 )
 
 type HtmlWriter interface {
@@ -74,8 +75,10 @@ func StartServerWithOptionsAndParams(options Options, params Params) error {
 			myState.showRequestorGoodBuildsHandler)
 		html.HandleFunc("/showRequestorErrorBuilds",
 			myState.showRequestorErrorBuildsHandler)
+		html.HandleFunc("/searchBuildLogs", myState.searchBuildLogsHandler)
 	}
 	if params.DaemonMode {
+		listener = srpc.NewTlsSniffingListener(listener)
 		go http.Serve(listener, nil)
 	} else {
 		http.Serve(listener, nil)
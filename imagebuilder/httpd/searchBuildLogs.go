@@ -0,0 +1,97 @@
+package httpd
+
+import (
+	"bufio"
+	"fmt"
+	stdhtml "html"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/html"
+	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
+)
+
+func writeSearchBuildLogsForm(writer io.Writer,
+	request proto.SearchBuildLogsRequest, maxAge string) {
+	fmt.Fprintln(writer, `<form action="/searchBuildLogs" method="get">`)
+	fmt.Fprintf(writer,
+		"Pattern: <input type=\"text\" name=\"pattern\" value=\"%s\" size=\"40\">\n",
+		stdhtml.EscapeString(request.Pattern))
+	fmt.Fprintf(writer,
+		"Stream: <input type=\"text\" name=\"stream\" value=\"%s\">\n",
+		stdhtml.EscapeString(request.StreamName))
+	fmt.Fprintf(writer,
+		"Max age: <input type=\"text\" name=\"maxAge\" value=\"%s\" size=\"6\">\n",
+		stdhtml.EscapeString(maxAge))
+	fmt.Fprintln(writer, `<input type="submit" value="Search">`)
+	fmt.Fprintln(writer, "</form>")
+}
+
+func (s state) searchBuildLogsHandler(w http.ResponseWriter,
+	req *http.Request) {
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	queries := req.URL.Query()
+	request := proto.SearchBuildLogsRequest{
+		Pattern:    queries.Get("pattern"),
+		StreamName: queries.Get("stream"),
+	}
+	maxAge := queries.Get("maxAge")
+	fmt.Fprintln(writer, "<title>build log search</title>")
+	fmt.Fprintln(writer, `<style>
+                          table, th, td {
+                          border-collapse: collapse;
+                          }
+                          </style>`)
+	fmt.Fprintln(writer, "<body>")
+	writeSearchBuildLogsForm(writer, request, maxAge)
+	if request.Pattern == "" {
+		fmt.Fprintln(writer, "</body>")
+		return
+	}
+	if maxAge != "" {
+		if duration, err := time.ParseDuration(maxAge); err != nil {
+			fmt.Fprintf(writer, "Invalid max age: %s<br>\n",
+				stdhtml.EscapeString(err.Error()))
+			fmt.Fprintln(writer, "</body>")
+			return
+		} else {
+			request.MaxAge = duration
+		}
+	}
+	startTime := time.Now()
+	results, err := s.buildLogReporter.SearchBuildLogs(request)
+	if err != nil {
+		fmt.Fprintf(writer, "Error searching: %s<br>\n",
+			stdhtml.EscapeString(err.Error()))
+		fmt.Fprintln(writer, "</body>")
+		return
+	}
+	fmt.Fprintf(writer, "<p>Found %d matching build logs in %s<br>\n",
+		len(results), format.Duration(time.Since(startTime)))
+	fmt.Fprintln(writer, `<table border="1">`)
+	tw, _ := html.NewTableWriter(writer, true, "Image Name", "Build Time",
+		"Duration", "Error", "Matches")
+	for _, result := range results {
+		lines := make([]string, 0, len(result.Matches)+1)
+		for _, match := range result.Matches {
+			lines = append(lines, fmt.Sprintf("%d: %s", match.LineNumber,
+				stdhtml.EscapeString(match.Line)))
+		}
+		if omitted := result.NumMatches - uint(len(result.Matches)); omitted > 0 {
+			lines = append(lines, fmt.Sprintf("(%d more)", omitted))
+		}
+		tw.WriteRow("", "",
+			fmt.Sprintf("<a href=\"showBuildLog?%s\">%s</a>",
+				result.ImageName, result.ImageName),
+			result.BuildTime.Format(format.TimeFormatSeconds),
+			format.Duration(result.Duration),
+			stdhtml.EscapeString(result.Error),
+			"<pre>"+strings.Join(lines, "\n")+"</pre>")
+	}
+	tw.Close()
+	fmt.Fprintln(writer, "</body>")
+}
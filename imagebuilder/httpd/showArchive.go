@@ -87,6 +87,7 @@ func (s state) showBuildLogArchiveHandler(w http.ResponseWriter,
                           </style>`)
 	fmt.Fprintln(writer, "<body>")
 	fmt.Fprintln(writer, "<h3>")
+	fmt.Fprintln(writer, `<a href="searchBuildLogs">Search build logs</a><p>`)
 	summary := s.buildLogReporter.GetSummary()
 	fmt.Fprintln(writer, "Build summary per image stream:<br>")
 	var numBuilds, numGoodBuilds, numErrorBuilds uint64
@@ -4,7 +4,9 @@ import (
 	"io"
 	"time"
 
+	"github.com/Cloud-Foundations/Dominator/lib/blobstore"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
+	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
 )
 
 type BuildInfo struct {
@@ -18,12 +20,14 @@ type BuildLogArchiver interface {
 }
 
 type BuildLogArchiveOptions struct {
-	Quota  uint64
-	Topdir string
+	Quota             uint64                     // Zero: unlimited.
+	RetentionPolicies map[string]RetentionPolicy // Key: stream name prefix.
+	Topdir            string                     // Ignored if ObjectStore.
 }
 
 type BuildLogArchiveParams struct {
-	Logger log.DebugLogger
+	Logger      log.DebugLogger
+	ObjectStore ObjectStore // If nil, logs are stored under Topdir.
 }
 
 type BuildLogReporter interface {
@@ -32,6 +36,8 @@ type BuildLogReporter interface {
 	GetBuildInfosForStream(streamName string, incGood, incBad bool) *BuildInfos
 	GetBuildLog(imageName string) (io.ReadCloser, error)
 	GetSummary() *Summary
+	SearchBuildLogs(request proto.SearchBuildLogsRequest) (
+		[]proto.BuildLogSearchResult, error)
 }
 
 type BuildLogger interface {
@@ -44,12 +50,27 @@ type BuildInfos struct {
 	ImagesByAge []string             // May be empty.
 }
 
+// ObjectStore is a simple object (blob) store, such as an S3 bucket.
+type ObjectStore = blobstore.Store
+
+type ObjectInfo = blobstore.ObjectInfo
+
 type RequestorSummary struct {
 	NumBuilds      uint64
 	NumGoodBuilds  uint64
 	NumErrorBuilds uint64
 }
 
+// RetentionPolicy specifies how long build logs for image streams are kept.
+// The policy for a stream is the one with the longest matching stream name
+// prefix. The empty prefix matches all streams.
+type RetentionPolicy struct {
+	MaxAge    time.Duration // Zero: no limit.
+	MaxBuilds uint          // Per stream. Zero: no limit.
+}
+
+type S3ObjectStoreOptions = blobstore.S3Options
+
 type StreamSummary struct {
 	NumBuilds      uint64
 	NumGoodBuilds  uint64
@@ -69,3 +90,16 @@ func New(options BuildLogArchiveOptions,
 func NewNullLogger() BuildLogArchiver {
 	return newNullLogger()
 }
+
+// NewMemoryObjectStore returns an ObjectStore which stores objects in memory.
+// This is useful for testing.
+func NewMemoryObjectStore() ObjectStore {
+	return blobstore.NewMemoryStore()
+}
+
+// NewS3ObjectStore returns an ObjectStore which stores objects in an S3 (or
+// S3-compatible) bucket. Credentials are loaded using the standard AWS
+// mechanisms.
+func NewS3ObjectStore(options S3ObjectStoreOptions) (ObjectStore, error) {
+	return blobstore.NewS3Store(options)
+}
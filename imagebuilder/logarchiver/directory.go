@@ -0,0 +1,139 @@
+package logarchiver
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/wsyscall"
+)
+
+type directoryStorage struct {
+	fileSizeIncrement uint64
+	topdir            string
+}
+
+func newDirectoryStorage(topdir string) (*directoryStorage, error) {
+	storage := &directoryStorage{topdir: topdir}
+	if err := storage.computeFileSizeIncrement(); err != nil {
+		return nil, err
+	}
+	return storage, nil
+}
+
+func (s *directoryStorage) computeFileSizeIncrement() error {
+	if err := os.MkdirAll(s.topdir, fsutil.DirPerms); err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(s.topdir, "******")
+	if err != nil {
+		return err
+	}
+	filename := file.Name()
+	defer os.Remove(filename)
+	if _, err := file.Write([]byte{'\n'}); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	var statbuf wsyscall.Stat_t
+	if err := wsyscall.Stat(filename, &statbuf); err != nil {
+		return err
+	}
+	if statbuf.Blocks < 1 {
+		statbuf.Blocks = 1
+	}
+	s.fileSizeIncrement = uint64(statbuf.Blocks) * 512
+	return nil
+}
+
+func (s *directoryStorage) delete(imageName string) error {
+	return os.RemoveAll(filepath.Join(s.topdir, imageName))
+}
+
+func (s *directoryStorage) getBuildLog(imageName string) (
+	io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.topdir, imageName, "buildLog"))
+}
+
+func (s *directoryStorage) load(fn func(imageName string,
+	buildInfo BuildInfo, logSize uint64, modTime time.Time) error) error {
+	return s.loadDirectory("", fn)
+}
+
+func (s *directoryStorage) loadDirectory(dirname string,
+	fn func(imageName string, buildInfo BuildInfo, logSize uint64,
+		modTime time.Time) error) error {
+	dirpath := filepath.Join(s.topdir, dirname)
+	names, err := fsutil.ReadDirnames(dirpath, false)
+	if err != nil {
+		return err
+	}
+	var buildInfoPathname, buildLogPathname string
+	for _, name := range names {
+		switch name {
+		case "buildInfo":
+			buildInfoPathname = filepath.Join(dirpath, name)
+			continue
+		case "buildLog":
+			buildLogPathname = filepath.Join(dirpath, name)
+			continue
+		}
+		if err := s.loadDirectory(filepath.Join(dirname, name), fn); err != nil {
+			return err
+		}
+	}
+	if buildLogPathname == "" {
+		return nil
+	}
+	var buildInfo BuildInfo
+	if buildInfoPathname != "" {
+		if err := json.ReadFromFile(buildInfoPathname, &buildInfo); err != nil {
+			return err
+		}
+	}
+	if fi, err := os.Stat(buildLogPathname); err != nil {
+		return err
+	} else {
+		return fn(dirname, buildInfo, uint64(fi.Size()), fi.ModTime())
+	}
+}
+
+func (s *directoryStorage) put(imageName string, buildInfo BuildInfo,
+	buildLog []byte) error {
+	dirname := filepath.Join(s.topdir, imageName)
+	if err := os.MkdirAll(filepath.Dir(dirname), fsutil.DirPerms); err != nil {
+		return err
+	}
+	if err := os.Mkdir(dirname, fsutil.DirPerms); err != nil {
+		return err
+	}
+	doDelete := true
+	defer func() {
+		if doDelete {
+			os.RemoveAll(dirname)
+		}
+	}()
+	err := json.WriteToFile(filepath.Join(dirname, "buildInfo"),
+		fsutil.PublicFilePerms, "    ", buildInfo)
+	if err != nil {
+		return err
+	}
+	logfile := filepath.Join(dirname, "buildLog")
+	err = ioutil.WriteFile(logfile, buildLog, fsutil.PublicFilePerms)
+	if err != nil {
+		return err
+	}
+	doDelete = false
+	return nil
+}
+
+func (s *directoryStorage) sizeIncrement() uint64 {
+	return s.fileSizeIncrement
+}
@@ -2,7 +2,6 @@ package logarchiver
 
 import (
 	"io"
-	"os"
 	"path/filepath"
 )
 
@@ -69,7 +68,7 @@ func (a *buildLogArchiver) GetBuildInfosForStream(streamName string,
 
 func (a *buildLogArchiver) GetBuildLog(imageName string) (
 	io.ReadCloser, error) {
-	return os.Open(filepath.Join(a.options.Topdir, imageName, "buildLog"))
+	return a.storage.getBuildLog(imageName)
 }
 
 func (a *buildLogArchiver) GetSummary() *Summary {
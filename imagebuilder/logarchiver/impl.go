@@ -3,27 +3,23 @@ package logarchiver
 import (
 	"container/list"
 	"fmt"
-	"io/ioutil"
-	"os"
+	"io"
 	"path/filepath"
 	"sort"
 	"sync"
 	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/format"
-	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
-	"github.com/Cloud-Foundations/Dominator/lib/json"
-	"github.com/Cloud-Foundations/Dominator/lib/wsyscall"
 )
 
 type buildLogArchiver struct {
-	options           BuildLogArchiveOptions
-	params            BuildLogArchiveParams
-	fileSizeIncrement uint64
-	mutex             sync.Mutex                  // Lock everything below.
-	ageList           list.List                   // Oldest first.
-	imageStreams      map[string]*imageStreamType // Key: stream name.
-	totalSize         uint64
+	options      BuildLogArchiveOptions
+	params       BuildLogArchiveParams
+	storage      storage
+	mutex        sync.Mutex                  // Lock everything below.
+	ageList      list.List                   // Oldest first.
+	imageStreams map[string]*imageStreamType // Key: stream name.
+	totalSize    uint64
 }
 
 type imageStreamType struct {
@@ -31,6 +27,16 @@ type imageStreamType struct {
 	name   string
 }
 
+// storage is the backend which stores the build information and logs.
+type storage interface {
+	delete(imageName string) error
+	getBuildLog(imageName string) (io.ReadCloser, error)
+	load(fn func(imageName string, buildInfo BuildInfo, logSize uint64,
+		modTime time.Time) error) error
+	put(imageName string, buildInfo BuildInfo, buildLog []byte) error
+	sizeIncrement() uint64
+}
+
 type imageType struct {
 	ageListElement *list.Element
 	buildInfo      BuildInfo
@@ -55,11 +61,18 @@ func newBuildLogArchive(options BuildLogArchiveOptions,
 		options:      options,
 		params:       params,
 	}
-	if err := archive.computeFileSizeIncrement(); err != nil {
-		return nil, fmt.Errorf("error computing file size increment: %s", err)
+	if params.ObjectStore != nil {
+		archive.storage = newObjectStorage(params.ObjectStore)
+	} else {
+		storage, err := newDirectoryStorage(options.Topdir)
+		if err != nil {
+			return nil,
+				fmt.Errorf("error computing file size increment: %s", err)
+		}
+		archive.storage = storage
 	}
 	startTime := time.Now()
-	if err := archive.load(""); err != nil {
+	if err := archive.load(); err != nil {
 		return nil, err
 	}
 	loadedTime := time.Now()
@@ -70,6 +83,10 @@ func newBuildLogArchive(options BuildLogArchiveOptions,
 		format.FormatBytes(archive.totalSize),
 		format.Duration(loadedTime.Sub(startTime)),
 		format.Duration(sortedTime.Sub(loadedTime)))
+	archive.enforceAllRetentionPolicies()
+	if archive.haveMaxAge() {
+		go archive.retentionLoop()
+	}
 	return archive, nil
 }
 
@@ -102,7 +119,8 @@ func (a *buildLogArchiver) addEntryWithCheck(image *imageType,
 	name string) error {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
-	for a.imageTotalSize(image)+a.totalSize < a.options.Quota {
+	if a.options.Quota < 1 ||
+		a.imageTotalSize(image)+a.totalSize < a.options.Quota {
 		a.addEntry(image, name, true)
 		return nil
 	}
@@ -113,11 +131,9 @@ func (a *buildLogArchiver) addEntryWithCheck(image *imageType,
 	var deletedLogs uint
 	origTotalSize := a.totalSize
 	for a.totalSize > targetSize {
-		oldestElement := a.ageList.Front()
-		if err := a.deleteEntry(oldestElement); err != nil {
+		if err := a.deleteEntry(a.ageList.Front()); err != nil {
 			return err
 		}
-		a.ageList.Remove(oldestElement)
 		deletedLogs++
 	}
 	a.params.Logger.Printf("Deleted %d archived build logs consuming %s\n",
@@ -128,122 +144,57 @@ func (a *buildLogArchiver) addEntryWithCheck(image *imageType,
 
 func (a *buildLogArchiver) AddBuildLog(imageName string, buildInfo BuildInfo,
 	buildLog []byte) error {
-	dirname := filepath.Join(a.options.Topdir, imageName)
-	if err := os.MkdirAll(filepath.Dir(dirname), fsutil.DirPerms); err != nil {
-		return err
-	}
-	if err := os.Mkdir(dirname, fsutil.DirPerms); err != nil {
-		return err
-	}
-	doDelete := true
-	defer func() {
-		if doDelete {
-			os.RemoveAll(dirname)
-		}
-	}()
-	err := json.WriteToFile(filepath.Join(dirname, "buildInfo"),
-		fsutil.PublicFilePerms, "    ", buildInfo)
-	if err != nil {
-		return err
-	}
-	logfile := filepath.Join(dirname, "buildLog")
-	err = ioutil.WriteFile(logfile, buildLog, fsutil.PublicFilePerms)
-	if err != nil {
+	if err := a.storage.put(imageName, buildInfo, buildLog); err != nil {
 		return err
 	}
 	image := a.makeEntry(buildInfo, uint64(len(buildLog)), time.Now(),
 		imageName)
 	if err := a.addEntryWithCheck(image, imageName); err != nil {
+		a.storage.delete(imageName)
 		return err
 	}
-	doDelete = false
 	a.params.Logger.Debugf(0, "Archived build log for: %s, %s (%s total)\n",
 		imageName, format.FormatBytes(a.imageTotalSize(image)),
 		format.FormatBytes(a.totalSize))
+	a.enforceRetentionPolicy(filepath.Dir(imageName))
 	return nil
 }
 
-func (a *buildLogArchiver) computeFileSizeIncrement() error {
-	if err := os.MkdirAll(a.options.Topdir, fsutil.DirPerms); err != nil {
-		return err
-	}
-	file, err := ioutil.TempFile(a.options.Topdir, "******")
+// deleteEntry deletes the image from storage, the image stream and the
+// ageList.
+// No lock is taken.
+func (a *buildLogArchiver) deleteEntry(element *list.Element) error {
+	image := element.Value.(*imageType)
+	imageStream := image.imageStream
+	err := a.storage.delete(filepath.Join(imageStream.name, image.name))
 	if err != nil {
 		return err
 	}
-	filename := file.Name()
-	defer os.Remove(filename)
-	if _, err := file.Write([]byte{'\n'}); err != nil {
-		file.Close()
-		return err
-	}
-	if err := file.Close(); err != nil {
-		return err
-	}
-	var statbuf wsyscall.Stat_t
-	if err := wsyscall.Stat(filename, &statbuf); err != nil {
-		return err
-	}
-	if statbuf.Blocks < 1 {
-		statbuf.Blocks = 1
-	}
-	a.fileSizeIncrement = uint64(statbuf.Blocks) * 512
+	a.removeEntry(element)
 	return nil
 }
 
-func (a *buildLogArchiver) deleteEntry(element *list.Element) error {
+// removeEntry removes the image from the image stream and the ageList,
+// leaving the build log in storage.
+// No lock is taken.
+func (a *buildLogArchiver) removeEntry(element *list.Element) {
 	image := element.Value.(*imageType)
-	imageStream := image.imageStream
-	dirname := filepath.Join(a.options.Topdir, imageStream.name, image.name)
-	if err := os.RemoveAll(dirname); err != nil {
-		return err
-	}
-	delete(imageStream.images, image.name)
+	delete(image.imageStream.images, image.name)
 	a.totalSize -= a.imageTotalSize(image)
-	return nil
+	a.ageList.Remove(element)
 }
 
 func (a *buildLogArchiver) imageTotalSize(image *imageType) uint64 {
-	return image.logSize + a.fileSizeIncrement
+	return image.logSize + a.storage.sizeIncrement()
 }
 
-func (a *buildLogArchiver) load(dirname string) error {
-	dirpath := filepath.Join(a.options.Topdir, dirname)
-	names, err := fsutil.ReadDirnames(dirpath, false)
-	if err != nil {
-		return err
-	}
-	var buildInfoPathname, buildLogPathname string
-	for _, name := range names {
-		switch name {
-		case "buildInfo":
-			buildInfoPathname = filepath.Join(dirpath, name)
-			continue
-		case "buildLog":
-			buildLogPathname = filepath.Join(dirpath, name)
-			continue
-		}
-		if err := a.load(filepath.Join(dirname, name)); err != nil {
-			return err
-		}
-	}
-	if buildLogPathname == "" {
+func (a *buildLogArchiver) load() error {
+	return a.storage.load(func(imageName string, buildInfo BuildInfo,
+		logSize uint64, modTime time.Time) error {
+		image := a.makeEntry(buildInfo, logSize, modTime, imageName)
+		a.addEntry(image, imageName, false)
 		return nil
-	}
-	var buildInfo BuildInfo
-	if buildInfoPathname != "" {
-		if err := json.ReadFromFile(buildInfoPathname, &buildInfo); err != nil {
-			return err
-		}
-	}
-	if fi, err := os.Stat(buildLogPathname); err != nil {
-		return err
-	} else {
-		image := a.makeEntry(buildInfo, uint64(fi.Size()), fi.ModTime(),
-			dirname)
-		a.addEntry(image, dirname, false)
-	}
-	return nil
+	})
 }
 
 func (a *buildLogArchiver) makeAgeList() {
@@ -266,7 +217,7 @@ func (a *buildLogArchiver) makeEntry(buildInfo BuildInfo, logSize uint64,
 	modTime time.Time, name string) *imageType {
 	image := &imageType{
 		buildInfo: buildInfo,
-		logSize:   roundUp(logSize, a.fileSizeIncrement),
+		logSize:   roundUp(logSize, a.storage.sizeIncrement()),
 		modTime:   modTime,
 		name:      filepath.Base(name),
 	}
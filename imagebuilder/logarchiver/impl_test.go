@@ -0,0 +1,62 @@
+package logarchiver
+
+import (
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
+)
+
+func TestObjectStoreRetentionAndSearch(t *testing.T) {
+	store := NewMemoryObjectStore()
+	logger := testlogger.New(t)
+	archiver, err := New(
+		BuildLogArchiveOptions{
+			RetentionPolicies: map[string]RetentionPolicy{
+				"":        {MaxBuilds: 2},
+				"keep/me": {MaxBuilds: 10},
+			},
+		},
+		BuildLogArchiveParams{Logger: logger, ObjectStore: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a/s/1", "a/s/2", "a/s/3", "keep/me/1",
+		"keep/me/2", "keep/me/3"} {
+		err := archiver.AddBuildLog(name, BuildInfo{RequestorUsername: "u"},
+			[]byte("line one\nerror: "+name+"\nline three\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(archiver.GetBuildInfosForStream("a/s", true,
+		true).Builds); n != 2 {
+		t.Errorf("stream a/s has %d builds, want 2", n)
+	}
+	if n := len(archiver.GetBuildInfosForStream("keep/me", true,
+		true).Builds); n != 3 {
+		t.Errorf("stream keep/me has %d builds, want 3", n)
+	}
+	// Reload from the store to check persistence.
+	archiver, err = New(BuildLogArchiveOptions{},
+		BuildLogArchiveParams{Logger: logger, ObjectStore: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := archiver.SearchBuildLogs(proto.SearchBuildLogsRequest{
+		Pattern:    "^error: .*/[23]$",
+		StreamName: "a",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	for _, result := range results {
+		if result.NumMatches != 1 || len(result.Matches) != 1 ||
+			result.Matches[0].LineNumber != 2 {
+			t.Errorf("unexpected result: %v", result)
+		}
+	}
+}
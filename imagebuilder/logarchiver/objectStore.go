@@ -0,0 +1,122 @@
+package logarchiver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	buildInfoObject = "buildInfo"
+	buildLogObject  = "buildLog"
+)
+
+type objectStorage struct {
+	store ObjectStore
+}
+
+type loadedObjects struct {
+	haveBuildInfo bool
+	log           *ObjectInfo
+}
+
+func newObjectStorage(store ObjectStore) *objectStorage {
+	return &objectStorage{store: store}
+}
+
+func (s *objectStorage) delete(imageName string) error {
+	err := s.store.DeleteObject(path.Join(imageName, buildInfoObject))
+	if err != nil {
+		return err
+	}
+	return s.store.DeleteObject(path.Join(imageName, buildLogObject))
+}
+
+func (s *objectStorage) getBuildLog(imageName string) (io.ReadCloser, error) {
+	return s.store.GetObject(path.Join(imageName, buildLogObject))
+}
+
+func (s *objectStorage) load(fn func(imageName string, buildInfo BuildInfo,
+	logSize uint64, modTime time.Time) error) error {
+	images := make(map[string]*loadedObjects)
+	err := s.store.ListObjects(func(object ObjectInfo) error {
+		index := strings.LastIndexByte(object.Key, '/')
+		if index < 1 {
+			return nil
+		}
+		imageName := object.Key[:index]
+		image := images[imageName]
+		if image == nil {
+			image = &loadedObjects{}
+			images[imageName] = image
+		}
+		switch object.Key[index+1:] {
+		case buildInfoObject:
+			image.haveBuildInfo = true
+		case buildLogObject:
+			image.log = &object
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for imageName, image := range images {
+		if image.log == nil {
+			continue
+		}
+		var buildInfo BuildInfo
+		if image.haveBuildInfo {
+			if err := s.readBuildInfo(imageName, &buildInfo); err != nil {
+				return err
+			}
+		}
+		err := fn(imageName, buildInfo, image.log.Size, image.log.ModTime)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *objectStorage) put(imageName string, buildInfo BuildInfo,
+	buildLog []byte) error {
+	data, err := json.MarshalIndent(buildInfo, "", "    ")
+	if err != nil {
+		return err
+	}
+	err = s.store.PutObject(path.Join(imageName, buildInfoObject),
+		append(data, '\n'))
+	if err != nil {
+		return err
+	}
+	err = s.store.PutObject(path.Join(imageName, buildLogObject), buildLog)
+	if err != nil {
+		s.store.DeleteObject(path.Join(imageName, buildInfoObject))
+		return err
+	}
+	return nil
+}
+
+func (s *objectStorage) readBuildInfo(imageName string,
+	buildInfo *BuildInfo) error {
+	key := path.Join(imageName, buildInfoObject)
+	reader, err := s.store.GetObject(key)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	if err := json.NewDecoder(reader).Decode(buildInfo); err != nil {
+		return fmt.Errorf("error decoding: %s: %s", key, err)
+	}
+	return nil
+}
+
+// The size of the build information object is small compared to the log and
+// storage is not allocated in blocks, so no rounding is done.
+func (s *objectStorage) sizeIncrement() uint64 {
+	return 1
+}
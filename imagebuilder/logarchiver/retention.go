@@ -0,0 +1,116 @@
+package logarchiver
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+)
+
+// getRetentionPolicy returns the retention policy for the specified stream,
+// using the policy with the longest matching prefix.
+func (a *buildLogArchiver) getRetentionPolicy(streamName string) (
+	RetentionPolicy, bool) {
+	var policy RetentionPolicy
+	matchLength := -1
+	for prefix, candidate := range a.options.RetentionPolicies {
+		if len(prefix) <= matchLength {
+			continue
+		}
+		if prefix == "" || streamName == prefix ||
+			strings.HasPrefix(streamName, prefix+"/") {
+			policy = candidate
+			matchLength = len(prefix)
+		}
+	}
+	return policy, matchLength >= 0
+}
+
+func (a *buildLogArchiver) enforceAllRetentionPolicies() {
+	if len(a.options.RetentionPolicies) < 1 {
+		return
+	}
+	a.mutex.Lock()
+	streamNames := make([]string, 0, len(a.imageStreams))
+	for streamName := range a.imageStreams {
+		streamNames = append(streamNames, streamName)
+	}
+	a.mutex.Unlock()
+	for _, streamName := range streamNames {
+		a.enforceRetentionPolicy(streamName)
+	}
+}
+
+// enforceRetentionPolicy deletes the build logs for the specified stream
+// which are older than the maximum age or exceed the maximum number of builds.
+func (a *buildLogArchiver) enforceRetentionPolicy(streamName string) {
+	policy, ok := a.getRetentionPolicy(streamName)
+	if !ok || (policy.MaxAge <= 0 && policy.MaxBuilds < 1) {
+		return
+	}
+	imageNames, deletedSize := a.removeExpiredEntries(streamName, policy)
+	var deletedLogs uint
+	for _, imageName := range imageNames {
+		if err := a.storage.delete(imageName); err != nil {
+			a.params.Logger.Printf("Error deleting build log for: %s: %s\n",
+				imageName, err)
+			continue
+		}
+		deletedLogs++
+	}
+	if deletedLogs > 0 {
+		a.params.Logger.Debugf(0,
+			"Retention policy for: %s deleted %d build logs consuming %s\n",
+			streamName, deletedLogs, format.FormatBytes(deletedSize))
+	}
+}
+
+func (a *buildLogArchiver) haveMaxAge() bool {
+	for _, policy := range a.options.RetentionPolicies {
+		if policy.MaxAge > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *buildLogArchiver) retentionLoop() {
+	for range time.Tick(time.Hour) {
+		a.enforceAllRetentionPolicies()
+	}
+}
+
+// removeExpiredEntries removes the entries for the specified stream which are
+// older than the maximum age or exceed the maximum number of builds. The names
+// of the images are returned, so that their build logs may be deleted from
+// storage without holding the lock, along with the total size.
+func (a *buildLogArchiver) removeExpiredEntries(streamName string,
+	policy RetentionPolicy) ([]string, uint64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	imageStream := a.imageStreams[streamName]
+	if imageStream == nil {
+		return nil, 0
+	}
+	images := make([]*imageType, 0, len(imageStream.images))
+	for _, image := range imageStream.images {
+		images = append(images, image)
+	}
+	// Sort so that newest mtime is the first slice entry.
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].modTime.After(images[j].modTime)
+	})
+	var imageNames []string
+	origTotalSize := a.totalSize
+	for index, image := range images {
+		if (policy.MaxBuilds < 1 || uint(index) < policy.MaxBuilds) &&
+			(policy.MaxAge <= 0 || time.Since(image.modTime) <= policy.MaxAge) {
+			continue
+		}
+		a.removeEntry(image.ageListElement)
+		imageNames = append(imageNames, filepath.Join(streamName, image.name))
+	}
+	return imageNames, origTotalSize - a.totalSize
+}
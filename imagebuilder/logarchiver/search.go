@@ -0,0 +1,109 @@
+package logarchiver
+
+import (
+	"bufio"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
+)
+
+const (
+	defaultMaxMatchesPerLog = 10
+	defaultMaxResults       = 100
+	maxMatchLineLength      = 512
+)
+
+func truncateLine(line string) string {
+	if len(line) <= maxMatchLineLength {
+		return line
+	}
+	return line[:maxMatchLineLength] + "..."
+}
+
+func (a *buildLogArchiver) SearchBuildLogs(
+	request proto.SearchBuildLogsRequest) (
+	[]proto.BuildLogSearchResult, error) {
+	regex, err := regexp.Compile(request.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	if request.MaxMatchesPerLog < 1 {
+		request.MaxMatchesPerLog = defaultMaxMatchesPerLog
+	}
+	if request.MaxResults < 1 {
+		request.MaxResults = defaultMaxResults
+	}
+	var candidates []proto.BuildLogSearchResult
+	var earliestTime time.Time
+	if request.MaxAge > 0 {
+		earliestTime = time.Now().Add(-request.MaxAge)
+	}
+	a.mutex.Lock()
+	for element := a.ageList.Back(); element != nil; element = element.Prev() {
+		image := element.Value.(*imageType)
+		if image.modTime.Before(earliestTime) {
+			break
+		}
+		streamName := image.imageStream.name
+		if request.StreamName != "" && streamName != request.StreamName &&
+			!strings.HasPrefix(streamName, request.StreamName+"/") {
+			continue
+		}
+		candidates = append(candidates, proto.BuildLogSearchResult{
+			BuildTime:         image.modTime,
+			Duration:          image.buildInfo.Duration,
+			Error:             image.buildInfo.Error,
+			ImageName:         filepath.Join(streamName, image.name),
+			RequestorUsername: image.buildInfo.RequestorUsername,
+		})
+	}
+	a.mutex.Unlock()
+	var results []proto.BuildLogSearchResult
+	for _, result := range candidates {
+		if err := a.searchBuildLog(&result, regex,
+			request.MaxMatchesPerLog); err != nil {
+			// The log may have been deleted since the candidates were found.
+			a.params.Logger.Debugf(1, "Error searching build log for: %s: %s\n",
+				result.ImageName, err)
+			continue
+		}
+		if result.NumMatches < 1 {
+			continue
+		}
+		results = append(results, result)
+		if uint(len(results)) >= request.MaxResults {
+			break
+		}
+	}
+	return results, nil
+}
+
+func (a *buildLogArchiver) searchBuildLog(result *proto.BuildLogSearchResult,
+	regex *regexp.Regexp, maxMatches uint) error {
+	reader, err := a.storage.getBuildLog(result.ImageName)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	var lineNumber uint
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if !regex.MatchString(line) {
+			continue
+		}
+		result.NumMatches++
+		if uint(len(result.Matches)) < maxMatches {
+			result.Matches = append(result.Matches, proto.BuildLogMatch{
+				Line:       truncateLine(line),
+				LineNumber: lineNumber,
+			})
+		}
+	}
+	return scanner.Err()
+}
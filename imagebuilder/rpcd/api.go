@@ -32,10 +32,17 @@ func Setup(builder *builder.Builder, logger log.Logger) (*htmlWriter, error) {
 	}
 	srpc.RegisterNameWithOptions("Imaginator", srpcObj,
 		srpc.ReceiverOptions{
+			AuditedMethods: []string{
+				"BuildImage",
+				"DisableAutoBuilds",
+				"DisableBuildRequests",
+				"ReplaceIdleSlaves",
+			},
 			PublicMethods: []string{
 				"BuildImage",
 				"GetDependencies",
 				"GetDirectedGraph",
+				"SearchBuildLogs",
 			}})
 	return (*htmlWriter)(srpcObj), nil
 }
@@ -0,0 +1,18 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
+)
+
+func (t *srpcType) SearchBuildLogs(conn *srpc.Conn,
+	request proto.SearchBuildLogsRequest,
+	reply *proto.SearchBuildLogsResponse) error {
+	if results, err := t.builder.SearchBuildLogs(request); err != nil {
+		reply.Error = errors.ErrorToString(err)
+	} else {
+		reply.Results = results
+	}
+	return nil
+}
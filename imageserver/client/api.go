@@ -100,3 +100,9 @@ func ListUnreferencedObjects(client srpc.ClientI) (
 func MakeDirectory(client srpc.ClientI, dirname string) error {
 	return makeDirectory(client, dirname)
 }
+
+func ScanImageVulnerabilities(client srpc.ClientI,
+	request proto.ScanImageVulnerabilitiesRequest) (
+	proto.ScanImageVulnerabilitiesResponse, error) {
+	return scanImageVulnerabilities(client, request)
+}
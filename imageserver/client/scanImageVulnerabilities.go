@@ -0,0 +1,19 @@
+package client
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+func scanImageVulnerabilities(client srpc.ClientI,
+	request imageserver.ScanImageVulnerabilitiesRequest) (
+	imageserver.ScanImageVulnerabilitiesResponse, error) {
+	var reply imageserver.ScanImageVulnerabilitiesResponse
+	err := client.RequestReply("ImageServer.ScanImageVulnerabilities",
+		request, &reply)
+	if err != nil {
+		return reply, err
+	}
+	return reply, errors.New(reply.Error)
+}
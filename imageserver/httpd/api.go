@@ -9,6 +9,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/imageserver/scanner"
 	"github.com/Cloud-Foundations/Dominator/lib/html"
 	"github.com/Cloud-Foundations/Dominator/lib/objectserver/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 )
 
 type HtmlWriter interface {
@@ -38,8 +39,10 @@ func StartServer(portNum uint, imdb *scanner.ImageDataBase,
 	html.HandleFunc("/listImages", myState.listImagesHandler)
 	html.HandleFunc("/listPackages", myState.listPackagesHandler)
 	html.HandleFunc("/listReleaseNotes", myState.listReleaseNotesHandler)
+	html.HandleFunc("/listSBOM", myState.listSBOMHandler)
 	html.HandleFunc("/listTriggers", myState.listTriggersHandler)
 	html.HandleFunc("/showImage", myState.showImageHandler)
+	listener = srpc.NewTlsSniffingListener(listener)
 	if daemon {
 		go http.Serve(listener, nil)
 	} else {
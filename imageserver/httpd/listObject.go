@@ -5,10 +5,10 @@ import (
 	"io"
 
 	"github.com/Cloud-Foundations/Dominator/lib/hash"
-	"github.com/Cloud-Foundations/Dominator/lib/objectserver/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/objectserver"
 )
 
-func listObject(writer io.Writer, objSrv *filesystem.ObjectServer,
+func listObject(writer io.Writer, objSrv objectserver.ObjectGetter,
 	hashP *hash.Hash) {
 	_, reader, err := objSrv.GetObject(*hashP)
 	if err != nil {
@@ -0,0 +1,38 @@
+package httpd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/objectserver"
+)
+
+func (s state) listSBOMHandler(w http.ResponseWriter, req *http.Request) {
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	imageName := req.URL.RawQuery
+	listSBOM(writer, imageName, s.imageDataBase.GetImage(imageName),
+		s.objectServer)
+}
+
+func listSBOM(writer io.Writer, imageName string, img *image.Image,
+	objSrv objectserver.ObjectGetter) {
+	fmt.Fprintf(writer, "<title>image %s</title>\n", imageName)
+	fmt.Fprintln(writer, "<body>")
+	fmt.Fprintln(writer, "<h3>")
+	if img == nil {
+		fmt.Fprintf(writer, "Image: %s UNKNOWN!\n", imageName)
+		return
+	}
+	if img.SBOM == nil || img.SBOM.Object == nil {
+		fmt.Fprintf(writer, "No SBOM data for image: %s\n", imageName)
+		return
+	}
+	fmt.Fprintf(writer, "SBOM for image: %s<br>\n", imageName)
+	fmt.Fprintln(writer, "</h3>")
+	listObject(writer, objSrv, img.SBOM.Object)
+	fmt.Fprintln(writer, "</body>")
+}
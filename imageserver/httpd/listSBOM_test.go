@@ -0,0 +1,39 @@
+package httpd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/objectserver/memory"
+)
+
+func TestListSBOM(t *testing.T) {
+	objSrv := memory.NewObjectServer()
+	data := []byte(`{"spdxVersion": "SPDX-2.3"}`)
+	hashVal, _, err := objSrv.AddObject(bytes.NewReader(data),
+		uint64(len(data)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := &bytes.Buffer{}
+	listSBOM(buffer, "test", &image.Image{
+		SBOM: &image.Annotation{Object: &hashVal},
+	}, objSrv)
+	if output := buffer.String(); !strings.Contains(output,
+		"<pre>\n"+string(data)+"</pre>") {
+		t.Errorf("SBOM not in output: %s", output)
+	}
+	buffer.Reset()
+	listSBOM(buffer, "test", &image.Image{}, objSrv)
+	if output := buffer.String(); !strings.Contains(output,
+		"No SBOM data for image: test") {
+		t.Errorf("missing SBOM not reported: %s", output)
+	}
+	buffer.Reset()
+	listSBOM(buffer, "test", nil, objSrv)
+	if output := buffer.String(); !strings.Contains(output, "UNKNOWN") {
+		t.Errorf("unknown image not reported: %s", output)
+	}
+}
@@ -63,6 +63,7 @@ func (s state) showImageHandler(w http.ResponseWriter, req *http.Request) {
 		"listReleaseNotes")
 	showAnnotation(writer, img.BuildLog, imageName, "Build log",
 		"listBuildLog")
+	showAnnotation(writer, img.SBOM, imageName, "SBOM", "listSBOM")
 	if img.CreatedBy != "" {
 		fmt.Fprintf(writer, "Created by: %s\n<br>", img.CreatedBy)
 	}
@@ -5,11 +5,13 @@ import (
 	"flag"
 	"io"
 	"sync"
+	"time"
 
 	"github.com/Cloud-Foundations/Dominator/imageserver/scanner"
 	"github.com/Cloud-Foundations/Dominator/lib/filter"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/objectserver"
+	"github.com/Cloud-Foundations/Dominator/lib/osv"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 )
 
@@ -22,22 +24,30 @@ var (
 		"Filename containing filter to exclude images from replication (default do not exclude any)")
 	replicationIncludeFilter = flag.String("replicationIncludeFilter", "",
 		"Filename containing filter to include images for replication (default include all)")
+	vulnerabilityFeedDirectory = flag.String("vulnerabilityFeedDirectory",
+		"", "Directory containing OSV vulnerability feed (default none)")
+	vulnerabilityFeedReloadInterval = flag.Duration(
+		"vulnerabilityFeedReloadInterval", time.Hour,
+		"Interval between reloads of the vulnerability feed")
 )
 
 type srpcType struct {
-	imageDataBase             *scanner.ImageDataBase
-	excludeFilter             *filter.Filter
-	finishedReplication       <-chan struct{} // Closed when finished.
-	includeFilter             *filter.Filter
-	replicationMaster         string
-	imageserverResource       *srpc.ClientResource
-	objSrv                    objectserver.FullObjectServer
-	archiveMode               bool
-	logger                    log.DebugLogger
-	numReplicationClientsLock sync.RWMutex // Protect numReplicationClients.
-	numReplicationClients     uint
-	imagesBeingInjectedLock   sync.Mutex // Protect imagesBeingInjected.
-	imagesBeingInjected       map[string]struct{}
+	imageDataBase                 *scanner.ImageDataBase
+	excludeFilter                 *filter.Filter
+	finishedReplication           <-chan struct{} // Closed when finished.
+	includeFilter                 *filter.Filter
+	replicationMaster             string
+	imageserverResource           *srpc.ClientResource
+	objSrv                        objectserver.FullObjectServer
+	archiveMode                   bool
+	logger                        log.DebugLogger
+	numReplicationClientsLock     sync.RWMutex // Protect numReplicationClients.
+	numReplicationClients         uint
+	imagesBeingInjectedLock       sync.Mutex // Protect imagesBeingInjected.
+	imagesBeingInjected           map[string]struct{}
+	vulnerabilityDatabaseLock     sync.RWMutex // Protect vulnerability fields.
+	vulnerabilityDatabase         *osv.Database
+	vulnerabilityDatabaseLoadTime time.Time
 }
 
 type htmlWriter srpcType
@@ -80,6 +90,15 @@ func Setup(imdb *scanner.ImageDataBase, replicationMaster string,
 		}
 	}
 	srpc.RegisterNameWithOptions("ImageServer", srpcObj, srpc.ReceiverOptions{
+		AuditedMethods: []string{
+			"AddImage",
+			"AddImageTrusted",
+			"ChangeImageExpiration",
+			"ChownDirectory",
+			"DeleteImage",
+			"DeleteUnreferencedObjects",
+			"MakeDirectory",
+		},
 		PublicMethods: []string{
 			"ChangeImageExpiration",
 			"CheckDirectory",
@@ -96,7 +115,12 @@ func Setup(imdb *scanner.ImageDataBase, replicationMaster string,
 			"ListDirectories",
 			"ListImages",
 			"ListSelectedImages",
+			"ScanImageVulnerabilities",
 		}})
+	if *vulnerabilityFeedDirectory != "" {
+		go srpcObj.vulnerabilityFeedLoader(*vulnerabilityFeedDirectory,
+			*vulnerabilityFeedReloadInterval)
+	}
 	if replicationMaster != "" {
 		go srpcObj.replicator(finishedReplication)
 	} else {
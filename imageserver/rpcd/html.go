@@ -3,6 +3,9 @@ package rpcd
 import (
 	"fmt"
 	"io"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/format"
 )
 
 func (hw *htmlWriter) writeHtml(writer io.Writer) {
@@ -12,6 +15,15 @@ func (hw *htmlWriter) writeHtml(writer io.Writer) {
 	}
 	fmt.Fprintf(writer, "Replication clients: %d<br>\n",
 		hw.getNumReplicationClients())
+	hw.vulnerabilityDatabaseLock.RLock()
+	db := hw.vulnerabilityDatabase
+	loadTime := hw.vulnerabilityDatabaseLoadTime
+	hw.vulnerabilityDatabaseLock.RUnlock()
+	if db != nil {
+		fmt.Fprintf(writer,
+			"Vulnerability feed: %d vulnerabilities, loaded %s ago<br>\n",
+			db.NumVulnerabilities(), format.Duration(time.Since(loadTime)))
+	}
 }
 
 func (hw *htmlWriter) getNumReplicationClients() uint {
@@ -0,0 +1,120 @@
+package rpcd
+
+import (
+	"errors"
+	"time"
+
+	liberrors "github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/osv"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
+)
+
+var osReleaseFiles = [][]string{
+	{"etc", "os-release"},
+	{"usr", "lib", "os-release"},
+}
+
+func (t *srpcType) ScanImageVulnerabilities(conn *srpc.Conn,
+	request imageserver.ScanImageVulnerabilitiesRequest,
+	reply *imageserver.ScanImageVulnerabilitiesResponse) error {
+	response, err := t.scanImageVulnerabilities(request)
+	response.Error = liberrors.ErrorToString(err)
+	*reply = response
+	return nil
+}
+
+func (t *srpcType) scanImageVulnerabilities(
+	request imageserver.ScanImageVulnerabilitiesRequest) (
+	imageserver.ScanImageVulnerabilitiesResponse, error) {
+	var response imageserver.ScanImageVulnerabilitiesResponse
+	db := t.getVulnerabilityDatabase()
+	if db == nil {
+		return response, errors.New("no vulnerability feed loaded")
+	}
+	img := t.imageDataBase.GetImage(request.ImageName)
+	if img == nil {
+		return response, errors.New("image not found")
+	}
+	if len(img.Packages) < 1 {
+		return response, errors.New("image has no package data")
+	}
+	response.Ecosystem = request.Ecosystem
+	if response.Ecosystem == "" {
+		ecosystem, err := t.getImageEcosystem(img)
+		if err != nil {
+			return response, err
+		}
+		response.Ecosystem = ecosystem
+	}
+	response.Vulnerabilities = db.MatchPackages(response.Ecosystem,
+		img.Packages)
+	return response, nil
+}
+
+func (t *srpcType) getImageEcosystem(img *image.Image) (string, error) {
+	for _, pathComponents := range osReleaseFiles {
+		inode := lookupRegularInode(&img.FileSystem.DirectoryInode,
+			pathComponents)
+		if inode == nil || inode.Size < 1 {
+			continue
+		}
+		_, reader, err := t.objSrv.GetObject(inode.Hash)
+		if err != nil {
+			return "", err
+		}
+		defer reader.Close()
+		return osv.EcosystemFromOsRelease(reader)
+	}
+	return "", errors.New("no os-release file in image")
+}
+
+func lookupRegularInode(directory *filesystem.DirectoryInode,
+	pathComponents []string) *filesystem.RegularInode {
+	for index, name := range pathComponents {
+		var found filesystem.GenericInode
+		for _, dirent := range directory.EntryList {
+			if dirent.Name == name {
+				found = dirent.Inode()
+				break
+			}
+		}
+		if found == nil {
+			return nil
+		}
+		if index == len(pathComponents)-1 {
+			inode, _ := found.(*filesystem.RegularInode)
+			return inode
+		}
+		if directory, _ = found.(*filesystem.DirectoryInode); directory == nil {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (t *srpcType) getVulnerabilityDatabase() *osv.Database {
+	t.vulnerabilityDatabaseLock.RLock()
+	defer t.vulnerabilityDatabaseLock.RUnlock()
+	return t.vulnerabilityDatabase
+}
+
+func (t *srpcType) vulnerabilityFeedLoader(dirname string,
+	interval time.Duration) {
+	for {
+		if db, err := osv.LoadDirectory(dirname, t.logger); err != nil {
+			t.logger.Printf("error loading vulnerability feed: %s\n", err)
+		} else {
+			t.vulnerabilityDatabaseLock.Lock()
+			t.vulnerabilityDatabase = db
+			t.vulnerabilityDatabaseLoadTime = time.Now()
+			t.vulnerabilityDatabaseLock.Unlock()
+		}
+		if interval <= 0 {
+			return
+		}
+		time.Sleep(interval)
+	}
+}
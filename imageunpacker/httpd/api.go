@@ -8,6 +8,7 @@ import (
 
 	"github.com/Cloud-Foundations/Dominator/imageunpacker/unpacker"
 	"github.com/Cloud-Foundations/Dominator/lib/html"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 )
 
 type HtmlWriter interface {
@@ -30,6 +31,7 @@ func StartServer(portNum uint, unpackerObj *unpacker.Unpacker,
 	html.HandleFunc("/", myState.statusHandler)
 	html.HandleFunc("/showFileSystem", myState.showFileSystemHandler)
 	html.HandleFunc("/showStreamDashboard", myState.showStreamDashboardHandler)
+	listener = srpc.NewTlsSniffingListener(listener)
 	if daemon {
 		go http.Serve(listener, nil)
 	} else {
@@ -0,0 +1,55 @@
+// Package blobstore provides simple object (blob) stores, such as S3 buckets.
+package blobstore
+
+import (
+	"io"
+	"time"
+)
+
+type ObjectInfo struct {
+	Key     string
+	ModTime time.Time
+	Size    uint64
+}
+
+type S3Options struct {
+	Bucket   string
+	Endpoint string // Optional, for S3-compatible services.
+	Prefix   string // Prepended to keys.
+	Profile  string // Optional AWS profile name.
+	Region   string
+}
+
+// Store is a simple object (blob) store. Deleting an object which does not
+// exist is not an error.
+type Store interface {
+	DeleteObject(key string) error
+	GetObject(key string) (io.ReadCloser, error)
+	ListObjects(fn func(object ObjectInfo) error) error
+	PutObject(key string, data []byte) error
+}
+
+// NewDirectoryStore returns a Store which stores objects as files under topdir.
+// This is useful as a local stand-in for a remote object store.
+func NewDirectoryStore(topdir string) (Store, error) {
+	return newDirectoryStore(topdir)
+}
+
+// NewMemoryStore returns a Store which stores objects in memory. This is useful
+// for testing.
+func NewMemoryStore() Store {
+	return newMemoryStore()
+}
+
+// NewS3Store returns a Store which stores objects in an S3 (or S3-compatible)
+// bucket. Credentials are loaded using the standard AWS mechanisms.
+func NewS3Store(options S3Options) (Store, error) {
+	return newS3Store(options)
+}
+
+// NewSubStore returns a Store which stores objects in store, with prefix
+// prepended to the keys. Only objects with keys starting with prefix are
+// listed and the prefix is removed from their keys.
+func NewSubStore(store Store, prefix string) Store {
+	return newSubStore(store, prefix)
+}
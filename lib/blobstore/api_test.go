@@ -0,0 +1,68 @@
+package blobstore
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func listKeys(t *testing.T, store Store) []string {
+	var keys []string
+	err := store.ListObjects(func(object ObjectInfo) error {
+		keys = append(keys, object.Key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func testStore(t *testing.T, store Store) {
+	if err := store.PutObject("a/one", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	subStore := NewSubStore(store, "b/")
+	if err := subStore.PutObject("two", []byte("22")); err != nil {
+		t.Fatal(err)
+	}
+	if keys := listKeys(t, store); len(keys) != 2 {
+		t.Errorf("keys: %v, want 2 keys", keys)
+	}
+	keys := listKeys(t, subStore)
+	if len(keys) != 1 || keys[0] != "two" {
+		t.Errorf("sub-store keys: %v, want: [two]", keys)
+	}
+	reader, err := store.GetObject("b/two")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "22" {
+		t.Errorf("data: \"%s\", want: \"22\"", string(data))
+	}
+	if err := subStore.DeleteObject("two"); err != nil {
+		t.Fatal(err)
+	}
+	if err := subStore.DeleteObject("two"); err != nil {
+		t.Errorf("deleting missing object: %s", err)
+	}
+	if keys := listKeys(t, subStore); len(keys) != 0 {
+		t.Errorf("sub-store keys after delete: %v", keys)
+	}
+}
+
+func TestDirectoryStore(t *testing.T) {
+	store, err := NewDirectoryStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, store)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
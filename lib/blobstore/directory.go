@@ -0,0 +1,69 @@
+package blobstore
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+)
+
+type directoryStore struct {
+	topdir string
+}
+
+func newDirectoryStore(topdir string) (*directoryStore, error) {
+	if err := os.MkdirAll(topdir, fsutil.DirPerms); err != nil {
+		return nil, err
+	}
+	return &directoryStore{topdir: topdir}, nil
+}
+
+func (s *directoryStore) DeleteObject(key string) error {
+	err := os.Remove(s.getFilename(key))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *directoryStore) GetObject(key string) (io.ReadCloser, error) {
+	return os.Open(s.getFilename(key))
+}
+
+func (s *directoryStore) ListObjects(fn func(object ObjectInfo) error) error {
+	return filepath.Walk(s.topdir,
+		func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// Skip temporary files from incomplete writes.
+			if !fi.Mode().IsRegular() || strings.HasSuffix(path, "~") {
+				return nil
+			}
+			key, err := filepath.Rel(s.topdir, path)
+			if err != nil {
+				return err
+			}
+			return fn(ObjectInfo{
+				Key:     filepath.ToSlash(key),
+				ModTime: fi.ModTime(),
+				Size:    uint64(fi.Size()),
+			})
+		})
+}
+
+func (s *directoryStore) PutObject(key string, data []byte) error {
+	filename := s.getFilename(key)
+	if err := os.MkdirAll(filepath.Dir(filename), fsutil.DirPerms); err != nil {
+		return err
+	}
+	return fsutil.CopyToFile(filename, fsutil.PublicFilePerms,
+		bytes.NewReader(data), uint64(len(data)))
+}
+
+func (s *directoryStore) getFilename(key string) string {
+	return filepath.Join(s.topdir, filepath.FromSlash(filepath.Clean("/"+key)))
+}
@@ -0,0 +1,76 @@
+package blobstore
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+type memoryObject struct {
+	data    []byte
+	modTime time.Time
+}
+
+type memoryStore struct {
+	mutex   sync.Mutex
+	objects map[string]memoryObject // Key: object key.
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{objects: make(map[string]memoryObject)}
+}
+
+func (s *memoryStore) DeleteObject(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *memoryStore) GetObject(key string) (io.ReadCloser, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if object, ok := s.objects[key]; !ok {
+		return nil, fmt.Errorf("%s: %s", key, os.ErrNotExist)
+	} else {
+		return ioutil.NopCloser(bytes.NewReader(object.data)), nil
+	}
+}
+
+func (s *memoryStore) ListObjects(
+	fn func(object ObjectInfo) error) error {
+	s.mutex.Lock()
+	objects := make([]ObjectInfo, 0, len(s.objects))
+	for key, object := range s.objects {
+		objects = append(objects, ObjectInfo{
+			Key:     key,
+			ModTime: object.modTime,
+			Size:    uint64(len(object.data)),
+		})
+	}
+	s.mutex.Unlock()
+	sort.Slice(objects, func(left, right int) bool {
+		return objects[left].Key < objects[right].Key
+	})
+	for _, object := range objects {
+		if err := fn(object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) PutObject(key string, data []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.objects[key] = memoryObject{
+		data:    append([]byte(nil), data...),
+		modTime: time.Now(),
+	}
+	return nil
+}
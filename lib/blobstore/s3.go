@@ -0,0 +1,105 @@
+package blobstore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+type s3Store struct {
+	bucket string
+	client *s3.S3
+	prefix string
+}
+
+func newS3Store(options S3Options) (*s3Store, error) {
+	if options.Bucket == "" {
+		return nil, errors.New("no bucket specified")
+	}
+	config := aws.Config{}
+	if options.Region != "" {
+		config.Region = aws.String(options.Region)
+	}
+	if options.Endpoint != "" {
+		config.Endpoint = aws.String(options.Endpoint)
+		config.S3ForcePathStyle = aws.Bool(true)
+	}
+	awsSession, err := session.NewSessionWithOptions(session.Options{
+		Config:            config,
+		Profile:           options.Profile,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating session: %s", err)
+	}
+	prefix := strings.Trim(options.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &s3Store{
+		bucket: options.Bucket,
+		client: s3.New(awsSession),
+		prefix: prefix,
+	}, nil
+}
+
+func (s *s3Store) DeleteObject(key string) error {
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.prefix + key),
+	})
+	return err
+}
+
+func (s *s3Store) GetObject(key string) (io.ReadCloser, error) {
+	output, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.prefix + key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output.Body, nil
+}
+
+func (s *s3Store) ListObjects(fn func(object ObjectInfo) error) error {
+	var fnErr error
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.prefix),
+	}
+	err := s.client.ListObjectsV2Pages(input,
+		func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			for _, object := range page.Contents {
+				key := aws.StringValue(object.Key)
+				fnErr = fn(ObjectInfo{
+					Key:     strings.TrimPrefix(key, s.prefix),
+					ModTime: aws.TimeValue(object.LastModified),
+					Size:    uint64(aws.Int64Value(object.Size)),
+				})
+				if fnErr != nil {
+					return false
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return err
+	}
+	return fnErr
+}
+
+func (s *s3Store) PutObject(key string, data []byte) error {
+	_, err := s.client.PutObject(&s3.PutObjectInput{
+		Body:   bytes.NewReader(data),
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.prefix + key),
+	})
+	return err
+}
@@ -0,0 +1,42 @@
+package blobstore
+
+import (
+	"io"
+	"strings"
+)
+
+type subStore struct {
+	prefix string
+	store  Store
+}
+
+func newSubStore(store Store, prefix string) Store {
+	if s, ok := store.(*s3Store); ok { // Let S3 filter the listing.
+		newStore := *s
+		newStore.prefix += prefix
+		return &newStore
+	}
+	return &subStore{prefix: prefix, store: store}
+}
+
+func (s *subStore) DeleteObject(key string) error {
+	return s.store.DeleteObject(s.prefix + key)
+}
+
+func (s *subStore) GetObject(key string) (io.ReadCloser, error) {
+	return s.store.GetObject(s.prefix + key)
+}
+
+func (s *subStore) ListObjects(fn func(object ObjectInfo) error) error {
+	return s.store.ListObjects(func(object ObjectInfo) error {
+		if !strings.HasPrefix(object.Key, s.prefix) {
+			return nil
+		}
+		object.Key = object.Key[len(s.prefix):]
+		return fn(object)
+	})
+}
+
+func (s *subStore) PutObject(key string, data []byte) error {
+	return s.store.PutObject(s.prefix+key, data)
+}
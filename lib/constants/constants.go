@@ -1,17 +1,18 @@
 package constants
 
 const (
-	SubPortNumber                = 6969
-	DominatorPortNumber          = 6970
-	ImageServerPortNumber        = 6971
-	BasicFileGenServerPortNumber = 6972
-	SimpleMdbServerPortNumber    = 6973
-	ImageUnpackerPortNumber      = 6974
-	ImaginatorPortNumber         = 6975
-	HypervisorPortNumber         = 6976
-	FleetManagerPortNumber       = 6977
-	InstallerPortNumber          = 6978
-	DisruptionManagerPortNumber  = 6979
+	SubPortNumber                 = 6969
+	DominatorPortNumber           = 6970
+	ImageServerPortNumber         = 6971
+	BasicFileGenServerPortNumber  = 6972
+	SimpleMdbServerPortNumber     = 6973
+	ImageUnpackerPortNumber       = 6974
+	ImaginatorPortNumber          = 6975
+	HypervisorPortNumber          = 6976
+	FleetManagerPortNumber        = 6977
+	InstallerPortNumber           = 6978
+	DisruptionManagerPortNumber   = 6979
+	CachingObjectServerPortNumber = 6980
 
 	DefaultCpuPercent          = 50
 	DefaultNetworkSpeedPercent = 10
@@ -8,6 +8,7 @@ import (
 
 	"github.com/Cloud-Foundations/Dominator/lib/filegen"
 	"github.com/Cloud-Foundations/Dominator/lib/html"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 )
 
 type HtmlWriter interface {
@@ -28,6 +29,7 @@ func StartServer(portNum uint, manager *filegen.Manager, daemon bool) error {
 	myState := &state{manager}
 	html.HandleFunc("/", myState.statusHandler)
 	html.HandleFunc("/listGenerators", myState.listGeneratorsHandler)
+	listener = srpc.NewTlsSniffingListener(listener)
 	if daemon {
 		go http.Serve(listener, nil)
 	} else {
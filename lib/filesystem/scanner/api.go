@@ -33,6 +33,7 @@ type FileSystem struct {
 	scanFilter              *filter.Filter
 	checkScanDisableRequest func() bool
 	hasher                  Hasher
+	isDirty                 func(inodeNumber uint64) bool
 	dev                     uint64
 	inodeNumber             uint64
 	filesystem.FileSystem
@@ -55,7 +56,19 @@ func ScanFileSystem(rootDirectoryName string,
 	checkScanDisableRequest func() bool, hasher Hasher, oldFS *FileSystem) (
 	*FileSystem, error) {
 	return scanFileSystem(rootDirectoryName, fsScanContext, scanFilter,
-		checkScanDisableRequest, hasher, oldFS)
+		checkScanDisableRequest, hasher, oldFS, nil)
+}
+
+// ScanFileSystemIncremental is similar to ScanFileSystem, except that the
+// data of a regular file are only read and hashed if isDirty returns true for
+// the inode number or if the size or modification time differ from the inode
+// in oldFS. Otherwise the hash from oldFS is used.
+func ScanFileSystemIncremental(rootDirectoryName string,
+	fsScanContext *fsrateio.ReaderContext, scanFilter *filter.Filter,
+	checkScanDisableRequest func() bool, hasher Hasher, oldFS *FileSystem,
+	isDirty func(inodeNumber uint64) bool) (*FileSystem, error) {
+	return scanFileSystem(rootDirectoryName, fsScanContext, scanFilter,
+		checkScanDisableRequest, hasher, oldFS, isDirty)
 }
 
 func (fs *FileSystem) GetObject(hashVal hash.Hash) (
@@ -48,8 +48,8 @@ func makeSpecialInode(stat *wsyscall.Stat_t) *filesystem.SpecialInode {
 
 func scanFileSystem(rootDirectoryName string,
 	fsScanContext *fsrateio.ReaderContext, scanFilter *filter.Filter,
-	checkScanDisableRequest func() bool, hasher Hasher, oldFS *FileSystem) (
-	*FileSystem, error) {
+	checkScanDisableRequest func() bool, hasher Hasher, oldFS *FileSystem,
+	isDirty func(inodeNumber uint64) bool) (*FileSystem, error) {
 	if checkScanDisableRequest != nil && checkScanDisableRequest() {
 		return nil, errors.New("DisableScan")
 	}
@@ -58,6 +58,7 @@ func scanFileSystem(rootDirectoryName string,
 	fileSystem.fsScanContext = fsScanContext
 	fileSystem.scanFilter = scanFilter
 	fileSystem.checkScanDisableRequest = checkScanDisableRequest
+	fileSystem.isDirty = isDirty
 	if hasher == nil {
 		fileSystem.hasher = GetSimpleHasher(false)
 	} else {
@@ -224,7 +225,8 @@ func addRegularFile(dirent *filesystem.DirectoryEntry,
 		return errors.New("inode changed type: " + dirent.Name)
 	}
 	inode := makeRegularInode(stat)
-	if inode.Size > 0 {
+	if inode.Size > 0 && !copyRegularInodeHash(inode, fileSystem, oldFS,
+		stat.Ino) {
 		err := scanRegularInode(inode, fileSystem,
 			path.Join(directoryPathName, dirent.Name))
 		if err != nil {
@@ -245,6 +247,30 @@ func addRegularFile(dirent *filesystem.DirectoryEntry,
 	return nil
 }
 
+// copyRegularInodeHash will copy the hash from the old inode if the inode is
+// not dirty and the size and modification time are unchanged. It returns true
+// if the hash was copied.
+func copyRegularInodeHash(inode *filesystem.RegularInode,
+	fileSystem, oldFS *FileSystem, inodeNumber uint64) bool {
+	if fileSystem.isDirty == nil || fileSystem.isDirty(inodeNumber) {
+		return false
+	}
+	if oldFS == nil || oldFS.InodeTable == nil {
+		return false
+	}
+	oldInode, ok := oldFS.InodeTable[inodeNumber].(*filesystem.RegularInode)
+	if !ok {
+		return false
+	}
+	if inode.Size != oldInode.Size ||
+		inode.MtimeSeconds != oldInode.MtimeSeconds ||
+		inode.MtimeNanoSeconds != oldInode.MtimeNanoSeconds {
+		return false
+	}
+	inode.Hash = oldInode.Hash
+	return true
+}
+
 func addSymlink(dirent *filesystem.DirectoryEntry,
 	fileSystem, oldFS *FileSystem,
 	directoryPathName string, stat *wsyscall.Stat_t) error {
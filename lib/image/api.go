@@ -37,6 +37,7 @@ type Image struct {
 	Triggers      *triggers.Triggers
 	ReleaseNotes  *Annotation
 	BuildLog      *Annotation
+	SBOM          *Annotation // Software Bill of Materials (SPDX JSON).
 	CreatedOn     time.Time
 	ExpiresAt     time.Time
 	Packages      []Package
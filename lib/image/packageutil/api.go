@@ -14,3 +14,15 @@ func GetPackageList(packager func(cmd string, w io.Writer) error) (
 	[]image.Package, error) {
 	return getPackageList(packager)
 }
+
+// ParseApkInstalled will read an Alpine package database (typically
+// /lib/apk/db/installed) and will return the list of installed packages.
+func ParseApkInstalled(reader io.Reader) ([]image.Package, error) {
+	return parseApkInstalled(reader)
+}
+
+// ParseDpkgStatus will read a dpkg status file (typically
+// /var/lib/dpkg/status) and will return the list of installed packages.
+func ParseDpkgStatus(reader io.Reader) ([]image.Package, error) {
+	return parseDpkgStatus(reader)
+}
@@ -0,0 +1,107 @@
+package packageutil
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+)
+
+func sortPackages(packageMap map[string]image.Package) []image.Package {
+	packages := make([]image.Package, 0, len(packageMap))
+	for _, pkg := range packageMap {
+		packages = append(packages, pkg)
+	}
+	sort.Slice(packages, func(left, right int) bool {
+		return packages[left].Name < packages[right].Name
+	})
+	return packages
+}
+
+func parseApkInstalled(reader io.Reader) ([]image.Package, error) {
+	packageMap := make(map[string]image.Package)
+	var pkg image.Package
+	addPackage := func() {
+		if pkg.Name != "" {
+			packageMap[pkg.Name] = pkg
+		}
+		pkg = image.Package{}
+	}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			addPackage()
+			continue
+		}
+		if len(line) < 2 || line[1] != ':' {
+			continue
+		}
+		switch line[0] {
+		case 'P':
+			pkg.Name = line[2:]
+		case 'V':
+			pkg.Version = line[2:]
+		case 'I':
+			if size, err := strconv.ParseUint(line[2:], 10, 64); err == nil {
+				pkg.Size = size
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	addPackage()
+	return sortPackages(packageMap), nil
+}
+
+func parseDpkgStatus(reader io.Reader) ([]image.Package, error) {
+	packageMap := make(map[string]image.Package)
+	var pkg image.Package
+	var installed bool
+	addPackage := func() {
+		if pkg.Name != "" && installed {
+			packageMap[pkg.Name] = pkg
+		}
+		pkg = image.Package{}
+		installed = false
+	}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			addPackage()
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' { // Continuation line.
+			continue
+		}
+		splitLine := strings.SplitN(line, ":", 2)
+		if len(splitLine) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(splitLine[1])
+		switch splitLine[0] {
+		case "Installed-Size": // KiB.
+			if size, err := strconv.ParseUint(value, 10, 64); err == nil {
+				pkg.Size = size << 10
+			}
+		case "Package":
+			pkg.Name = value
+		case "Status":
+			installed = strings.HasSuffix(value, " installed")
+		case "Version":
+			pkg.Version = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	addPackage()
+	return sortPackages(packageMap), nil
+}
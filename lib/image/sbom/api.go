@@ -0,0 +1,79 @@
+package sbom
+
+import (
+	"io"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+)
+
+const SpdxVersion = "SPDX-2.3"
+
+type Checksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type CreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+// Document is a Software Bill of Materials in SPDX JSON format.
+type Document struct {
+	SpdxVersion       string         `json:"spdxVersion"`
+	DataLicense       string         `json:"dataLicense"`
+	SPDXID            string         `json:"SPDXID"`
+	Name              string         `json:"name"`
+	DocumentNamespace string         `json:"documentNamespace"`
+	CreationInfo      CreationInfo   `json:"creationInfo"`
+	Packages          []Package      `json:"packages,omitempty"`
+	Files             []File         `json:"files,omitempty"`
+	Relationships     []Relationship `json:"relationships,omitempty"`
+}
+
+type File struct {
+	SPDXID    string     `json:"SPDXID"`
+	FileName  string     `json:"fileName"`
+	Checksums []Checksum `json:"checksums"`
+}
+
+type Package struct {
+	SPDXID           string `json:"SPDXID"`
+	Name             string `json:"name"`
+	VersionInfo      string `json:"versionInfo,omitempty"`
+	DownloadLocation string `json:"downloadLocation"`
+	FilesAnalyzed    bool   `json:"filesAnalyzed"`
+	Comment          string `json:"comment,omitempty"`
+}
+
+type Relationship struct {
+	SpdxElementId      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSpdxElement string `json:"relatedSpdxElement"`
+}
+
+// New will create an SPDX document for the specified image. The packages are
+// taken from img.Packages and the files (with their SHA512 checksums) from
+// img.FileSystem. Computed files are not included since their contents are
+// not known. The name is used for the document name and namespace.
+func New(img *image.Image, name string, createdOn time.Time) (
+	*Document, error) {
+	return newDocument(img, name, createdOn)
+}
+
+// Read will read and decode an SPDX JSON document from reader.
+func Read(reader io.Reader) (*Document, error) {
+	return read(reader)
+}
+
+// List will write a sorted, line-oriented summary of the packages and files
+// in the document to writer. This is suitable for diffing.
+func (doc *Document) List(writer io.Writer) error {
+	return doc.list(writer)
+}
+
+// Write will write the document in JSON format to writer.
+func (doc *Document) Write(writer io.Writer) error {
+	return doc.write(writer)
+}
@@ -0,0 +1,124 @@
+package sbom
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	libjson "github.com/Cloud-Foundations/Dominator/lib/json"
+)
+
+const (
+	creator         = "Tool: Dominator"
+	documentId      = "SPDXRef-DOCUMENT"
+	namespacePrefix = "https://spdx.org/spdxdocs/dominator/"
+	noAssertion     = "NOASSERTION"
+)
+
+func newDocument(img *image.Image, name string, createdOn time.Time) (
+	*Document, error) {
+	if img.FileSystem == nil {
+		return nil, errors.New("no file-system data")
+	}
+	doc := &Document{
+		SpdxVersion:       SpdxVersion,
+		DataLicense:       "CC0-1.0",
+		SPDXID:            documentId,
+		Name:              name,
+		DocumentNamespace: namespacePrefix + url.PathEscape(name),
+		CreationInfo: CreationInfo{
+			Created:  createdOn.UTC().Format(time.RFC3339),
+			Creators: []string{creator},
+		},
+	}
+	for index, pkg := range img.Packages {
+		spdxId := fmt.Sprintf("SPDXRef-Package-%d", index)
+		doc.Packages = append(doc.Packages, Package{
+			SPDXID:           spdxId,
+			Name:             pkg.Name,
+			VersionInfo:      pkg.Version,
+			DownloadLocation: noAssertion,
+			Comment:          fmt.Sprintf("size: %d", pkg.Size),
+		})
+		doc.Relationships = append(doc.Relationships, Relationship{
+			SpdxElementId:      documentId,
+			RelationshipType:   "DESCRIBES",
+			RelatedSpdxElement: spdxId,
+		})
+	}
+	err := img.FileSystem.ForEachFile(
+		func(name string, inodeNumber uint64,
+			inode filesystem.GenericInode) error {
+			if inode, ok := inode.(*filesystem.RegularInode); ok {
+				hashText, _ := inode.Hash.MarshalText()
+				doc.Files = append(doc.Files, File{
+					SPDXID:   fmt.Sprintf("SPDXRef-File-%d", len(doc.Files)),
+					FileName: "." + name,
+					Checksums: []Checksum{{
+						Algorithm:     "SHA512",
+						ChecksumValue: string(hashText),
+					}},
+				})
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func read(reader io.Reader) (*Document, error) {
+	var doc Document
+	if err := json.NewDecoder(reader).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(doc.SpdxVersion, "SPDX-") {
+		return nil, fmt.Errorf("unsupported SPDX version: \"%s\"",
+			doc.SpdxVersion)
+	}
+	return &doc, nil
+}
+
+func (doc *Document) list(writer io.Writer) error {
+	lines := make([]string, 0, len(doc.Packages)+len(doc.Files))
+	for _, pkg := range doc.Packages {
+		lines = append(lines,
+			fmt.Sprintf("package %s %s", pkg.Name, pkg.VersionInfo))
+	}
+	for _, file := range doc.Files {
+		var checksum string
+		for _, cksum := range file.Checksums {
+			if cksum.Algorithm == "SHA512" {
+				checksum = cksum.ChecksumValue
+				break
+			}
+		}
+		if checksum == "" && len(file.Checksums) > 0 {
+			checksum = file.Checksums[0].Algorithm + ":" +
+				file.Checksums[0].ChecksumValue
+		}
+		lines = append(lines,
+			fmt.Sprintf("file %s %s", file.FileName, checksum))
+	}
+	sort.Strings(lines)
+	w := bufio.NewWriter(writer)
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func (doc *Document) write(writer io.Writer) error {
+	return libjson.WriteWithIndent(writer, "    ", doc)
+}
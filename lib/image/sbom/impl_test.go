@@ -0,0 +1,82 @@
+package sbom
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+)
+
+func makeTestImage() *image.Image {
+	fs := &filesystem.FileSystem{
+		InodeTable: filesystem.InodeTable{
+			1: &filesystem.RegularInode{Size: 1, Hash: hash.Hash{1}},
+			2: &filesystem.DirectoryInode{},
+		},
+		DirectoryInode: filesystem.DirectoryInode{
+			EntryList: []*filesystem.DirectoryEntry{
+				{Name: "file", InodeNumber: 1},
+				{Name: "dir", InodeNumber: 2},
+			},
+		},
+	}
+	fs.RebuildInodePointers()
+	return &image.Image{
+		FileSystem: fs,
+		Packages:   []image.Package{{Name: "pkg", Version: "1.0"}},
+	}
+}
+
+func TestNewAndRead(t *testing.T) {
+	createdOn := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	doc, err := New(makeTestImage(), "test/image", createdOn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.DocumentNamespace != namespacePrefix+"test%2Fimage" {
+		t.Errorf("namespace: %s", doc.DocumentNamespace)
+	}
+	if doc.CreationInfo.Created != "2020-01-02T03:04:05Z" {
+		t.Errorf("created: %s", doc.CreationInfo.Created)
+	}
+	if len(doc.Packages) != 1 || len(doc.Relationships) != 1 {
+		t.Fatalf("packages: %d, relationships: %d",
+			len(doc.Packages), len(doc.Relationships))
+	}
+	if len(doc.Files) != 1 || doc.Files[0].FileName != "./file" {
+		t.Fatalf("unexpected files: %v", doc.Files)
+	}
+	buffer := &bytes.Buffer{}
+	if err := doc.Write(buffer); err != nil {
+		t.Fatal(err)
+	}
+	readDoc, err := Read(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listBuffer := &bytes.Buffer{}
+	if err := readDoc.List(listBuffer); err != nil {
+		t.Fatal(err)
+	}
+	hashText, _ := hash.Hash{1}.MarshalText()
+	expected := "file ./file " + string(hashText) + "\npackage pkg 1.0\n"
+	if listBuffer.String() != expected {
+		t.Errorf("list: %s != %s", listBuffer.String(), expected)
+	}
+}
+
+func TestNewWithoutFileSystem(t *testing.T) {
+	if _, err := New(&image.Image{}, "test", time.Now()); err == nil {
+		t.Error("no error for image without file-system")
+	}
+}
+
+func TestReadBadVersion(t *testing.T) {
+	_, err := Read(bytes.NewBufferString(`{"spdxVersion": "CycloneDX"}`))
+	if err == nil {
+		t.Error("no error for unsupported version")
+	}
+}
@@ -49,10 +49,17 @@ func NewObjectServer(baseDir string, maxCachedBytes uint64,
 	return newObjectServer(baseDir, maxCachedBytes, objectServerAddress, logger)
 }
 
+func (objSrv *ObjectServer) CheckObjects(hashes []hash.Hash) ([]uint64, error) {
+	return objSrv.checkObjects(hashes)
+}
+
 func (objSrv *ObjectServer) FetchObjects(hashes []hash.Hash) error {
 	return objSrv.fetchObjects(hashes)
 }
 
+// GetObjects returns a reader for the objects. Objects which are not cached
+// are fetched from the upstream object server and cached. The reader also
+// implements objectserver.FullObjectsReader.
 func (objSrv *ObjectServer) GetObjects(hashes []hash.Hash) (
 	objectserver.ObjectsReader, error) {
 	return objSrv.getObjects(hashes)
@@ -0,0 +1,36 @@
+package cachingreader
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+	"github.com/Cloud-Foundations/Dominator/lib/objectserver/client"
+)
+
+func (objSrv *ObjectServer) checkObjects(hashes []hash.Hash) (
+	[]uint64, error) {
+	sizes := make([]uint64, len(hashes))
+	var hashesToCheck []hash.Hash
+	var checkToIndex []int
+	objSrv.rwLock.RLock()
+	for index, hashVal := range hashes {
+		if object, ok := objSrv.objects[hashVal]; ok {
+			sizes[index] = object.size
+		} else {
+			checkToIndex = append(checkToIndex, index)
+			hashesToCheck = append(hashesToCheck, hashVal)
+		}
+	}
+	objSrv.rwLock.RUnlock()
+	if len(hashesToCheck) < 1 {
+		return sizes, nil
+	}
+	objectClient := client.NewObjectClient(objSrv.objectServerAddress)
+	defer objectClient.Close()
+	upstreamSizes, err := objectClient.CheckObjects(hashesToCheck)
+	if err != nil {
+		return nil, err
+	}
+	for index, size := range upstreamSizes {
+		sizes[checkToIndex[index]] = size
+	}
+	return sizes, nil
+}
@@ -30,6 +30,7 @@ type objectsReader struct {
 	objectClient       *client.ObjectClient
 	objectsReader      objectserver.FullObjectsReader
 	objSrv             *ObjectServer
+	sizes              []uint64
 	totalBytes         uint64
 	totalObjects       uint
 	waitedObjects      uint
@@ -93,6 +94,7 @@ func (objSrv *ObjectServer) getObjects(hashes []hash.Hash) (
 			len(hashes)),
 		objSrv:        objSrv,
 		objectsToRead: make([]*objectType, len(hashes)),
+		sizes:         make([]uint64, len(hashes)),
 	}
 	var hashesToFetch []hash.Hash
 	var fetchToReadIndex []int
@@ -104,6 +106,7 @@ func (objSrv *ObjectServer) getObjects(hashes []hash.Hash) (
 			hashesToFetch = append(hashesToFetch, hashVal)
 		} else {
 			or.objectsToRead[index] = object
+			or.sizes[index] = object.size
 		}
 	}
 	if len(hashesToFetch) < 1 {
@@ -119,6 +122,7 @@ func (objSrv *ObjectServer) getObjects(hashes []hash.Hash) (
 	sizes := or.objectsReader.ObjectSizes()
 	for index, hashVal := range hashesToFetch {
 		size := sizes[index]
+		or.sizes[fetchToReadIndex[index]] = size
 		if !objSrv.releaseSpaceWithLock(size) {
 			continue // Too large: read directly without caching.
 		}
@@ -184,6 +188,10 @@ func (or *objectsReader) NextObject() (uint64, io.ReadCloser, error) {
 	return or.nextObject(false)
 }
 
+func (or *objectsReader) ObjectSizes() []uint64 {
+	return or.sizes
+}
+
 func (or *objectsReader) nextObject(skipOpen bool) (
 	uint64, io.ReadCloser, error) {
 	if len(or.objectsToRead) < 1 {
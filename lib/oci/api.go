@@ -0,0 +1,182 @@
+/*
+Package oci reads container images in the Open Container Initiative (OCI)
+image layout and Docker archive (docker save) formats.
+
+The layers of an image are merged in order, applying whiteout files, and
+the result may be converted to a Dominator file-system or unpacked into a
+directory. Ownership, permissions and modification times recorded in the
+layers are preserved.
+*/
+package oci
+
+import (
+	"io"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/filter"
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/objectserver"
+)
+
+const (
+	MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeImageConfig        = "application/vnd.oci.image.config.v1+json"
+	MediaTypeImageIndex         = "application/vnd.oci.image.index.v1+json"
+	MediaTypeImageLayer         = "application/vnd.oci.image.layer.v1.tar"
+	MediaTypeImageLayerGzip     = "application/vnd.oci.image.layer.v1.tar+gzip"
+	MediaTypeImageManifest      = "application/vnd.oci.image.manifest.v1+json"
+	RefNameAnnotation           = "org.opencontainers.image.ref.name"
+)
+
+// Descriptor describes a blob (content addressable object).
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *Platform         `json:"platform,omitempty"`
+}
+
+// Hasher is used to store the contents of regular files.
+type Hasher interface {
+	Hash(reader io.Reader, length uint64) (hash.Hash, error)
+}
+
+// Image is an opened container image. It must be closed when no longer needed.
+type Image struct {
+	Config ImageConfig
+	closer io.Closer
+	layers []layerType
+	opener blobOpener
+}
+
+// ImageConfig contains the OCI image configuration.
+type ImageConfig struct {
+	Architecture string        `json:"architecture"`
+	Config       RuntimeConfig `json:"config"`
+	Created      string        `json:"created,omitempty"`
+	OS           string        `json:"os"`
+	RootFS       RootFS        `json:"rootfs"`
+}
+
+// Layer describes a layer written to an image layout.
+type Layer struct {
+	Descriptor
+	DiffID string // Digest of the uncompressed layer.
+}
+
+// LayoutWriter writes images to an OCI image layout directory. The layout
+// also contains a Docker archive manifest, so that an archive of the layout
+// may be loaded with docker load.
+type LayoutWriter struct {
+	dirname         string
+	dockerManifests []dockerManifestEntry
+	manifests       []Descriptor
+}
+
+// Platform describes the platform an image manifest applies to.
+type Platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// RootFS lists the uncompressed layer digests of an image.
+type RootFS struct {
+	DiffIDs []string `json:"diff_ids"`
+	Type    string   `json:"type"`
+}
+
+// RuntimeConfig contains the default execution parameters of an image.
+type RuntimeConfig struct {
+	Cmd        []string          `json:"Cmd,omitempty"`
+	Entrypoint []string          `json:"Entrypoint,omitempty"`
+	Env        []string          `json:"Env,omitempty"`
+	Labels     map[string]string `json:"Labels,omitempty"`
+	User       string            `json:"User,omitempty"`
+	WorkingDir string            `json:"WorkingDir,omitempty"`
+}
+
+// Open will open the image at pathname, which may be an OCI image layout
+// directory, a tar archive of an OCI image layout or a Docker archive. The
+// reference selects the image to use if there are several in the layout or
+// archive. If reference is empty the first image is used.
+func Open(pathname, reference string) (*Image, error) {
+	return openImage(pathname, reference)
+}
+
+// Close will release the resources used by the image.
+func (img *Image) Close() error {
+	return img.close()
+}
+
+// FileSystem will merge the layers of the image into a file-system. The
+// contents of regular files are stored using hasher. Files matching filter are
+// excluded. If a dpkg or apk package database is found, the list of installed
+// packages is returned.
+func (img *Image) FileSystem(hasher Hasher, filter *filter.Filter) (
+	*filesystem.FileSystem, []image.Package, error) {
+	return img.fileSystem(hasher, filter)
+}
+
+// NumLayers returns the number of layers in the image.
+func (img *Image) NumLayers() int {
+	return len(img.layers)
+}
+
+// Unpack will merge the layers of the image into the empty directory rootDir.
+// Files matching filter are excluded. Setting ownership and creating device
+// nodes requires appropriate privileges.
+func (img *Image) Unpack(rootDir string, filter *filter.Filter) error {
+	return img.unpack(rootDir, filter)
+}
+
+// DiffFileSystems will return a file-system which contains the entries in
+// target which are new or different compared to source, and whiteout entries
+// for those which were removed. This is suitable for writing as a layer on top
+// of a layer containing source.
+func DiffFileSystems(source, target *filesystem.FileSystem) (
+	*filesystem.FileSystem, error) {
+	return diffFileSystems(source, target)
+}
+
+// NewConfig will return an image configuration for the specified architecture
+// (e.g. "amd64") with the image metadata (build commit, git URL, creation time
+// and tags) mapped to labels.
+func NewConfig(img *image.Image, architecture string) ImageConfig {
+	return newConfig(img, architecture)
+}
+
+// NewLayoutWriter will create an OCI image layout in the directory dirname.
+// Close must be called to write the image index.
+func NewLayoutWriter(dirname string) (*LayoutWriter, error) {
+	return newLayoutWriter(dirname)
+}
+
+// WriteArchive will write a tar archive of the directory dirname, which
+// would typically be an image layout, to writer.
+func WriteArchive(writer io.Writer, dirname string) error {
+	return writeArchive(writer, dirname)
+}
+
+// AddImage will add an image with the specified configuration and layers
+// (lowest first). If reference is not empty, it is recorded as the image
+// name (i.e. "debian:12").
+func (w *LayoutWriter) AddImage(config ImageConfig, layers []Layer,
+	reference string) error {
+	return w.addImage(config, layers, reference)
+}
+
+// AddLayer will write a gzip compressed layer containing fs, reading the
+// contents of regular files from objectsGetter.
+func (w *LayoutWriter) AddLayer(fs *filesystem.FileSystem,
+	objectsGetter objectserver.ObjectsGetter) (Layer, error) {
+	return w.addLayer(fs, objectsGetter)
+}
+
+// Close will write the image index.
+func (w *LayoutWriter) Close() error {
+	return w.close()
+}
@@ -0,0 +1,125 @@
+package oci
+
+import (
+	"syscall"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+)
+
+type differ struct {
+	fileSystem      *filesystem.FileSystem
+	nextInodeNumber uint64
+}
+
+func diffFileSystems(source, target *filesystem.FileSystem) (
+	*filesystem.FileSystem, error) {
+	d := &differ{
+		fileSystem: &filesystem.FileSystem{
+			InodeTable: make(filesystem.InodeTable),
+		},
+	}
+	for inodeNumber := range target.InodeTable {
+		if inodeNumber >= d.nextInodeNumber {
+			d.nextInodeNumber = inodeNumber + 1
+		}
+	}
+	rootDirectory := d.diffDirectory(&source.DirectoryInode,
+		&target.DirectoryInode)
+	if rootDirectory == nil {
+		rootDirectory = d.copyDirectory(&target.DirectoryInode)
+	}
+	d.fileSystem.DirectoryInode = *rootDirectory
+	d.fileSystem.ComputeTotalDataBytes()
+	if err := d.fileSystem.RebuildInodePointers(); err != nil {
+		return nil, err
+	}
+	return d.fileSystem, nil
+}
+
+// addTree adds an entry and (for directories) everything below it.
+func (d *differ) addTree(directory *filesystem.DirectoryInode,
+	entry *filesystem.DirectoryEntry) {
+	inode := entry.Inode()
+	if dirInode, ok := inode.(*filesystem.DirectoryInode); ok {
+		newDirectory := d.copyDirectory(dirInode)
+		for _, child := range dirInode.EntryList {
+			d.addTree(newDirectory, child)
+		}
+		inode = newDirectory
+		d.fileSystem.DirectoryCount++
+	}
+	d.appendEntry(directory, entry.Name, entry.InodeNumber, inode)
+}
+
+func (d *differ) appendEntry(directory *filesystem.DirectoryInode,
+	name string, inodeNumber uint64, inode filesystem.GenericInode) {
+	newEntry := &filesystem.DirectoryEntry{
+		Name:        name,
+		InodeNumber: inodeNumber,
+	}
+	newEntry.SetInode(inode)
+	directory.EntryList = append(directory.EntryList, newEntry)
+	d.fileSystem.InodeTable[inodeNumber] = inode
+}
+
+func (d *differ) copyDirectory(
+	directory *filesystem.DirectoryInode) *filesystem.DirectoryInode {
+	return &filesystem.DirectoryInode{
+		Mode: directory.Mode,
+		Uid:  directory.Uid,
+		Gid:  directory.Gid,
+	}
+}
+
+// diffDirectory returns a directory containing the entries from target which
+// are new or changed compared to source, plus whiteout entries for entries
+// which were removed. It returns nil if there are no changes.
+func (d *differ) diffDirectory(source,
+	target *filesystem.DirectoryInode) *filesystem.DirectoryInode {
+	if source.EntriesByName == nil {
+		source.BuildEntryMap()
+	}
+	newDirectory := d.copyDirectory(target)
+	targetNames := make(map[string]struct{}, len(target.EntryList))
+	for _, entry := range target.EntryList {
+		targetNames[entry.Name] = struct{}{}
+		sourceEntry, ok := source.EntriesByName[entry.Name]
+		if !ok {
+			d.addTree(newDirectory, entry)
+			continue
+		}
+		sourceDirectory, sourceIsDir :=
+			sourceEntry.Inode().(*filesystem.DirectoryInode)
+		targetDirectory, targetIsDir :=
+			entry.Inode().(*filesystem.DirectoryInode)
+		if sourceIsDir && targetIsDir {
+			diffDirectory := d.diffDirectory(sourceDirectory, targetDirectory)
+			if diffDirectory != nil {
+				d.appendEntry(newDirectory, entry.Name, entry.InodeNumber,
+					diffDirectory)
+				d.fileSystem.DirectoryCount++
+			}
+			continue
+		}
+		sameType, sameMetadata, sameData := filesystem.CompareInodes(
+			sourceEntry.Inode(), entry.Inode(), nil)
+		if !sameType || !sameMetadata || !sameData {
+			d.addTree(newDirectory, entry)
+		}
+	}
+	for _, entry := range source.EntryList {
+		if _, ok := targetNames[entry.Name]; ok {
+			continue
+		}
+		d.appendEntry(newDirectory, whiteoutPrefix+entry.Name,
+			d.nextInodeNumber,
+			&filesystem.RegularInode{Mode: syscall.S_IFREG})
+		d.nextInodeNumber++
+	}
+	if len(newDirectory.EntryList) < 1 &&
+		filesystem.CompareDirectoriesMetadata(source, target, nil) {
+		return nil
+	}
+	sortEntries(newDirectory)
+	return newDirectory
+}
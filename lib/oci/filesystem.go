@@ -0,0 +1,229 @@
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"syscall"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/filter"
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/image/packageutil"
+)
+
+const (
+	apkDatabase          = "/lib/apk/db/installed"
+	dpkgDatabase         = "/var/lib/dpkg/status"
+	defaultDirectoryMode = syscall.S_IFDIR | syscall.S_IRWXU |
+		syscall.S_IRGRP | syscall.S_IXGRP | syscall.S_IROTH | syscall.S_IXOTH
+)
+
+type fsBuilder struct {
+	directoryTable  map[string]*filesystem.DirectoryInode
+	fileSystem      *filesystem.FileSystem
+	hashes          map[string]hash.Hash
+	inodeTable      map[string]uint64
+	nextInodeNumber uint64
+}
+
+func sortEntries(directory *filesystem.DirectoryInode) {
+	sort.Slice(directory.EntryList, func(left, right int) bool {
+		return directory.EntryList[left].Name < directory.EntryList[right].Name
+	})
+}
+
+func isRegularWithData(header *tar.Header) bool {
+	return header.Typeflag == tar.TypeReg && header.Size > 0
+}
+
+func (img *Image) fileSystem(hasher Hasher, filter *filter.Filter) (
+	*filesystem.FileSystem, []image.Package, error) {
+	entries, err := img.merge(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make(map[string]hash.Hash)
+	packageDatabases := make(map[string][]byte)
+	err = img.readLayers(entries, isRegularWithData,
+		func(reader io.Reader, header *tar.Header, index int) error {
+			if header.Name == apkDatabase || header.Name == dpkgDatabase {
+				data, err := ioutil.ReadAll(io.LimitReader(reader, header.Size))
+				if err != nil {
+					return err
+				}
+				packageDatabases[header.Name] = data
+				reader = bytes.NewReader(data)
+			}
+			hashVal, err := hasher.Hash(reader, uint64(header.Size))
+			if err != nil {
+				return err
+			}
+			hashes[header.Name] = hashVal
+			return nil
+		})
+	if err != nil {
+		return nil, nil, err
+	}
+	builder := &fsBuilder{
+		directoryTable: make(map[string]*filesystem.DirectoryInode),
+		fileSystem: &filesystem.FileSystem{
+			InodeTable: make(filesystem.InodeTable),
+		},
+		hashes:          hashes,
+		inodeTable:      make(map[string]uint64),
+		nextInodeNumber: 1,
+	}
+	if err := builder.build(entries); err != nil {
+		return nil, nil, err
+	}
+	var packages []image.Package
+	if data, ok := packageDatabases[dpkgDatabase]; ok {
+		packages, err = packageutil.ParseDpkgStatus(bytes.NewReader(data))
+	} else if data, ok := packageDatabases[apkDatabase]; ok {
+		packages, err = packageutil.ParseApkInstalled(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading package database: %s", err)
+	}
+	return builder.fileSystem, packages, nil
+}
+
+func (builder *fsBuilder) addEntry(name string,
+	inode filesystem.GenericInode) error {
+	parent, err := builder.getDirectory(path.Dir(name))
+	if err != nil {
+		return err
+	}
+	entry := &filesystem.DirectoryEntry{
+		Name:        path.Base(name),
+		InodeNumber: builder.nextInodeNumber,
+	}
+	entry.SetInode(inode)
+	parent.EntryList = append(parent.EntryList, entry)
+	builder.fileSystem.InodeTable[builder.nextInodeNumber] = inode
+	builder.inodeTable[name] = builder.nextInodeNumber
+	builder.nextInodeNumber++
+	return nil
+}
+
+func (builder *fsBuilder) build(entries entryTable) error {
+	fs := builder.fileSystem
+	fs.DirectoryInode.Mode = defaultDirectoryMode
+	builder.directoryTable["/"] = &fs.DirectoryInode
+	var hardlinks []*entryType
+	for _, name := range entries.sortedNames() {
+		header := entries[name].header
+		mode := filesystem.FileMode(header.Mode & ^syscall.S_IFMT)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			inode := &filesystem.DirectoryInode{
+				Mode: mode | syscall.S_IFDIR,
+				Uid:  uint32(header.Uid),
+				Gid:  uint32(header.Gid),
+			}
+			if name == "/" {
+				fs.DirectoryInode = *inode
+				continue
+			}
+			if err := builder.addEntry(name, inode); err != nil {
+				return err
+			}
+			builder.directoryTable[name] = inode
+		case tar.TypeReg:
+			inode := &filesystem.RegularInode{
+				Mode:             mode | syscall.S_IFREG,
+				Uid:              uint32(header.Uid),
+				Gid:              uint32(header.Gid),
+				MtimeNanoSeconds: int32(header.ModTime.Nanosecond()),
+				MtimeSeconds:     header.ModTime.Unix(),
+				Size:             uint64(header.Size),
+				Hash:             builder.hashes[name],
+			}
+			if err := builder.addEntry(name, inode); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			hardlinks = append(hardlinks, entries[name])
+		case tar.TypeSymlink:
+			inode := &filesystem.SymlinkInode{
+				Uid:     uint32(header.Uid),
+				Gid:     uint32(header.Gid),
+				Symlink: header.Linkname,
+			}
+			if err := builder.addEntry(name, inode); err != nil {
+				return err
+			}
+		case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+			if header.Devminor > 255 {
+				return fmt.Errorf("%s: minor device number: %d too large",
+					name, header.Devminor)
+			}
+			switch header.Typeflag {
+			case tar.TypeChar:
+				mode |= syscall.S_IFCHR
+			case tar.TypeBlock:
+				mode |= syscall.S_IFBLK
+			case tar.TypeFifo:
+				mode |= syscall.S_IFIFO
+			}
+			inode := &filesystem.SpecialInode{
+				Mode:             mode,
+				Uid:              uint32(header.Uid),
+				Gid:              uint32(header.Gid),
+				MtimeNanoSeconds: int32(header.ModTime.Nanosecond()),
+				MtimeSeconds:     header.ModTime.Unix(),
+				Rdev:             uint64(header.Devmajor<<8 | header.Devminor),
+			}
+			if err := builder.addEntry(name, inode); err != nil {
+				return err
+			}
+		}
+	}
+	for _, entry := range hardlinks {
+		target, err := entries.resolveHardlink(entry)
+		if err != nil {
+			return err
+		}
+		parent, err := builder.getDirectory(path.Dir(entry.header.Name))
+		if err != nil {
+			return err
+		}
+		inodeNumber := builder.inodeTable[target.header.Name]
+		newEntry := &filesystem.DirectoryEntry{
+			Name:        path.Base(entry.header.Name),
+			InodeNumber: inodeNumber,
+		}
+		newEntry.SetInode(builder.fileSystem.InodeTable[inodeNumber])
+		parent.EntryList = append(parent.EntryList, newEntry)
+	}
+	for _, directory := range builder.directoryTable {
+		sortEntries(directory)
+	}
+	fs.DirectoryCount = uint64(len(builder.directoryTable))
+	fs.ComputeTotalDataBytes()
+	return nil
+}
+
+// getDirectory returns the directory inode for dirname, creating it and any
+// missing parents if they were not included in the layers.
+func (builder *fsBuilder) getDirectory(dirname string) (
+	*filesystem.DirectoryInode, error) {
+	if directory, ok := builder.directoryTable[dirname]; ok {
+		return directory, nil
+	}
+	if _, ok := builder.inodeTable[dirname]; ok {
+		return nil, fmt.Errorf("%s: not a directory", dirname)
+	}
+	directory := &filesystem.DirectoryInode{Mode: defaultDirectoryMode}
+	if err := builder.addEntry(dirname, directory); err != nil {
+		return nil, err
+	}
+	builder.directoryTable[dirname] = directory
+	return directory, nil
+}
@@ -0,0 +1,264 @@
+package oci
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filter"
+)
+
+const (
+	opaqueWhiteout = ".wh..wh..opq"
+	whiteoutPrefix = ".wh."
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+type entryFunc func(reader io.Reader, header *tar.Header, index int) error
+
+type entryType struct {
+	header *tar.Header
+	index  int
+	layer  int
+}
+
+type entryTable map[string]*entryType // Key: normalised pathname.
+
+func decompress(reader io.Reader) (io.Reader, func(), error) {
+	bufReader := bufio.NewReader(reader)
+	magic, err := bufReader.Peek(4)
+	if err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+	if bytes.HasPrefix(magic, gzipMagic) {
+		gzipReader, err := gzip.NewReader(bufReader)
+		if err != nil {
+			return nil, nil, err
+		}
+		return gzipReader, func() { gzipReader.Close() }, nil
+	}
+	if bytes.HasPrefix(magic, zstdMagic) {
+		return nil, nil, errors.New("zstd compressed layers are not supported")
+	}
+	return bufReader, func() {}, nil
+}
+
+func isDirectory(header *tar.Header) bool {
+	return header.Typeflag == tar.TypeDir
+}
+
+func normaliseName(name string) string {
+	return path.Clean("/" + name)
+}
+
+// sortedNames returns the pathnames in the table in lexical order, which
+// guarantees that directories are listed before their contents.
+func (entries entryTable) sortedNames() []string {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// deleteChildren deletes the contents of a directory which came from layers
+// below the specified layer (an opaque whiteout).
+func (entries entryTable) deleteChildren(dirname string, layer int) {
+	prefix := strings.TrimSuffix(dirname, "/") + "/"
+	for name, entry := range entries {
+		if entry.layer < layer && strings.HasPrefix(name, prefix) {
+			delete(entries, name)
+		}
+	}
+}
+
+// deleteTree deletes a pathname and everything below it.
+func (entries entryTable) deleteTree(name string) {
+	delete(entries, name)
+	prefix := strings.TrimSuffix(name, "/") + "/"
+	for childName := range entries {
+		if strings.HasPrefix(childName, prefix) {
+			delete(entries, childName)
+		}
+	}
+}
+
+// resolveHardlink returns the entry for the regular file a hardlink refers to.
+func (entries entryTable) resolveHardlink(entry *entryType) (
+	*entryType, error) {
+	for count := 0; count < 16; count++ {
+		target, ok := entries[entry.header.Linkname]
+		if !ok {
+			return nil, fmt.Errorf("missing hardlink target: %s",
+				entry.header.Linkname)
+		}
+		if target.header.Typeflag != tar.TypeLink {
+			if target.header.Typeflag != tar.TypeReg {
+				return nil, fmt.Errorf("hardlink target: %s is not a file",
+					entry.header.Linkname)
+			}
+			return target, nil
+		}
+		entry = target
+	}
+	return nil, fmt.Errorf("too many levels of hardlinks: %s",
+		entry.header.Name)
+}
+
+func (img *Image) merge(filter *filter.Filter) (entryTable, error) {
+	entries := make(entryTable)
+	for layerIndex := range img.layers {
+		err := img.readLayer(layerIndex, true,
+			func(reader io.Reader, header *tar.Header, index int) error {
+				dirname, leafName := path.Split(header.Name)
+				if leafName == opaqueWhiteout {
+					entries.deleteChildren(dirname, layerIndex)
+					return nil
+				}
+				if strings.HasPrefix(leafName, whiteoutPrefix) {
+					entries.deleteTree(path.Join(dirname,
+						leafName[len(whiteoutPrefix):]))
+					return nil
+				}
+				if header.Name == "/.subd" ||
+					strings.HasPrefix(header.Name, "/.subd/") {
+					return nil
+				}
+				if filter != nil && filter.Match(header.Name) {
+					return nil
+				}
+				if oldEntry, ok := entries[header.Name]; ok {
+					if !isDirectory(oldEntry.header) || !isDirectory(header) {
+						entries.deleteTree(header.Name)
+					}
+				}
+				// Replace any non-directories which are now parents.
+				for parent := path.Dir(header.Name); parent != "/"; {
+					if entry, ok := entries[parent]; ok &&
+						!isDirectory(entry.header) {
+						entries.deleteTree(parent)
+					}
+					parent = path.Dir(parent)
+				}
+				entries[header.Name] = &entryType{
+					header: header,
+					index:  index,
+					layer:  layerIndex,
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// readLayer reads the specified layer, calling fn for each entry. Entries are
+// numbered so that they may be identified on subsequent reads.
+func (img *Image) readLayer(layerIndex int, verify bool, fn entryFunc) error {
+	layer := img.layers[layerIndex]
+	blob, err := img.opener(layer.name)
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+	var rawReader io.Reader = blob
+	var digester hash.Hash
+	if verify && strings.HasPrefix(layer.digest, "sha256:") {
+		digester = sha256.New()
+		rawReader = io.TeeReader(blob, digester)
+	}
+	reader, closer, err := decompress(rawReader)
+	if err != nil {
+		return fmt.Errorf("error reading layer: %s: %s", layer.name, err)
+	}
+	defer closer()
+	tarReader := tar.NewReader(reader)
+	for index := 0; ; index++ {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error reading layer: %s: %s", layer.name, err)
+		}
+		switch header.Typeflag {
+		case tar.TypeXGlobalHeader:
+			continue
+		case tar.TypeRegA:
+			header.Typeflag = tar.TypeReg
+		case tar.TypeReg, tar.TypeLink, tar.TypeSymlink, tar.TypeChar,
+			tar.TypeBlock, tar.TypeDir, tar.TypeFifo:
+		default:
+			return fmt.Errorf("%s: unsupported file type: %v",
+				header.Name, header.Typeflag)
+		}
+		header.Name = normaliseName(header.Name)
+		if header.Typeflag == tar.TypeLink {
+			header.Linkname = normaliseName(header.Linkname)
+		}
+		if err := fn(tarReader, header, index); err != nil {
+			return err
+		}
+	}
+	if digester != nil {
+		if _, err := io.Copy(ioutil.Discard, rawReader); err != nil {
+			return err
+		}
+		digest := "sha256:" + hex.EncodeToString(digester.Sum(nil))
+		if digest != layer.digest {
+			return fmt.Errorf("layer: %s has digest: %s", layer.name, digest)
+		}
+	}
+	return nil
+}
+
+// readLayers reads the entries which were selected by merge, calling fn for
+// each entry which satisfies the selector. Layers with no selected entries are
+// skipped.
+func (img *Image) readLayers(entries entryTable,
+	selector func(header *tar.Header) bool, fn entryFunc) error {
+	layersToRead := make(map[int]struct{})
+	for _, entry := range entries {
+		if selector(entry.header) {
+			layersToRead[entry.layer] = struct{}{}
+		}
+	}
+	for layerIndex := range img.layers {
+		if _, ok := layersToRead[layerIndex]; !ok {
+			continue
+		}
+		err := img.readLayer(layerIndex, false,
+			func(reader io.Reader, header *tar.Header, index int) error {
+				entry, ok := entries[header.Name]
+				if !ok || entry.layer != layerIndex || entry.index != index {
+					return nil
+				}
+				if !selector(entry.header) {
+					return nil
+				}
+				return fn(reader, entry.header, index)
+			})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+)
+
+type testEntry struct {
+	name     string
+	typeflag byte
+	data     string
+	linkname string
+}
+
+type testHasher struct{}
+
+func (testHasher) Hash(reader io.Reader, length uint64) (hash.Hash, error) {
+	var hashVal hash.Hash
+	data, err := ioutil.ReadAll(io.LimitReader(reader, int64(length)))
+	if err != nil {
+		return hashVal, err
+	}
+	sum := sha512.Sum512(data)
+	copy(hashVal[:], sum[:])
+	return hashVal, nil
+}
+
+func makeLayer(t *testing.T, entries []testEntry, compress bool) []byte {
+	buffer := &bytes.Buffer{}
+	var writer io.Writer = buffer
+	var gzipWriter *gzip.Writer
+	if compress {
+		gzipWriter = gzip.NewWriter(buffer)
+		writer = gzipWriter
+	}
+	tarWriter := tar.NewWriter(writer)
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:     entry.name,
+			Typeflag: entry.typeflag,
+			Linkname: entry.linkname,
+			Mode:     0644,
+			ModTime:  time.Unix(1000000000, 0),
+			Size:     int64(len(entry.data)),
+		}
+		if entry.typeflag == tar.TypeDir {
+			header.Mode = 0755
+		}
+		if entry.typeflag != tar.TypeReg {
+			header.Size = 0
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Size > 0 {
+			if _, err := tarWriter.Write([]byte(entry.data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gzipWriter != nil {
+		if err := gzipWriter.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buffer.Bytes()
+}
+
+func compareNames(t *testing.T, got, want []string) {
+	if len(got) != len(want) {
+		t.Fatalf("names = %v, want %v", got, want)
+	}
+	for index, name := range got {
+		if name != want[index] {
+			t.Fatalf("names = %v, want %v", got, want)
+		}
+	}
+}
+
+func listNames(t *testing.T, fs *filesystem.FileSystem) []string {
+	if err := fs.RebuildInodePointers(); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	err := fs.ForEachFile(func(name string, inodeNumber uint64,
+		inode filesystem.GenericInode) error {
+		names = append(names, name)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func makeArchive(t *testing.T, files map[string][]byte) []byte {
+	buffer := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(buffer)
+	for name, data := range files {
+		header := &tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0644,
+			Size:     int64(len(data)),
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func testFiles(t *testing.T) map[string][]byte {
+	return map[string][]byte{
+		"manifest.json": []byte(`[{"Config": "config.json",` +
+			` "RepoTags": ["test:latest"],` +
+			` "Layers": ["l1/layer.tar", "l2/layer.tar"]}]`),
+		"config.json": []byte(`{"architecture": "amd64", "os": "linux",` +
+			` "config": {"Labels": {"key": "value"}}}`),
+		"l1/layer.tar": makeLayer(t, []testEntry{
+			{name: "etc/", typeflag: tar.TypeDir},
+			{name: "etc/keep", typeflag: tar.TypeReg, data: "keep"},
+			{name: "etc/remove", typeflag: tar.TypeReg, data: "remove"},
+			{name: "opaque/", typeflag: tar.TypeDir},
+			{name: "opaque/old", typeflag: tar.TypeReg, data: "old"},
+			{name: "var/lib/dpkg/status", typeflag: tar.TypeReg,
+				data: "Package: libc6\nStatus: install ok installed\n" +
+					"Installed-Size: 2\nVersion: 2.36-9\n\n" +
+					"Package: gone\nStatus: deinstall ok config-files\n" +
+					"Version: 1.0\n"},
+			{name: "replaced", typeflag: tar.TypeDir},
+			{name: "replaced/child", typeflag: tar.TypeReg, data: "child"},
+		}, false),
+		"l2/layer.tar": makeLayer(t, []testEntry{
+			{name: "etc/.wh.remove", typeflag: tar.TypeReg},
+			{name: "etc/link", typeflag: tar.TypeLink, linkname: "etc/keep"},
+			{name: "opaque/", typeflag: tar.TypeDir},
+			{name: "opaque/.wh..wh..opq", typeflag: tar.TypeReg},
+			{name: "opaque/new", typeflag: tar.TypeReg, data: "new"},
+			{name: "replaced", typeflag: tar.TypeSymlink, linkname: "etc"},
+		}, true),
+	}
+}
+
+func writeTestFiles(t *testing.T, dirname string, files map[string][]byte) {
+	for name, data := range files {
+		pathname := filepath.Join(dirname, name)
+		if err := os.MkdirAll(filepath.Dir(pathname), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(pathname, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMergeDockerArchive(t *testing.T) {
+	dirname, err := ioutil.TempDir("", "oci-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dirname)
+	files := testFiles(t)
+	writeTestFiles(t, dirname, files)
+	archiveFilename := filepath.Join(dirname, "archive.tar")
+	archive := makeArchive(t, files)
+	if err := ioutil.WriteFile(archiveFilename, archive, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, pathname := range []string{dirname, archiveFilename} {
+		testMergeDockerArchive(t, pathname)
+	}
+}
+
+func testMergeDockerArchive(t *testing.T, pathname string) {
+	img, err := Open(pathname, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer img.Close()
+	if img.NumLayers() != 2 {
+		t.Fatalf("NumLayers() = %d, want 2", img.NumLayers())
+	}
+	if value := img.Config.Config.Labels["key"]; value != "value" {
+		t.Errorf("label key = %q, want \"value\"", value)
+	}
+	fs, packages, err := img.FileSystem(testHasher{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareNames(t, listNames(t, fs), []string{"/", "/etc", "/etc/keep",
+		"/etc/link", "/opaque", "/opaque/new", "/replaced", "/var", "/var/lib",
+		"/var/lib/dpkg", "/var/lib/dpkg/status"})
+	if len(packages) != 1 || packages[0].Name != "libc6" ||
+		packages[0].Version != "2.36-9" || packages[0].Size != 2048 {
+		t.Errorf("packages = %v, want [{libc6 2048 2.36-9}]", packages)
+	}
+}
@@ -0,0 +1,315 @@
+package oci
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	dockerManifestFile = "manifest.json"
+	ociIndexFile       = "index.json"
+	ociLayoutFile      = "oci-layout"
+)
+
+type blobOpener func(name string) (io.ReadCloser, error)
+
+type dockerManifestEntry struct {
+	Config   string
+	RepoTags []string
+	Layers   []string
+}
+
+type indexType struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	Manifests     []Descriptor `json:"manifests"`
+}
+
+type layerType struct {
+	digest string // Empty if unknown.
+	name   string
+}
+
+type manifestType struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	Config        Descriptor   `json:"config"`
+	Layers        []Descriptor `json:"layers"`
+}
+
+type tarMember struct {
+	linkname string
+	offset   int64
+	size     int64
+}
+
+type tarIndex struct {
+	file    *os.File
+	members map[string]tarMember
+}
+
+func blobName(digest string) (string, error) {
+	splitDigest := strings.SplitN(digest, ":", 2)
+	if len(splitDigest) != 2 || splitDigest[0] == "" || splitDigest[1] == "" ||
+		strings.ContainsAny(digest, "/\\") {
+		return "", fmt.Errorf("invalid digest: %s", digest)
+	}
+	return path.Join("blobs", splitDigest[0], splitDigest[1]), nil
+}
+
+func cleanMemberName(name string) string {
+	name = path.Clean("/" + name)
+	if name == "/" {
+		return ""
+	}
+	return name[1:]
+}
+
+func openImage(pathname, reference string) (*Image, error) {
+	fi, err := os.Stat(pathname)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		img := &Image{opener: func(name string) (io.ReadCloser, error) {
+			return os.Open(filepath.Join(pathname, filepath.FromSlash(name)))
+		}}
+		if err := img.readTop(reference); err != nil {
+			return nil, err
+		}
+		return img, nil
+	}
+	file, err := os.Open(pathname)
+	if err != nil {
+		return nil, err
+	}
+	index, err := indexTar(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error reading: %s: %s", pathname, err)
+	}
+	img := &Image{closer: file, opener: index.open}
+	if err := img.readTop(reference); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return img, nil
+}
+
+func indexTar(file *os.File) (*tarIndex, error) {
+	index := &tarIndex{file: file, members: make(map[string]tarMember)}
+	tarReader := tar.NewReader(file)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := cleanMemberName(header.Name)
+		switch header.Typeflag {
+		case tar.TypeReg, tar.TypeRegA:
+			offset, err := file.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return nil, err
+			}
+			index.members[name] = tarMember{offset: offset, size: header.Size}
+		case tar.TypeLink:
+			if member, ok := index.members[cleanMemberName(
+				header.Linkname)]; ok {
+				index.members[name] = member
+			}
+		case tar.TypeSymlink:
+			index.members[name] = tarMember{linkname: cleanMemberName(
+				path.Join(path.Dir(name), header.Linkname))}
+		}
+	}
+	return index, nil
+}
+
+func readJson(opener blobOpener, name string, value interface{}) error {
+	reader, err := opener(name)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	if err := json.NewDecoder(reader).Decode(value); err != nil {
+		return fmt.Errorf("error decoding: %s: %s", name, err)
+	}
+	return nil
+}
+
+func selectPlatform(manifests []Descriptor) (Descriptor, error) {
+	if len(manifests) < 1 {
+		return Descriptor{}, errors.New("no manifests in index")
+	}
+	for _, manifest := range manifests {
+		if manifest.Platform == nil {
+			continue
+		}
+		if manifest.Platform.OS == "linux" &&
+			manifest.Platform.Architecture == runtime.GOARCH {
+			return manifest, nil
+		}
+	}
+	for _, manifest := range manifests {
+		if manifest.Platform == nil {
+			return manifest, nil
+		}
+	}
+	return Descriptor{}, fmt.Errorf("no manifest for platform: linux/%s",
+		runtime.GOARCH)
+}
+
+func (index *tarIndex) open(name string) (io.ReadCloser, error) {
+	name = cleanMemberName(name)
+	for count := 0; count < 16; count++ {
+		member, ok := index.members[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: not found in archive", name)
+		}
+		if member.linkname == "" {
+			reader := io.NewSectionReader(index.file, member.offset,
+				member.size)
+			return io.NopCloser(reader), nil
+		}
+		name = member.linkname
+	}
+	return nil, fmt.Errorf("%s: too many levels of symbolic links", name)
+}
+
+func (img *Image) close() error {
+	if img.closer == nil {
+		return nil
+	}
+	return img.closer.Close()
+}
+
+func (img *Image) readDockerArchive(reference string) error {
+	var manifests []dockerManifestEntry
+	if err := readJson(img.opener, dockerManifestFile, &manifests); err != nil {
+		return err
+	}
+	if len(manifests) < 1 {
+		return errors.New("no images in archive")
+	}
+	manifest := manifests[0]
+	if reference != "" {
+		found := false
+		for _, entry := range manifests {
+			for _, tag := range entry.RepoTags {
+				if tag == reference || tag == reference+":latest" {
+					manifest = entry
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("image: %s not found in archive", reference)
+		}
+	}
+	if err := readJson(img.opener, manifest.Config, &img.Config); err != nil {
+		return err
+	}
+	for _, name := range manifest.Layers {
+		img.layers = append(img.layers, layerType{name: name})
+	}
+	return nil
+}
+
+func (img *Image) readManifest(descriptor Descriptor) error {
+	for count := 0; count < 4; count++ {
+		name, err := blobName(descriptor.Digest)
+		if err != nil {
+			return err
+		}
+		switch descriptor.MediaType {
+		case MediaTypeImageIndex, MediaTypeDockerManifestList:
+			var index indexType
+			if err := readJson(img.opener, name, &index); err != nil {
+				return err
+			}
+			descriptor, err = selectPlatform(index.Manifests)
+			if err != nil {
+				return err
+			}
+			continue
+		case MediaTypeImageManifest, MediaTypeDockerManifest, "":
+		default:
+			return fmt.Errorf("unsupported manifest media type: %s",
+				descriptor.MediaType)
+		}
+		var manifest manifestType
+		if err := readJson(img.opener, name, &manifest); err != nil {
+			return err
+		}
+		if name, err = blobName(manifest.Config.Digest); err != nil {
+			return err
+		}
+		if err := readJson(img.opener, name, &img.Config); err != nil {
+			return err
+		}
+		for _, layer := range manifest.Layers {
+			if strings.HasSuffix(layer.MediaType, "+zstd") {
+				return fmt.Errorf("unsupported layer media type: %s",
+					layer.MediaType)
+			}
+			name, err := blobName(layer.Digest)
+			if err != nil {
+				return err
+			}
+			img.layers = append(img.layers,
+				layerType{digest: layer.Digest, name: name})
+		}
+		return nil
+	}
+	return errors.New("too many levels of nested indices")
+}
+
+func (img *Image) readOciLayout(reference string) error {
+	var index indexType
+	if err := readJson(img.opener, ociIndexFile, &index); err != nil {
+		return err
+	}
+	if len(index.Manifests) < 1 {
+		return errors.New("no manifests in layout")
+	}
+	if reference == "" {
+		return img.readManifest(index.Manifests[0])
+	}
+	for _, descriptor := range index.Manifests {
+		refName := descriptor.Annotations[RefNameAnnotation]
+		if refName == "" {
+			continue
+		}
+		if refName == reference || strings.HasSuffix(reference, ":"+refName) {
+			return img.readManifest(descriptor)
+		}
+	}
+	return fmt.Errorf("image: %s not found in layout", reference)
+}
+
+func (img *Image) readTop(reference string) error {
+	if reader, err := img.opener(ociLayoutFile); err == nil {
+		reader.Close()
+		return img.readOciLayout(reference)
+	}
+	if reader, err := img.opener(dockerManifestFile); err == nil {
+		reader.Close()
+		return img.readDockerArchive(reference)
+	}
+	return errors.New("not an OCI image layout or Docker archive")
+}
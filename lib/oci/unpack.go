@@ -0,0 +1,134 @@
+package oci
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filter"
+)
+
+const (
+	dirPerms = syscall.S_IRWXU | syscall.S_IRGRP | syscall.S_IXGRP |
+		syscall.S_IROTH | syscall.S_IXOTH
+	privateDirectoryMode = syscall.S_IRWXU
+	privateFileMode      = syscall.S_IRUSR | syscall.S_IWUSR
+)
+
+func isUnpackedFromLayer(header *tar.Header) bool {
+	return header.Typeflag != tar.TypeDir && header.Typeflag != tar.TypeLink
+}
+
+func setMetadata(pathname string, header *tar.Header) error {
+	if err := os.Lchown(pathname, header.Uid, header.Gid); err != nil {
+		return err
+	}
+	if header.Typeflag == tar.TypeSymlink {
+		return nil
+	}
+	if err := syscall.Chmod(pathname, uint32(header.Mode&07777)); err != nil {
+		return &os.PathError{Op: "chmod", Path: pathname, Err: err}
+	}
+	return os.Chtimes(pathname, header.ModTime, header.ModTime)
+}
+
+func unpackEntry(rootDir string, reader io.Reader, header *tar.Header) error {
+	pathname := filepath.Join(rootDir, header.Name)
+	if err := os.MkdirAll(filepath.Dir(pathname), dirPerms); err != nil {
+		return err
+	}
+	switch header.Typeflag {
+	case tar.TypeReg:
+		file, err := os.OpenFile(pathname, os.O_CREATE|os.O_EXCL|os.O_WRONLY,
+			privateFileMode)
+		if err != nil {
+			return err
+		}
+		_, err = io.CopyN(file, reader, header.Size)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("error writing: %s: %s", pathname, err)
+		}
+	case tar.TypeSymlink:
+		if err := os.Symlink(header.Linkname, pathname); err != nil {
+			return err
+		}
+	case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+		mode := uint32(header.Mode & 07777)
+		switch header.Typeflag {
+		case tar.TypeChar:
+			mode |= syscall.S_IFCHR
+		case tar.TypeBlock:
+			mode |= syscall.S_IFBLK
+		case tar.TypeFifo:
+			mode |= syscall.S_IFIFO
+		}
+		err := syscall.Mknod(pathname, mode,
+			int(header.Devmajor<<8|header.Devminor))
+		if err != nil {
+			return &os.PathError{Op: "mknod", Path: pathname, Err: err}
+		}
+	}
+	return setMetadata(pathname, header)
+}
+
+func (img *Image) unpack(rootDir string, filter *filter.Filter) error {
+	entries, err := img.merge(filter)
+	if err != nil {
+		return err
+	}
+	names := entries.sortedNames()
+	// Create directories first so that the contents may be written in layer
+	// order. Directory metadata is applied last, since writing the contents
+	// changes the modification times and may require write permission.
+	var directories []*tar.Header
+	for _, name := range names {
+		header := entries[name].header
+		if header.Typeflag != tar.TypeDir {
+			continue
+		}
+		err := os.MkdirAll(filepath.Join(rootDir, name), privateDirectoryMode)
+		if err != nil {
+			return err
+		}
+		directories = append(directories, header)
+	}
+	err = img.readLayers(entries, isUnpackedFromLayer,
+		func(reader io.Reader, header *tar.Header, index int) error {
+			return unpackEntry(rootDir, reader, header)
+		})
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		entry := entries[name]
+		if entry.header.Typeflag != tar.TypeLink {
+			continue
+		}
+		target, err := entries.resolveHardlink(entry)
+		if err != nil {
+			return err
+		}
+		pathname := filepath.Join(rootDir, name)
+		if err := os.MkdirAll(filepath.Dir(pathname), dirPerms); err != nil {
+			return err
+		}
+		err = os.Link(filepath.Join(rootDir, target.header.Name), pathname)
+		if err != nil {
+			return err
+		}
+	}
+	for index := len(directories) - 1; index >= 0; index-- {
+		header := directories[index]
+		if err := setMetadata(filepath.Join(rootDir, header.Name),
+			header); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package oci
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	fstar "github.com/Cloud-Foundations/Dominator/lib/filesystem/tar"
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/objectserver"
+)
+
+const (
+	filePerms = syscall.S_IRUSR | syscall.S_IWUSR | syscall.S_IRGRP |
+		syscall.S_IROTH
+	ociLayoutContents = `{"imageLayoutVersion": "1.0.0"}`
+)
+
+type countingWriter struct {
+	count uint64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.count += uint64(len(p))
+	return len(p), nil
+}
+
+func newConfig(img *image.Image, architecture string) ImageConfig {
+	config := ImageConfig{
+		Architecture: architecture,
+		OS:           "linux",
+		RootFS:       RootFS{Type: "layers"},
+	}
+	if !img.CreatedOn.IsZero() {
+		config.Created = img.CreatedOn.UTC().Format(time.RFC3339)
+	}
+	labels := make(map[string]string, len(img.Tags)+3)
+	for key, value := range img.Tags {
+		labels[key] = value
+	}
+	if img.BuildCommitId != "" {
+		labels["org.opencontainers.image.revision"] = img.BuildCommitId
+	}
+	if img.BuildGitUrl != "" {
+		labels["org.opencontainers.image.source"] = img.BuildGitUrl
+	}
+	if config.Created != "" {
+		labels["org.opencontainers.image.created"] = config.Created
+	}
+	if len(labels) > 0 {
+		config.Config.Labels = labels
+	}
+	return config
+}
+
+func newLayoutWriter(dirname string) (*LayoutWriter, error) {
+	if err := os.MkdirAll(filepath.Join(dirname, "blobs", "sha256"),
+		dirPerms); err != nil {
+		return nil, err
+	}
+	err := ioutil.WriteFile(filepath.Join(dirname, ociLayoutFile),
+		[]byte(ociLayoutContents), filePerms)
+	if err != nil {
+		return nil, err
+	}
+	return &LayoutWriter{dirname: dirname}, nil
+}
+
+func writeArchive(writer io.Writer, dirname string) error {
+	tarWriter := tar.NewWriter(writer)
+	err := filepath.Walk(dirname,
+		func(pathname string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if pathname == dirname {
+				return nil
+			}
+			name, err := filepath.Rel(dirname, pathname)
+			if err != nil {
+				return err
+			}
+			header, err := tar.FileInfoHeader(fi, "")
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(name)
+			if fi.IsDir() {
+				header.Name += "/"
+			}
+			header.Uid = 0
+			header.Gid = 0
+			header.Uname = ""
+			header.Gname = ""
+			if err := tarWriter.WriteHeader(header); err != nil {
+				return err
+			}
+			if !fi.Mode().IsRegular() {
+				return nil
+			}
+			file, err := os.Open(pathname)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			_, err = io.Copy(tarWriter, file)
+			return err
+		})
+	if err != nil {
+		tarWriter.Close()
+		return err
+	}
+	return tarWriter.Close()
+}
+
+func (w *LayoutWriter) addImage(config ImageConfig, layers []Layer,
+	reference string) error {
+	config.RootFS.DiffIDs = make([]string, 0, len(layers))
+	manifest := manifestType{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeImageManifest,
+	}
+	dockerManifest := dockerManifestEntry{}
+	for _, layer := range layers {
+		config.RootFS.DiffIDs = append(config.RootFS.DiffIDs, layer.DiffID)
+		manifest.Layers = append(manifest.Layers, layer.Descriptor)
+		name, err := blobName(layer.Digest)
+		if err != nil {
+			return err
+		}
+		dockerManifest.Layers = append(dockerManifest.Layers, name)
+	}
+	var err error
+	manifest.Config, err = w.writeJsonBlob(MediaTypeImageConfig, config)
+	if err != nil {
+		return err
+	}
+	dockerManifest.Config, err = blobName(manifest.Config.Digest)
+	if err != nil {
+		return err
+	}
+	descriptor, err := w.writeJsonBlob(MediaTypeImageManifest, manifest)
+	if err != nil {
+		return err
+	}
+	if reference != "" {
+		descriptor.Annotations = map[string]string{
+			RefNameAnnotation: reference,
+		}
+		if strings.LastIndexByte(reference, ':') <=
+			strings.LastIndexByte(reference, '/') {
+			reference += ":latest"
+		}
+		dockerManifest.RepoTags = []string{reference}
+	}
+	w.manifests = append(w.manifests, descriptor)
+	w.dockerManifests = append(w.dockerManifests, dockerManifest)
+	return nil
+}
+
+func (w *LayoutWriter) addLayer(fs *filesystem.FileSystem,
+	objectsGetter objectserver.ObjectsGetter) (Layer, error) {
+	var diffID string
+	descriptor, err := w.writeBlob(MediaTypeImageLayerGzip,
+		func(writer io.Writer) error {
+			gzipWriter := gzip.NewWriter(writer)
+			diffDigester := sha256.New()
+			tarWriter := tar.NewWriter(io.MultiWriter(gzipWriter,
+				diffDigester))
+			if err := fstar.Encode(tarWriter, fs, objectsGetter); err != nil {
+				return err
+			}
+			if err := tarWriter.Close(); err != nil {
+				return err
+			}
+			if err := gzipWriter.Close(); err != nil {
+				return err
+			}
+			diffID = "sha256:" + hex.EncodeToString(diffDigester.Sum(nil))
+			return nil
+		})
+	if err != nil {
+		return Layer{}, err
+	}
+	return Layer{Descriptor: descriptor, DiffID: diffID}, nil
+}
+
+func (w *LayoutWriter) close() error {
+	sort.SliceStable(w.manifests, func(left, right int) bool {
+		return w.manifests[left].Annotations[RefNameAnnotation] <
+			w.manifests[right].Annotations[RefNameAnnotation]
+	})
+	index := indexType{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeImageIndex,
+		Manifests:     w.manifests,
+	}
+	if index.Manifests == nil {
+		index.Manifests = []Descriptor{}
+	}
+	if err := w.writeJsonFile(ociIndexFile, index); err != nil {
+		return err
+	}
+	return w.writeJsonFile(dockerManifestFile, w.dockerManifests)
+}
+
+// writeBlob writes a blob using writeFunc, computing the digest and size.
+func (w *LayoutWriter) writeBlob(mediaType string,
+	writeFunc func(writer io.Writer) error) (Descriptor, error) {
+	blobDir := filepath.Join(w.dirname, "blobs", "sha256")
+	file, err := ioutil.TempFile(blobDir, ".tmp")
+	if err != nil {
+		return Descriptor{}, err
+	}
+	tmpFilename := file.Name()
+	defer os.Remove(tmpFilename)
+	digester := sha256.New()
+	counter := &countingWriter{}
+	err = writeFunc(io.MultiWriter(file, digester, counter))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Descriptor{}, err
+	}
+	if err := os.Chmod(tmpFilename, filePerms); err != nil {
+		return Descriptor{}, err
+	}
+	hexDigest := hex.EncodeToString(digester.Sum(nil))
+	err = os.Rename(tmpFilename, filepath.Join(blobDir, hexDigest))
+	if err != nil {
+		return Descriptor{}, err
+	}
+	return Descriptor{
+		MediaType: mediaType,
+		Digest:    "sha256:" + hexDigest,
+		Size:      int64(counter.count),
+	}, nil
+}
+
+func (w *LayoutWriter) writeJsonBlob(mediaType string,
+	value interface{}) (Descriptor, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	return w.writeBlob(mediaType, func(writer io.Writer) error {
+		_, err := writer.Write(data)
+		return err
+	})
+}
+
+func (w *LayoutWriter) writeJsonFile(filename string,
+	value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(w.dirname, filename), data,
+		filePerms)
+}
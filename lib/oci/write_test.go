@@ -0,0 +1,109 @@
+package oci
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/objectserver/memory"
+)
+
+type objectHasher struct {
+	objectServer *memory.ObjectServer
+}
+
+func (h objectHasher) Hash(reader io.Reader, length uint64) (hash.Hash, error) {
+	hashVal, _, err := h.objectServer.AddObject(reader, length, nil)
+	return hashVal, err
+}
+
+func openFileSystem(t *testing.T, pathname, reference string,
+	hasher Hasher) (*Image, *filesystem.FileSystem) {
+	img, err := Open(pathname, reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs, _, err := img.FileSystem(hasher, nil)
+	if err != nil {
+		img.Close()
+		t.Fatal(err)
+	}
+	return img, fs
+}
+
+func TestWriteDiffLayout(t *testing.T) {
+	dirname, err := ioutil.TempDir("", "oci-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dirname)
+	fullDir := filepath.Join(dirname, "full")
+	sourceDir := filepath.Join(dirname, "source")
+	files := testFiles(t)
+	writeTestFiles(t, fullDir, files)
+	files["manifest.json"] = []byte(
+		`[{"Config": "config.json", "Layers": ["l1/layer.tar"]}]`)
+	writeTestFiles(t, sourceDir, files)
+	hasher := objectHasher{memory.NewObjectServer()}
+	img, sourceFs := openFileSystem(t, sourceDir, "", hasher)
+	img.Close()
+	img, targetFs := openFileSystem(t, fullDir, "", hasher)
+	img.Close()
+	diffFs, err := DiffFileSystems(sourceFs, targetFs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareNames(t, listNames(t, diffFs), []string{"/", "/etc",
+		"/etc/.wh.remove", "/etc/link", "/opaque", "/opaque/.wh.old",
+		"/opaque/new", "/replaced"})
+	layoutDir := filepath.Join(dirname, "layout")
+	layoutWriter, err := NewLayoutWriter(layoutDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var layers []Layer
+	for _, fs := range []*filesystem.FileSystem{sourceFs, diffFs} {
+		layer, err := layoutWriter.AddLayer(fs, hasher.objectServer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		layers = append(layers, layer)
+	}
+	config := NewConfig(&image.Image{BuildCommitId: "abc"}, runtime.GOARCH)
+	if err := layoutWriter.AddImage(config, layers, "test:1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := layoutWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archiveFilename := filepath.Join(dirname, "layout.tar")
+	file, err := os.Create(archiveFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteArchive(file, layoutDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	wantNames := listNames(t, targetFs)
+	for _, pathname := range []string{layoutDir, archiveFilename} {
+		img, fs := openFileSystem(t, pathname, "test:1", hasher)
+		if img.NumLayers() != 2 {
+			t.Errorf("NumLayers() = %d, want 2", img.NumLayers())
+		}
+		revision := img.Config.Config.Labels["org.opencontainers.image.revision"]
+		if revision != "abc" {
+			t.Errorf("revision label = %q, want \"abc\"", revision)
+		}
+		img.Close()
+		compareNames(t, listNames(t, fs), wantNames)
+	}
+}
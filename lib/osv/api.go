@@ -0,0 +1,77 @@
+/*
+Package osv matches package lists against an offline vulnerability feed.
+
+The feed is a directory tree of JSON files in the Open Source Vulnerability
+(OSV) format, such as the per-ecosystem exports published at
+https://osv.dev/. Versions are compared using the rules for the ecosystem
+(distribution) the feed entry applies to: dpkg rules for Debian and Ubuntu,
+rpm rules for Red Hat derived and SUSE distributions and generic version
+string rules for everything else.
+
+Distribution feeds (i.e. Debian and Ubuntu) describe source packages
+whereas images list binary packages. Where a feed entry lists the binary
+packages built from the source package (as the Ubuntu feed does), the entry
+is matched against those binary packages. Otherwise it is only matched
+against a binary package with the same name as the source package, so
+vulnerabilities in libraries with differently named binary packages (i.e.
+openssl and libssl3) are not found.
+*/
+package osv
+
+import (
+	"io"
+
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+// Database contains the vulnerabilities loaded from a feed.
+type Database struct {
+	ecosystems         map[string]map[string][]*affected // Key: base, package.
+	numEntries         uint
+	numVulnerabilities uint
+}
+
+// Match describes a package which is affected by a vulnerability.
+type Match struct {
+	Aliases        []string `json:",omitempty"`
+	Ecosystem      string
+	FixedVersion   string `json:",omitempty"` // Empty: no fix available.
+	Id             string
+	PackageName    string
+	PackageVersion string
+	Severity       string `json:",omitempty"`
+	Summary        string `json:",omitempty"`
+}
+
+// CompareVersions will compare two version strings using the rules for the
+// specified ecosystem. It returns -1 if left is older than right, 0 if they are
+// equal and 1 if left is newer than right.
+func CompareVersions(ecosystem, left, right string) int {
+	return getCompareFunc(ecosystem)(left, right)
+}
+
+// EcosystemFromOsRelease will read an os-release(5) file from reader and will
+// return the corresponding OSV ecosystem name (i.e. "Debian:11").
+func EcosystemFromOsRelease(reader io.Reader) (string, error) {
+	return ecosystemFromOsRelease(reader)
+}
+
+// LoadDirectory will load all the OSV JSON files in the directory tree rooted
+// at dirname. Files which cannot be decoded are logged and skipped.
+func LoadDirectory(dirname string, logger log.DebugLogger) (*Database, error) {
+	return loadDirectory(dirname, logger)
+}
+
+// MatchPackages will return the vulnerabilities which affect the specified
+// packages in the specified ecosystem. If the ecosystem has no release
+// component (i.e. "Debian"), vulnerabilities for all releases are matched.
+func (db *Database) MatchPackages(ecosystem string,
+	packages []image.Package) []Match {
+	return db.matchPackages(ecosystem, packages)
+}
+
+// NumVulnerabilities returns the number of vulnerabilities in the database.
+func (db *Database) NumVulnerabilities() uint {
+	return db.numVulnerabilities
+}
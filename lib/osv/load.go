@@ -0,0 +1,238 @@
+package osv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+type affected struct {
+	release       string
+	ranges        []rangeType
+	versions      map[string]struct{}
+	vulnerability *vulnerability
+}
+
+type affectedType struct {
+	EcosystemSpecific struct {
+		Binaries []binaryType `json:"binaries"`
+	} `json:"ecosystem_specific"`
+	Package  packageType `json:"package"`
+	Ranges   []rangeType `json:"ranges"`
+	Versions []string    `json:"versions"`
+}
+
+type binaryType struct {
+	BinaryName string `json:"binary_name"`
+}
+
+type eventType struct {
+	Introduced   string `json:"introduced"`
+	Fixed        string `json:"fixed"`
+	LastAffected string `json:"last_affected"`
+}
+
+type packageType struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+}
+
+type rangeType struct {
+	Type   string      `json:"type"`
+	Events []eventType `json:"events"`
+}
+
+type severityType struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+type vulnerability struct {
+	aliases  []string
+	id       string
+	severity string
+	summary  string
+}
+
+type vulnerabilityType struct {
+	Affected         []affectedType `json:"affected"`
+	Aliases          []string       `json:"aliases"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+	Id        string         `json:"id"`
+	Severity  []severityType `json:"severity"`
+	Summary   string         `json:"summary"`
+	Withdrawn string         `json:"withdrawn"`
+}
+
+func ecosystemFromOsRelease(reader io.Reader) (string, error) {
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		splitLine := strings.SplitN(line, "=", 2)
+		if len(splitLine) != 2 {
+			continue
+		}
+		fields[splitLine[0]] = strings.Trim(splitLine[1], `"'`)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	id := fields["ID"]
+	versionId := fields["VERSION_ID"]
+	majorVersion := strings.SplitN(versionId, ".", 2)[0]
+	switch id {
+	case "":
+		return "", errors.New("no ID in os-release")
+	case "almalinux":
+		return "AlmaLinux:" + majorVersion, nil
+	case "alpine":
+		splitVersion := strings.SplitN(versionId, ".", 3)
+		if len(splitVersion) < 2 {
+			return "Alpine", nil
+		}
+		return "Alpine:v" + splitVersion[0] + "." + splitVersion[1], nil
+	case "debian":
+		return "Debian:" + majorVersion, nil
+	case "opensuse-leap":
+		return "openSUSE:Leap " + versionId, nil
+	case "rhel", "centos":
+		return "Red Hat", nil
+	case "rocky":
+		return "Rocky Linux:" + majorVersion, nil
+	case "sles":
+		return "SUSE", nil
+	case "ubuntu":
+		return "Ubuntu:" + versionId, nil
+	}
+	return "", errors.New("unsupported distribution: " + id)
+}
+
+// splitEcosystem returns the base and release of an ecosystem. Qualifiers
+// are dropped, so that "Ubuntu:Pro:22.04:LTS" has the release "22.04", which
+// matches the ecosystem returned by ecosystemFromOsRelease.
+func splitEcosystem(ecosystem string) (string, string) {
+	splitEcosystem := strings.Split(ecosystem, ":")
+	if len(splitEcosystem) < 2 {
+		return ecosystem, ""
+	}
+	release := splitEcosystem[1]
+	if splitEcosystem[0] == "Ubuntu" && release == "Pro" &&
+		len(splitEcosystem) > 2 {
+		release = splitEcosystem[2]
+	}
+	return splitEcosystem[0], release
+}
+
+func loadDirectory(dirname string, logger log.DebugLogger) (*Database, error) {
+	db := &Database{ecosystems: make(map[string]map[string][]*affected)}
+	err := filepath.Walk(dirname,
+		func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !fi.Mode().IsRegular() || !strings.HasSuffix(path, ".json") {
+				return nil
+			}
+			if err := db.loadFile(path); err != nil {
+				logger.Printf("error loading: %s: %s\n", path, err)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	logger.Debugf(0, "loaded %d vulnerabilities (%d package entries) from: %s\n",
+		db.numVulnerabilities, db.numEntries, dirname)
+	return db, nil
+}
+
+func (db *Database) add(rawVuln *vulnerabilityType) {
+	if rawVuln.Withdrawn != "" || len(rawVuln.Affected) < 1 {
+		return
+	}
+	vuln := &vulnerability{
+		aliases: rawVuln.Aliases,
+		id:      rawVuln.Id,
+		summary: rawVuln.Summary,
+	}
+	if rawVuln.DatabaseSpecific.Severity != "" {
+		vuln.severity = rawVuln.DatabaseSpecific.Severity
+	} else if len(rawVuln.Severity) > 0 {
+		vuln.severity = rawVuln.Severity[0].Score
+	}
+	db.numVulnerabilities++
+	for _, rawAffected := range rawVuln.Affected {
+		base, release := splitEcosystem(rawAffected.Package.Ecosystem)
+		if base == "" || rawAffected.Package.Name == "" {
+			continue
+		}
+		entry := &affected{
+			release:       release,
+			ranges:        rawAffected.Ranges,
+			vulnerability: vuln,
+		}
+		if len(rawAffected.Versions) > 0 {
+			entry.versions = make(map[string]struct{},
+				len(rawAffected.Versions))
+			for _, version := range rawAffected.Versions {
+				entry.versions[version] = struct{}{}
+			}
+		}
+		packages := db.ecosystems[base]
+		if packages == nil {
+			packages = make(map[string][]*affected)
+			db.ecosystems[base] = packages
+		}
+		// Entries are for source packages but images list binary packages.
+		// Index the entry under the binary packages built from the source
+		// package, if the feed lists them.
+		names := map[string]struct{}{rawAffected.Package.Name: {}}
+		for _, binary := range rawAffected.EcosystemSpecific.Binaries {
+			if binary.BinaryName != "" {
+				names[binary.BinaryName] = struct{}{}
+			}
+		}
+		for name := range names {
+			packages[name] = append(packages[name], entry)
+		}
+		db.numEntries++
+	}
+}
+
+func (db *Database) loadFile(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var rawVulns []vulnerabilityType
+		if err := json.Unmarshal(data, &rawVulns); err != nil {
+			return err
+		}
+		for index := range rawVulns {
+			db.add(&rawVulns[index])
+		}
+		return nil
+	}
+	var rawVuln vulnerabilityType
+	if err := json.Unmarshal(data, &rawVuln); err != nil {
+		return err
+	}
+	db.add(&rawVuln)
+	return nil
+}
@@ -0,0 +1,79 @@
+package osv
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+)
+
+const ubuntuVulnerability = `{
+	"id": "UBUNTU-CVE-2023-0001",
+	"affected": [{
+		"package": {"ecosystem": "Ubuntu:22.04:LTS", "name": "openssl"},
+		"ranges": [{
+			"type": "ECOSYSTEM",
+			"events": [{"introduced": "0"}, {"fixed": "3.0.2-0ubuntu1.10"}]
+		}],
+		"ecosystem_specific": {"binaries": [{"binary_name": "libssl3"}]}
+	}]
+}`
+
+func TestSplitEcosystem(t *testing.T) {
+	var tests = []struct {
+		ecosystem, base, release string
+	}{
+		{"Debian", "Debian", ""},
+		{"Debian:11", "Debian", "11"},
+		{"Ubuntu:22.04:LTS", "Ubuntu", "22.04"},
+		{"Ubuntu:Pro:18.04:LTS", "Ubuntu", "18.04"},
+		{"openSUSE:Leap 15.4", "openSUSE", "Leap 15.4"},
+	}
+	for _, test := range tests {
+		base, release := splitEcosystem(test.ecosystem)
+		if base != test.base || release != test.release {
+			t.Errorf("splitEcosystem(%q) = %q, %q, want %q, %q",
+				test.ecosystem, base, release, test.base, test.release)
+		}
+	}
+}
+
+func TestMatchBinaryPackages(t *testing.T) {
+	dirname, err := ioutil.TempDir("", "osv-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dirname)
+	err = ioutil.WriteFile(filepath.Join(dirname, "vuln.json"),
+		[]byte(ubuntuVulnerability), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := LoadDirectory(dirname, testlogger.New(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.NumVulnerabilities() != 1 {
+		t.Fatalf("NumVulnerabilities() = %d, want 1", db.NumVulnerabilities())
+	}
+	ecosystem, err := EcosystemFromOsRelease(strings.NewReader(
+		"ID=ubuntu\nVERSION_ID=\"22.04\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches := db.MatchPackages(ecosystem, []image.Package{
+		{Name: "libssl3", Version: "3.0.2-0ubuntu1.9"},
+		{Name: "openssl", Version: "3.0.2-0ubuntu1.10"},
+	})
+	if len(matches) != 1 {
+		t.Fatalf("matches = %v, want 1 match", matches)
+	}
+	if matches[0].PackageName != "libssl3" ||
+		matches[0].FixedVersion != "3.0.2-0ubuntu1.10" {
+		t.Errorf("unexpected match: %v", matches[0])
+	}
+}
@@ -0,0 +1,95 @@
+package osv
+
+import (
+	"sort"
+
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+)
+
+func (db *Database) matchPackages(ecosystem string,
+	packages []image.Package) []Match {
+	base, release := splitEcosystem(ecosystem)
+	packageTable := db.ecosystems[base]
+	if len(packageTable) < 1 {
+		return nil
+	}
+	compareFunc := getCompareFunc(base)
+	var matches []Match
+	for _, pkg := range packages {
+		found := make(map[string]struct{})
+		for _, entry := range packageTable[pkg.Name] {
+			if release != "" && entry.release != "" &&
+				entry.release != release {
+				continue
+			}
+			if _, ok := found[entry.vulnerability.id]; ok {
+				continue
+			}
+			isAffected, fixedVersion := entry.match(pkg.Version, compareFunc)
+			if !isAffected {
+				continue
+			}
+			found[entry.vulnerability.id] = struct{}{}
+			matchEcosystem := base
+			if entry.release != "" {
+				matchEcosystem += ":" + entry.release
+			}
+			matches = append(matches, Match{
+				Aliases:        entry.vulnerability.aliases,
+				Ecosystem:      matchEcosystem,
+				FixedVersion:   fixedVersion,
+				Id:             entry.vulnerability.id,
+				PackageName:    pkg.Name,
+				PackageVersion: pkg.Version,
+				Severity:       entry.vulnerability.severity,
+				Summary:        entry.vulnerability.summary,
+			})
+		}
+	}
+	sort.SliceStable(matches, func(left, right int) bool {
+		if matches[left].PackageName != matches[right].PackageName {
+			return matches[left].PackageName < matches[right].PackageName
+		}
+		return matches[left].Id < matches[right].Id
+	})
+	return matches
+}
+
+// match returns true if the version is affected, and the version which fixes
+// the vulnerability, if known.
+func (entry *affected) match(version string,
+	compareFunc func(left, right string) int) (bool, string) {
+	if _, ok := entry.versions[version]; ok {
+		return true, ""
+	}
+	for _, rangeEntry := range entry.ranges {
+		if rangeEntry.Type != "ECOSYSTEM" {
+			continue
+		}
+		var isAffected bool
+		var fixedVersion string
+		for _, event := range rangeEntry.Events {
+			switch {
+			case event.Introduced != "":
+				if event.Introduced == "0" ||
+					compareFunc(version, event.Introduced) >= 0 {
+					isAffected = true
+				}
+			case event.Fixed != "":
+				if compareFunc(version, event.Fixed) >= 0 {
+					isAffected = false
+				} else if isAffected && fixedVersion == "" {
+					fixedVersion = event.Fixed
+				}
+			case event.LastAffected != "":
+				if compareFunc(version, event.LastAffected) > 0 {
+					isAffected = false
+				}
+			}
+		}
+		if isAffected {
+			return true, fixedVersion
+		}
+	}
+	return false, ""
+}
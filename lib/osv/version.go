@@ -0,0 +1,259 @@
+package osv
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/verstr"
+)
+
+func getCompareFunc(ecosystem string) func(left, right string) int {
+	base, _ := splitEcosystem(ecosystem)
+	switch base {
+	case "Debian", "Ubuntu":
+		return compareDebianVersions
+	case "AlmaLinux", "Mageia", "openSUSE", "Photon OS", "Red Hat",
+		"Rocky Linux", "SUSE":
+		return compareRpmVersions
+	}
+	return compareGenericVersions
+}
+
+func isDigit(char byte) bool {
+	return char >= '0' && char <= '9'
+}
+
+func isAlpha(char byte) bool {
+	return (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z')
+}
+
+func sign(value int) int {
+	if value < 0 {
+		return -1
+	}
+	if value > 0 {
+		return 1
+	}
+	return 0
+}
+
+// splitEpoch splits "epoch:rest" into the numeric epoch and the rest.
+func splitEpoch(version string) (uint64, string) {
+	if index := strings.IndexByte(version, ':'); index > 0 {
+		epoch, err := strconv.ParseUint(version[:index], 10, 64)
+		if err == nil {
+			return epoch, version[index+1:]
+		}
+	}
+	return 0, version
+}
+
+func compareEpochs(left, right uint64) int {
+	if left < right {
+		return -1
+	}
+	if left > right {
+		return 1
+	}
+	return 0
+}
+
+func compareGenericVersions(left, right string) int {
+	if left == right {
+		return 0
+	}
+	if verstr.Less(left, right) {
+		return -1
+	}
+	return 1
+}
+
+// compareDebianVersions implements the dpkg version comparison algorithm.
+func compareDebianVersions(left, right string) int {
+	leftEpoch, leftVersion := splitEpoch(left)
+	rightEpoch, rightVersion := splitEpoch(right)
+	if result := compareEpochs(leftEpoch, rightEpoch); result != 0 {
+		return result
+	}
+	leftUpstream, leftRevision := splitDebianRevision(leftVersion)
+	rightUpstream, rightRevision := splitDebianRevision(rightVersion)
+	if result := compareDebianFragments(leftUpstream,
+		rightUpstream); result != 0 {
+		return result
+	}
+	return compareDebianFragments(leftRevision, rightRevision)
+}
+
+func splitDebianRevision(version string) (string, string) {
+	if index := strings.LastIndexByte(version, '-'); index >= 0 {
+		return version[:index], version[index+1:]
+	}
+	return version, ""
+}
+
+func debianOrder(str string, index int) int {
+	if index >= len(str) {
+		return 0
+	}
+	char := str[index]
+	switch {
+	case isDigit(char):
+		return 0
+	case isAlpha(char):
+		return int(char)
+	case char == '~':
+		return -1
+	}
+	return int(char) + 256
+}
+
+func compareDebianFragments(left, right string) int {
+	leftIndex, rightIndex := 0, 0
+	for leftIndex < len(left) || rightIndex < len(right) {
+		for (leftIndex < len(left) && !isDigit(left[leftIndex])) ||
+			(rightIndex < len(right) && !isDigit(right[rightIndex])) {
+			leftOrder := debianOrder(left, leftIndex)
+			rightOrder := debianOrder(right, rightIndex)
+			if leftOrder != rightOrder {
+				return sign(leftOrder - rightOrder)
+			}
+			leftIndex++
+			rightIndex++
+		}
+		for leftIndex < len(left) && left[leftIndex] == '0' {
+			leftIndex++
+		}
+		for rightIndex < len(right) && right[rightIndex] == '0' {
+			rightIndex++
+		}
+		firstDiff := 0
+		for leftIndex < len(left) && isDigit(left[leftIndex]) &&
+			rightIndex < len(right) && isDigit(right[rightIndex]) {
+			if firstDiff == 0 {
+				firstDiff = int(left[leftIndex]) - int(right[rightIndex])
+			}
+			leftIndex++
+			rightIndex++
+		}
+		if leftIndex < len(left) && isDigit(left[leftIndex]) {
+			return 1
+		}
+		if rightIndex < len(right) && isDigit(right[rightIndex]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return sign(firstDiff)
+		}
+	}
+	return 0
+}
+
+// compareRpmVersions implements the rpm version comparison algorithm for
+// [epoch:]version[-release] strings.
+func compareRpmVersions(left, right string) int {
+	leftEpoch, leftVersion := splitEpoch(left)
+	rightEpoch, rightVersion := splitEpoch(right)
+	if result := compareEpochs(leftEpoch, rightEpoch); result != 0 {
+		return result
+	}
+	leftVersion, leftRelease := splitDebianRevision(leftVersion)
+	rightVersion, rightRelease := splitDebianRevision(rightVersion)
+	if result := rpmVerCmp(leftVersion, rightVersion); result != 0 {
+		return result
+	}
+	if leftRelease == "" || rightRelease == "" {
+		return 0
+	}
+	return rpmVerCmp(leftRelease, rightRelease)
+}
+
+func isRpmSeparator(char byte) bool {
+	return !isDigit(char) && !isAlpha(char) && char != '~' && char != '^'
+}
+
+func rpmVerCmp(left, right string) int {
+	if left == right {
+		return 0
+	}
+	for len(left) > 0 || len(right) > 0 {
+		for len(left) > 0 && isRpmSeparator(left[0]) {
+			left = left[1:]
+		}
+		for len(right) > 0 && isRpmSeparator(right[0]) {
+			right = right[1:]
+		}
+		if (len(left) > 0 && left[0] == '~') ||
+			(len(right) > 0 && right[0] == '~') {
+			if len(left) < 1 || left[0] != '~' {
+				return 1
+			}
+			if len(right) < 1 || right[0] != '~' {
+				return -1
+			}
+			left = left[1:]
+			right = right[1:]
+			continue
+		}
+		if (len(left) > 0 && left[0] == '^') ||
+			(len(right) > 0 && right[0] == '^') {
+			if len(left) < 1 {
+				return -1
+			}
+			if len(right) < 1 {
+				return 1
+			}
+			if left[0] != '^' {
+				return 1
+			}
+			if right[0] != '^' {
+				return -1
+			}
+			left = left[1:]
+			right = right[1:]
+			continue
+		}
+		if len(left) < 1 || len(right) < 1 {
+			break
+		}
+		var leftSegment, rightSegment string
+		isNumeric := isDigit(left[0])
+		if isNumeric {
+			leftSegment, left = splitRpmSegment(left, isDigit)
+			rightSegment, right = splitRpmSegment(right, isDigit)
+		} else {
+			leftSegment, left = splitRpmSegment(left, isAlpha)
+			rightSegment, right = splitRpmSegment(right, isAlpha)
+		}
+		if rightSegment == "" {
+			if isNumeric {
+				return 1
+			}
+			return -1
+		}
+		if isNumeric {
+			leftSegment = strings.TrimLeft(leftSegment, "0")
+			rightSegment = strings.TrimLeft(rightSegment, "0")
+			if len(leftSegment) != len(rightSegment) {
+				return sign(len(leftSegment) - len(rightSegment))
+			}
+		}
+		if result := strings.Compare(leftSegment, rightSegment); result != 0 {
+			return result
+		}
+	}
+	if len(left) < 1 && len(right) < 1 {
+		return 0
+	}
+	if len(left) > 0 {
+		return 1
+	}
+	return -1
+}
+
+func splitRpmSegment(str string, selectFunc func(byte) bool) (string, string) {
+	index := 0
+	for index < len(str) && selectFunc(str[index]) {
+		index++
+	}
+	return str[:index], str[index:]
+}
@@ -0,0 +1,75 @@
+package osv
+
+import (
+	"testing"
+)
+
+func TestCompareDebianVersions(t *testing.T) {
+	var tests = []struct {
+		left, right string
+		want        int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.1", -1},
+		{"1.10", "1.9", 1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0", "1.0+deb11u1", -1},
+		{"1:0.9", "2.0", 1},
+		{"1.1.1n-0+deb11u3", "1.1.1n-0+deb11u4", -1},
+		{"1.1.1n-0+deb11u4", "1.1.1n-0+deb11u4", 0},
+		{"2.31-13+deb11u5", "2.31-13", 1},
+		{"1.0a", "1.0", 1},
+		{"1.01", "1.1", 0},
+	}
+	for _, test := range tests {
+		got := CompareVersions("Debian:11", test.left, test.right)
+		if got != test.want {
+			t.Errorf("compareDebianVersions(%q, %q) = %d, want %d",
+				test.left, test.right, got, test.want)
+		}
+	}
+}
+
+func TestCompareRpmVersions(t *testing.T) {
+	var tests = []struct {
+		left, right string
+		want        int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.1", -1},
+		{"1.10", "1.9", 1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0^git1", "1.0", 1},
+		{"1.0a", "1.0", 1},
+		{"1.0a", "1.0.1", -1},
+		{"2.17-326.el7_9", "2.17-325.el7_9", 1},
+		{"1:1.0-1", "2.0-1", 1},
+		{"1.0-1.el8", "1.0", 0},
+	}
+	for _, test := range tests {
+		got := CompareVersions("Rocky Linux:8", test.left, test.right)
+		if got != test.want {
+			t.Errorf("compareRpmVersions(%q, %q) = %d, want %d",
+				test.left, test.right, got, test.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	entry := &affected{
+		ranges: []rangeType{{
+			Type: "ECOSYSTEM",
+			Events: []eventType{
+				{Introduced: "0"},
+				{Fixed: "1.2-1"},
+			},
+		}},
+	}
+	if isAffected, fixed := entry.match("1.1-3",
+		compareDebianVersions); !isAffected || fixed != "1.2-1" {
+		t.Errorf("1.1-3: got %v %q, want true \"1.2-1\"", isAffected, fixed)
+	}
+	if isAffected, _ := entry.match("1.2-1", compareDebianVersions); isAffected {
+		t.Error("1.2-1: got affected")
+	}
+}
@@ -41,6 +41,29 @@ exchange messages using the appropriate coder (GOB is preferred, JSON is
 available as a fallback). Most method handlers wait for client messages and
 then respond. Once the method handler exits (without an error code), the
 server waits for another method call.
+
+Clients which cannot implement this protocol may use the REST gateway, which
+is registered with the HTTP default mux at:
+
+	/api/Service/Method     POST a JSON request (Content-Type:
+	                        application/json) to call Service.Method.
+	/api/openapi.json       OpenAPI description of the methods.
+
+The same authentication and authorisation checks are applied, using the
+client certificate from the TLS connection, so servers which require TLS
+must serve HTTP from a listener created with NewTlsSniffingListener. For
+request/reply methods the body is the request and the response is a single
+JSON object. For other (streamed) methods the body contains the messages for
+the method handler to decode and the response is a chunked stream of JSON
+values, one per line (NDJSON). Raw methods (which take only a *Conn) may read
+and write the connection directly, so their response is sent as
+application/octet-stream, although messages they encode are still JSON. Since
+a HTTP/1.x server cannot read the body once the response has started, the body
+(up to 16 MiB) is read completely before the method is called, so a client
+cannot send messages in reply to streamed responses. The request body is
+treated as the read side of a connection: once exhausted, reads block until the
+client closes the connection. If a streamed method fails after the response
+has started, the error is sent in the X-Srpc-Error trailer.
 */
 package srpc
 
@@ -52,6 +75,7 @@ import (
 	"flag"
 	stdlog "log"
 	"net"
+	"net/http"
 	"os"
 	"sync"
 	"time"
@@ -143,6 +167,21 @@ type AuthInformation struct {
 	Username         string
 }
 
+// CallRecord describes a call to an audited method (see
+// ReceiverOptions.AuditedMethods). It is passed to the hook registered with
+// SetCallHook.
+type CallRecord struct {
+	AuthInformation *AuthInformation
+	Duration        time.Duration
+	Error           error  // From the method or the reason for rejection.
+	Method          string // Service.Method
+	Rejected        bool   // If true, the method was not called.
+	RemoteAddr      string
+	Request         interface{} // The first message decoded, if any.
+	Response        interface{} // The response for request/reply methods.
+	StartTime       time.Time
+}
+
 type ClientI interface {
 	Call(serviceMethod string) (*Conn, error)
 	Close() error
@@ -210,6 +249,34 @@ func RegisterNameWithOptions(name string, rcvr interface{},
 	return registerName(name, rcvr, options)
 }
 
+// GetAuthInformationForRequest returns the authentication information for an
+// HTTP request received over a TLS connection with a client certificate. The
+// HaveMethodAccess field is set if the certificate would permit calling the
+// specified method. This allows web pages to apply the same authorisation as
+// SRPC methods.
+func GetAuthInformationForRequest(req *http.Request,
+	serviceMethod string) (*AuthInformation, error) {
+	return getAuthInformationForRequest(req, serviceMethod)
+}
+
+// GetServerTlsConfig returns a copy of the configuration registered with
+// RegisterServerTlsConfig, or nil if none was registered.
+func GetServerTlsConfig() *tls.Config {
+	if serverTlsConfig == nil {
+		return nil
+	}
+	return serverTlsConfig.Clone()
+}
+
+// NewTlsSniffingListener returns a listener which accepts both plain and TLS
+// connections from listener. Connections which start with a TLS handshake use
+// the configuration registered with RegisterServerTlsConfig, so that HTTP
+// handlers (such as the REST gateway) may authenticate the client with a
+// certificate. If no configuration was registered, listener is returned.
+func NewTlsSniffingListener(listener net.Listener) net.Listener {
+	return newTlsSniffingListener(listener)
+}
+
 // RegisterServerTlsConfig registers the configuration for TLS server
 // connections.
 // If requireTls is true, any non-TLS connection will be rejected.
@@ -259,6 +326,14 @@ func SetDefaultGrantMethod(grantMethod func(serviceMethod string,
 	defaultGrantMethod = grantMethod
 }
 
+// SetCallHook registers the hook function which is called after each call to
+// an audited method completes, and when a call to an audited method is
+// rejected by the authorisation checks or by a MethodBlocker. The hook is
+// called synchronously, so it should not block.
+func SetCallHook(hook func(record CallRecord)) {
+	callHook = hook
+}
+
 // SetDefaultLogger will override the default logger used.
 func SetDefaultLogger(l log.DebugLogger) {
 	logger = l
@@ -460,6 +535,8 @@ type Conn struct {
 	Decoder
 	Encoder
 	*bufio.ReadWriter
+	auditRequest     interface{}
+	auditResponse    interface{}
 	conn             net.Conn
 	groupList        map[string]struct{}
 	haveMethodAccess bool
@@ -518,5 +595,6 @@ func (conn *Conn) Username() string {
 }
 
 type ReceiverOptions struct {
-	PublicMethods []string
+	AuditedMethods []string // Calls are passed to the hook (see SetCallHook).
+	PublicMethods  []string
 }
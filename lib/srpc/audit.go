@@ -0,0 +1,51 @@
+package srpc
+
+import (
+	"reflect"
+	"time"
+)
+
+// auditDecoder records a copy of the first message decoded for the audit
+// record.
+type auditDecoder struct {
+	conn    *Conn
+	decoder Decoder
+}
+
+func (d *auditDecoder) Decode(e interface{}) error {
+	if err := d.decoder.Decode(e); err != nil {
+		return err
+	}
+	if d.conn.auditRequest == nil {
+		d.conn.auditRequest = reflect.Indirect(reflect.ValueOf(e)).Interface()
+	}
+	return nil
+}
+
+// audit passes a record of the call to the hook, if the method is audited.
+func (m *methodWrapper) audit(conn *Conn, startTime time.Time, err error,
+	rejected bool) {
+	if !m.audited || callHook == nil {
+		return
+	}
+	if err == ErrorCloseClient {
+		err = nil
+	}
+	callHook(CallRecord{
+		AuthInformation: conn.GetAuthInformation(),
+		Duration:        time.Since(startTime),
+		Error:           err,
+		Method:          m.serviceMethod,
+		Rejected:        rejected,
+		RemoteAddr:      conn.remoteAddr,
+		Request:         conn.auditRequest,
+		Response:        conn.auditResponse,
+		StartTime:       startTime,
+	})
+}
+
+func (m *methodWrapper) auditRejected(conn *Conn, err error) {
+	conn.auditRequest = nil
+	conn.auditResponse = nil
+	m.audit(conn, time.Now(), err, true)
+}
@@ -0,0 +1,90 @@
+package srpc
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/proto/test"
+)
+
+type auditServerType struct {
+	serverType
+}
+
+var (
+	auditMutex   sync.Mutex
+	auditRecords []CallRecord
+)
+
+func init() {
+	RegisterNameWithOptions("AuditTest", &auditServerType{},
+		ReceiverOptions{AuditedMethods: []string{"Plain", "RequestReply"}})
+	SetCallHook(func(record CallRecord) {
+		auditMutex.Lock()
+		defer auditMutex.Unlock()
+		auditRecords = append(auditRecords, record)
+	})
+}
+
+func getAuditRecords() []CallRecord {
+	auditMutex.Lock()
+	defer auditMutex.Unlock()
+	records := auditRecords
+	auditRecords = nil
+	return records
+}
+
+func TestAuditRequestReply(t *testing.T) {
+	getAuditRecords()
+	client, err := makeClientServer(&gobCoder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	var response test.EchoResponse
+	err = client.RequestReply("AuditTest.RequestReply",
+		test.EchoRequest{Request: "audit0"}, &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.RequestReply("Test.RequestReply",
+		test.EchoRequest{Request: "unaudited"}, &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := getAuditRecords()
+	if len(records) != 1 {
+		t.Fatalf("number of records: %d != 1", len(records))
+	}
+	record := records[0]
+	if record.Method != "AuditTest.RequestReply" {
+		t.Errorf("Method: %s != AuditTest.RequestReply", record.Method)
+	}
+	if record.Error != nil || record.Rejected {
+		t.Errorf("unexpected failure: %v", record.Error)
+	}
+	if request, ok := record.Request.(test.EchoRequest); !ok {
+		t.Errorf("unexpected request: %v", record.Request)
+	} else if request.Request != "audit0" {
+		t.Errorf("Request: %s != audit0", request.Request)
+	}
+	if response, ok := record.Response.(test.EchoResponse); !ok {
+		t.Errorf("unexpected response: %v", record.Response)
+	} else if response.Response != "audit0" {
+		t.Errorf("Response: %s != audit0", response.Response)
+	}
+}
+
+func TestAuditStream(t *testing.T) {
+	getAuditRecords()
+	makeRestRequest("AuditTest/Plain", `{"Request": "audit1"}`)
+	records := getAuditRecords()
+	if len(records) != 1 {
+		t.Fatalf("number of records: %d != 1", len(records))
+	}
+	if request, ok := records[0].Request.(test.EchoRequest); !ok {
+		t.Errorf("unexpected request: %v", records[0].Request)
+	} else if request.Request != "audit1" {
+		t.Errorf("Request: %s != audit1", request.Request)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Package auditlog records calls to audited SRPC methods.
+
+Each call to a method listed in ReceiverOptions.AuditedMethods (typically the
+methods which change state) is recorded with the identity of the caller, a
+summary of the request with secrets redacted, the result and the duration.
+Calls which were rejected by the authorisation checks or by a MethodBlocker
+are also recorded. Records are written as JSON lines to a local log using
+lib/logbuf, which rotates the files and enforces the quota (lines which are
+not JSON objects are written by lib/logbuf). Records may also be forwarded to
+a collector, which is any server which called Setup with -auditLogCollect.
+*/
+package auditlog
+
+import (
+	"flag"
+
+	"github.com/Cloud-Foundations/Dominator/lib/flagutil"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/logbuf"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/auditlog"
+)
+
+var (
+	collect = flag.Bool("auditLogCollect", false,
+		"If true, accept audit records forwarded from other servers")
+	collector = flag.String("auditLogCollector", "",
+		"Address of server to forward audit records to (host:port)")
+	directory = flag.String("auditLogDir", "",
+		"Directory to write audit log to. Default: audit subdirectory of "+
+			"-logDir")
+	maxFileSize = flagutil.Size(10 << 20)
+	quota       = flagutil.Size(100 << 20)
+)
+
+// Logger writes audit records to a local log and forwards them to a
+// collector.
+type Logger struct {
+	forwardChannel chan<- proto.Record // nil: do not forward.
+	logBuffer      *logbuf.LogBuffer   // nil: do not write locally.
+	logger         log.DebugLogger
+	options        Options
+}
+
+type Options struct {
+	Collector   string // Address of server to forward records to.
+	Directory   string // If empty, records are not written locally.
+	MaxFileSize flagutil.Size
+	Quota       flagutil.Size // Rotated files are deleted beyond this.
+}
+
+func init() {
+	flag.Var(&maxFileSize, "auditLogMaxFileSize",
+		"Maximum size for an audit log file. If exceeded, new file is created")
+	flag.Var(&quota, "auditLogQuota",
+		"Audit log quota. If exceeded, old audit logs are deleted")
+}
+
+// New creates a Logger. Call its Record method to record calls.
+func New(options Options, logger log.DebugLogger) (*Logger, error) {
+	return newLogger(options, logger)
+}
+
+// Setup creates a Logger using the command-line flags and registers it with
+// srpc.SetCallHook. If -auditLogDir is empty, the audit subdirectory of the
+// lib/logbuf -logDir is used. If both are empty, no local log is written. If
+// -auditLogCollect is true, the AuditLog receiver is also registered so that
+// other servers can forward records to this server.
+func Setup(logger log.DebugLogger) error {
+	return setup(logger)
+}
+
+// Record records a call. It is suitable for passing to srpc.SetCallHook.
+func (l *Logger) Record(record srpc.CallRecord) {
+	l.record(record)
+}
+
+// WriteRecords writes records to the local log without forwarding them.
+func (l *Logger) WriteRecords(records []proto.Record) error {
+	return l.writeRecords(records)
+}
@@ -0,0 +1,36 @@
+package auditlog
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/auditlog"
+)
+
+type collectorType struct {
+	logger *Logger
+}
+
+func registerCollector(logger *Logger) error {
+	return srpc.RegisterName("AuditLog", &collectorType{logger})
+}
+
+// AddRecords writes records forwarded from another server to the local log,
+// tagged with the username from the certificate of the server. They are not
+// forwarded again.
+func (t *collectorType) AddRecords(conn *srpc.Conn,
+	request proto.AddRecordsRequest,
+	reply *proto.AddRecordsResponse) error {
+	sender := conn.Username()
+	if sender == "" {
+		*reply = proto.AddRecordsResponse{"sender is not authenticated"}
+		return nil
+	}
+	records := make([]proto.Record, 0, len(request.Records))
+	for _, record := range request.Records {
+		record.Hostname = sender
+		records = append(records, record)
+	}
+	*reply = proto.AddRecordsResponse{
+		errors.ErrorToString(t.logger.writeRecords(records))}
+	return nil
+}
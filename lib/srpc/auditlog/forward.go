@@ -0,0 +1,66 @@
+package auditlog
+
+import (
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/backoffdelay"
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/auditlog"
+)
+
+const (
+	forwardBufferLength = 4096
+	maxRecordsPerBatch  = 256
+)
+
+// forwarder sends the records it receives to the collector in batches. Records
+// are held until the collector accepts them.
+func (l *Logger) forwarder(forwardChannel <-chan proto.Record) {
+	var client *srpc.Client
+	sleeper := backoffdelay.NewExponential(time.Second, time.Minute, 1)
+	for record := range forwardChannel {
+		records := []proto.Record{record}
+	batchLoop:
+		for len(records) < maxRecordsPerBatch {
+			select {
+			case record := <-forwardChannel:
+				records = append(records, record)
+			default:
+				break batchLoop
+			}
+		}
+		for {
+			var err error
+			if client == nil {
+				client, err = srpc.DialHTTP("tcp", l.options.Collector,
+					time.Minute)
+			}
+			if err == nil {
+				err = l.sendRecords(client, records)
+			}
+			if err == nil {
+				sleeper.Reset()
+				break
+			}
+			l.logger.Printf("error forwarding audit records to: %s: %s\n",
+				l.options.Collector, err)
+			if client != nil {
+				client.Close()
+				client = nil
+			}
+			sleeper.Sleep()
+		}
+	}
+}
+
+func (l *Logger) sendRecords(client *srpc.Client,
+	records []proto.Record) error {
+	request := proto.AddRecordsRequest{Records: records}
+	var reply proto.AddRecordsResponse
+	err := client.RequestReply("AuditLog.AddRecords", request, &reply)
+	if err != nil {
+		return err
+	}
+	return errors.New(reply.Error)
+}
@@ -0,0 +1,124 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/logbuf"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/auditlog"
+)
+
+func setup(logger log.DebugLogger) error {
+	directory := *directory
+	if directory == "" {
+		if logDir := logbuf.GetStandardOptions().Directory; logDir != "" {
+			directory = filepath.Join(logDir, "audit")
+		}
+	}
+	auditLogger, err := newLogger(Options{
+		Collector:   *collector,
+		Directory:   directory,
+		MaxFileSize: maxFileSize,
+		Quota:       quota,
+	}, logger)
+	if err != nil {
+		return err
+	}
+	if *collect {
+		if auditLogger.options.Directory == "" {
+			return errors.New("cannot collect audit records without a log")
+		}
+		if err := registerCollector(auditLogger); err != nil {
+			return err
+		}
+	}
+	srpc.SetCallHook(auditLogger.Record)
+	return nil
+}
+
+func newLogger(options Options, logger log.DebugLogger) (*Logger, error) {
+	auditLogger := &Logger{
+		logger:  logger,
+		options: options,
+	}
+	if options.Directory != "" {
+		// Create the directory here, since lib/logbuf does not return errors.
+		err := os.MkdirAll(options.Directory, fsutil.PrivateDirPerms)
+		if err != nil {
+			return nil, err
+		}
+		auditLogger.logBuffer = logbuf.NewWithOptions(logbuf.Options{
+			Directory:   options.Directory,
+			MaxFileSize: options.MaxFileSize,
+			Quota:       options.Quota,
+		})
+	}
+	if options.Collector != "" {
+		forwardChannel := make(chan proto.Record, forwardBufferLength)
+		auditLogger.forwardChannel = forwardChannel
+		go auditLogger.forwarder(forwardChannel)
+	}
+	return auditLogger, nil
+}
+
+// makeRecord converts a call record into an audit record.
+func makeRecord(callRecord srpc.CallRecord) proto.Record {
+	record := proto.Record{
+		Duration:   callRecord.Duration,
+		Method:     callRecord.Method,
+		Rejected:   callRecord.Rejected,
+		RemoteAddr: callRecord.RemoteAddr,
+		Request:    summarise(callRecord.Request),
+		StartTime:  callRecord.StartTime,
+	}
+	if authInfo := callRecord.AuthInformation; authInfo != nil {
+		record.Username = authInfo.Username
+		for group := range authInfo.GroupList {
+			record.Groups = append(record.Groups, group)
+		}
+		sort.Strings(record.Groups)
+	}
+	if callRecord.Error != nil {
+		record.Error = callRecord.Error.Error()
+	} else {
+		record.Error = getResponseError(callRecord.Response)
+	}
+	return record
+}
+
+func (l *Logger) record(callRecord srpc.CallRecord) {
+	record := makeRecord(callRecord)
+	if err := l.writeRecords([]proto.Record{record}); err != nil {
+		l.logger.Printf("error writing audit record: %s\n", err)
+	}
+	if l.forwardChannel != nil {
+		select {
+		case l.forwardChannel <- record:
+		default:
+			l.logger.Printf("dropping audit record for %s: queue full\n",
+				record.Method)
+		}
+	}
+}
+
+// writeRecords writes each record as a line to the local log. The log buffer
+// flushes the file shortly after writing.
+func (l *Logger) writeRecords(records []proto.Record) error {
+	if l.logBuffer == nil {
+		return nil
+	}
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		l.logBuffer.Write(append(line, '\n'))
+	}
+	return nil
+}
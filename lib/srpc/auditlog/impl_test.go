@@ -0,0 +1,62 @@
+package auditlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+	proto "github.com/Cloud-Foundations/Dominator/proto/auditlog"
+)
+
+func TestWriteRecords(t *testing.T) {
+	directory := filepath.Join(t.TempDir(), "audit")
+	auditLogger, err := newLogger(Options{Directory: directory},
+		testlogger.New(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = auditLogger.writeRecords([]proto.Record{
+		{Method: "Test.One", Username: "alice"},
+		{Method: "Test.Two", Username: "bob"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := auditLogger.logBuffer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(filepath.Join(directory, "latest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var methods []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record proto.Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		methods = append(methods, record.Method)
+	}
+	if len(methods) != 2 || methods[0] != "Test.One" ||
+		methods[1] != "Test.Two" {
+		t.Errorf("methods: %v", methods)
+	}
+}
+
+func TestNoDirectory(t *testing.T) {
+	auditLogger, err := newLogger(Options{}, testlogger.New(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auditLogger.logBuffer != nil {
+		t.Error("log buffer created without a directory")
+	}
+	if err := auditLogger.writeRecords([]proto.Record{{}}); err != nil {
+		t.Error(err)
+	}
+}
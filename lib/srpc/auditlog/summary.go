@@ -0,0 +1,165 @@
+package auditlog
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxSummaryDepth        = 8
+	maxSummaryElements     = 8
+	maxSummaryLength       = 1024
+	maxSummaryStringLength = 64
+	redacted               = "<redacted>"
+)
+
+var (
+	secretFieldNames = regexp.MustCompile(`(?i)credential|identitykey|` +
+		`passphrase|password|privatekey|secret|token`)
+	typeOfTextMarshaler = reflect.TypeOf(
+		(*encoding.TextMarshaler)(nil)).Elem()
+)
+
+type summariser struct {
+	builder strings.Builder
+}
+
+// getResponseError returns the Error field of a response, if present.
+func getResponseError(response interface{}) string {
+	value := reflect.Indirect(reflect.ValueOf(response))
+	if value.Kind() != reflect.Struct {
+		return ""
+	}
+	field := value.FieldByName("Error")
+	if field.Kind() != reflect.String {
+		return ""
+	}
+	return field.String()
+}
+
+// summarise returns a compact description of a request. Byte slices are
+// replaced with their length, long strings and slices are truncated and the
+// values of fields which may contain secrets are redacted.
+func summarise(request interface{}) string {
+	if request == nil {
+		return ""
+	}
+	s := &summariser{}
+	s.write(reflect.ValueOf(request), 0)
+	summary := s.builder.String()
+	if len(summary) > maxSummaryLength {
+		summary = summary[:maxSummaryLength-3] + "..."
+	}
+	return summary
+}
+
+func (s *summariser) write(value reflect.Value, depth int) {
+	if depth > maxSummaryDepth || s.builder.Len() > maxSummaryLength {
+		s.builder.WriteString("...")
+		return
+	}
+	if value.Kind() != reflect.Ptr && value.Kind() != reflect.Interface &&
+		value.Type().Implements(typeOfTextMarshaler) {
+		if text, err := value.Interface().(encoding.TextMarshaler).
+			MarshalText(); err == nil {
+			s.writeString(string(text))
+			return
+		}
+	}
+	switch value.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16,
+		reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8,
+		reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		fmt.Fprint(&s.builder, value.Interface())
+	case reflect.String:
+		s.writeString(value.String())
+	case reflect.Array, reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			fmt.Fprintf(&s.builder, "<%d bytes>", value.Len())
+			return
+		}
+		s.builder.WriteByte('[')
+		for index := 0; index < value.Len(); index++ {
+			if index > 0 {
+				s.builder.WriteByte(' ')
+			}
+			if index >= maxSummaryElements {
+				fmt.Fprintf(&s.builder, "...(%d total)", value.Len())
+				break
+			}
+			s.write(value.Index(index), depth+1)
+		}
+		s.builder.WriteByte(']')
+	case reflect.Map:
+		s.writeMap(value, depth)
+	case reflect.Interface, reflect.Ptr:
+		if value.IsNil() {
+			s.builder.WriteString("nil")
+		} else {
+			s.write(value.Elem(), depth)
+		}
+	case reflect.Struct:
+		s.writeStruct(value, depth)
+	default:
+		s.builder.WriteString(value.Kind().String())
+	}
+}
+
+func (s *summariser) writeMap(value reflect.Value, depth int) {
+	entries := make([]string, 0, value.Len())
+	iter := value.MapRange()
+	for iter.Next() {
+		entry := &summariser{}
+		entry.write(iter.Key(), depth+1)
+		entry.builder.WriteByte(':')
+		if secretFieldNames.MatchString(fmt.Sprint(iter.Key())) {
+			entry.builder.WriteString(redacted)
+		} else {
+			entry.write(iter.Value(), depth+1)
+		}
+		entries = append(entries, entry.builder.String())
+	}
+	sort.Strings(entries)
+	if len(entries) > maxSummaryElements {
+		entries = append(entries[:maxSummaryElements],
+			"...("+strconv.Itoa(len(entries))+" total)")
+	}
+	s.builder.WriteString("map[" + strings.Join(entries, " ") + "]")
+}
+
+func (s *summariser) writeString(str string) {
+	if len(str) > maxSummaryStringLength {
+		str = str[:maxSummaryStringLength] + "..."
+	}
+	s.builder.WriteString(strconv.Quote(str))
+}
+
+// writeStruct writes the exported fields which are not zero values.
+func (s *summariser) writeStruct(value reflect.Value, depth int) {
+	s.builder.WriteByte('{')
+	first := true
+	for index := 0; index < value.NumField(); index++ {
+		field := value.Type().Field(index)
+		fieldValue := value.Field(index)
+		if field.PkgPath != "" || fieldValue.IsZero() {
+			continue
+		}
+		if !first {
+			s.builder.WriteByte(' ')
+		}
+		first = false
+		s.builder.WriteString(field.Name + ":")
+		if secretFieldNames.MatchString(field.Name) {
+			s.builder.WriteString(redacted)
+		} else {
+			s.write(fieldValue, depth+1)
+		}
+	}
+	s.builder.WriteByte('}')
+}
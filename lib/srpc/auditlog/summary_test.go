@@ -0,0 +1,94 @@
+package auditlog
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+)
+
+type testRequestType struct {
+	AccessToken []byte
+	Address     net.IP
+	Count       uint
+	Names       []string
+	Password    string
+	Tags        map[string]string
+	hidden      string
+}
+
+type testResponseType struct {
+	Error string
+}
+
+func TestGetResponseError(t *testing.T) {
+	if err := getResponseError(testResponseType{"failed"}); err != "failed" {
+		t.Errorf("error: %s != failed", err)
+	}
+	if err := getResponseError(&testResponseType{}); err != "" {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := getResponseError(nil); err != "" {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestMakeRecord(t *testing.T) {
+	record := makeRecord(srpc.CallRecord{
+		AuthInformation: &srpc.AuthInformation{
+			GroupList: map[string]struct{}{"b": {}, "a": {}},
+			Username:  "user",
+		},
+		Error:    errors.New("denied"),
+		Method:   "Service.Method",
+		Rejected: true,
+		Response: testResponseType{"ignored"},
+	})
+	if record.Username != "user" {
+		t.Errorf("Username: %s != user", record.Username)
+	}
+	if strings.Join(record.Groups, ",") != "a,b" {
+		t.Errorf("Groups: %v != [a b]", record.Groups)
+	}
+	if record.Error != "denied" {
+		t.Errorf("Error: %s != denied", record.Error)
+	}
+	record = makeRecord(srpc.CallRecord{Response: testResponseType{"bad"}})
+	if record.Error != "bad" {
+		t.Errorf("Error: %s != bad", record.Error)
+	}
+}
+
+func TestSummarise(t *testing.T) {
+	summary := summarise(testRequestType{
+		AccessToken: []byte("token"),
+		Address:     net.ParseIP("10.0.0.1"),
+		Names:       []string{"a", "b", "c", "d", "e", "f", "g", "h", "i"},
+		Password:    "hunter2",
+		Tags:        map[string]string{"Name": "vm", "ApiToken": "abc"},
+		hidden:      "hidden",
+	})
+	expected := `{AccessToken:<redacted> Address:"10.0.0.1" ` +
+		`Names:["a" "b" "c" "d" "e" "f" "g" "h" ...(9 total)] ` +
+		`Password:<redacted> ` +
+		`Tags:map["ApiToken":<redacted> "Name":"vm"]}`
+	if summary != expected {
+		t.Errorf("summary: %s != %s", summary, expected)
+	}
+	summary = summarise(testRequestType{Names: []string{
+		strings.Repeat("x", 2*maxSummaryStringLength)}})
+	expected = `{Names:["` + strings.Repeat("x", maxSummaryStringLength) +
+		`..."]}`
+	if summary != expected {
+		t.Errorf("summary: %s != %s", summary, expected)
+	}
+	summary = summarise(make([]string, 10*maxSummaryLength))
+	if len(summary) > maxSummaryLength {
+		t.Errorf("summary length: %d > %d", len(summary), maxSummaryLength)
+	}
+	if summary := summarise(nil); summary != "" {
+		t.Errorf("unexpected summary: %s", summary)
+	}
+}
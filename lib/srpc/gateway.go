@@ -0,0 +1,222 @@
+package srpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+const (
+	restApiPath         = "/api/"
+	restErrorTrailer    = "X-Srpc-Error"
+	restMaxRequestBytes = 16 << 20
+	restOpenApiPath     = restApiPath + "openapi.json"
+	restRawMimeType     = "application/octet-stream"
+	restStreamMimeType  = "application/x-ndjson"
+)
+
+// restRequestReader reads the buffered request body. Once the body is
+// exhausted it blocks until the client goes away, so that the body behaves
+// like the read side of an SRPC connection.
+type restRequestReader struct {
+	body io.Reader
+	done <-chan struct{}
+}
+
+// restResponseWriter flushes each write, so that streamed responses are sent
+// as they are produced.
+type restResponseWriter struct {
+	flusher http.Flusher
+	written bool
+	writer  io.Writer
+}
+
+func (r *restRequestReader) Read(p []byte) (int, error) {
+	nRead, err := r.body.Read(p)
+	if err == io.EOF && nRead < 1 {
+		<-r.done
+	}
+	return nRead, err
+}
+
+func (w *restResponseWriter) Write(p []byte) (int, error) {
+	w.written = true
+	nWritten, err := w.writer.Write(p)
+	if w.flusher != nil {
+		w.flusher.Flush()
+	}
+	return nWritten, err
+}
+
+func restApiHttpHandler(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == restOpenApiPath {
+		openApiHttpHandler(w, req)
+		return
+	}
+	if req.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// Requiring JSON (or binary data for raw methods) prevents browsers
+	// sending simple cross-site requests.
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json", restRawMimeType, restStreamMimeType:
+	default:
+		http.Error(w, "Content-Type must be application/json",
+			http.StatusUnsupportedMediaType)
+		return
+	}
+	splitPath := strings.Split(strings.TrimPrefix(req.URL.Path, restApiPath),
+		"/")
+	if len(splitPath) != 2 || splitPath[0] == "" {
+		http.Error(w, "path must be "+restApiPath+"Service/Method",
+			http.StatusNotFound)
+		return
+	}
+	serviceName := splitPath[0]
+	methodName := splitPath[1]
+	serviceMethod := serviceName + "." + methodName
+	if receiver, ok := receivers[serviceName]; !ok {
+		http.Error(w, "unknown service: "+serviceName, http.StatusNotFound)
+		return
+	} else if _, ok := receiver.methods[methodName]; !ok {
+		http.Error(w, serviceName+": unknown method: "+methodName,
+			http.StatusNotFound)
+		return
+	}
+	conn, err := makeRestConn(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	method, err := conn.findMethod(serviceMethod)
+	if err != nil {
+		if err == ErrorAccessToMethodDenied {
+			http.Error(w, err.Error(), http.StatusForbidden)
+		} else {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		}
+		return
+	}
+	defer conn.callReleaseNotifier()
+	// A HTTP/1.x server cannot read the body once the response has started,
+	// so read all of it first.
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body,
+		restMaxRequestBytes))
+	if err != nil {
+		http.Error(w, "error reading request: "+err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+	restResponse := &restResponseWriter{writer: w}
+	if flusher, ok := w.(http.Flusher); ok {
+		restResponse.flusher = flusher
+	}
+	conn.ReadWriter = bufio.NewReadWriter(
+		bufio.NewReader(&restRequestReader{bytes.NewReader(body),
+			req.Context().Done()}),
+		bufio.NewWriter(restResponse))
+	if method.methodType == methodTypeRequestReply {
+		serveRestRequestReply(w, body, conn, method)
+	} else {
+		serveRestStream(w, conn, serviceMethod, method, restResponse)
+	}
+}
+
+// makeRestConn returns a server connection with the authentication
+// information for the request, applying the same checks as for SRPC
+// connections.
+func makeRestConn(req *http.Request) (*Conn, error) {
+	conn := &Conn{
+		localAddr:  req.Host,
+		remoteAddr: req.RemoteAddr,
+	}
+	if tlsRequired {
+		if req.TLS == nil {
+			return nil, ErrorMissingCertificate
+		}
+		if serverTlsConfig == nil ||
+			!checkVerifiedChains(req.TLS.VerifiedChains,
+				serverTlsConfig.ClientCAs) {
+			return nil, ErrorBadCertificate
+		}
+	}
+	allowMethodPowers := req.URL.Query().Get(doNotUseMethodPowers) != "true"
+	if req.TLS != nil {
+		var err error
+		conn.isEncrypted = true
+		conn.username, conn.permittedMethods, conn.groupList, err =
+			getAuth(*req.TLS, allowMethodPowers)
+		if err != nil {
+			return nil, err
+		}
+	} else if !allowMethodPowers {
+		conn.permittedMethods = make(map[string]struct{})
+	}
+	return conn, nil
+}
+
+// serveRestRequestReply decodes the request from the body and writes the
+// response as a single JSON object.
+func serveRestRequestReply(w http.ResponseWriter, body []byte, conn *Conn,
+	method *methodWrapper) {
+	request := reflect.New(method.requestType)
+	err := json.NewDecoder(bytes.NewReader(body)).Decode(request.Interface())
+	if err != nil && err != io.EOF { // An empty body is a zero request.
+		http.Error(w, "error decoding request: "+err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+	var response reflect.Value
+	err = method.track(conn, func() error {
+		var err error
+		response, err = method.callRequestReply(conn, request)
+		return err
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response.Interface()); err != nil {
+		logger.Printf("error encoding response: %s\n", err)
+	}
+}
+
+// serveRestStream calls a streamed method, with the buffered body providing the
+// messages to decode and the encoded messages sent in a chunked response,
+// one JSON value per line. Raw methods may read and write the connection
+// directly, so their response is sent as binary data. Since the status has
+// been sent by the time a method fails, a failure is reported in the
+// X-Srpc-Error trailer.
+func serveRestStream(w http.ResponseWriter, conn *Conn, serviceMethod string,
+	method *methodWrapper, restResponse *restResponseWriter) {
+	if method.methodType == methodTypeRaw {
+		w.Header().Set("Content-Type", restRawMimeType)
+	} else {
+		w.Header().Set("Content-Type", restStreamMimeType)
+	}
+	w.Header().Set("Trailer", restErrorTrailer)
+	err := method.call(conn, &jsonCoder{})
+	if flushErr := conn.Flush(); err == nil {
+		err = flushErr
+	}
+	if err == nil || err == ErrorCloseClient {
+		return
+	}
+	if !restResponse.written {
+		w.Header().Del("Trailer")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(restErrorTrailer, err.Error())
+	logger.Printf("SRPC/REST(%s): %s: %s\n", conn.remoteAddr, serviceMethod,
+		err)
+}
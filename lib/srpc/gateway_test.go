@@ -0,0 +1,116 @@
+package srpc
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/proto/test"
+)
+
+func makeRestRequest(serviceMethod, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", restApiPath+serviceMethod,
+		strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	restApiHttpHandler(recorder, req)
+	return recorder
+}
+
+func TestRestRequestReply(t *testing.T) {
+	recorder := makeRestRequest("Test/RequestReply",
+		`{"Request": "rest0"}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status: %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var response test.EchoResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Response != "rest0" {
+		t.Errorf("Response: %s != rest0", response.Response)
+	}
+}
+
+func TestRestStream(t *testing.T) {
+	recorder := makeRestRequest("Test/Plain", `{"Request": "rest1"}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status: %d: %s", recorder.Code, recorder.Body.String())
+	}
+	// Test.Plain is a raw method.
+	if contentType := recorder.Header().Get("Content-Type"); contentType !=
+		restRawMimeType {
+		t.Errorf("Content-Type: %s != %s", contentType, restRawMimeType)
+	}
+	scanner := bufio.NewScanner(recorder.Body)
+	if !scanner.Scan() {
+		t.Fatal("no response line")
+	}
+	var response test.EchoResponse
+	if err := json.Unmarshal(scanner.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Response != "rest1" {
+		t.Errorf("Response: %s != rest1", response.Response)
+	}
+}
+
+func TestRestErrors(t *testing.T) {
+	tests := []struct {
+		serviceMethod string
+		status        int
+	}{
+		{"NoService/None", http.StatusNotFound},
+		{"Test/None", http.StatusNotFound},
+		{"Test", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		recorder := makeRestRequest(test.serviceMethod, "{}")
+		if recorder.Code != test.status {
+			t.Errorf("%s: status: %d != %d",
+				test.serviceMethod, recorder.Code, test.status)
+		}
+	}
+	recorder := makeRestRequest("Test/RequestReply", "{")
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("bad request: status: %d != %d",
+			recorder.Code, http.StatusBadRequest)
+	}
+	req := httptest.NewRequest("POST", restApiPath+"Test/RequestReply",
+		strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "text/plain")
+	recorder = httptest.NewRecorder()
+	restApiHttpHandler(recorder, req)
+	if recorder.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("text/plain: status: %d != %d",
+			recorder.Code, http.StatusUnsupportedMediaType)
+	}
+}
+
+func TestOpenApiDescription(t *testing.T) {
+	description := makeOpenApiDescription()
+	paths := description["paths"].(jsonObject)
+	if _, ok := paths[restApiPath+"Test/RequestReply"]; !ok {
+		t.Fatal("missing Test/RequestReply path")
+	}
+	components := description["components"].(jsonObject)
+	schemas := components["schemas"].(map[string]interface{})
+	schema, ok := schemas["test.EchoRequest"].(jsonObject)
+	if !ok {
+		t.Fatal("missing test.EchoRequest schema")
+	}
+	plain := paths[restApiPath+"Test/Plain"].(jsonObject)["post"].(jsonObject)
+	if plain["summary"] != "Raw method" {
+		t.Errorf("Test/Plain summary: %v", plain["summary"])
+	}
+	properties := schema["properties"].(jsonObject)
+	if _, ok := properties["Request"]; !ok {
+		t.Error("missing Request property")
+	}
+	if _, err := json.Marshal(description); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,257 @@
+package srpc
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const openApiVersion = "3.0.3"
+
+type openApiSchemas struct {
+	names   map[reflect.Type]string // Component name for named structs.
+	schemas map[string]interface{}  // Key: component name.
+}
+
+type jsonObject map[string]interface{}
+
+var (
+	invalidComponentNameCharacters = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+	typeOfJsonMarshaler            = reflect.TypeOf(
+		(*json.Marshaler)(nil)).Elem()
+	typeOfTextMarshaler = reflect.TypeOf(
+		(*encoding.TextMarshaler)(nil)).Elem()
+	typeOfTime = reflect.TypeOf(time.Time{})
+)
+
+// makeOpenApiDescription returns an OpenAPI description of the methods
+// available through the REST gateway.
+func makeOpenApiDescription() jsonObject {
+	schemas := &openApiSchemas{
+		names:   make(map[reflect.Type]string),
+		schemas: make(map[string]interface{}),
+	}
+	paths := make(jsonObject)
+	for receiverName, receiver := range receivers {
+		if receiverName == "" {
+			continue
+		}
+		for methodName, method := range receiver.methods {
+			paths[restApiPath+receiverName+"/"+methodName] = jsonObject{
+				"post": schemas.makeOperation(receiverName, methodName,
+					method),
+			}
+		}
+	}
+	return jsonObject{
+		"openapi": openApiVersion,
+		"info": jsonObject{
+			"title":   "SRPC REST gateway",
+			"version": "1",
+		},
+		"paths":      paths,
+		"components": jsonObject{"schemas": schemas.schemas},
+	}
+}
+
+func openApiHttpHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "    ")
+	encoder.Encode(makeOpenApiDescription())
+}
+
+func (s *openApiSchemas) makeOperation(receiverName, methodName string,
+	method *methodWrapper) jsonObject {
+	operation := jsonObject{
+		"operationId": receiverName + "." + methodName,
+		"tags":        []string{receiverName},
+	}
+	if !method.public {
+		operation["description"] = "Requires method access."
+	}
+	if method.methodType == methodTypeRaw {
+		operation["summary"] = "Raw method"
+		rawContent := jsonObject{
+			restRawMimeType: jsonObject{
+				"schema": jsonObject{"type": "string", "format": "binary"},
+			},
+		}
+		operation["requestBody"] = jsonObject{"content": rawContent}
+		operation["responses"] = jsonObject{
+			"200": jsonObject{
+				"description": "The data written by the method, which may" +
+					" include JSON values. Failures are reported in the " +
+					restErrorTrailer + " trailer.",
+				"content": rawContent,
+			},
+		}
+		return operation
+	}
+	if method.methodType != methodTypeRequestReply {
+		operation["summary"] = "Streamed method"
+		streamContent := jsonObject{
+			restStreamMimeType: jsonObject{"schema": jsonObject{}},
+		}
+		operation["requestBody"] = jsonObject{"content": streamContent}
+		operation["responses"] = jsonObject{
+			"200": jsonObject{
+				"description": "A stream of JSON values, one per line." +
+					" Failures are reported in the " + restErrorTrailer +
+					" trailer.",
+				"content": streamContent,
+			},
+		}
+		return operation
+	}
+	operation["requestBody"] = jsonObject{
+		"content": jsonObject{
+			"application/json": jsonObject{
+				"schema": s.makeSchema(method.requestType),
+			},
+		},
+	}
+	operation["responses"] = jsonObject{
+		"200": jsonObject{
+			"description": "Reply",
+			"content": jsonObject{
+				"application/json": jsonObject{
+					"schema": s.makeSchema(method.responseType),
+				},
+			},
+		},
+	}
+	return operation
+}
+
+// makeSchema returns the schema for values of type t, following the
+// encoding/json rules.
+func (s *openApiSchemas) makeSchema(t reflect.Type) jsonObject {
+	switch {
+	case t == typeOfTime:
+		return jsonObject{"type": "string", "format": "date-time"}
+	case t.Implements(typeOfJsonMarshaler),
+		reflect.PtrTo(t).Implements(typeOfJsonMarshaler):
+		return jsonObject{}
+	case t.Implements(typeOfTextMarshaler),
+		reflect.PtrTo(t).Implements(typeOfTextMarshaler):
+		return jsonObject{"type": "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return jsonObject{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return jsonObject{"type": "integer", "format": "int32"}
+	case reflect.Int64:
+		return jsonObject{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		return jsonObject{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return jsonObject{"type": "number"}
+	case reflect.String:
+		return jsonObject{"type": "string"}
+	case reflect.Array, reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return jsonObject{"type": "string", "format": "byte"}
+		}
+		return jsonObject{"type": "array", "items": s.makeSchema(t.Elem())}
+	case reflect.Map:
+		return jsonObject{
+			"type":                 "object",
+			"additionalProperties": s.makeSchema(t.Elem()),
+		}
+	case reflect.Ptr:
+		schema := s.makeSchema(t.Elem())
+		if _, ok := schema["$ref"]; ok {
+			return schema
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.makeStructSchema(t)
+		}
+		return jsonObject{"$ref": "#/components/schemas/" + s.register(t)}
+	}
+	return jsonObject{} // Interfaces and anything else: any value.
+}
+
+// makeStructSchema returns the schema for a struct. Fields of embedded
+// structs without a JSON name are promoted.
+func (s *openApiSchemas) makeStructSchema(t reflect.Type) jsonObject {
+	properties := make(jsonObject)
+	s.addStructFields(t, properties)
+	return jsonObject{"type": "object", "properties": properties}
+}
+
+// addStructFields adds the fields of a struct to properties. Fields already
+// present are not replaced, since shallower fields take precedence.
+func (s *openApiSchemas) addStructFields(t reflect.Type,
+	properties jsonObject) {
+	var embeddedTypes []reflect.Type
+	for index := 0; index < t.NumField(); index++ {
+		field := t.Field(index)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct &&
+				!fieldType.Implements(typeOfJsonMarshaler) &&
+				!fieldType.Implements(typeOfTextMarshaler) {
+				embeddedTypes = append(embeddedTypes, fieldType)
+				continue
+			}
+		}
+		if field.PkgPath != "" { // Unexported.
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := properties[name]; !ok {
+			properties[name] = s.makeSchema(field.Type)
+		}
+	}
+	for _, embeddedType := range embeddedTypes {
+		s.addStructFields(embeddedType, properties)
+	}
+}
+
+// register adds the schema for a named struct to the components, returning
+// the component name.
+func (s *openApiSchemas) register(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+	baseName := invalidComponentNameCharacters.ReplaceAllString(
+		path.Base(t.PkgPath())+"."+t.Name(), "_")
+	name := baseName
+	for suffix := 2; s.schemas[name] != nil; suffix++ {
+		name = baseName + "_" + strconv.Itoa(suffix)
+	}
+	s.names[t] = name
+	s.schemas[name] = jsonObject{} // Placeholder for recursive types.
+	s.schemas[name] = s.makeStructSchema(t)
+	return name
+}
@@ -49,7 +49,9 @@ type builtinReceiver struct{} // NOTE: GrantMethod allows all access.
 
 type methodWrapper struct {
 	methodType                    int
+	audited                       bool
 	public                        bool
+	serviceMethod                 string
 	fn                            reflect.Value
 	requestType                   reflect.Type
 	responseType                  reflect.Type
@@ -69,6 +71,7 @@ type receiverType struct {
 }
 
 var (
+	callHook           func(record CallRecord)
 	defaultGrantMethod = func(serviceMethod string,
 		authInfo *AuthInformation) bool {
 		return false
@@ -110,6 +113,7 @@ func init() {
 	http.HandleFunc(jsonTlsRpcPath, jsonTlsHttpHandler)
 	http.HandleFunc(listMethodsPath, listMethodsHttpHandler)
 	http.HandleFunc(listPublicMethodsPath, listPublicMethodsHttpHandler)
+	http.HandleFunc(restApiPath, restApiHttpHandler)
 	registerServerMetrics()
 }
 
@@ -190,6 +194,8 @@ func _registerName(name string, rcvr interface{},
 	if err != nil {
 		return err
 	}
+	auditedMethods := stringutil.ConvertListToMap(options.AuditedMethods,
+		false)
 	publicMethods := stringutil.ConvertListToMap(options.PublicMethods, false)
 	for index := 0; index < typeOfReceiver.NumMethod(); index++ {
 		method := typeOfReceiver.Method(index)
@@ -202,6 +208,10 @@ func _registerName(name string, rcvr interface{},
 			continue
 		}
 		receiver.methods[method.Name] = mVal
+		mVal.serviceMethod = name + "." + method.Name
+		if _, ok := auditedMethods[method.Name]; ok {
+			mVal.audited = true
+		}
 		if _, ok := publicMethods[method.Name]; ok {
 			mVal.public = true
 		}
@@ -480,6 +490,32 @@ func httpHandler(w http.ResponseWriter, req *http.Request, doTls bool,
 	serverMetricsMutex.Unlock()
 }
 
+func getAuthInformationForRequest(req *http.Request,
+	serviceMethod string) (*AuthInformation, error) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) < 1 {
+		return nil, ErrorMissingCertificate
+	}
+	if serverTlsConfig == nil ||
+		!checkVerifiedChains(req.TLS.VerifiedChains,
+			serverTlsConfig.ClientCAs) {
+		return nil, ErrorBadCertificate
+	}
+	username, permittedMethods, groupList, err := getAuth(*req.TLS, true)
+	if err != nil {
+		return nil, err
+	}
+	conn := &Conn{
+		groupList:        groupList,
+		permittedMethods: permittedMethods,
+		username:         username,
+	}
+	return &AuthInformation{
+		GroupList:        groupList,
+		HaveMethodAccess: conn.checkMethodAccess(serviceMethod),
+		Username:         username,
+	}, nil
+}
+
 func checkVerifiedChains(verifiedChains [][]*x509.Certificate,
 	certPool *x509.CertPool) bool {
 	for _, vChain := range verifiedChains {
@@ -615,11 +651,13 @@ func (conn *Conn) findMethod(serviceMethod string) (*methodWrapper, error) {
 		conn.haveMethodAccess = false
 		if !method.public {
 			method.numDeniedCalls++
+			method.auditRejected(conn, ErrorAccessToMethodDenied)
 			return nil, ErrorAccessToMethodDenied
 		}
 	}
 	authInfo := conn.GetAuthInformation()
 	if rn, err := receiver.blockMethod(methodName, authInfo); err != nil {
+		method.auditRejected(conn, err)
 		return nil, err
 	} else {
 		conn.releaseNotifier = rn
@@ -698,19 +736,14 @@ func listPublicMethodsHttpHandler(w http.ResponseWriter, req *http.Request) {
 }
 
 func (m *methodWrapper) call(conn *Conn, makeCoder coderMaker) error {
-	m.numPermittedCalls++
-	startTime := time.Now()
-	err := m._call(conn, makeCoder)
-	timeTaken := time.Since(startTime)
-	if err == nil {
-		m.successfulCallsDistribution.Add(timeTaken)
-	} else {
-		m.failedCallsDistribution.Add(timeTaken)
-	}
-	return err
+	return m.track(conn, func() error { return m._call(conn, makeCoder) })
 }
 
-func (m *methodWrapper) _call(conn *Conn, makeCoder coderMaker) error {
+// track calls fn, recording the method call metrics and auditing the call.
+func (m *methodWrapper) track(conn *Conn, fn func() error) error {
+	conn.auditRequest = nil
+	conn.auditResponse = nil
+	m.numPermittedCalls++
 	serverMetricsMutex.Lock()
 	numRunningMethods++
 	serverMetricsMutex.Unlock()
@@ -727,8 +760,24 @@ func (m *methodWrapper) _call(conn *Conn, makeCoder coderMaker) error {
 			serverMetricsMutex.Unlock()
 		}
 	}()
+	startTime := time.Now()
+	err := fn()
+	timeTaken := time.Since(startTime)
+	if err == nil {
+		m.successfulCallsDistribution.Add(timeTaken)
+	} else {
+		m.failedCallsDistribution.Add(timeTaken)
+	}
+	m.audit(conn, startTime, err, false)
+	return err
+}
+
+func (m *methodWrapper) _call(conn *Conn, makeCoder coderMaker) error {
 	connValue := reflect.ValueOf(conn)
 	conn.Decoder = makeCoder.MakeDecoder(conn)
+	if m.audited {
+		conn.Decoder = &auditDecoder{conn: conn, decoder: conn.Decoder}
+	}
 	conn.Encoder = makeCoder.MakeEncoder(conn)
 	switch m.methodType {
 	case methodTypeRaw:
@@ -751,23 +800,15 @@ func (m *methodWrapper) _call(conn *Conn, makeCoder coderMaker) error {
 		return nil
 	case methodTypeRequestReply:
 		request := reflect.New(m.requestType)
-		response := reflect.New(m.responseType)
 		if err := conn.Decode(request.Interface()); err != nil {
 			_, err = conn.WriteString(err.Error() + "\n")
 			return err
 		}
-		startTime := time.Now()
-		returnValues := m.fn.Call([]reflect.Value{connValue, request.Elem(),
-			response})
-		timeTaken := time.Since(startTime)
-		errInter := returnValues[0].Interface()
-		if errInter != nil {
-			m.failedRRCallsDistribution.Add(timeTaken)
-			err := errInter.(error)
+		response, err := m.callRequestReply(conn, request)
+		if err != nil {
 			_, err = conn.WriteString(err.Error() + "\n")
 			return err
 		}
-		m.successfulRRCallsDistribution.Add(timeTaken)
 		if _, err := conn.WriteString("\n"); err != nil {
 			return err
 		}
@@ -775,3 +816,24 @@ func (m *methodWrapper) _call(conn *Conn, makeCoder coderMaker) error {
 	}
 	return errors.New("unknown method type")
 }
+
+// callRequestReply calls a request/reply method with a pointer to the
+// request and returns a pointer to the response.
+func (m *methodWrapper) callRequestReply(conn *Conn,
+	request reflect.Value) (reflect.Value, error) {
+	response := reflect.New(m.responseType)
+	startTime := time.Now()
+	returnValues := m.fn.Call([]reflect.Value{reflect.ValueOf(conn),
+		request.Elem(), response})
+	timeTaken := time.Since(startTime)
+	if m.audited {
+		conn.auditRequest = request.Elem().Interface()
+		conn.auditResponse = response.Elem().Interface()
+	}
+	if errInter := returnValues[0].Interface(); errInter != nil {
+		m.failedRRCallsDistribution.Add(timeTaken)
+		return response, errInter.(error)
+	}
+	m.successfulRRCallsDistribution.Add(timeTaken)
+	return response, nil
+}
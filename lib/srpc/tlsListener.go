@@ -0,0 +1,109 @@
+package srpc
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+
+	libnet "github.com/Cloud-Foundations/Dominator/lib/net"
+)
+
+const tlsRecordTypeHandshake = 0x16
+
+// peekedConn is a connection for which the first byte has already been read.
+type peekedConn struct {
+	net.Conn
+	peeked []byte
+}
+
+// peekedTCPConn is a peekedConn for a connection which supports keep-alives,
+// which the SRPC server requires.
+type peekedTCPConn struct {
+	*peekedConn
+	tcpConn libnet.TCPConn
+}
+
+// tlsSniffingListener accepts both plain and TLS connections on the same port.
+// Connections which start with a TLS handshake are wrapped with a TLS server,
+// so that web pages and the REST gateway may authenticate the client with a
+// certificate. The SRPC connections (which start with a plain HTTP CONNECT) are
+// passed unchanged.
+type tlsSniffingListener struct {
+	net.Listener
+	config  *tls.Config
+	conns   chan net.Conn
+	errChan chan error
+}
+
+func newTlsSniffingListener(listener net.Listener) net.Listener {
+	config := GetServerTlsConfig()
+	if config == nil {
+		return listener
+	}
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+	l := &tlsSniffingListener{
+		Listener: listener,
+		config:   config,
+		conns:    make(chan net.Conn),
+		errChan:  make(chan error, 1),
+	}
+	go l.acceptLoop()
+	return l
+}
+
+func (conn *peekedConn) Read(b []byte) (int, error) {
+	if len(conn.peeked) > 0 {
+		nCopied := copy(b, conn.peeked)
+		conn.peeked = conn.peeked[nCopied:]
+		return nCopied, nil
+	}
+	return conn.Conn.Read(b)
+}
+
+func (conn *peekedTCPConn) SetKeepAlive(keepalive bool) error {
+	return conn.tcpConn.SetKeepAlive(keepalive)
+}
+
+func (conn *peekedTCPConn) SetKeepAlivePeriod(d time.Duration) error {
+	return conn.tcpConn.SetKeepAlivePeriod(d)
+}
+
+func (l *tlsSniffingListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case err := <-l.errChan:
+		return nil, err
+	}
+}
+
+func (l *tlsSniffingListener) acceptLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			l.errChan <- err
+			return
+		}
+		go l.sniff(conn)
+	}
+}
+
+// sniff reads the first byte from the connection (with a timeout, so that idle
+// connections do not block) to determine if the client is starting TLS.
+func (l *tlsSniffingListener) sniff(conn net.Conn) {
+	buffer := make([]byte, 1)
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if _, err := conn.Read(buffer); err != nil {
+		conn.Close()
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+	pConn := &peekedConn{Conn: conn, peeked: buffer}
+	if buffer[0] == tlsRecordTypeHandshake {
+		l.conns <- tls.Server(pConn, l.config)
+	} else if tcpConn, ok := conn.(libnet.TCPConn); ok {
+		l.conns <- &peekedTCPConn{peekedConn: pConn, tcpConn: tcpConn}
+	} else {
+		l.conns <- pConn
+	}
+}
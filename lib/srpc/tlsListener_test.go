@@ -0,0 +1,67 @@
+package srpc
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	libnet "github.com/Cloud-Foundations/Dominator/lib/net"
+)
+
+type testKeepAliveConn struct {
+	net.Conn
+	keepAlive bool
+}
+
+func (conn *testKeepAliveConn) SetKeepAlive(keepalive bool) error {
+	conn.keepAlive = keepalive
+	return nil
+}
+
+func (conn *testKeepAliveConn) SetKeepAlivePeriod(d time.Duration) error {
+	return nil
+}
+
+func testSniff(t *testing.T, conn net.Conn) net.Conn {
+	l := &tlsSniffingListener{conns: make(chan net.Conn, 1)}
+	go l.sniff(conn)
+	select {
+	case conn := <-l.conns:
+		return conn
+	case <-time.After(time.Second):
+		t.Fatal("timed out sniffing connection")
+	}
+	return nil
+}
+
+func TestSniffPlainConn(t *testing.T) {
+	for _, keepAlive := range []bool{false, true} {
+		serverConn, clientConn := net.Pipe()
+		go func() {
+			io.WriteString(clientConn, "CONNECT")
+			clientConn.Close()
+		}()
+		var conn net.Conn = serverConn
+		if keepAlive {
+			conn = &testKeepAliveConn{Conn: serverConn}
+		}
+		sniffedConn := testSniff(t, conn)
+		tcpConn, ok := sniffedConn.(libnet.TCPConn)
+		if ok != keepAlive {
+			t.Errorf("keep-alive supported: %v, want: %v", ok, keepAlive)
+		} else if ok {
+			if err := tcpConn.SetKeepAlive(true); err != nil {
+				t.Error(err)
+			} else if !conn.(*testKeepAliveConn).keepAlive {
+				t.Error("keep-alive not passed to connection")
+			}
+		}
+		data, err := io.ReadAll(sniffedConn)
+		if err != nil {
+			t.Error(err)
+		} else if string(data) != "CONNECT" {
+			t.Errorf("read: %q", string(data))
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Package systemd drives the systemd service manager directly over its private
+// D-Bus socket, without requiring a bus daemon.
+package systemd
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	ActionReload     = "reload"
+	ActionRestart    = "restart"
+	ActionStart      = "start"
+	ActionStop       = "stop"
+	ActionTryRestart = "try-restart"
+)
+
+// Conn is a connection to the systemd manager.
+type Conn struct {
+	conn        net.Conn
+	mutex       sync.Mutex        // Protect everything below.
+	jobResults  map[string]string // Key: queued job path, value: result.
+	nextSerial  uint32
+	removedJobs map[string]string // Other jobs removed while queueing.
+}
+
+type Job struct {
+	Action string // One of the Action* constants.
+	Unit   string
+}
+
+type JobResult struct {
+	Job
+	Error  error  // Set if the job could not be queued or did not complete.
+	Result string // The job result from systemd, "done" on success.
+}
+
+// Manager is the interface to the systemd manager which is required to run
+// jobs. It is implemented by *Conn and may be mocked for testing.
+type Manager interface {
+	EnqueueJob(job Job) (string, error)
+	WaitForJobs(jobPaths []string, timeout time.Duration) (
+		map[string]string, error)
+}
+
+// Dial connects to the private socket of the systemd manager and subscribes to
+// job completion signals. The caller must have root privileges.
+func Dial() (*Conn, error) {
+	return dial(privateSocketPath)
+}
+
+// IsRunning returns true if the system was booted with systemd.
+func IsRunning() bool {
+	return isRunning()
+}
+
+// RunJobs queues all the jobs at once so that systemd may order them according
+// to the unit dependencies, then waits up to timeout for the jobs to complete.
+// A result is returned for each job, in the same order.
+func RunJobs(manager Manager, jobs []Job, timeout time.Duration) []JobResult {
+	return runJobs(manager, jobs, timeout)
+}
+
+// UnitName returns the name of the unit for a service. If the service name
+// does not have a unit type suffix, ".service" is appended.
+func UnitName(service string) string {
+	return unitName(service)
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// EnqueueJob queues a job for a unit and returns the path of the job object.
+func (c *Conn) EnqueueJob(job Job) (string, error) {
+	return c.enqueueJob(job)
+}
+
+// WaitForJobs waits for the specified jobs to complete, returning the result
+// for each job (keyed by job path). An error is returned if the timeout is
+// reached before all jobs complete.
+func (c *Conn) WaitForJobs(jobPaths []string, timeout time.Duration) (
+	map[string]string, error) {
+	return c.waitForJobs(jobPaths, timeout)
+}
@@ -0,0 +1,235 @@
+package systemd
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	managerInterface  = "org.freedesktop.systemd1.Manager"
+	managerPath       = "/org/freedesktop/systemd1"
+	privateSocketPath = "/run/systemd/private"
+	systemdService    = "org.freedesktop.systemd1"
+
+	callTimeout = 30 * time.Second
+)
+
+var actionToMethod = map[string]string{
+	ActionReload:     "ReloadUnit",
+	ActionRestart:    "RestartUnit",
+	ActionStart:      "StartUnit",
+	ActionStop:       "StopUnit",
+	ActionTryRestart: "TryRestartUnit",
+}
+
+func isRunning() bool {
+	fi, err := os.Stat("/run/systemd/system")
+	return err == nil && fi.IsDir()
+}
+
+func unitName(service string) string {
+	if index := strings.LastIndexByte(service, '.'); index > 0 {
+		switch service[index+1:] {
+		case "automount", "mount", "path", "scope", "service", "slice",
+			"socket", "swap", "target", "timer":
+			return service
+		}
+	}
+	return service + ".service"
+}
+
+func dial(socketPath string) (*Conn, error) {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	c := &Conn{
+		conn:       conn,
+		jobResults: make(map[string]string),
+		nextSerial: 1,
+	}
+	if err := c.authenticate(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if _, err := c.call("Subscribe", "", nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// authenticate performs the SASL EXTERNAL authentication exchange, which
+// relies on the peer credentials of the socket.
+func (c *Conn) authenticate() error {
+	c.conn.SetDeadline(time.Now().Add(callTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+	uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+	_, err := fmt.Fprintf(c.conn, "\x00AUTH EXTERNAL %s\r\n", uid)
+	if err != nil {
+		return err
+	}
+	// Read one byte at a time so that no message data is buffered.
+	reader := bufio.NewReaderSize(oneByteReader{c.conn}, 16)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "OK ") {
+		return errors.New("authentication failed: " + strings.TrimSpace(line))
+	}
+	_, err = c.conn.Write([]byte("BEGIN\r\n"))
+	return err
+}
+
+type oneByteReader struct {
+	conn net.Conn
+}
+
+func (r oneByteReader) Read(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:1]
+	}
+	return r.conn.Read(p)
+}
+
+// call invokes a method on the manager and returns the reply body. Signals
+// received while waiting are processed.
+func (c *Conn) call(method, signature string, args []interface{}) (
+	[]interface{}, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.callLocked(method, signature, args)
+}
+
+// callLocked is the same as call, except the mutex must be held.
+func (c *Conn) callLocked(method, signature string, args []interface{}) (
+	[]interface{}, error) {
+	serial := c.nextSerial
+	c.nextSerial++
+	request := &message{
+		body:        args,
+		destination: systemdService,
+		iface:       managerInterface,
+		member:      method,
+		messageType: messageTypeMethodCall,
+		path:        managerPath,
+		serial:      serial,
+		signature:   signature,
+	}
+	data, err := request.encode()
+	if err != nil {
+		return nil, err
+	}
+	c.conn.SetDeadline(time.Now().Add(callTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+	if _, err := c.conn.Write(data); err != nil {
+		return nil, err
+	}
+	for {
+		reply, err := c.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		if reply.replySerial != serial {
+			continue
+		}
+		switch reply.messageType {
+		case messageTypeMethodReturn:
+			return reply.body, nil
+		case messageTypeError:
+			errorMessage := reply.errorName
+			if len(reply.body) > 0 {
+				if text, ok := reply.body[0].(string); ok {
+					errorMessage += ": " + text
+				}
+			}
+			return nil, errors.New(errorMessage)
+		}
+	}
+}
+
+// readMessage reads the next message, recording the result of any completed
+// jobs which were queued by this connection. Jobs removed while a job is being
+// queued are also recorded, since a job may complete before the reply with
+// its path is received. The mutex must be held.
+func (c *Conn) readMessage() (*message, error) {
+	m, err := readMessage(c.conn)
+	if err != nil {
+		return nil, err
+	}
+	if m.messageType == messageTypeSignal && m.iface == managerInterface &&
+		m.member == "JobRemoved" && len(m.body) == 4 {
+		jobPath, _ := m.body[1].(string)
+		result, _ := m.body[3].(string)
+		if _, ok := c.jobResults[jobPath]; ok {
+			c.jobResults[jobPath] = result
+		} else if c.removedJobs != nil {
+			c.removedJobs[jobPath] = result
+		}
+	}
+	return m, nil
+}
+
+func (c *Conn) enqueueJob(job Job) (string, error) {
+	method, ok := actionToMethod[job.Action]
+	if !ok {
+		return "", errors.New("unsupported action: " + job.Action)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.removedJobs = make(map[string]string)
+	defer func() { c.removedJobs = nil }()
+	reply, err := c.callLocked(method, "ss",
+		[]interface{}{unitName(job.Unit), "replace"})
+	if err != nil {
+		return "", err
+	}
+	if len(reply) != 1 {
+		return "", errors.New("malformed reply")
+	}
+	jobPath, ok := reply[0].(string)
+	if !ok {
+		return "", errors.New("malformed reply")
+	}
+	c.jobResults[jobPath] = c.removedJobs[jobPath] // "" if not yet removed.
+	return jobPath, nil
+}
+
+func (c *Conn) waitForJobs(jobPaths []string, timeout time.Duration) (
+	map[string]string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.conn.SetDeadline(time.Now().Add(timeout))
+	defer c.conn.SetDeadline(time.Time{})
+	// Jobs which do not complete in time are forgotten.
+	defer func() {
+		for _, jobPath := range jobPaths {
+			delete(c.jobResults, jobPath)
+		}
+	}()
+	results := make(map[string]string, len(jobPaths))
+	for {
+		for _, jobPath := range jobPaths {
+			if result := c.jobResults[jobPath]; result != "" {
+				results[jobPath] = result
+			}
+		}
+		if len(results) >= len(jobPaths) {
+			return results, nil
+		}
+		if _, err := c.readMessage(); err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				return results, errors.New("timed out waiting for jobs")
+			}
+			return results, err
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package systemd
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A minimal implementation of the D-Bus wire protocol, sufficient for calling
+// methods on the systemd manager and receiving its signals.
+
+const (
+	messageTypeMethodCall   = 1
+	messageTypeMethodReturn = 2
+	messageTypeError        = 3
+	messageTypeSignal       = 4
+
+	fieldPath        = 1
+	fieldInterface   = 2
+	fieldMember      = 3
+	fieldErrorName   = 4
+	fieldReplySerial = 5
+	fieldDestination = 6
+	fieldSender      = 7
+	fieldSignature   = 8
+	fieldUnixFds     = 9
+
+	maxMessageLength = 1 << 27
+)
+
+type message struct {
+	body        []interface{}
+	destination string
+	errorName   string
+	iface       string
+	member      string
+	messageType byte
+	path        string
+	replySerial uint32
+	serial      uint32
+	signature   string
+}
+
+type encoder struct {
+	buffer []byte
+}
+
+type decoder struct {
+	buffer []byte
+	offset int
+	order  binary.ByteOrder
+}
+
+func (e *encoder) align(alignment int) {
+	for len(e.buffer)%alignment != 0 {
+		e.buffer = append(e.buffer, 0)
+	}
+}
+
+func (e *encoder) writeByte(value byte) {
+	e.buffer = append(e.buffer, value)
+}
+
+func (e *encoder) writeUint32(value uint32) {
+	e.align(4)
+	e.buffer = binary.LittleEndian.AppendUint32(e.buffer, value)
+}
+
+func (e *encoder) writeString(value string) {
+	e.writeUint32(uint32(len(value)))
+	e.buffer = append(e.buffer, value...)
+	e.buffer = append(e.buffer, 0)
+}
+
+func (e *encoder) writeSignature(value string) {
+	e.writeByte(byte(len(value)))
+	e.buffer = append(e.buffer, value...)
+	e.buffer = append(e.buffer, 0)
+}
+
+func (e *encoder) writeValue(signature byte, value interface{}) error {
+	switch signature {
+	case 'y':
+		e.writeByte(value.(byte))
+	case 'u':
+		e.writeUint32(value.(uint32))
+	case 's', 'o':
+		e.writeString(value.(string))
+	case 'g':
+		e.writeSignature(value.(string))
+	default:
+		return fmt.Errorf("unsupported type: %c", signature)
+	}
+	return nil
+}
+
+func (e *encoder) writeHeaderField(code byte, signature byte,
+	value interface{}) error {
+	e.align(8)
+	e.writeByte(code)
+	e.writeSignature(string(signature))
+	return e.writeValue(signature, value)
+}
+
+// encode returns the message in wire format (little endian).
+func (m *message) encode() ([]byte, error) {
+	var body encoder
+	if len(m.body) != len(m.signature) {
+		return nil, errors.New("body does not match signature")
+	}
+	for index, value := range m.body {
+		if err := body.writeValue(m.signature[index], value); err != nil {
+			return nil, err
+		}
+	}
+	e := &encoder{buffer: make([]byte, 0, 256+len(body.buffer))}
+	e.writeByte('l')
+	e.writeByte(m.messageType)
+	e.writeByte(0) // Flags.
+	e.writeByte(1) // Protocol version.
+	e.writeUint32(uint32(len(body.buffer)))
+	e.writeUint32(m.serial)
+	e.writeUint32(0) // Header fields length: filled in below.
+	fieldsStart := len(e.buffer)
+	fields := []struct {
+		code      byte
+		signature byte
+		value     string
+	}{
+		{fieldPath, 'o', m.path},
+		{fieldInterface, 's', m.iface},
+		{fieldMember, 's', m.member},
+		{fieldErrorName, 's', m.errorName},
+		{fieldDestination, 's', m.destination},
+		{fieldSignature, 'g', m.signature},
+	}
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+		err := e.writeHeaderField(field.code, field.signature, field.value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if m.replySerial > 0 {
+		err := e.writeHeaderField(fieldReplySerial, 'u', m.replySerial)
+		if err != nil {
+			return nil, err
+		}
+	}
+	binary.LittleEndian.PutUint32(e.buffer[12:],
+		uint32(len(e.buffer)-fieldsStart))
+	e.align(8)
+	return append(e.buffer, body.buffer...), nil
+}
+
+func (d *decoder) align(alignment int) error {
+	for d.offset%alignment != 0 {
+		d.offset++
+	}
+	if d.offset > len(d.buffer) {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+func (d *decoder) readByte() (byte, error) {
+	if d.offset >= len(d.buffer) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	d.offset++
+	return d.buffer[d.offset-1], nil
+}
+
+func (d *decoder) readUint32() (uint32, error) {
+	if err := d.align(4); err != nil {
+		return 0, err
+	}
+	if d.offset+4 > len(d.buffer) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	d.offset += 4
+	return d.order.Uint32(d.buffer[d.offset-4:]), nil
+}
+
+func (d *decoder) readBytes(length int) (string, error) {
+	if d.offset+length+1 > len(d.buffer) {
+		return "", io.ErrUnexpectedEOF
+	}
+	value := string(d.buffer[d.offset : d.offset+length])
+	d.offset += length + 1 // Skip trailing NUL.
+	return value, nil
+}
+
+func (d *decoder) readString() (string, error) {
+	length, err := d.readUint32()
+	if err != nil {
+		return "", err
+	}
+	return d.readBytes(int(length))
+}
+
+func (d *decoder) readSignature() (string, error) {
+	length, err := d.readByte()
+	if err != nil {
+		return "", err
+	}
+	return d.readBytes(int(length))
+}
+
+func (d *decoder) readValue(signature byte) (interface{}, error) {
+	switch signature {
+	case 'y':
+		return d.readByte()
+	case 'u':
+		return d.readUint32()
+	case 's', 'o':
+		return d.readString()
+	case 'g':
+		return d.readSignature()
+	default:
+		return nil, fmt.Errorf("unsupported type: %c", signature)
+	}
+}
+
+// readMessage reads and decodes a message. Message bodies with types other
+// than those supported by decoder.readValue are skipped.
+func readMessage(reader io.Reader) (*message, error) {
+	fixedHeader := make([]byte, 16)
+	if _, err := io.ReadFull(reader, fixedHeader); err != nil {
+		return nil, err
+	}
+	d := &decoder{}
+	switch fixedHeader[0] {
+	case 'l':
+		d.order = binary.LittleEndian
+	case 'B':
+		d.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("bad endianness: %d", fixedHeader[0])
+	}
+	bodyLength := d.order.Uint32(fixedHeader[4:])
+	fieldsLength := d.order.Uint32(fixedHeader[12:])
+	headerLength := (16 + int(fieldsLength) + 7) &^ 7
+	if bodyLength > maxMessageLength || fieldsLength > maxMessageLength {
+		return nil, errors.New("message too long")
+	}
+	d.buffer = make([]byte, headerLength+int(bodyLength))
+	copy(d.buffer, fixedHeader)
+	if _, err := io.ReadFull(reader, d.buffer[16:]); err != nil {
+		return nil, err
+	}
+	m := &message{
+		messageType: fixedHeader[1],
+		serial:      d.order.Uint32(fixedHeader[8:]),
+	}
+	d.offset = 16
+	for d.offset < 16+int(fieldsLength) {
+		if err := d.align(8); err != nil {
+			return nil, err
+		}
+		code, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+		signature, err := d.readSignature()
+		if err != nil {
+			return nil, err
+		}
+		if len(signature) != 1 {
+			return nil, fmt.Errorf("unsupported header signature: %s",
+				signature)
+		}
+		value, err := d.readValue(signature[0])
+		if err != nil {
+			return nil, err
+		}
+		switch code {
+		case fieldPath:
+			m.path, _ = value.(string)
+		case fieldInterface:
+			m.iface, _ = value.(string)
+		case fieldMember:
+			m.member, _ = value.(string)
+		case fieldErrorName:
+			m.errorName, _ = value.(string)
+		case fieldReplySerial:
+			m.replySerial, _ = value.(uint32)
+		case fieldDestination:
+			m.destination, _ = value.(string)
+		case fieldSignature:
+			m.signature, _ = value.(string)
+		}
+	}
+	d.offset = headerLength
+	for index := 0; index < len(m.signature); index++ {
+		value, err := d.readValue(m.signature[index])
+		if err != nil {
+			m.body = nil // Unsupported body: ignore it.
+			break
+		}
+		m.body = append(m.body, value)
+	}
+	return m, nil
+}
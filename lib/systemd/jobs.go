@@ -0,0 +1,42 @@
+package systemd
+
+import (
+	"errors"
+	"time"
+)
+
+func runJobs(manager Manager, jobs []Job, timeout time.Duration) []JobResult {
+	results := make([]JobResult, len(jobs))
+	jobPaths := make([]string, 0, len(jobs))
+	jobPathToIndex := make(map[string]int, len(jobs))
+	for index, job := range jobs {
+		results[index].Job = job
+		jobPath, err := manager.EnqueueJob(job)
+		if err != nil {
+			results[index].Error = err
+			continue
+		}
+		jobPaths = append(jobPaths, jobPath)
+		jobPathToIndex[jobPath] = index
+	}
+	if len(jobPaths) < 1 {
+		return results
+	}
+	jobResults, err := manager.WaitForJobs(jobPaths, timeout)
+	for _, jobPath := range jobPaths {
+		result := &results[jobPathToIndex[jobPath]]
+		if jobResult, ok := jobResults[jobPath]; !ok {
+			if err != nil {
+				result.Error = err
+			} else {
+				result.Error = errors.New("no result for job")
+			}
+		} else {
+			result.Result = jobResult
+			if jobResult != "done" {
+				result.Error = errors.New("job " + jobResult)
+			}
+		}
+	}
+	return results
+}
@@ -0,0 +1,94 @@
+package systemd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeManager struct {
+	enqueued []Job
+	results  map[string]string // Key: unit name.
+}
+
+func (m *fakeManager) EnqueueJob(job Job) (string, error) {
+	if _, ok := m.results[job.Unit]; !ok {
+		return "", errors.New("unit not found")
+	}
+	m.enqueued = append(m.enqueued, job)
+	return "/job/" + job.Unit, nil
+}
+
+func (m *fakeManager) WaitForJobs(jobPaths []string, timeout time.Duration) (
+	map[string]string, error) {
+	if len(m.enqueued) != len(jobPaths) {
+		return nil, errors.New("jobs not batched")
+	}
+	results := make(map[string]string)
+	for _, jobPath := range jobPaths {
+		results[jobPath] = m.results[jobPath[len("/job/"):]]
+	}
+	return results, nil
+}
+
+func TestRunJobs(t *testing.T) {
+	manager := &fakeManager{results: map[string]string{
+		"good": "done",
+		"bad":  "failed",
+	}}
+	results := RunJobs(manager, []Job{
+		{ActionRestart, "good"},
+		{ActionReload, "bad"},
+		{ActionStart, "missing"},
+	}, time.Second)
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if results[0].Error != nil || results[0].Result != "done" {
+		t.Errorf("good job: %v", results[0])
+	}
+	if results[1].Error == nil || results[1].Result != "failed" {
+		t.Errorf("bad job: %v", results[1])
+	}
+	if results[2].Error == nil {
+		t.Errorf("missing job did not fail")
+	}
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	request := &message{
+		body:        []interface{}{uint32(7), "/job/7", "x.service", "done"},
+		iface:       managerInterface,
+		member:      "JobRemoved",
+		messageType: messageTypeSignal,
+		path:        managerPath,
+		serial:      3,
+		signature:   "uoss",
+	}
+	data, err := request.encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := readMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.member != "JobRemoved" || reply.serial != 3 ||
+		reply.signature != "uoss" || len(reply.body) != 4 ||
+		reply.body[1] != "/job/7" || reply.body[3] != "done" {
+		t.Errorf("message mismatch: %+v", reply)
+	}
+}
+
+func TestUnitName(t *testing.T) {
+	for service, unit := range map[string]string{
+		"sshd":          "sshd.service",
+		"docker.socket": "docker.socket",
+		"foo.bar":       "foo.bar.service",
+	} {
+		if got := UnitName(service); got != unit {
+			t.Errorf("UnitName(%s)=%s, want %s", service, got, unit)
+		}
+	}
+}
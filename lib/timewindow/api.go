@@ -0,0 +1,59 @@
+// Package timewindow implements recurring time windows, specified with a
+// cron-like schedule for the start of each window and a duration.
+//
+// The specification format is:
+//
+//	[TZ=<zone>] <minute> <hour> <day-of-month> <month> <day-of-week> <duration>
+//
+// The schedule fields follow the crontab(5) syntax: lists, ranges, steps, "*"
+// and names for months and days of the week are supported. If both the
+// day-of-month and day-of-week fields are restricted, a day matches if either
+// field matches. The duration is specified in Go syntax (e.g. "2h30m"). The
+// time zone defaults to UTC. For example, the following specifies a window
+// from 2am until 6am on Saturdays and Sundays, Pacific time:
+//
+//	TZ=America/Los_Angeles 0 2 * * Sat,Sun 4h
+package timewindow
+
+import (
+	"time"
+)
+
+type Window struct {
+	daysOfMonth   bitSet
+	daysOfWeek    bitSet
+	duration      time.Duration
+	hours         bitSet
+	location      *time.Location
+	minutes       bitSet
+	months        bitSet
+	restrictDoM   bool
+	restrictDoW   bool
+	specification string
+}
+
+// Parse parses a window specification.
+func Parse(specification string) (*Window, error) {
+	return parse(specification)
+}
+
+// Duration returns the duration of each window.
+func (w *Window) Duration() time.Duration {
+	return w.duration
+}
+
+// IsOpen returns true if the time is within a window.
+func (w *Window) IsOpen(t time.Time) bool {
+	return w.isOpen(t)
+}
+
+// NextStart returns the start time of the first window which starts at or
+// after the specified time. The zero time is returned if there is no such
+// window within 5 years.
+func (w *Window) NextStart(t time.Time) time.Time {
+	return w.nextStart(t)
+}
+
+func (w *Window) String() string {
+	return w.specification
+}
@@ -0,0 +1,177 @@
+package timewindow
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type bitSet uint64
+
+type field struct {
+	bits    *bitSet
+	minimum uint
+	maximum uint
+	names   []string
+}
+
+var (
+	dayNames   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul",
+		"aug", "sep", "oct", "nov", "dec"}
+)
+
+func (b bitSet) has(value int) bool {
+	return b&(1<<uint(value)) != 0
+}
+
+func parse(specification string) (*Window, error) {
+	fields := strings.Fields(specification)
+	w := &Window{location: time.UTC, specification: specification}
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "TZ=") {
+		location, err := time.LoadLocation(fields[0][3:])
+		if err != nil {
+			return nil, err
+		}
+		w.location = location
+		fields = fields[1:]
+	}
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("%s: expected 6 fields, got %d",
+			specification, len(fields))
+	}
+	parsers := []field{
+		{&w.minutes, 0, 59, nil},
+		{&w.hours, 0, 23, nil},
+		{&w.daysOfMonth, 1, 31, nil},
+		{&w.months, 1, 12, monthNames},
+		{&w.daysOfWeek, 0, 7, dayNames},
+	}
+	for index, parser := range parsers {
+		if err := parser.parse(fields[index]); err != nil {
+			return nil, fmt.Errorf("%s: %s", specification, err)
+		}
+	}
+	if w.daysOfWeek.has(7) { // Sunday may be specified as 0 or 7.
+		w.daysOfWeek |= 1
+	}
+	w.restrictDoM = fields[2] != "*"
+	w.restrictDoW = fields[4] != "*"
+	duration, err := time.ParseDuration(fields[5])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", specification, err)
+	}
+	if duration <= 0 {
+		return nil, fmt.Errorf("%s: duration must be positive", specification)
+	}
+	w.duration = duration
+	return w, nil
+}
+
+func (f field) parse(text string) error {
+	for _, item := range strings.Split(text, ",") {
+		if err := f.parseItem(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f field) parseItem(item string) error {
+	step := uint(1)
+	if index := strings.IndexByte(item, '/'); index >= 0 {
+		value, err := strconv.ParseUint(item[index+1:], 10, 8)
+		if err != nil || value < 1 {
+			return errors.New("bad step: " + item)
+		}
+		step = uint(value)
+		item = item[:index]
+	}
+	first, last := f.minimum, f.maximum
+	if item != "*" {
+		var err error
+		rangeItems := strings.SplitN(item, "-", 2)
+		if first, err = f.parseValue(rangeItems[0]); err != nil {
+			return err
+		}
+		last = first
+		if len(rangeItems) > 1 {
+			if last, err = f.parseValue(rangeItems[1]); err != nil {
+				return err
+			}
+		} else if step > 1 {
+			last = f.maximum
+		}
+		if last < first {
+			return errors.New("bad range: " + item)
+		}
+	}
+	for value := first; value <= last; value += step {
+		*f.bits |= 1 << value
+	}
+	return nil
+}
+
+func (f field) parseValue(text string) (uint, error) {
+	for index, name := range f.names {
+		if strings.EqualFold(text, name) {
+			if f.minimum > 0 {
+				return uint(index) + f.minimum, nil
+			}
+			return uint(index), nil
+		}
+	}
+	value, err := strconv.ParseUint(text, 10, 8)
+	if err != nil {
+		return 0, errors.New("bad value: " + text)
+	}
+	if uint(value) < f.minimum || uint(value) > f.maximum {
+		return 0, fmt.Errorf("value: %d out of range", value)
+	}
+	return uint(value), nil
+}
+
+func (w *Window) isOpen(t time.Time) bool {
+	start := w.nextStart(t.Add(-w.duration).Add(time.Nanosecond))
+	return !start.IsZero() && !start.After(t)
+}
+
+func (w *Window) matchDay(t time.Time) bool {
+	domMatch := w.daysOfMonth.has(t.Day())
+	dowMatch := w.daysOfWeek.has(int(t.Weekday()))
+	if w.restrictDoM && w.restrictDoW {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+func (w *Window) nextStart(t time.Time) time.Time {
+	t = t.In(w.location)
+	if truncated := t.Truncate(time.Minute); !truncated.Equal(t) {
+		t = truncated.Add(time.Minute)
+	}
+	stopTime := t.AddDate(5, 0, 0)
+	for t.Before(stopTime) {
+		year, month, day := t.Date()
+		if !w.months.has(int(month)) {
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, w.location)
+			continue
+		}
+		if !w.matchDay(t) {
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, w.location)
+			continue
+		}
+		if !w.hours.has(t.Hour()) {
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, w.location)
+			continue
+		}
+		if !w.minutes.has(t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
@@ -0,0 +1,46 @@
+package timewindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindow(t *testing.T) {
+	w, err := Parse("TZ=America/Los_Angeles 30 2 * * Sat,sun 4h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	location, _ := time.LoadLocation("America/Los_Angeles")
+	friday := time.Date(2024, 3, 1, 12, 0, 0, 0, location)
+	if w.IsOpen(friday) {
+		t.Errorf("open on Friday")
+	}
+	start := w.NextStart(friday)
+	want := time.Date(2024, 3, 2, 2, 30, 0, 0, location)
+	if !start.Equal(want) {
+		t.Errorf("next start: %s, want: %s", start, want)
+	}
+	if !w.IsOpen(start) || !w.IsOpen(start.Add(4*time.Hour-time.Second)) {
+		t.Errorf("not open during window")
+	}
+	if w.IsOpen(start.Add(4 * time.Hour)) {
+		t.Errorf("open after window")
+	}
+	if !w.IsOpen(time.Date(2024, 3, 3, 4, 0, 0, 0, location)) {
+		t.Errorf("not open on Sunday")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"0 2 * * *",
+		"60 2 * * * 1h",
+		"0 2 * * * -1h",
+		"0 2 5-1 * * 1h",
+		"TZ=Nowhere/Special 0 2 * * * 1h",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("no error parsing: %s", spec)
+		}
+	}
+}
@@ -5,23 +5,38 @@ import (
 	"regexp"
 )
 
+// HealthCheck specifies how to verify that a service is healthy after it is
+// started. All the specified checks must pass.
+type HealthCheck struct {
+	Command        string `json:",omitempty"` // Run with sh -c, pass if exit=0.
+	HttpUrl        string `json:",omitempty"`
+	HttpStatus     int    `json:",omitempty"` // Default: 200.
+	TcpPort        uint16 `json:",omitempty"` // Connect to localhost.
+	TimeoutSeconds uint   `json:",omitempty"` // Default: 30.
+	MaxRetries     uint   `json:",omitempty"` // Restarts after a failure.
+}
+
 type MergeableTriggers struct {
 	triggers map[string]*mergeableTrigger // Key: service name.
 }
 
 type mergeableTrigger struct {
-	matchLines map[string]struct{}
-	doReboot   bool
-	highImpact bool
+	matchLines  map[string]struct{}
+	action      string
+	doReboot    bool
+	healthCheck *HealthCheck
+	highImpact  bool
 }
 
 type Trigger struct {
 	MatchLines   []string
 	matchRegexes []*regexp.Regexp
 	Service      string
-	SortName     string `json:",omitempty"`
-	DoReboot     bool   `json:",omitempty"`
-	HighImpact   bool   `json:",omitempty"`
+	Action       string       `json:",omitempty"` // Default: stop+start.
+	SortName     string       `json:",omitempty"`
+	DoReboot     bool         `json:",omitempty"`
+	HealthCheck  *HealthCheck `json:",omitempty"`
+	HighImpact   bool         `json:",omitempty"`
 }
 
 func (trigger *Trigger) RegisterStrings(registerFunc func(string)) {
@@ -55,6 +70,11 @@ func New() *Triggers {
 	return newTriggers()
 }
 
+// CheckValid returns an error if any of the triggers are invalid.
+func (triggers *Triggers) CheckValid() error {
+	return triggers.checkValid()
+}
+
 func (triggers *Triggers) Len() int {
 	return len(triggers.Triggers)
 }
@@ -80,8 +100,11 @@ func (mt *MergeableTriggers) ExportTriggers() *Triggers {
 	return mt.exportTriggers()
 }
 
-func (mt *MergeableTriggers) Merge(triggers *Triggers) {
-	mt.merge(triggers)
+// Merge will merge triggers into the set. Triggers for the same service are
+// combined. If the triggers specify different health checks for the same
+// service, the first health check is kept and an error is returned.
+func (mt *MergeableTriggers) Merge(triggers *Triggers) error {
+	return mt.merge(triggers)
 }
 
 func (triggers *Triggers) Match(line string) {
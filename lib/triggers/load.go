@@ -3,6 +3,7 @@ package triggers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 
@@ -23,6 +24,9 @@ func decode(jsonData []byte) (*Triggers, error) {
 	if err := json.Unmarshal(jsonData, &trig.Triggers); err != nil {
 		return nil, errors.New("error decoding triggers " + err.Error())
 	}
+	if err := trig.checkValid(); err != nil {
+		return nil, err
+	}
 	return &trig, nil
 }
 
@@ -31,5 +35,20 @@ func read(reader io.Reader) (*Triggers, error) {
 	if err := libjson.Read(reader, &trig.Triggers); err != nil {
 		return nil, errors.New("error decoding triggers " + err.Error())
 	}
+	if err := trig.checkValid(); err != nil {
+		return nil, err
+	}
 	return &trig, nil
 }
+
+func (triggers *Triggers) checkValid() error {
+	for _, trigger := range triggers.Triggers {
+		switch trigger.Action {
+		case "", "reload", "restart", "try-restart":
+		default:
+			return fmt.Errorf("service: %s: unsupported action: %s",
+				trigger.Service, trigger.Action)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package triggers
+
+import (
+	"testing"
+)
+
+func TestDecodeAction(t *testing.T) {
+	_, err := Decode([]byte(`[{"Service": "httpd", "Action": "reload"}]`))
+	if err != nil {
+		t.Errorf("error decoding valid action: %s", err)
+	}
+	_, err = Decode([]byte(`[{"Service": "httpd", "Action": "kill"}]`))
+	if err == nil {
+		t.Error("no error decoding invalid action")
+	}
+}
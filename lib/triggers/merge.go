@@ -1,6 +1,7 @@
 package triggers
 
 import (
+	"fmt"
 	"sort"
 
 	"github.com/Cloud-Foundations/Dominator/lib/stringutil"
@@ -20,10 +21,12 @@ func (mt *MergeableTriggers) exportTriggers() *Triggers {
 		trigger := mt.triggers[service]
 		matchLines := stringutil.ConvertMapKeysToList(trigger.matchLines, true)
 		triggerList = append(triggerList, &Trigger{
-			MatchLines: matchLines,
-			Service:    service,
-			DoReboot:   trigger.doReboot,
-			HighImpact: trigger.highImpact,
+			MatchLines:  matchLines,
+			Service:     service,
+			Action:      trigger.action,
+			DoReboot:    trigger.doReboot,
+			HealthCheck: trigger.healthCheck,
+			HighImpact:  trigger.highImpact,
 		})
 	}
 	triggers := New()
@@ -31,10 +34,11 @@ func (mt *MergeableTriggers) exportTriggers() *Triggers {
 	return triggers
 }
 
-func (mt *MergeableTriggers) merge(triggers *Triggers) {
+func (mt *MergeableTriggers) merge(triggers *Triggers) error {
 	if triggers == nil || len(triggers.Triggers) < 1 {
-		return
+		return nil
 	}
+	var err error
 	if mt.triggers == nil {
 		mt.triggers = make(map[string]*mergeableTrigger, len(triggers.Triggers))
 	}
@@ -48,11 +52,24 @@ func (mt *MergeableTriggers) merge(triggers *Triggers) {
 		for _, matchLine := range trigger.MatchLines {
 			trig.matchLines[matchLine] = struct{}{}
 		}
+		if trigger.Action != "" {
+			trig.action = trigger.Action
+		}
 		if trigger.DoReboot {
 			trig.doReboot = true
 		}
+		if trigger.HealthCheck != nil {
+			if trig.healthCheck == nil {
+				trig.healthCheck = trigger.HealthCheck
+			} else if *trig.healthCheck != *trigger.HealthCheck &&
+				err == nil {
+				err = fmt.Errorf("conflicting health checks for service: %s",
+					trigger.Service)
+			}
+		}
 		if trigger.HighImpact {
 			trig.highImpact = true
 		}
 	}
+	return err
 }
@@ -0,0 +1,57 @@
+package triggers
+
+import (
+	"testing"
+)
+
+func TestMergeHealthCheck(t *testing.T) {
+	healthCheck := &HealthCheck{TcpPort: 80}
+	var mt MergeableTriggers
+	mt.Merge(&Triggers{Triggers: []*Trigger{
+		{MatchLines: []string{"/etc/httpd/.*"}, Service: "httpd"},
+	}})
+	mt.Merge(&Triggers{Triggers: []*Trigger{
+		{
+			MatchLines:  []string{"/usr/sbin/httpd"},
+			Service:     "httpd",
+			HealthCheck: healthCheck,
+		},
+	}})
+	triggers := mt.ExportTriggers()
+	if len(triggers.Triggers) != 1 {
+		t.Fatalf("got %d triggers, want 1", len(triggers.Triggers))
+	}
+	trigger := triggers.Triggers[0]
+	if len(trigger.MatchLines) != 2 {
+		t.Errorf("got %d match lines, want 2", len(trigger.MatchLines))
+	}
+	if trigger.HealthCheck != healthCheck {
+		t.Errorf("health check not merged")
+	}
+}
+
+func TestMergeConflictingHealthChecks(t *testing.T) {
+	var mt MergeableTriggers
+	err := mt.Merge(&Triggers{Triggers: []*Trigger{
+		{Service: "httpd", HealthCheck: &HealthCheck{TcpPort: 80}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mt.Merge(&Triggers{Triggers: []*Trigger{
+		{Service: "httpd", HealthCheck: &HealthCheck{TcpPort: 80}},
+	}})
+	if err != nil {
+		t.Errorf("error merging identical health checks: %s", err)
+	}
+	err = mt.Merge(&Triggers{Triggers: []*Trigger{
+		{Service: "httpd", HealthCheck: &HealthCheck{TcpPort: 8080}},
+	}})
+	if err == nil {
+		t.Error("no error merging conflicting health checks")
+	}
+	triggers := mt.ExportTriggers()
+	if port := triggers.Triggers[0].HealthCheck.TcpPort; port != 80 {
+		t.Errorf("health check port: %d, want 80", port)
+	}
+}
@@ -5,6 +5,14 @@ func (trigger *Trigger) registerStrings(registerFunc func(string)) {
 		registerFunc(str)
 	}
 	registerFunc(trigger.Service)
+	if healthCheck := trigger.HealthCheck; healthCheck != nil {
+		if healthCheck.Command != "" {
+			registerFunc(healthCheck.Command)
+		}
+		if healthCheck.HttpUrl != "" {
+			registerFunc(healthCheck.HttpUrl)
+		}
+	}
 }
 
 func (trigger *Trigger) replaceStrings(replaceFunc func(string) string) {
@@ -12,6 +20,14 @@ func (trigger *Trigger) replaceStrings(replaceFunc func(string) string) {
 		trigger.MatchLines[index] = replaceFunc(str)
 	}
 	trigger.Service = replaceFunc(trigger.Service)
+	if healthCheck := trigger.HealthCheck; healthCheck != nil {
+		if healthCheck.Command != "" {
+			healthCheck.Command = replaceFunc(healthCheck.Command)
+		}
+		if healthCheck.HttpUrl != "" {
+			healthCheck.HttpUrl = replaceFunc(healthCheck.HttpUrl)
+		}
+	}
 }
 
 func (triggers *Triggers) registerStrings(registerFunc func(string)) {
@@ -110,6 +110,13 @@ func SetAllUid(uid int) error {
 	return setAllUid(uid)
 }
 
+// SetCpuAffinity sets the CPUs which the specified thread may run on. If tid is
+// zero, the affinity of the calling thread is set.
+// On platforms which do not support CPU affinity, an error is always returned.
+func SetCpuAffinity(tid int, cpus []uint) error {
+	return setCpuAffinity(tid, cpus)
+}
+
 // SetPriority sets the CPU priority of the specified process, for all OS
 // threads. If pid is zero, the priority of the calling process is set.
 // On platforms which do not support changing the process priority, an error is
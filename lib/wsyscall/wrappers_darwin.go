@@ -144,6 +144,10 @@ func setAllUid(uid int) error {
 	return syscall.Setreuid(uid, uid)
 }
 
+func setCpuAffinity(tid int, cpus []uint) error {
+	return syscall.ENOTSUP
+}
+
 func setPriority(pid, priority int) error {
 	return syscall.Setpriority(syscall.PRIO_PROCESS, pid, priority)
 }
@@ -15,6 +15,7 @@ import (
 	"runtime"
 	"strconv"
 	"syscall"
+	"unsafe"
 )
 
 const (
@@ -167,6 +168,22 @@ func setAllUid(uid int) error {
 	return syscall.Setresuid(uid, uid, uid)
 }
 
+func setCpuAffinity(tid int, cpus []uint) error {
+	var mask [16]uint64 // Up to 1024 CPUs.
+	for _, cpu := range cpus {
+		if cpu >= uint(len(mask)*64) {
+			return fmt.Errorf("CPU: %d out of range", cpu)
+		}
+		mask[cpu/64] |= 1 << (cpu % 64)
+	}
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY,
+		uintptr(tid), uintptr(len(mask)*8), uintptr(unsafe.Pointer(&mask[0])))
+	if errno != 0 {
+		return os.NewSyscallError("sched_setaffinity", errno)
+	}
+	return nil
+}
+
 // setPriority sets the priority of the specified process, for all OS threads.
 // It will iterate over all the threads and set the priority on each, since the
 // Linux implementation of setpriority(2) only applies to a thread, not the
@@ -79,6 +79,10 @@ func setAllUid(uid int) error {
 	return syscall.ENOTSUP
 }
 
+func setCpuAffinity(tid int, cpus []uint) error {
+	return syscall.ENOTSUP
+}
+
 func setPriority(pid, priority int) error {
 	return syscall.ENOTSUP
 }
@@ -0,0 +1,28 @@
+package auditlog
+
+import (
+	"time"
+)
+
+// Record describes a call to an audited SRPC method.
+type Record struct {
+	Duration   time.Duration
+	Error      string   `json:",omitempty"` // Empty: the call succeeded.
+	Groups     []string `json:",omitempty"`
+	Hostname   string   `json:",omitempty"` // Forwarding server identity.
+	Method     string   // Service.Method
+	Rejected   bool     `json:",omitempty"` // If true, method was not called.
+	RemoteAddr string
+	Request    string `json:",omitempty"` // Summary with secrets redacted.
+	StartTime  time.Time
+	Username   string `json:",omitempty"`
+}
+
+// Records are forwarded to a collector with the AuditLog.AddRecords method.
+type AddRecordsRequest struct {
+	Records []Record
+}
+
+type AddRecordsResponse struct {
+	Error string
+}
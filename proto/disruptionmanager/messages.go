@@ -1,6 +1,8 @@
 package disruptionmanager
 
 import (
+	"time"
+
 	"github.com/Cloud-Foundations/Dominator/lib/mdb"
 	"github.com/Cloud-Foundations/Dominator/proto/sub"
 )
@@ -49,4 +51,33 @@ type DisruptionRequestResponse struct {
 	Response sub.DisruptionState
 }
 
+// Health RPC request. This is sent to the Service.Health method of a machine
+// for an srpc:// readiness check.
+type HealthRequest struct{}
+
+// Health RPC response. The machine is ready if Healthy is true.
+type HealthResponse struct {
+	Error   string
+	Healthy bool
+}
+
+// ReplicaHeartbeat RPC request. A replica sends this to all replicas
+// (including itself) to obtain or renew the leadership lease. The leader
+// includes its state for replicas which have not acknowledged the latest
+// version.
+type ReplicaHeartbeatRequest struct {
+	Candidate     string
+	LeaseDuration time.Duration
+	State         []byte `json:",omitempty"` // JSON encoding of the groups.
+	StateVersion  uint64
+}
+
+// ReplicaHeartbeat RPC response.
+type ReplicaHeartbeatResponse struct {
+	Error        string
+	Granted      bool
+	Leader       string `json:",omitempty"` // Holder of the lease if refused.
+	StateVersion uint64
+}
+
 type RequestType uint
@@ -5,9 +5,15 @@ import (
 
 	"github.com/Cloud-Foundations/Dominator/lib/mdb"
 	"github.com/Cloud-Foundations/Dominator/lib/tags"
+	"github.com/Cloud-Foundations/Dominator/lib/triggers"
 	"github.com/Cloud-Foundations/Dominator/proto/sub"
 )
 
+const (
+	MaintenancePolicyAllChanges        = "AllChanges"
+	MaintenancePolicyDisruptiveChanges = "DisruptiveChanges"
+)
+
 type ClearSafetyShutoffRequest struct {
 	Hostname string
 }
@@ -42,10 +48,35 @@ type GetDefaultImageResponse struct {
 	ImageName string
 }
 
+type GetMaintenanceWindowsRequest struct{}
+
+type GetMaintenanceWindowsResponse MaintenanceWindows
+
+type GetSubDriftRequest struct {
+	Hostname string
+}
+
+type GetSubDriftResponse struct {
+	Error string
+	Drift *SubDrift // nil: the sub is not audited or has no drift.
+}
+
 type GetSubsConfigurationRequest struct{}
 
 type GetSubsConfigurationResponse sub.Configuration
 
+type GetUpdateHistoryRequest struct {
+	Hostname   string        // Empty: match all hostnames.
+	MaxAge     time.Duration // Zero: no limit.
+	MaxEntries uint          // Zero: no limit.
+	PathName   string        // Empty: match all paths.
+}
+
+type GetUpdateHistoryResponse struct {
+	Error   string
+	Entries []UpdateHistoryEntry // Newest first.
+}
+
 type GetInfoForSubsRequest struct {
 	Hostnames        []string       // Empty: match all hostnames.
 	LocationsToMatch []string       // Empty: match all locations.
@@ -70,21 +101,53 @@ type ListSubsResponse struct {
 	Hostnames []string
 }
 
+// MaintenanceWindows specifies when updates may be sent to subs. If Windows is
+// empty, updates may be sent at any time. Each window is specified in the
+// format documented in the lib/timewindow package.
+type MaintenanceWindows struct {
+	Policy  string   `json:",omitempty"` // Default: AllChanges.
+	Windows []string `json:",omitempty"`
+}
+
 type SetDefaultImageRequest struct {
 	ImageName string
 }
 
 type SetDefaultImageResponse struct{}
 
+type SetMaintenanceWindowsRequest MaintenanceWindows
+
+type SetMaintenanceWindowsResponse struct{}
+
+type SubDrift struct {
+	ComputeTime   time.Time
+	ImageName     string
+	PathsToAdd    []string            `json:",omitempty"`
+	PathsToChange []string            `json:",omitempty"`
+	PathsToDelete []string            `json:",omitempty"`
+	Triggers      []*triggers.Trigger `json:",omitempty"`
+}
+
 type SubInfo struct {
 	mdb.Machine
+	ABRoot              *sub.ABRootStatus   `json:",omitempty"`
 	LastNote            string              `json:",omitempty"`
 	LastDisruptionState sub.DisruptionState `json:",omitempty"`
 	LastScanDuration    time.Duration       `json:",omitempty"`
 	LastSuccessfulImage string              `json:",omitempty"`
 	LastSyncTime        time.Time           `json:",omitempty"`
 	LastUpdateTime      time.Time           `json:",omitempty"`
+	NextMaintenanceTime time.Time           `json:",omitempty"`
 	StartTime           time.Time           `json:",omitempty"`
 	Status              string
 	SystemUptime        *time.Duration `json:",omitempty"`
 }
+
+type UpdateHistoryEntry struct {
+	Hostname     string
+	ImageName    string
+	PathsChanged []string `json:",omitempty"`
+	PathsDeleted []string `json:",omitempty"`
+	Time         time.Time
+	Triggers     []string `json:",omitempty"` // Service names.
+}
@@ -16,6 +16,16 @@ type ChangeMachineTagsResponse struct {
 	Error string
 }
 
+type CheckPlacementRequest struct {
+	HypervisorHostname string
+	PlacementGroup     PlacementGroup
+	VmToPlace          net.IP // Ignored when checking PlacementGroup.
+}
+
+type CheckPlacementResponse struct {
+	Error string
+}
+
 type GetHypervisorForVMRequest struct {
 	IpAddress net.IP
 }
@@ -30,7 +40,9 @@ type GetHypervisorsInLocationRequest struct {
 	IncludeUnhealthy      bool
 	IncludeVMs            bool
 	Location              string
+	PlacementGroup        PlacementGroup // Exclude if anti-affinity broken.
 	SubnetId              string
+	VmToPlace             net.IP // Ignored when checking PlacementGroup.
 }
 
 type GetHypervisorsInLocationResponse struct {
@@ -43,7 +55,8 @@ type Hypervisor struct {
 	AllocatedMemory      uint64 `json:",omitempty"`
 	AllocatedVolumeBytes uint64 `json:",omitempty"`
 	Machine
-	VMs []proto.VmInfo `json:",omitempty"`
+	NumaNodes []proto.NumaNode `json:",omitempty"`
+	VMs       []proto.VmInfo   `json:",omitempty"`
 }
 
 type GetMachineInfoRequest struct {
@@ -92,8 +105,10 @@ type ListHypervisorsInLocationRequest struct {
 	HypervisorTagsToMatch tags.MatchTags // Empty: match all tags.
 	IncludeUnhealthy      bool
 	Location              string
+	PlacementGroup        PlacementGroup // Exclude if anti-affinity broken.
 	SubnetId              string
 	TagsToInclude         []string
+	VmToPlace             net.IP // Ignored when checking PlacementGroup.
 }
 
 type ListHypervisorsInLocationResponse struct {
@@ -148,6 +163,14 @@ type NetworkEntry struct {
 	VlanTrunk      bool         `json:",omitempty"`
 }
 
+// PlacementGroup specifies a group of VMs which should not share a failure
+// domain. If Spread is zero, the failure domain is the Hypervisor, else it is
+// the topology directory at depth Spread.
+type PlacementGroup struct {
+	Name   string `json:",omitempty"`
+	Spread uint   `json:",omitempty"`
+}
+
 type PowerOnMachineRequest struct {
 	Hostname string
 }
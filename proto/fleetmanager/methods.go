@@ -3,9 +3,40 @@ package fleetmanager
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"net"
+	"strconv"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/tags"
+)
+
+const (
+	PlacementGroupTag  = "PlacementGroup"
+	PlacementSpreadTag = "PlacementSpread"
 )
 
+// GetPlacementGroup returns the placement group for a VM from the
+// PlacementGroup and PlacementSpread tags. The PlacementSpread tag may be
+// "hypervisor" (the default) or the depth of the topology directory.
+func GetPlacementGroup(vmTags tags.Tags) (PlacementGroup, error) {
+	name := vmTags[PlacementGroupTag]
+	if name == "" {
+		return PlacementGroup{}, nil
+	}
+	spread := vmTags[PlacementSpreadTag]
+	switch spread {
+	case "", "hypervisor":
+		return PlacementGroup{Name: name}, nil
+	}
+	depth, err := strconv.ParseUint(spread, 10, 32)
+	if err != nil || depth < 1 {
+		return PlacementGroup{},
+			fmt.Errorf("invalid %s: %s", PlacementSpreadTag, spread)
+	}
+	return PlacementGroup{Name: name, Spread: uint(depth)}, nil
+}
+
 func listsEqual(left, right []string) bool {
 	if len(left) != len(right) {
 		return false
@@ -114,3 +145,24 @@ func (addr *HardwareAddr) UnmarshalText(text []byte) error {
 		return nil
 	}
 }
+
+// FailureDomain returns the failure domain of a Hypervisor for the placement
+// group. No two VMs in the group should share a failure domain. Hypervisors
+// without a location are treated as their own failure domain.
+func (pg PlacementGroup) FailureDomain(hostname, location string) string {
+	if pg.Spread < 1 || location == "" {
+		return hostname
+	}
+	dirs := strings.Split(location, "/")
+	if uint(len(dirs)) > pg.Spread {
+		dirs = dirs[:pg.Spread]
+	}
+	return strings.Join(dirs, "/")
+}
+
+func (pg PlacementGroup) String() string {
+	if pg.Spread < 1 {
+		return pg.Name + " (spread: hypervisor)"
+	}
+	return fmt.Sprintf("%s (spread: depth %d)", pg.Name, pg.Spread)
+}
@@ -0,0 +1,65 @@
+package fleetmanager
+
+import (
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/tags"
+)
+
+func TestGetPlacementGroup(t *testing.T) {
+	pg, err := GetPlacementGroup(tags.Tags{"Name": "vm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pg.Name != "" {
+		t.Errorf("unexpected placement group: %s", pg)
+	}
+	pg, err = GetPlacementGroup(tags.Tags{PlacementGroupTag: "db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pg != (PlacementGroup{Name: "db"}) {
+		t.Errorf("unexpected placement group: %s", pg)
+	}
+	pg, err = GetPlacementGroup(tags.Tags{
+		PlacementGroupTag:  "db",
+		PlacementSpreadTag: "2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pg != (PlacementGroup{Name: "db", Spread: 2}) {
+		t.Errorf("unexpected placement group: %s", pg)
+	}
+	for _, spread := range []string{"0", "-1", "rack"} {
+		_, err := GetPlacementGroup(tags.Tags{
+			PlacementGroupTag:  "db",
+			PlacementSpreadTag: spread,
+		})
+		if err == nil {
+			t.Errorf("invalid spread: %s not rejected", spread)
+		}
+	}
+}
+
+func TestFailureDomain(t *testing.T) {
+	tests := []struct {
+		spread   uint
+		location string
+		domain   string
+	}{
+		{0, "dc1/row2/rack3", "hyper1"},
+		{1, "dc1/row2/rack3", "dc1"},
+		{2, "dc1/row2/rack3", "dc1/row2"},
+		{5, "dc1/row2/rack3", "dc1/row2/rack3"},
+		{2, "", "hyper1"},
+	}
+	for _, test := range tests {
+		pg := PlacementGroup{Name: "db", Spread: test.spread}
+		domain := pg.FailureDomain("hyper1", test.location)
+		if domain != test.domain {
+			t.Errorf("spread: %d location: %s: domain: %s != %s",
+				test.spread, test.location, domain, test.domain)
+		}
+	}
+}
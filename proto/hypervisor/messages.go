@@ -72,6 +72,15 @@ type AddVmVolumesResponse struct {
 	Error string
 }
 
+type BackupVmRequest struct {
+	IpAddress net.IP
+}
+
+type BackupVmResponse struct {
+	Backup VmBackupInfo
+	Error  string
+}
+
 type BecomePrimaryVmOwnerRequest struct {
 	IpAddress net.IP
 }
@@ -126,6 +135,27 @@ type ChangeVmDestroyProtectionResponse struct {
 	Error string
 }
 
+// ChangeVmIoLimitsRequest replaces all the IO limits for the VM. Interfaces
+// and volumes for which no limits are given are not limited.
+type ChangeVmIoLimitsRequest struct {
+	IpAddress      net.IP
+	NetworkLimits  []InterfaceIoLimits
+	VolumeIoLimits []VolumeIoLimits
+}
+
+type ChangeVmIoLimitsResponse struct {
+	Error string
+}
+
+type ChangeVmLifecyclePolicyRequest struct {
+	IpAddress       net.IP
+	LifecyclePolicy LifecyclePolicy
+}
+
+type ChangeVmLifecyclePolicyResponse struct {
+	Error string
+}
+
 type ChangeVmMachineTypeRequest struct {
 	MachineType MachineType
 	IpAddress   net.IP
@@ -153,6 +183,15 @@ type ChangeVmOwnerUsersResponse struct {
 	Error string
 }
 
+type ChangeVmSecurityGroupsRequest struct {
+	IpAddress      net.IP
+	SecurityGroups []string
+}
+
+type ChangeVmSecurityGroupsResponse struct {
+	Error string
+}
+
 type ChangeVmSizeRequest struct {
 	IpAddress   net.IP
 	MemoryInMiB uint64
@@ -269,6 +308,7 @@ type CreateVmRequest struct {
 	SkipBootloader       bool
 	SkipMemoryCheck      bool
 	StorageIndices       []uint
+	ThinRootVolume       bool // Root volume is an overlay on a cached image.
 	UserDataSize         uint64
 	VmInfo
 } // The following data are streamed afterwards in the following order:
@@ -374,9 +414,10 @@ type ExportLocalVmResponse struct {
 type GetCapacityRequest struct{}
 
 type GetCapacityResponse struct {
-	MemoryInMiB      uint64 `json:",omitempty"`
-	NumCPUs          uint   `json:",omitempty"`
-	TotalVolumeBytes uint64 `json:",omitempty"`
+	MemoryInMiB      uint64     `json:",omitempty"`
+	NumaNodes        []NumaNode `json:",omitempty"`
+	NumCPUs          uint       `json:",omitempty"`
+	TotalVolumeBytes uint64     `json:",omitempty"`
 }
 
 type GetRootCookiePathRequest struct{}
@@ -395,21 +436,24 @@ type GetUpdatesRequest struct {
 }
 
 type Update struct {
-	HaveAddressPool  bool               `json:",omitempty"`
-	AddressPool      []Address          `json:",omitempty"` // Used & free.
-	HaveDisabled     bool               `json:",omitempty"`
-	Disabled         bool               `json:",omitempty"`
-	MemoryInMiB      *uint64            `json:",omitempty"`
-	NumCPUs          *uint              `json:",omitempty"`
-	NumFreeAddresses map[string]uint    `json:",omitempty"` // Key: subnet ID.
-	HealthStatus     string             `json:",omitempty"`
-	HaveSerialNumber bool               `json:",omitempty"`
-	SerialNumber     string             `json:",omitempty"`
-	HaveSubnets      bool               `json:",omitempty"`
-	Subnets          []Subnet           `json:",omitempty"`
-	TotalVolumeBytes *uint64            `json:",omitempty"`
-	HaveVMs          bool               `json:",omitempty"`
-	VMs              map[string]*VmInfo `json:",omitempty"` // Key: IP address.
+	HaveAddressPool    bool               `json:",omitempty"`
+	AddressPool        []Address          `json:",omitempty"` // Used & free.
+	HaveDisabled       bool               `json:",omitempty"`
+	Disabled           bool               `json:",omitempty"`
+	MemoryInMiB        *uint64            `json:",omitempty"`
+	NumCPUs            *uint              `json:",omitempty"`
+	NumaNodes          []NumaNode         `json:",omitempty"`
+	NumFreeAddresses   map[string]uint    `json:",omitempty"` // Key: subnet ID.
+	HealthStatus       string             `json:",omitempty"`
+	HaveSecurityGroups bool               `json:",omitempty"`
+	SecurityGroups     []SecurityGroup    `json:",omitempty"`
+	HaveSerialNumber   bool               `json:",omitempty"`
+	SerialNumber       string             `json:",omitempty"`
+	HaveSubnets        bool               `json:",omitempty"`
+	Subnets            []Subnet           `json:",omitempty"`
+	TotalVolumeBytes   *uint64            `json:",omitempty"`
+	HaveVMs            bool               `json:",omitempty"`
+	VMs                map[string]*VmInfo `json:",omitempty"` // Key: IP address.
 }
 
 type GetVmAccessTokenRequest struct {
@@ -497,6 +541,13 @@ type HoldVmLockResponse struct {
 	Error string
 }
 
+type ListSecurityGroupsRequest struct{}
+
+type ListSecurityGroupsResponse struct {
+	Error          string
+	SecurityGroups []SecurityGroup `json:",omitempty"`
+}
+
 type ListSubnetsRequest struct {
 	Sort bool
 }
@@ -506,6 +557,14 @@ type ListSubnetsResponse struct {
 	Subnets []Subnet `json:",omitempty"`
 }
 
+// InterfaceIoLimits contains the rate limits for a network interface, from the
+// perspective of the VM. A value of zero means no limit. The limits for the
+// primary interface are first, followed by the secondary interfaces.
+type InterfaceIoLimits struct {
+	EgressBitsPerSecond  uint64 `json:",omitempty"`
+	IngressBitsPerSecond uint64 `json:",omitempty"`
+}
+
 type ImportLocalVmRequest struct {
 	SkipMemoryCheck    bool
 	VerificationCookie []byte `json:",omitempty"`
@@ -517,6 +576,16 @@ type ImportLocalVmResponse struct {
 	Error string
 }
 
+// LifecyclePolicy controls when the Hypervisor will automatically stop or
+// destroy a VM. A zero value means the VM is never stopped or destroyed.
+type LifecyclePolicy struct {
+	BackupBeforeDestroy bool          `json:",omitempty"`
+	DestroyOnExpiry     bool          `json:",omitempty"` // Else stop.
+	ExpiresAt           time.Time     `json:",omitempty"`
+	IdleTimeout         time.Duration `json:",omitempty"` // Stop when idle.
+	WarningPeriod       time.Duration `json:",omitempty"` // Email owners.
+}
+
 type ListVMsRequest struct {
 	IgnoreStateMask uint64
 	OwnerGroups     []string
@@ -529,6 +598,15 @@ type ListVMsResponse struct {
 	IpAddresses []net.IP
 }
 
+type ListVmBackupsRequest struct {
+	IpAddress net.IP
+}
+
+type ListVmBackupsResponse struct {
+	Backups []VmBackupInfo // Oldest first.
+	Error   string
+}
+
 type ListVolumeDirectoriesRequest struct{}
 
 type ListVolumeDirectoriesResponse struct {
@@ -537,13 +615,16 @@ type ListVolumeDirectoriesResponse struct {
 }
 
 type LocalVolume struct {
+	BaseVolume         string `json:",omitempty"` // Backing file for QCOW2.
 	DirectoryToCleanup string
 	Filename           string
+	OldBaseVolume      string `json:",omitempty"` // Backing file for .old.
 }
 
 type LocalVmInfo struct {
 	VmInfo
-	VolumeLocations []LocalVolume
+	LifecycleWarnedExpiry time.Time `json:",omitempty"` // Owners were warned.
+	VolumeLocations       []LocalVolume
 }
 
 type MachineType uint
@@ -582,6 +663,16 @@ type NetbootMachineResponse struct {
 	Error string
 }
 
+// NumaNode describes a NUMA node of a Hypervisor and the cores and memory
+// which are dedicated to VMs.
+type NumaNode struct {
+	AllocatedCores  uint     `json:",omitempty"`
+	AllocatedMemory uint64   `json:",omitempty"` // MiB.
+	Cores           [][]uint `json:",omitempty"` // CPU numbers for each core.
+	Id              uint     `json:",omitempty"`
+	MemoryInMiB     uint64   `json:",omitempty"`
+}
+
 type PatchVmImageRequest struct {
 	ImageName    string
 	ImageTimeout time.Duration
@@ -683,6 +774,15 @@ type ReplaceVmUserDataResponse struct {
 	Error string
 }
 
+type RestoreVmFromBackupRequest struct {
+	BackupId  string
+	IpAddress net.IP
+}
+
+type RestoreVmFromBackupResponse struct {
+	Error string
+}
+
 type RestoreVmFromSnapshotRequest struct {
 	IpAddress         net.IP
 	ForceIfNotStopped bool
@@ -728,6 +828,28 @@ type ScanVmRootResponse struct {
 	FileSystem *filesystem.FileSystem
 }
 
+// SecurityGroup specifies the traffic which is permitted to and from the VMs it
+// is attached to. Traffic which does not match an ingress rule is dropped.
+// Egress traffic is only filtered if an attached group has egress rules.
+type SecurityGroup struct {
+	Id           string
+	Description  string         `json:",omitempty"`
+	EgressRules  []SecurityRule `json:",omitempty"`
+	IngressRules []SecurityRule `json:",omitempty"`
+}
+
+// SecurityRule matches traffic to or from peers. The peers are specified by
+// CIDR, by address or by the tags of VMs. If no peers are specified, all peers
+// are matched.
+type SecurityRule struct {
+	CIDR          string         `json:",omitempty"`
+	FromPort      uint16         `json:",omitempty"`
+	PeerAddresses []net.IP       `json:",omitempty"`
+	PeerTags      tags.MatchTags `json:",omitempty"`
+	Protocol      string         `json:",omitempty"` // Empty: all protocols.
+	ToPort        uint16         `json:",omitempty"` // 0: same as FromPort.
+}
+
 type SetDisabledStateRequest struct {
 	Disable bool
 }
@@ -791,6 +913,16 @@ type TraceVmMetadataResponse struct {
 	Error string
 } // A stream of strings (trace paths) follow.
 
+type UpdateSecurityGroupsRequest struct {
+	Add    []SecurityGroup
+	Change []SecurityGroup
+	Delete []string
+}
+
+type UpdateSecurityGroupsResponse struct {
+	Error string
+}
+
 type UpdateSubnetsRequest struct {
 	Add    []Subnet
 	Change []Subnet
@@ -801,30 +933,46 @@ type UpdateSubnetsResponse struct {
 	Error string
 }
 
+// VmBackupInfo describes a backup of the volumes of a VM. The volumes are
+// stored as content-addressed chunks, so UploadedBytes (the size of the chunks
+// which were not already in the object store) may be much smaller than the
+// total size of the volumes.
+type VmBackupInfo struct {
+	BackupId      string
+	CreatedOn     time.Time
+	UploadedBytes uint64   `json:",omitempty"`
+	VolumeSizes   []uint64 `json:",omitempty"`
+}
+
 type VmInfo struct {
 	Address            Address
-	ChangedStateOn     time.Time   `json:",omitempty"`
-	ConsoleType        ConsoleType `json:",omitempty"`
-	CreatedOn          time.Time   `json:",omitempty"`
-	CpuPriority        int         `json:",omitempty"`
-	DestroyOnPowerdown bool        `json:",omitempty"`
-	DestroyProtection  bool        `json:",omitempty"`
-	DisableVirtIO      bool        `json:",omitempty"`
-	ExtraKernelOptions string      `json:",omitempty"`
-	Hostname           string      `json:",omitempty"`
-	IdentityExpires    time.Time   `json:",omitempty"`
-	IdentityName       string      `json:",omitempty"`
-	ImageName          string      `json:",omitempty"`
-	ImageURL           string      `json:",omitempty"`
-	MachineType        MachineType `json:",omitempty"`
+	ChangedStateOn     time.Time       `json:",omitempty"`
+	ConsoleType        ConsoleType     `json:",omitempty"`
+	CreatedOn          time.Time       `json:",omitempty"`
+	CpuPriority        int             `json:",omitempty"`
+	DedicatedCPUs      bool            `json:",omitempty"`
+	DestroyOnPowerdown bool            `json:",omitempty"`
+	DestroyProtection  bool            `json:",omitempty"`
+	DisableVirtIO      bool            `json:",omitempty"`
+	ExtraKernelOptions string          `json:",omitempty"`
+	Hostname           string          `json:",omitempty"`
+	IdentityExpires    time.Time       `json:",omitempty"`
+	IdentityName       string          `json:",omitempty"`
+	ImageName          string          `json:",omitempty"`
+	ImageURL           string          `json:",omitempty"`
+	LifecyclePolicy    LifecyclePolicy `json:",omitempty"`
+	MachineType        MachineType     `json:",omitempty"`
 	MemoryInMiB        uint64
 	MilliCPUs          uint
-	OwnerGroups        []string `json:",omitempty"`
-	OwnerUsers         []string `json:",omitempty"`
-	SpreadVolumes      bool     `json:",omitempty"`
+	NetworkLimits      []InterfaceIoLimits `json:",omitempty"`
+	OwnerGroups        []string            `json:",omitempty"`
+	OwnerUsers         []string            `json:",omitempty"`
+	PinnedCPUs         []uint              `json:",omitempty"` // Host CPUs.
+	SpreadVolumes      bool                `json:",omitempty"`
 	State              State
 	SecondaryAddresses []Address      `json:",omitempty"`
 	SecondarySubnetIDs []string       `json:",omitempty"`
+	SecurityGroups     []string       `json:",omitempty"`
 	SubnetId           string         `json:",omitempty"`
 	Tags               tags.Tags      `json:",omitempty"`
 	Uncommitted        bool           `json:",omitempty"`
@@ -837,6 +985,7 @@ type VmInfo struct {
 type Volume struct {
 	Format    VolumeFormat    `json:",omitempty"`
 	Interface VolumeInterface `json:",omitempty"`
+	IoLimits  VolumeIoLimits  `json:",omitempty"`
 	Size      uint64          `json:",omitempty"`
 	Type      VolumeType      `json:",omitempty"`
 }
@@ -851,6 +1000,13 @@ type VolumeInitialisationInfo struct {
 	ReservedBlocksPercentage uint16
 }
 
+// VolumeIoLimits contains the throttling limits for a volume. A value of zero
+// means no limit.
+type VolumeIoLimits struct {
+	BytesPerSecond uint64 `json:",omitempty"`
+	IOPS           uint64 `json:",omitempty"`
+}
+
 type VolumeType uint
 
 // The WatchDhcp() RPC is fully streamed.
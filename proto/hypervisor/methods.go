@@ -3,8 +3,11 @@ package hypervisor
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"net"
 	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/tags"
 )
 
 const (
@@ -207,6 +210,20 @@ func (al *AddressList) Set(value string) error {
 	return nil
 }
 
+func matchTagsEqual(left, right tags.MatchTags) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for key, leftValues := range left {
+		if rightValues, ok := right[key]; !ok {
+			return false
+		} else if !stringSlicesEqual(leftValues, rightValues) {
+			return false
+		}
+	}
+	return true
+}
+
 func stringSlicesEqual(left, right []string) bool {
 	if len(left) != len(right) {
 		return false
@@ -219,6 +236,18 @@ func stringSlicesEqual(left, right []string) bool {
 	return true
 }
 
+func uintSlicesEqual(left, right []uint) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for index, leftValue := range left {
+		if leftValue != right[index] {
+			return false
+		}
+	}
+	return true
+}
+
 func (consoleType *ConsoleType) CheckValid() error {
 	if _, ok := consoleTypeToText[*consoleType]; !ok {
 		return errors.New(consoleTypeUnknown)
@@ -262,6 +291,48 @@ func (consoleType *ConsoleType) UnmarshalText(text []byte) error {
 	}
 }
 
+func (policy *LifecyclePolicy) CheckValid() error {
+	if policy.IdleTimeout < 0 {
+		return errors.New("negative idle timeout")
+	}
+	if policy.WarningPeriod < 0 {
+		return errors.New("negative warning period")
+	}
+	if policy.ExpiresAt.IsZero() {
+		if policy.DestroyOnExpiry {
+			return errors.New("destroy on expiry requires an expiry time")
+		}
+		if policy.WarningPeriod > 0 {
+			return errors.New("warning period requires an expiry time")
+		}
+	}
+	if policy.BackupBeforeDestroy && !policy.DestroyOnExpiry {
+		return errors.New("backup before destroy requires destroy on expiry")
+	}
+	return nil
+}
+
+func (left *LifecyclePolicy) Equal(right *LifecyclePolicy) bool {
+	if left.BackupBeforeDestroy != right.BackupBeforeDestroy {
+		return false
+	}
+	if left.DestroyOnExpiry != right.DestroyOnExpiry {
+		return false
+	}
+	if !left.ExpiresAt.Equal(right.ExpiresAt) {
+		return false
+	}
+	if left.IdleTimeout != right.IdleTimeout {
+		return false
+	}
+	return left.WarningPeriod == right.WarningPeriod
+}
+
+// IsActive returns true if the policy may stop or destroy the VM.
+func (policy *LifecyclePolicy) IsActive() bool {
+	return !policy.ExpiresAt.IsZero() || policy.IdleTimeout > 0
+}
+
 func (machineType *MachineType) CheckValid() error {
 	if _, ok := machineTypeToText[*machineType]; !ok {
 		return errors.New(machineTypeUnknown)
@@ -305,6 +376,161 @@ func (machineType *MachineType) UnmarshalText(text []byte) error {
 	}
 }
 
+// CountNumaNodeAllocations returns a copy of the NUMA nodes with the cores and
+// memory which are dedicated to the VMs counted. The node for a VM is the node
+// containing the first of its pinned CPUs.
+func CountNumaNodeAllocations(nodes []NumaNode, vms []*VmInfo) []NumaNode {
+	if len(nodes) < 1 {
+		return nil
+	}
+	cpuToNode := make(map[uint]int)
+	newNodes := make([]NumaNode, 0, len(nodes))
+	for index, node := range nodes {
+		node.AllocatedCores = 0
+		node.AllocatedMemory = 0
+		newNodes = append(newNodes, node)
+		for _, core := range node.Cores {
+			for _, cpu := range core {
+				cpuToNode[cpu] = index
+			}
+		}
+	}
+	for _, vm := range vms {
+		if len(vm.PinnedCPUs) < 1 {
+			continue
+		}
+		if index, ok := cpuToNode[vm.PinnedCPUs[0]]; ok {
+			node := &newNodes[index]
+			node.AllocatedCores += node.NumCoresForCPUs(
+				uint(len(vm.PinnedCPUs)))
+			node.AllocatedMemory += vm.MemoryInMiB
+		}
+	}
+	return newNodes
+}
+
+// CanDedicate returns true if the NUMA node has sufficient free cores and
+// memory for a VM with the specified number of dedicated CPUs.
+func (node *NumaNode) CanDedicate(numCPUs uint, memoryInMiB uint64) bool {
+	if node.NumCoresForCPUs(numCPUs) > node.FreeCores() {
+		return false
+	}
+	return memoryInMiB+node.AllocatedMemory <= node.MemoryInMiB
+}
+
+// FreeCores returns the number of cores which are not dedicated to VMs.
+func (node *NumaNode) FreeCores() uint {
+	if node.AllocatedCores >= uint(len(node.Cores)) {
+		return 0
+	}
+	return uint(len(node.Cores)) - node.AllocatedCores
+}
+
+// NumCoresForCPUs returns the number of whole cores required to provide the
+// specified number of CPUs (hyperthreads).
+func (node *NumaNode) NumCoresForCPUs(numCPUs uint) uint {
+	threadsPerCore := uint(1)
+	if len(node.Cores) > 0 && len(node.Cores[0]) > 0 {
+		threadsPerCore = uint(len(node.Cores[0]))
+	}
+	return (numCPUs + threadsPerCore - 1) / threadsPerCore
+}
+
+func (group *SecurityGroup) CheckValid() error {
+	if group.Id == "" {
+		return errors.New("no security group ID")
+	}
+	for _, rule := range group.EgressRules {
+		if err := rule.CheckValid(); err != nil {
+			return fmt.Errorf("security group: %s: %s", group.Id, err)
+		}
+	}
+	for _, rule := range group.IngressRules {
+		if err := rule.CheckValid(); err != nil {
+			return fmt.Errorf("security group: %s: %s", group.Id, err)
+		}
+	}
+	return nil
+}
+
+func (left *SecurityGroup) Equal(right *SecurityGroup) bool {
+	if left.Id != right.Id {
+		return false
+	}
+	if left.Description != right.Description {
+		return false
+	}
+	if len(left.EgressRules) != len(right.EgressRules) {
+		return false
+	}
+	if len(left.IngressRules) != len(right.IngressRules) {
+		return false
+	}
+	for index, leftRule := range left.EgressRules {
+		if !leftRule.Equal(&right.EgressRules[index]) {
+			return false
+		}
+	}
+	for index, leftRule := range left.IngressRules {
+		if !leftRule.Equal(&right.IngressRules[index]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (rule *SecurityRule) CheckValid() error {
+	switch rule.Protocol {
+	case "", "icmp":
+		if rule.FromPort != 0 || rule.ToPort != 0 {
+			return errors.New("ports require the tcp or udp protocol")
+		}
+	case "tcp", "udp":
+		if rule.FromPort == 0 && rule.ToPort != 0 {
+			return fmt.Errorf("port range: 0-%d requires FromPort",
+				rule.ToPort)
+		}
+		if rule.ToPort != 0 && rule.ToPort < rule.FromPort {
+			return fmt.Errorf("invalid port range: %d-%d",
+				rule.FromPort, rule.ToPort)
+		}
+	default:
+		return errors.New("unsupported protocol: " + rule.Protocol)
+	}
+	if rule.CIDR != "" {
+		if ipAddr, _, err := net.ParseCIDR(rule.CIDR); err != nil {
+			return err
+		} else if ipAddr.To4() == nil {
+			return errors.New("not an IPv4 CIDR: " + rule.CIDR)
+		}
+	}
+	for _, ipAddr := range rule.PeerAddresses {
+		if ipAddr.To4() == nil {
+			return errors.New("not an IPv4 address: " + ipAddr.String())
+		}
+	}
+	return nil
+}
+
+func (left *SecurityRule) Equal(right *SecurityRule) bool {
+	if left.CIDR != right.CIDR {
+		return false
+	}
+	if left.FromPort != right.FromPort {
+		return false
+	}
+	if !IpListsEqual(left.PeerAddresses, right.PeerAddresses) {
+		return false
+	}
+	if !matchTagsEqual(left.PeerTags, right.PeerTags) {
+		return false
+	}
+	if left.Protocol != right.Protocol {
+		return false
+	}
+	return left.ToPort == right.ToPort
+}
+
 func (state State) MarshalText() ([]byte, error) {
 	if text := state.String(); text == stateUnknown {
 		return nil, errors.New(text)
@@ -404,6 +630,9 @@ func (left *VmInfo) Equal(right *VmInfo) bool {
 	if left.CpuPriority != right.CpuPriority {
 		return false
 	}
+	if left.DedicatedCPUs != right.DedicatedCPUs {
+		return false
+	}
 	if left.DestroyOnPowerdown != right.DestroyOnPowerdown {
 		return false
 	}
@@ -431,6 +660,9 @@ func (left *VmInfo) Equal(right *VmInfo) bool {
 	if left.ImageURL != right.ImageURL {
 		return false
 	}
+	if !left.LifecyclePolicy.Equal(&right.LifecyclePolicy) {
+		return false
+	}
 	if left.MachineType != right.MachineType {
 		return false
 	}
@@ -440,12 +672,23 @@ func (left *VmInfo) Equal(right *VmInfo) bool {
 	if left.MilliCPUs != right.MilliCPUs {
 		return false
 	}
+	if len(left.NetworkLimits) != len(right.NetworkLimits) {
+		return false
+	}
+	for index, leftLimits := range left.NetworkLimits {
+		if leftLimits != right.NetworkLimits[index] {
+			return false
+		}
+	}
 	if !stringSlicesEqual(left.OwnerGroups, right.OwnerGroups) {
 		return false
 	}
 	if !stringSlicesEqual(left.OwnerUsers, right.OwnerUsers) {
 		return false
 	}
+	if !uintSlicesEqual(left.PinnedCPUs, right.PinnedCPUs) {
+		return false
+	}
 	if left.SpreadVolumes != right.SpreadVolumes {
 		return false
 	}
@@ -466,6 +709,9 @@ func (left *VmInfo) Equal(right *VmInfo) bool {
 	if !stringSlicesEqual(left.SecondarySubnetIDs, right.SecondarySubnetIDs) {
 		return false
 	}
+	if !stringSlicesEqual(left.SecurityGroups, right.SecurityGroups) {
+		return false
+	}
 	if left.SubnetId != right.SubnetId {
 		return false
 	}
@@ -40,7 +40,8 @@ func makeNonzeroVmInfo(t *testing.T, zeroIndex int) (VmInfo, bool) {
 				reflect.ValueOf("value"))
 		case reflect.Slice:
 			switch fieldName {
-			case "OwnerGroups", "OwnerUsers", "SecondarySubnetIDs":
+			case "OwnerGroups", "OwnerUsers", "SecondarySubnetIDs",
+				"SecurityGroups":
 				sliceValue := reflect.MakeSlice(stringType, 2, 2)
 				fieldValue.Set(sliceValue)
 				sliceValue.Index(0).SetString(fieldName)
@@ -51,6 +52,14 @@ func makeNonzeroVmInfo(t *testing.T, zeroIndex int) (VmInfo, bool) {
 					MacAddress: "01:02:03",
 				}}
 				fieldValue.Set(reflect.ValueOf(addresses))
+			case "NetworkLimits":
+				limits := []InterfaceIoLimits{{
+					EgressBitsPerSecond:  1,
+					IngressBitsPerSecond: 2,
+				}}
+				fieldValue.Set(reflect.ValueOf(limits))
+			case "PinnedCPUs":
+				fieldValue.Set(reflect.ValueOf([]uint{1, 2}))
 			case "Volumes":
 				volumes := []Volume{{
 					Format: 1,
@@ -71,6 +80,12 @@ func makeNonzeroVmInfo(t *testing.T, zeroIndex int) (VmInfo, bool) {
 				fieldValue.Set(reflect.ValueOf(address))
 			case "ChangedStateOn", "CreatedOn", "IdentityExpires":
 				fieldValue.Set(reflect.ValueOf(startTime))
+			case "LifecyclePolicy":
+				policy := LifecyclePolicy{
+					ExpiresAt:   startTime,
+					IdleTimeout: time.Hour,
+				}
+				fieldValue.Set(reflect.ValueOf(policy))
 			default:
 				t.Fatalf("Unsupported struct field: %s", fieldName)
 			}
@@ -114,3 +129,90 @@ func TestCompareEachField(t *testing.T) {
 		}
 	}
 }
+
+func TestLifecyclePolicyCheckValid(t *testing.T) {
+	validPolicies := []LifecyclePolicy{
+		{},
+		{IdleTimeout: time.Hour},
+		{ExpiresAt: startTime, WarningPeriod: time.Hour},
+		{BackupBeforeDestroy: true, DestroyOnExpiry: true,
+			ExpiresAt: startTime},
+	}
+	for _, policy := range validPolicies {
+		if err := policy.CheckValid(); err != nil {
+			t.Errorf("CheckValid(%v) = %s", policy, err)
+		}
+	}
+	invalidPolicies := []LifecyclePolicy{
+		{IdleTimeout: -time.Hour},
+		{DestroyOnExpiry: true},
+		{WarningPeriod: time.Hour},
+		{BackupBeforeDestroy: true, ExpiresAt: startTime},
+	}
+	for _, policy := range invalidPolicies {
+		if err := policy.CheckValid(); err == nil {
+			t.Errorf("CheckValid(%v) did not fail", policy)
+		}
+	}
+}
+
+func TestSecurityRuleCheckValid(t *testing.T) {
+	validRules := []SecurityRule{
+		{},
+		{CIDR: "10.0.0.0/8", Protocol: "icmp"},
+		{FromPort: 22, Protocol: "tcp"},
+		{FromPort: 8000, Protocol: "udp", ToPort: 8100},
+	}
+	for _, rule := range validRules {
+		if err := rule.CheckValid(); err != nil {
+			t.Errorf("CheckValid(%v) = %s", rule, err)
+		}
+	}
+	invalidRules := []SecurityRule{
+		{CIDR: "10.0.0.0"},
+		{CIDR: "fd00::/8"},
+		{FromPort: 22},
+		{FromPort: 22, Protocol: "icmp"},
+		{FromPort: 8100, Protocol: "tcp", ToPort: 8000},
+		{Protocol: "tcp", ToPort: 8000},
+		{Protocol: "sctp"},
+	}
+	for _, rule := range invalidRules {
+		if err := rule.CheckValid(); err == nil {
+			t.Errorf("CheckValid(%v) did not fail", rule)
+		}
+	}
+}
+
+func TestCountNumaNodeAllocations(t *testing.T) {
+	nodes := []NumaNode{
+		{Cores: [][]uint{{0, 4}, {1, 5}}, MemoryInMiB: 1024},
+		{Cores: [][]uint{{2, 6}, {3, 7}}, Id: 1, MemoryInMiB: 1024},
+	}
+	vms := []*VmInfo{
+		{MemoryInMiB: 512, PinnedCPUs: []uint{2, 6}},
+		{MemoryInMiB: 256},
+	}
+	newNodes := CountNumaNodeAllocations(nodes, vms)
+	if nodes[1].AllocatedCores != 0 {
+		t.Fatal("input nodes modified")
+	}
+	if newNodes[0].AllocatedCores != 0 || newNodes[0].AllocatedMemory != 0 {
+		t.Errorf("node 0: unexpected allocation: %+v", newNodes[0])
+	}
+	if newNodes[1].AllocatedCores != 1 || newNodes[1].AllocatedMemory != 512 {
+		t.Errorf("node 1: unexpected allocation: %+v", newNodes[1])
+	}
+	if newNodes[1].FreeCores() != 1 {
+		t.Errorf("node 1: free cores: %d != 1", newNodes[1].FreeCores())
+	}
+	if !newNodes[1].CanDedicate(2, 512) {
+		t.Error("node 1: cannot dedicate 2 CPUs")
+	}
+	if newNodes[1].CanDedicate(3, 512) {
+		t.Error("node 1: can dedicate 3 CPUs")
+	}
+	if newNodes[1].CanDedicate(1, 513) {
+		t.Error("node 1: can dedicate 513 MiB")
+	}
+}
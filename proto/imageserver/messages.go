@@ -6,6 +6,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
 	"github.com/Cloud-Foundations/Dominator/lib/hash"
 	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/osv"
 	"github.com/Cloud-Foundations/Dominator/lib/tags"
 )
 
@@ -166,3 +167,14 @@ type MakeDirectoryRequest struct {
 }
 
 type MakeDirectoryResponse struct{}
+
+type ScanImageVulnerabilitiesRequest struct {
+	Ecosystem string // Empty: determine from /etc/os-release in the image.
+	ImageName string
+}
+
+type ScanImageVulnerabilitiesResponse struct {
+	Ecosystem       string
+	Error           string
+	Vulnerabilities []osv.Match
+}
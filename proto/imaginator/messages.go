@@ -28,6 +28,21 @@ type BuildImageResponse struct {
 	SourceImageGitCommitId    string
 }
 
+type BuildLogMatch struct {
+	Line       string
+	LineNumber uint
+}
+
+type BuildLogSearchResult struct {
+	BuildTime         time.Time
+	Duration          time.Duration
+	Error             string `json:",omitempty"`
+	ImageName         string
+	Matches           []BuildLogMatch
+	NumMatches        uint   // May exceed len(Matches).
+	RequestorUsername string `json:",omitempty"`
+}
+
 type DisableAutoBuildsRequest struct {
 	DisableFor time.Duration
 }
@@ -90,3 +105,16 @@ type ReplaceIdleSlavesRequest struct {
 type ReplaceIdleSlavesResponse struct {
 	Error string
 }
+
+type SearchBuildLogsRequest struct {
+	MaxAge           time.Duration // Zero: no limit.
+	MaxMatchesPerLog uint          // Zero: default (10).
+	MaxResults       uint          // Zero: default (100).
+	Pattern          string        // Regular expression.
+	StreamName       string        // Empty: all streams.
+}
+
+type SearchBuildLogsResponse struct {
+	Error   string
+	Results []BuildLogSearchResult // Newest first.
+}
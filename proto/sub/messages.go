@@ -21,8 +21,27 @@ const (
 
 	ErrorDisruptionPending = "disruption pending"
 	ErrorDisruptionDenied  = "disruption denied"
+
+	UpdateModeInPlace = UpdateMode(0) // Mutate the live root.
+	UpdateModeABRoot  = UpdateMode(1) // Unpack to inactive root and reboot.
+
+	ABRootStateActivating  = "activating"
+	ABRootStateActive      = "active"
+	ABRootStateConfirming  = "confirming"
+	ABRootStateFellBack    = "fell back"
+	ABRootStateStageFailed = "staging failed"
+	ABRootStateStaged      = "staged"
+	ABRootStateStaging     = "staging"
 )
 
+type ABRootStatus struct {
+	ActiveLabel   string
+	InactiveLabel string
+	LastError     string `json:",omitempty"`
+	StagedImage   string `json:",omitempty"`
+	State         string
+}
+
 type BoostCpuLimitRequest struct{}
 
 type BoostCpuLimitResponse struct{}
@@ -80,6 +99,7 @@ type PollResponse struct {
 	LastSuccessfulImageName      string
 	LastUpdateError              string
 	LastUpdateHadTriggerFailures bool
+	LastUpdateTriggerFailures    []string `json:",omitempty"`
 	LastWriteError               string
 	LockedByAnotherClient        bool // Fetch() and Update() restricted.
 	LockedUntil                  time.Time
@@ -91,6 +111,7 @@ type PollResponse struct {
 	GenerationCount              uint64
 	SystemUptime                 *time.Duration
 	DisruptionState              DisruptionState
+	ABRoot                       *ABRootStatus `json:",omitempty"`
 	FileSystemFollows            bool
 	FileSystem                   *filesystem.FileSystem  // Streamed separately.
 	ObjectCache                  objectcache.ObjectCache // Streamed separately.
@@ -103,7 +124,10 @@ type SetConfigurationResponse struct{}
 type UpdateRequest struct {
 	ForceDisruption bool
 	ImageName       string
+	UpdateMode      UpdateMode
 	Wait            bool
+	// The complete file-system for the image, used for UpdateModeABRoot.
+	ImageFileSystem *filesystem.FileSystem `json:",omitempty"`
 	// The ordering here reflects the ordering that the sub is expected to use.
 	FilesToCopyToCache  []FileToCopyToCache
 	DirectoriesToMake   []Inode
@@ -116,3 +140,5 @@ type UpdateRequest struct {
 }
 
 type UpdateResponse struct{}
+
+type UpdateMode uint
@@ -7,6 +7,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/html"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/net/reverseconnection"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/tricorder/go/tricorder"
 )
 
@@ -29,7 +30,7 @@ func StartServer(portNum uint, logger log.DebugLogger) error {
 		return err
 	}
 	html.HandleFunc("/", statusHandler)
-	go http.Serve(listener, nil)
+	go http.Serve(srpc.NewTlsSniffingListener(listener), nil)
 	return nil
 }
 
@@ -0,0 +1,523 @@
+package rpcd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/constants"
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem/util"
+	"github.com/Cloud-Foundations/Dominator/lib/filter"
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+	jsonlib "github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/objectcache"
+	"github.com/Cloud-Foundations/Dominator/lib/objectserver"
+	"github.com/Cloud-Foundations/Dominator/lib/osutil"
+	"github.com/Cloud-Foundations/Dominator/lib/wsyscall"
+	"github.com/Cloud-Foundations/Dominator/proto/sub"
+)
+
+const abRootRecordFile = "ab-root.json"
+
+var (
+	abRootActivateCommand = flag.String("abRootActivateCommand",
+		"grub-reboot",
+		"Command to boot the specified root label once on the next reboot")
+	abRootCommitCommand = flag.String("abRootCommitCommand",
+		"grub-set-default",
+		"Command to make the specified root label the default boot entry")
+	abRootConfirmTimeout = flag.Duration("abRootConfirmTimeout",
+		15*time.Minute,
+		"Time for a newly activated root to report in before falling back")
+	abRootLabels = flag.String("abRootLabels", "",
+		"Comma separated pair of root partition labels for A/B updates")
+)
+
+// abRootRecord is written to both roots when a staged root is activated, so
+// that the outcome of the activation can be determined after the reboot.
+type abRootRecord struct {
+	ActivatedLabel string
+	Image          string
+	PreviousLabel  string
+}
+
+type abRootObjectsGetter struct {
+	hashToFilename map[hash.Hash]string
+	objectsDir     string
+}
+
+type abRootObjectsReader struct {
+	getter *abRootObjectsGetter
+	hashes []hash.Hash
+}
+
+func getABRootDevice(label string) (string, error) {
+	return filepath.EvalSymlinks(filepath.Join("/dev/disk/by-label", label))
+}
+
+// getActiveABRootLabel returns the label of the partition mounted as the
+// root file-system.
+func getActiveABRootLabel(labels []string) (string, error) {
+	var rootStat syscall.Stat_t
+	if err := syscall.Stat("/", &rootStat); err != nil {
+		return "", err
+	}
+	for _, label := range labels {
+		device, err := getABRootDevice(label)
+		if err != nil {
+			continue
+		}
+		var deviceStat syscall.Stat_t
+		if err := syscall.Stat(device, &deviceStat); err != nil {
+			continue
+		}
+		if deviceStat.Rdev == rootStat.Dev {
+			return label, nil
+		}
+	}
+	return "", errors.New("root file-system is not on an A/B root partition")
+}
+
+func readABRootRecord(filename string) (*abRootRecord, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	var record abRootRecord
+	if err := json.NewDecoder(file).Decode(&record); err != nil {
+		return nil, fmt.Errorf("error decoding: %s: %s", filename, err)
+	}
+	return &record, nil
+}
+
+func writeABRootRecord(filename string, record abRootRecord) error {
+	buffer := &bytes.Buffer{}
+	if err := jsonlib.WriteWithIndent(buffer, "    ", record); err != nil {
+		return err
+	}
+	return fsutil.CopyToFile(filename, fsutil.PublicFilePerms, buffer, 0)
+}
+
+func (getter *abRootObjectsGetter) GetObjects(hashes []hash.Hash) (
+	objectserver.ObjectsReader, error) {
+	return &abRootObjectsReader{getter: getter, hashes: hashes}, nil
+}
+
+func (or *abRootObjectsReader) Close() error {
+	return nil
+}
+
+// NextObject returns the next object from the object cache if present, else
+// from the file in the live root with the same contents.
+func (or *abRootObjectsReader) NextObject() (uint64, io.ReadCloser, error) {
+	if len(or.hashes) < 1 {
+		return 0, nil, errors.New("all objects have been consumed")
+	}
+	hashVal := or.hashes[0]
+	or.hashes = or.hashes[1:]
+	file, err := os.Open(filepath.Join(or.getter.objectsDir,
+		objectcache.HashToFilename(hashVal)))
+	if err != nil {
+		filename, ok := or.getter.hashToFilename[hashVal]
+		if !ok {
+			return 0, nil, fmt.Errorf("object: %x not available", hashVal)
+		}
+		if file, err = os.Open(filename); err != nil {
+			return 0, nil, err
+		}
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return 0, nil, err
+	}
+	return uint64(fi.Size()), file, nil
+}
+
+func (t *rpcType) setupABRoot() {
+	if *abRootLabels == "" {
+		return
+	}
+	labels := strings.Split(*abRootLabels, ",")
+	if len(labels) != 2 {
+		t.params.Logger.Printf("Error: -abRootLabels needs two labels\n")
+		return
+	}
+	activeLabel, err := getActiveABRootLabel(labels)
+	if err != nil {
+		t.params.Logger.Printf("Error: A/B root disabled: %s\n", err)
+		return
+	}
+	status := &sub.ABRootStatus{
+		ActiveLabel:   activeLabel,
+		InactiveLabel: labels[0],
+		State:         sub.ABRootStateActive,
+	}
+	if activeLabel == labels[0] {
+		status.InactiveLabel = labels[1]
+	}
+	t.abRoot = status
+	recordFile := filepath.Join(t.params.SubdDirectory, abRootRecordFile)
+	record, err := readABRootRecord(recordFile)
+	if err != nil {
+		t.params.Logger.Println(err)
+		os.Remove(recordFile)
+		return
+	}
+	if record == nil {
+		return
+	}
+	if record.ActivatedLabel == activeLabel {
+		status.State = sub.ABRootStateConfirming
+		go t.abRootConfirmWatchdog(*abRootConfirmTimeout)
+		return
+	}
+	status.State = sub.ABRootStateFellBack
+	status.LastError = fmt.Sprintf("image: %s on: %s did not report in",
+		record.Image, record.ActivatedLabel)
+	t.params.Logger.Printf("A/B root: fell back to: %s: %s\n",
+		activeLabel, status.LastError)
+	os.Remove(recordFile)
+}
+
+// abRootConfirmWatchdog reboots if the active root has not been confirmed
+// after the timeout. Since the root was activated for one boot only, the
+// previous root will be booted.
+func (t *rpcType) abRootConfirmWatchdog(timeout time.Duration) {
+	time.Sleep(timeout)
+	t.rwLock.RLock()
+	confirming := t.abRoot.State == sub.ABRootStateConfirming
+	t.rwLock.RUnlock()
+	if !confirming {
+		return
+	}
+	t.params.Logger.Printf(
+		"A/B root: not confirmed after %s, rebooting to previous root\n",
+		timeout)
+	rebootAndWait("", t.params.Logger)
+}
+
+// confirmABRoot makes a newly activated root the default boot entry once the
+// sub has been polled by the Dominator.
+func (t *rpcType) confirmABRoot() {
+	t.rwLock.Lock()
+	if t.abRoot == nil || t.abRoot.State != sub.ABRootStateConfirming {
+		t.rwLock.Unlock()
+		return
+	}
+	activeLabel := t.abRoot.ActiveLabel
+	t.abRoot.State = sub.ABRootStateActive
+	t.rwLock.Unlock()
+	t.params.Logger.Printf("A/B root: confirming: %s\n", activeLabel)
+	go func() {
+		if !osutil.RunCommand(t.params.Logger, *abRootCommitCommand,
+			activeLabel) {
+			t.rwLock.Lock()
+			t.abRoot.LastError = "error running: " + *abRootCommitCommand
+			t.rwLock.Unlock()
+			return
+		}
+		os.Remove(filepath.Join(t.params.SubdDirectory, abRootRecordFile))
+	}()
+}
+
+func (t *rpcType) setABRootState(state string, err error) {
+	t.rwLock.Lock()
+	defer t.rwLock.Unlock()
+	t.abRoot.State = state
+	if err != nil {
+		t.abRoot.LastError = err.Error()
+	} else {
+		t.abRoot.LastError = ""
+	}
+}
+
+// copyABRootEntry copies a single file, directory, symlink or device from the
+// live root to the staged root, preserving its ownership and mode.
+func copyABRootEntry(destName, sourceName string,
+	stat *wsyscall.Stat_t) error {
+	mode := os.FileMode(stat.Mode) & os.ModePerm
+	switch stat.Mode & wsyscall.S_IFMT {
+	case wsyscall.S_IFDIR:
+		if err := os.Mkdir(destName, mode); err != nil && !os.IsExist(err) {
+			return err
+		}
+	case wsyscall.S_IFREG:
+		if err := fsutil.CopyFile(destName, sourceName, mode); err != nil {
+			return err
+		}
+	case wsyscall.S_IFLNK:
+		target, err := os.Readlink(sourceName)
+		if err != nil {
+			return err
+		}
+		os.Remove(destName)
+		if err := os.Symlink(target, destName); err != nil {
+			return err
+		}
+		return os.Lchown(destName, int(stat.Uid), int(stat.Gid))
+	case wsyscall.S_IFBLK, wsyscall.S_IFCHR, wsyscall.S_IFIFO:
+		os.Remove(destName)
+		err := syscall.Mknod(destName, uint32(stat.Mode), int(stat.Rdev))
+		if err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+	if err := os.Lchown(destName, int(stat.Uid), int(stat.Gid)); err != nil {
+		return err
+	}
+	return os.Chmod(destName, os.FileMode(stat.Mode)&os.ModePerm|
+		os.FileMode(stat.Mode&(syscall.S_ISUID|syscall.S_ISGID|
+			syscall.S_ISVTX)))
+}
+
+// copyFilteredPaths copies the paths in the live root which are excluded by
+// the scan filter (host-local files such as SSH host keys and /var state)
+// into the staged root, since they are not part of the image. Paths on other
+// file-systems and the skipped paths are not copied.
+func copyFilteredPaths(destDir, rootDir string, scanFilter *filter.Filter,
+	skipPaths map[string]struct{}, logger log.DebugLogger) error {
+	if scanFilter == nil {
+		return nil
+	}
+	var rootStat wsyscall.Stat_t
+	if err := wsyscall.Lstat(rootDir, &rootStat); err != nil {
+		return err
+	}
+	var copyingDir string // Everything below an excluded directory is copied.
+	numCopied := 0
+	err := filepath.Walk(rootDir,
+		func(sourceName string, fi os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if _, ok := skipPaths[sourceName]; ok {
+				if fi.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			var stat wsyscall.Stat_t
+			if err := wsyscall.Lstat(sourceName, &stat); err != nil {
+				return err
+			}
+			if stat.Dev != rootStat.Dev {
+				if fi.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			pathName, err := filepath.Rel(rootDir, sourceName)
+			if err != nil {
+				return err
+			}
+			pathName = filepath.Join("/", pathName)
+			if copyingDir == "" ||
+				!strings.HasPrefix(pathName, copyingDir+"/") {
+				copyingDir = ""
+				if pathName == "/" || !scanFilter.Match(pathName) {
+					return nil
+				}
+				if fi.IsDir() {
+					copyingDir = pathName
+				}
+			}
+			destName := filepath.Join(destDir, pathName)
+			parentDir := filepath.Dir(destName)
+			if err := os.MkdirAll(parentDir, fsutil.DirPerms); err != nil {
+				return err
+			}
+			if err := copyABRootEntry(destName, sourceName, &stat); err != nil {
+				return fmt.Errorf("error copying: %s: %s", pathName, err)
+			}
+			numCopied++
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+	logger.Debugf(0, "copied %d filtered paths to staged root\n", numCopied)
+	return nil
+}
+
+// stageABRoot writes the image to the inactive root partition.
+func (t *rpcType) stageABRoot(request sub.UpdateRequest,
+	status sub.ABRootStatus) error {
+	fs := request.ImageFileSystem
+	if err := fs.RebuildInodePointers(); err != nil {
+		return err
+	}
+	fs.ComputeTotalDataBytes()
+	device, err := getABRootDevice(status.InactiveLabel)
+	if err != nil {
+		return err
+	}
+	liveFs := t.params.FileSystemHistory.FileSystem()
+	if liveFs == nil {
+		return errors.New("no file-system history yet")
+	}
+	objectsGetter := &abRootObjectsGetter{
+		hashToFilename: make(map[hash.Hash]string),
+		objectsDir:     t.config.ObjectsDirectoryName,
+	}
+	inodeToFilenames := liveFs.InodeToFilenamesTable()
+	for inum, inode := range liveFs.InodeTable {
+		inode, ok := inode.(*filesystem.RegularInode)
+		if !ok || inode.Size < 1 {
+			continue
+		}
+		if filenames := inodeToFilenames[inum]; len(filenames) > 0 {
+			objectsGetter.hashToFilename[inode.Hash] = filepath.Join(
+				liveFs.RootDirectoryName(), filenames[0])
+		}
+	}
+	unsupportedOptions, err := util.GetUnsupportedExt4fsOptions(fs,
+		objectsGetter)
+	if err != nil {
+		t.params.Logger.Printf("Error getting unsupported options: %s\n", err)
+	}
+	logger := t.params.Logger
+	if err := util.MakeExt4fs(device, status.InactiveLabel,
+		unsupportedOptions, 0, logger); err != nil {
+		return err
+	}
+	mountPoint := filepath.Join(t.params.SubdDirectory, "ab-root")
+	if err := os.MkdirAll(mountPoint, fsutil.DirPerms); err != nil {
+		return err
+	}
+	if err := wsyscall.Mount(device, mountPoint, "ext4", 0, ""); err != nil {
+		return fmt.Errorf("error mounting: %s: %s", device, err)
+	}
+	defer syscall.Unmount(mountPoint, 0)
+	if err := util.Unpack(fs, objectsGetter, mountPoint, logger); err != nil {
+		return err
+	}
+	err = copyFilteredPaths(mountPoint, t.config.RootDirectoryName,
+		t.params.ScannerConfiguration.ScanFilter,
+		map[string]struct{}{
+			t.config.ObjectsDirectoryName: {},
+			t.params.SubdDirectory:        {},
+		},
+		logger)
+	if err != nil {
+		return err
+	}
+	if err := util.WriteImageName(mountPoint, request.ImageName); err != nil {
+		return err
+	}
+	buffer := &bytes.Buffer{}
+	fmt.Fprintln(buffer, request.ImageName)
+	err = fsutil.CopyToFile(
+		filepath.Join(mountPoint, constants.PatchedImageNameFile),
+		fsutil.PublicFilePerms, buffer, 0)
+	if err != nil {
+		return err
+	}
+	subdDir := filepath.Join(mountPoint, t.params.SubdDirectory)
+	if err := os.MkdirAll(subdDir, fsutil.DirPerms); err != nil {
+		return err
+	}
+	return writeABRootRecord(filepath.Join(subdDir, abRootRecordFile),
+		abRootRecord{
+			ActivatedLabel: status.InactiveLabel,
+			Image:          request.ImageName,
+			PreviousLabel:  status.ActiveLabel,
+		})
+}
+
+// updateABRoot stages the image on the inactive root if needed and then
+// activates it, once disruption is permitted.
+func (t *rpcType) updateABRoot(request sub.UpdateRequest) error {
+	t.rwLock.RLock()
+	if t.abRoot == nil {
+		t.rwLock.RUnlock()
+		return errors.New("A/B root updates not configured")
+	}
+	status := *t.abRoot
+	t.rwLock.RUnlock()
+	if status.State == sub.ABRootStateConfirming {
+		return errors.New("A/B root not yet confirmed")
+	}
+	if status.StagedImage != request.ImageName ||
+		status.State != sub.ABRootStateStaged {
+		if request.ImageFileSystem == nil {
+			return errors.New("no image file-system for A/B root update")
+		}
+		t.setABRootState(sub.ABRootStateStaging, nil)
+		var err error
+		t.params.WorkdirGoroutine.Run(func() {
+			err = t.stageABRoot(request, status)
+		})
+		if err != nil {
+			t.setABRootState(sub.ABRootStateStageFailed, err)
+			return err
+		}
+		t.rwLock.Lock()
+		t.abRoot.StagedImage = request.ImageName
+		t.abRoot.State = sub.ABRootStateStaged
+		t.abRoot.LastError = ""
+		t.rwLock.Unlock()
+		t.params.Logger.Printf("A/B root: staged: %s on: %s\n",
+			request.ImageName, status.InactiveLabel)
+	}
+	if !request.ForceDisruption {
+		switch t.disruptionRequest() {
+		case sub.DisruptionStateRequested:
+			return errors.New(sub.ErrorDisruptionPending)
+		case sub.DisruptionStateDenied:
+			return errors.New(sub.ErrorDisruptionDenied)
+		}
+	}
+	err := writeABRootRecord(
+		filepath.Join(t.params.SubdDirectory, abRootRecordFile),
+		abRootRecord{
+			ActivatedLabel: status.InactiveLabel,
+			Image:          request.ImageName,
+			PreviousLabel:  status.ActiveLabel,
+		})
+	if err != nil {
+		return err
+	}
+	if !osutil.RunCommand(t.params.Logger, *abRootActivateCommand,
+		status.InactiveLabel) {
+		os.Remove(filepath.Join(t.params.SubdDirectory, abRootRecordFile))
+		return errors.New("error running: " + *abRootActivateCommand)
+	}
+	t.setABRootState(sub.ABRootStateActivating, nil)
+	t.params.Logger.Printf("A/B root: activating: %s, rebooting\n",
+		status.InactiveLabel)
+	rebootAndWait("", t.params.Logger)
+	return errors.New("reboot failed")
+}
+
+func (t *rpcType) updateABRootAndUnlock(request sub.UpdateRequest) error {
+	defer t.clearUpdateInProgress()
+	startTime := time.Now()
+	t.lastUpdateError = t.updateABRoot(request)
+	if t.lastUpdateError != nil {
+		t.params.Logger.Printf("Update(): last error: %s\n", t.lastUpdateError)
+	}
+	t.params.Logger.Printf("Update() completed in %s (A/B root)\n",
+		time.Since(startTime))
+	return t.lastUpdateError
+}
@@ -0,0 +1,85 @@
+package rpcd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filter"
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+)
+
+func TestCopyFilteredPaths(t *testing.T) {
+	rootDir := t.TempDir()
+	destDir := t.TempDir()
+	for _, dirname := range []string{
+		"etc/ssh",
+		"var/lib/app",
+		".subd/objects",
+	} {
+		err := os.MkdirAll(filepath.Join(rootDir, dirname), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, filename := range []string{
+		"etc/machine-id",
+		"etc/passwd",
+		"etc/ssh/ssh_host_rsa_key",
+		"etc/ssh/sshd_config",
+		"var/lib/app/state",
+		".subd/objects/object",
+	} {
+		err := os.WriteFile(filepath.Join(rootDir, filename),
+			[]byte(filename), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := os.Symlink("state", filepath.Join(rootDir, "var/lib/app/link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanFilter, err := filter.New([]string{
+		"/.subd",
+		"/etc/machine-id",
+		"/etc/ssh/ssh_host_.*",
+		"/var/lib",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = copyFilteredPaths(destDir, rootDir, scanFilter,
+		map[string]struct{}{filepath.Join(rootDir, ".subd"): {}},
+		testlogger.New(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, filename := range []string{
+		"etc/machine-id",
+		"etc/ssh/ssh_host_rsa_key",
+		"var/lib/app/state",
+	} {
+		data, err := os.ReadFile(filepath.Join(destDir, filename))
+		if err != nil {
+			t.Error(err)
+		} else if string(data) != filename {
+			t.Errorf("%s: got: %s", filename, string(data))
+		}
+	}
+	target, err := os.Readlink(filepath.Join(destDir, "var/lib/app/link"))
+	if err != nil {
+		t.Error(err)
+	} else if target != "state" {
+		t.Errorf("link target: %s", target)
+	}
+	for _, filename := range []string{
+		".subd",
+		"etc/passwd",
+		"etc/ssh/sshd_config",
+	} {
+		if _, err := os.Lstat(filepath.Join(destDir, filename)); err == nil {
+			t.Errorf("%s: copied", filename)
+		}
+	}
+}
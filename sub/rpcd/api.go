@@ -52,6 +52,7 @@ type rpcType struct {
 	disruptionManagerControl     chan<- bool // True: request; false: cancel.
 	ownerUsers                   map[string]struct{}
 	rwLock                       sync.RWMutex // Protect everything below.
+	abRoot                       *proto.ABRootStatus
 	disruptionState              proto.DisruptionState
 	getFilesLock                 sync.Mutex
 	fetchInProgress              bool // Fetch() & Update() mutually exclusive.
@@ -64,6 +65,7 @@ type rpcType struct {
 	lastSuccessfulImageName      string
 	lastUpdateError              error
 	lastUpdateHadTriggerFailures bool
+	lastUpdateTriggerFailures    []string
 	lastWriteError               string
 	lockedBy                     *srpc.Conn
 	lockedUntil                  time.Time
@@ -94,6 +96,7 @@ func Setup(config Config, params Params) *HtmlWriter {
 			}),
 	}
 	rpcObj.startDisruptionManager()
+	rpcObj.setupABRoot()
 	rpcObj.ownerUsers = stringutil.ConvertListToMap(
 		config.SubConfiguration.OwnerUsers, false)
 	srpc.RegisterNameWithOptions("Subd", rpcObj,
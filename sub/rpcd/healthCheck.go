@@ -0,0 +1,114 @@
+package rpcd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/backoffdelay"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/osutil"
+	"github.com/Cloud-Foundations/Dominator/lib/systemd"
+	"github.com/Cloud-Foundations/Dominator/lib/triggers"
+)
+
+const (
+	defaultHealthCheckTimeout = 30 * time.Second
+	healthCheckInterval       = time.Second
+)
+
+// checkServiceHealth waits for the service for the trigger to become healthy.
+// If it does not, the service is restarted (with a backoff delay) up to
+// HealthCheck.MaxRetries times before giving up. Services are restarted using
+// the systemd manager if it is not nil, else the service command is used.
+func checkServiceHealth(trigger *triggers.Trigger, manager systemd.Manager,
+	logger log.Logger) error {
+	healthCheck := trigger.HealthCheck
+	sleeper := backoffdelay.NewExponential(time.Second, 30*time.Second, 0)
+	for retry := uint(0); ; retry++ {
+		err := waitForHealthy(healthCheck)
+		if err == nil {
+			logger.Printf("Service %s is healthy\n", trigger.Service)
+			return nil
+		}
+		logger.Printf("Health check for service %s failed: %s\n",
+			trigger.Service, err)
+		if retry >= healthCheck.MaxRetries {
+			return fmt.Errorf("service %s unhealthy: %s", trigger.Service, err)
+		}
+		sleeper.Sleep()
+		if manager != nil {
+			err := restartSystemdService(manager, trigger.Service, logger)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		logger.Printf("Action: service %s restart\n", trigger.Service)
+		if !osutil.RunCommand(logger, "service", trigger.Service, "restart") {
+			return fmt.Errorf("service %s restart failed", trigger.Service)
+		}
+	}
+}
+
+// waitForHealthy repeatedly runs the health check until it passes or the
+// timeout is reached, returning the last error.
+func waitForHealthy(healthCheck *triggers.HealthCheck) error {
+	timeout := defaultHealthCheckTimeout
+	if healthCheck.TimeoutSeconds > 0 {
+		timeout = time.Duration(healthCheck.TimeoutSeconds) * time.Second
+	}
+	stopTime := time.Now().Add(timeout)
+	for {
+		err := runHealthCheck(healthCheck, time.Until(stopTime))
+		if err == nil {
+			return nil
+		}
+		if time.Until(stopTime) < healthCheckInterval {
+			return err
+		}
+		time.Sleep(healthCheckInterval)
+	}
+}
+
+func runHealthCheck(healthCheck *triggers.HealthCheck,
+	timeout time.Duration) error {
+	if healthCheck.Command != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, "/bin/sh", "-c", healthCheck.Command)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("command: %s: %s: %s", healthCheck.Command,
+				err, strings.TrimSpace(string(output)))
+		}
+	}
+	if healthCheck.TcpPort > 0 {
+		address := fmt.Sprintf("localhost:%d", healthCheck.TcpPort)
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err != nil {
+			return err
+		}
+		conn.Close()
+	}
+	if healthCheck.HttpUrl != "" {
+		client := &http.Client{Timeout: timeout}
+		resp, err := client.Get(healthCheck.HttpUrl)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		wantStatus := healthCheck.HttpStatus
+		if wantStatus == 0 {
+			wantStatus = http.StatusOK
+		}
+		if resp.StatusCode != wantStatus {
+			return fmt.Errorf("%s: got status: %s, want: %d",
+				healthCheck.HttpUrl, resp.Status, wantStatus)
+		}
+	}
+	return nil
+}
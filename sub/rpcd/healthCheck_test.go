@@ -0,0 +1,79 @@
+package rpcd
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+	"github.com/Cloud-Foundations/Dominator/lib/triggers"
+)
+
+func TestHttpHealthCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/unhealthy" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+	defer server.Close()
+	err := runHealthCheck(&triggers.HealthCheck{HttpUrl: server.URL},
+		time.Second)
+	if err != nil {
+		t.Errorf("healthy server failed: %s", err)
+	}
+	err = runHealthCheck(
+		&triggers.HealthCheck{HttpUrl: server.URL + "/unhealthy"},
+		time.Second)
+	if err == nil {
+		t.Error("unhealthy server passed")
+	}
+	err = runHealthCheck(&triggers.HealthCheck{
+		HttpUrl:    server.URL + "/unhealthy",
+		HttpStatus: http.StatusServiceUnavailable,
+	}, time.Second)
+	if err != nil {
+		t.Errorf("server with expected status failed: %s", err)
+	}
+}
+
+func TestTcpHealthCheck(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := uint16(listener.Addr().(*net.TCPAddr).Port)
+	healthCheck := &triggers.HealthCheck{TcpPort: port}
+	if err := runHealthCheck(healthCheck, time.Second); err != nil {
+		t.Errorf("listening port failed: %s", err)
+	}
+	listener.Close()
+	if err := runHealthCheck(healthCheck, time.Second); err == nil {
+		t.Error("closed port passed")
+	}
+}
+
+func TestCheckServiceHealth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+	defer server.Close()
+	logger := testlogger.New(t)
+	trigger := &triggers.Trigger{
+		Service: "test",
+		HealthCheck: &triggers.HealthCheck{
+			HttpUrl:        server.URL,
+			TimeoutSeconds: 1,
+		},
+	}
+	if err := checkServiceHealth(trigger, nil, logger); err == nil {
+		t.Error("unhealthy service passed")
+	}
+	trigger.HealthCheck.HttpStatus = http.StatusInternalServerError
+	if err := checkServiceHealth(trigger, nil, logger); err != nil {
+		t.Errorf("healthy service failed: %s", err)
+	}
+}
@@ -43,6 +43,7 @@ func (t *rpcType) Poll(conn *srpc.Conn) error {
 			response.LastUpdateError = t.lastUpdateError.Error()
 		}
 		response.LastUpdateHadTriggerFailures = t.lastUpdateHadTriggerFailures
+		response.LastUpdateTriggerFailures = t.lastUpdateTriggerFailures
 	}
 	response.InitialImageName = t.initialImageName
 	response.LastSuccessfulImageName = t.lastSuccessfulImageName
@@ -53,7 +54,14 @@ func (t *rpcType) Poll(conn *srpc.Conn) error {
 	response.LockedUntil = t.lockedUntil
 	response.FreeSpace = t.getFreeSpace()
 	response.DisruptionState = t.disruptionState
+	if t.abRoot != nil {
+		abRoot := *t.abRoot
+		response.ABRoot = &abRoot
+	}
 	t.rwLock.RUnlock()
+	if !request.ShortPollOnly {
+		t.confirmABRoot()
+	}
 	response.StartTime = startTime
 	response.PollTime = time.Now()
 	response.ScanCount = t.params.FileSystemHistory.ScanCount()
@@ -0,0 +1,92 @@
+package rpcd
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/systemd"
+	"github.com/Cloud-Foundations/Dominator/lib/triggers"
+)
+
+const journalExcerptLines = 20
+
+// restartSystemdService restarts the service using the systemd manager.
+func restartSystemdService(manager systemd.Manager, service string,
+	logger log.Logger) error {
+	job := systemd.Job{
+		Action: systemd.ActionRestart,
+		Unit:   systemd.UnitName(service),
+	}
+	logger.Printf("Action: systemd %s %s\n", job.Action, job.Unit)
+	result := systemd.RunJobs(manager, []systemd.Job{job},
+		*systemdJobTimeout)[0]
+	if result.Error != nil {
+		return fmt.Errorf("systemd %s %s: %s", job.Action, job.Unit,
+			result.Error)
+	}
+	return nil
+}
+
+// runSystemdTriggers queues jobs for all the triggers with the systemd manager
+// in one batch, so that systemd will order them according to the unit
+// dependencies. Returns a description of each failure.
+func runSystemdTriggers(manager systemd.Manager,
+	triggerList []*triggers.Trigger, action string,
+	logger log.Logger) []string {
+	if len(triggerList) < 1 {
+		return nil
+	}
+	jobs := make([]systemd.Job, 0, len(triggerList))
+	for _, trigger := range triggerList {
+		job := systemd.Job{
+			Action: getTriggerAction(trigger, action),
+			Unit:   systemd.UnitName(trigger.Service),
+		}
+		logger.Printf("Action: systemd %s %s\n", job.Action, job.Unit)
+		jobs = append(jobs, job)
+	}
+	var failures []string
+	for index, result := range systemd.RunJobs(manager, jobs,
+		*systemdJobTimeout) {
+		trigger := triggerList[index]
+		if result.Error != nil {
+			// Ignore failure for the "reboot" service: try later.
+			if action == "start" && trigger.DoReboot &&
+				trigger.Service == "reboot" {
+				continue
+			}
+			failures = append(failures, fmt.Sprintf("systemd %s %s: %s",
+				result.Action, result.Unit, result.Error))
+			logger.Printf("systemd %s %s failed: %s\n",
+				result.Action, result.Unit, result.Error)
+			logJournalExcerpt(result.Unit, logger)
+			continue
+		}
+		if action == "start" && trigger.HealthCheck != nil {
+			err := checkServiceHealth(trigger, manager, logger)
+			if err != nil {
+				failures = append(failures, err.Error())
+				logJournalExcerpt(result.Unit, logger)
+			}
+		}
+	}
+	return failures
+}
+
+// logJournalExcerpt logs the most recent journal entries for a unit.
+func logJournalExcerpt(unit string, logger log.Logger) {
+	cmd := exec.Command("journalctl", "--no-pager", "-q", "-u", unit,
+		"-n", fmt.Sprintf("%d", journalExcerptLines))
+	output, err := cmd.Output()
+	if err != nil {
+		logger.Printf("Error reading journal for %s: %s\n", unit, err)
+		return
+	}
+	logger.Printf("Journal for %s:\n", unit)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)),
+		"\n") {
+		logger.Printf("  %s\n", line)
+	}
+}
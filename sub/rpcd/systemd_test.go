@@ -0,0 +1,96 @@
+package rpcd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+	"github.com/Cloud-Foundations/Dominator/lib/systemd"
+	"github.com/Cloud-Foundations/Dominator/lib/triggers"
+)
+
+type fakeManager struct {
+	enqueued []systemd.Job
+	results  map[string]string // Key: unit name.
+}
+
+func (m *fakeManager) EnqueueJob(job systemd.Job) (string, error) {
+	if _, ok := m.results[job.Unit]; !ok {
+		return "", errors.New("unit not found")
+	}
+	m.enqueued = append(m.enqueued, job)
+	return "/job/" + job.Unit, nil
+}
+
+func (m *fakeManager) WaitForJobs(jobPaths []string, timeout time.Duration) (
+	map[string]string, error) {
+	results := make(map[string]string)
+	for _, jobPath := range jobPaths {
+		results[jobPath] = m.results[jobPath[len("/job/"):]]
+	}
+	return results, nil
+}
+
+func TestRunSystemdTriggers(t *testing.T) {
+	manager := &fakeManager{results: map[string]string{
+		"good.service":   "done",
+		"reload.service": "done",
+	}}
+	failures := runSystemdTriggers(manager, []*triggers.Trigger{
+		{Service: "good"},
+		{Action: "reload", Service: "reload"},
+	}, "start", testlogger.New(t))
+	if len(failures) > 0 {
+		t.Fatalf("unexpected failures: %v", failures)
+	}
+	if len(manager.enqueued) != 2 {
+		t.Fatalf("got %d jobs, want 2", len(manager.enqueued))
+	}
+	if manager.enqueued[0].Action != systemd.ActionStart {
+		t.Errorf("got action: %s, want: %s",
+			manager.enqueued[0].Action, systemd.ActionStart)
+	}
+	if manager.enqueued[1].Action != systemd.ActionReload {
+		t.Errorf("got action: %s, want: %s",
+			manager.enqueued[1].Action, systemd.ActionReload)
+	}
+}
+
+func TestRunSystemdTriggersFailure(t *testing.T) {
+	manager := &fakeManager{results: map[string]string{
+		"bad.service": "failed",
+	}}
+	failures := runSystemdTriggers(manager, []*triggers.Trigger{
+		{Service: "bad"},
+		{Service: "missing"},
+	}, "start", testlogger.New(t))
+	if len(failures) != 2 {
+		t.Fatalf("got %d failures, want 2: %v", len(failures), failures)
+	}
+}
+
+func TestHealthCheckRestartUsesManager(t *testing.T) {
+	manager := &fakeManager{results: map[string]string{
+		"sick.service": "done",
+	}}
+	trigger := &triggers.Trigger{
+		Service: "sick",
+		HealthCheck: &triggers.HealthCheck{
+			Command:        "false",
+			MaxRetries:     1,
+			TimeoutSeconds: 1,
+		},
+	}
+	if err := checkServiceHealth(trigger, manager,
+		testlogger.New(t)); err == nil {
+		t.Fatal("unhealthy service passed health check")
+	}
+	if len(manager.enqueued) != 1 {
+		t.Fatalf("got %d restarts, want 1", len(manager.enqueued))
+	}
+	if job := manager.enqueued[0]; job.Action != systemd.ActionRestart ||
+		job.Unit != "sick.service" {
+		t.Errorf("unexpected job: %v", job)
+	}
+}
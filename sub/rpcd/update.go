@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -14,6 +15,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/osutil"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/systemd"
 	"github.com/Cloud-Foundations/Dominator/lib/triggers"
 	"github.com/Cloud-Foundations/Dominator/proto/sub"
 	"github.com/Cloud-Foundations/Dominator/sub/lib"
@@ -26,6 +28,10 @@ var (
 		"If true, refuse all Update requests. For debugging only")
 	disableTriggers = flag.Bool("disableTriggers", false,
 		"If true, do not run any triggers. For debugging only")
+	systemdJobTimeout = flag.Duration("systemdJobTimeout", 10*time.Minute,
+		"Maximum time to wait for systemd jobs for triggers to complete")
+	useSystemd = flag.Bool("useSystemd", true,
+		"If true and systemd is running, run triggers using systemd directly")
 )
 
 type flusher interface {
@@ -34,6 +40,12 @@ type flusher interface {
 
 func (t *rpcType) Update(conn *srpc.Conn, request sub.UpdateRequest,
 	reply *sub.UpdateResponse) error {
+	if request.Triggers != nil {
+		if err := request.Triggers.CheckValid(); err != nil {
+			t.params.Logger.Println(err)
+			return err
+		}
+	}
 	if err := t.getUpdateLock(conn); err != nil {
 		t.params.Logger.Println(err)
 		return err
@@ -74,6 +86,9 @@ func (t *rpcType) getUpdateLock(conn *srpc.Conn) error {
 
 func (t *rpcType) updateAndUnlock(request sub.UpdateRequest,
 	rootDirectoryName string) error {
+	if request.UpdateMode == sub.UpdateModeABRoot {
+		return t.updateABRootAndUnlock(request)
+	}
 	defer t.clearUpdateInProgress()
 	defer t.params.ScannerConfiguration.BoostCpuLimit(t.params.Logger)
 	t.params.DisableScannerFunction(true)
@@ -87,6 +102,11 @@ func (t *rpcType) updateAndUnlock(request sub.UpdateRequest,
 		err = decoder.Decode(&trig.Triggers)
 		file.Close()
 		if err == nil {
+			// Actions and health checks come from the new triggers.
+			for _, trigger := range trig.Triggers {
+				trigger.Action = ""
+				trigger.HealthCheck = nil
+			}
 			oldTriggers.Merge(&trig)
 		} else {
 			t.params.Logger.Printf(
@@ -96,7 +116,9 @@ func (t *rpcType) updateAndUnlock(request sub.UpdateRequest,
 	if request.Triggers != nil {
 		// Merge new triggers into old triggers. This supports initial
 		// Domination of a machine and when the old triggers are incomplete.
-		oldTriggers.Merge(request.Triggers)
+		if err := oldTriggers.Merge(request.Triggers); err != nil {
+			t.params.Logger.Println(err)
+		}
 		file, err = os.Create(t.config.OldTriggersFilename)
 		if err == nil {
 			writer := bufio.NewWriter(file)
@@ -123,6 +145,9 @@ func (t *rpcType) updateAndUnlock(request sub.UpdateRequest,
 		options.DisruptionCancel = t.disruptionCancel
 		options.DisruptionRequest = t.disruptionRequest
 	}
+	t.rwLock.Lock()
+	t.lastUpdateTriggerFailures = nil
+	t.rwLock.Unlock()
 	t.params.WorkdirGoroutine.Run(func() {
 		hadTriggerFailures, fsChangeDuration, lastUpdateError =
 			lib.UpdateWithOptions(request, options)
@@ -158,11 +183,18 @@ func (t *rpcType) clearUpdateInProgress() {
 // Returns true if there were failures.
 func (t *rpcType) runTriggers(triggers []*triggers.Trigger, action string,
 	logger log.Logger) bool {
-	var retval bool
+	var failures []string
 	t.systemGoroutine.Run(func() {
-		retval = runTriggers(triggers, action, logger)
+		failures = runTriggers(triggers, action, logger)
 	})
-	return retval
+	if len(failures) < 1 {
+		return false
+	}
+	t.rwLock.Lock()
+	t.lastUpdateTriggerFailures = append(t.lastUpdateTriggerFailures,
+		failures...)
+	t.rwLock.Unlock()
+	return true
 }
 
 func forceRebootAndWait(logger log.Logger) {
@@ -202,10 +234,34 @@ func normalRebootAndWait(logger log.Logger) {
 	}
 }
 
-// Returns true if there were failures.
+// rebootAndWait reboots the machine, trying harder if it fails. It only
+// returns if all attempts failed.
+func rebootAndWait(logPrefix string, logger log.Logger) {
+	if logger, ok := logger.(flusher); ok {
+		logger.Flush()
+	}
+	// Catch and log some signals to try and handle cases where the init
+	// system signals subd but doesn't reboot, so we want to reach the hard
+	// reboot fallback.
+	signal.Reset(syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	signals := make(chan os.Signal, 1)
+	go handleSignals(signals, logger)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	time.Sleep(time.Second)
+	normalRebootAndWait(logger)
+	time.Sleep(time.Second)
+	forceRebootAndWait(logger)
+	time.Sleep(time.Second)
+	if err := osutil.HardReboot(logger); err != nil {
+		logger.Printf("%sHard reboot failed: %s\n", logPrefix, err)
+	}
+	time.Sleep(time.Second)
+}
+
+// Returns a description of each failure.
 func runTriggers(triggerList []*triggers.Trigger, action string,
-	logger log.Logger) bool {
-	hadFailures := false
+	logger log.Logger) []string {
+	var failures []string
 	needRestart := false
 	logPrefix := ""
 	var rebootingTriggers []*triggers.Trigger
@@ -223,9 +279,10 @@ func runTriggers(triggerList []*triggers.Trigger, action string,
 		} else {
 			logger.Printf("%sWill reboot on start, skipping %s actions\n",
 				logPrefix, action)
-			return hadFailures
+			return nil
 		}
 	}
+	serviceTriggers := make([]*triggers.Trigger, 0, len(triggerList))
 	for _, trigger := range triggerList {
 		if trigger.Service == "subd" {
 			// Never kill myself, just restart. Must do it last, so that other
@@ -235,57 +292,86 @@ func runTriggers(triggerList []*triggers.Trigger, action string,
 			}
 			continue
 		}
-		logger.Printf("%sAction: service %s %s\n",
-			logPrefix, trigger.Service, action)
-		if *disableTriggers {
-			continue
+		if action == "stop" && trigger.Action != "" {
+			continue // Service will be reloaded or restarted instead.
 		}
-		if !osutil.RunCommand(logger, "service", trigger.Service, action) {
-			// Ignore failure for the "reboot" service: try later.
-			if action != "start" ||
-				!trigger.DoReboot ||
-				trigger.Service != "reboot" {
-				hadFailures = true
-			}
+		serviceTriggers = append(serviceTriggers, trigger)
+	}
+	var systemdConn *systemd.Conn
+	if len(rebootingTriggers) < 1 && !*disableTriggers && *useSystemd &&
+		systemd.IsRunning() && len(serviceTriggers) > 0 {
+		var err error
+		if systemdConn, err = systemd.Dial(); err != nil {
+			logger.Printf("Error connecting to systemd: %s, using service\n",
+				err)
 		}
 	}
+	if systemdConn != nil {
+		failures = runSystemdTriggers(systemdConn, serviceTriggers, action,
+			logger)
+		systemdConn.Close()
+	} else {
+		failures = runServiceTriggers(serviceTriggers, action, logPrefix,
+			logger)
+	}
 	if len(rebootingTriggers) > 0 {
-		if hadFailures {
+		if len(failures) > 0 {
 			logger.Printf("%sSome triggers failed, will not reboot\n",
 				logPrefix)
-			return hadFailures
+			return failures
 		}
 		logger.Printf("%sRebooting\n", logPrefix)
 		if *disableTriggers {
-			return hadFailures
-		}
-		// If we get here, we are going to reboot and try harder if it fails.
-		if logger, ok := logger.(flusher); ok {
-			logger.Flush()
-		}
-		// Catch and log some signals to try and handle cases where the init
-		// system signals subd but doesn't reboot, so we want to reach the hard
-		// reboot fallback.
-		signal.Reset(syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
-		signals := make(chan os.Signal, 1)
-		go handleSignals(signals, logger)
-		signal.Notify(signals, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
-		time.Sleep(time.Second)
-		normalRebootAndWait(logger)
-		time.Sleep(time.Second)
-		forceRebootAndWait(logger)
-		time.Sleep(time.Second)
-		if err := osutil.HardReboot(logger); err != nil {
-			logger.Printf("%sHard reboot failed: %s\n", logPrefix, err)
+			return nil
 		}
-		time.Sleep(time.Second)
-		return true
+		rebootAndWait(logPrefix, logger)
+		return []string{"reboot failed"}
 	}
 	if needRestart {
 		logger.Printf("%sAction: service subd restart\n", logPrefix)
 		if !osutil.RunCommand(logger, "service", "subd", "restart") {
-			hadFailures = true
+			failures = append(failures, "service subd restart failed")
 		}
 	}
-	return hadFailures
+	return failures
+}
+
+// Returns a description of each failure.
+func runServiceTriggers(triggerList []*triggers.Trigger, action string,
+	logPrefix string, logger log.Logger) []string {
+	var failures []string
+	for _, trigger := range triggerList {
+		triggerAction := getTriggerAction(trigger, action)
+		logger.Printf("%sAction: service %s %s\n",
+			logPrefix, trigger.Service, triggerAction)
+		if *disableTriggers {
+			continue
+		}
+		if !osutil.RunCommand(logger, "service", trigger.Service,
+			triggerAction) {
+			// Ignore failure for the "reboot" service: try later.
+			if action != "start" ||
+				!trigger.DoReboot ||
+				trigger.Service != "reboot" {
+				failures = append(failures, fmt.Sprintf("service %s %s failed",
+					trigger.Service, triggerAction))
+			}
+			continue
+		}
+		if action == "start" && trigger.HealthCheck != nil {
+			if err := checkServiceHealth(trigger, nil, logger); err != nil {
+				failures = append(failures, err.Error())
+			}
+		}
+	}
+	return failures
+}
+
+// getTriggerAction returns the action to perform for the trigger, which
+// replaces the start action if specified.
+func getTriggerAction(trigger *triggers.Trigger, action string) string {
+	if action == "start" && trigger.Action != "" {
+		return trigger.Action
+	}
+	return action
 }
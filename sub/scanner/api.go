@@ -18,11 +18,14 @@ import (
 )
 
 type Configuration struct {
+	ChangeTracking       bool // Rehash only changed files between full scans.
 	CpuLimiter           *cpulimiter.CpuLimiter
 	DefaultCpuPercent    uint
 	FsScanContext        *fsrateio.ReaderContext
+	FullScanInterval     time.Duration // Used if ChangeTracking.
 	NetworkReaderContext *rateio.ReaderContext
 	ScanFilter           *filter.Filter
+	changeTrackingMode   string
 }
 
 func (configuration *Configuration) BoostCpuLimit(logger log.Logger) {
@@ -42,6 +45,12 @@ func (configuration *Configuration) WriteHtml(writer io.Writer) {
 	configuration.writeHtml(writer)
 }
 
+// ChangeTrackingMode returns the mechanism used to track changes (i.e.
+// "fanotify" or "inotify"), or an empty string if every scan is a full scan.
+func (configuration *Configuration) ChangeTrackingMode() string {
+	return configuration.changeTrackingMode
+}
+
 type FileSystemHistory struct {
 	rwMutex            sync.RWMutex
 	fileSystem         *FileSystem
@@ -107,7 +116,7 @@ type FileSystem struct {
 func ScanFileSystem(rootDirectoryName string, cacheDirectoryName string,
 	configuration *Configuration) (*FileSystem, error) {
 	return scanFileSystem(rootDirectoryName, cacheDirectoryName, configuration,
-		&FileSystem{})
+		&FileSystem{}, nil)
 }
 
 func (fs *FileSystem) ScanObjectCache() error {
@@ -43,4 +43,8 @@ func (configuration *Configuration) writeHtml(writer io.Writer) {
 			ctx.SpeedPercent(), format.FormatBytes(ctx.MaximumSpeed()))
 	}
 	fmt.Fprintf(writer, "Network Speed: %s<br>\n", speed)
+	if mode := configuration.changeTrackingMode; mode != "" {
+		fmt.Fprintf(writer, "Change tracking: %s (full scan every %s)<br>\n",
+			mode, format.Duration(configuration.fullScanInterval()))
+	}
 }
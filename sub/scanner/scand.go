@@ -8,6 +8,13 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 )
 
+const (
+	defaultFullScanInterval = 24 * time.Hour
+	// Writes to memory mapped files are not reported, so occasionally check
+	// the metadata of all files.
+	metadataScanInterval = time.Hour
+)
+
 var disableScanRequest chan bool
 var disableScanAcknowledge chan bool
 
@@ -39,11 +46,31 @@ func scannerDaemon(rootDirectoryName string, cacheDirectoryName string,
 	runtime.LockOSThread()
 	loweredPriority := false
 	var oldFS FileSystem
+	var tracker *changeTracker
+	if configuration.ChangeTracking {
+		var err error
+		tracker, err = startChangeTracker(rootDirectoryName, configuration,
+			logger)
+		if err != nil {
+			logger.Printf("Unable to track changes, using full scans: %s\n",
+				err)
+		} else {
+			configuration.changeTrackingMode = tracker.mechanism
+			logger.Printf("Tracking changes using: %s\n", tracker.mechanism)
+		}
+	}
+	var lastFullScanTime time.Time
 	var sleepUntil time.Time
 	for ; ; time.Sleep(time.Until(sleepUntil)) {
 		sleepUntil = time.Now().Add(time.Second)
+		var dirtyInodes map[uint64]struct{}
+		if tracker != nil && oldFS.InodeTable != nil {
+			dirtyInodes = waitForChanges(tracker,
+				lastFullScanTime.Add(configuration.fullScanInterval()))
+		}
+		startTime := time.Now()
 		fs, err := scanFileSystem(rootDirectoryName, cacheDirectoryName,
-			configuration, &oldFS)
+			configuration, &oldFS, dirtyInodes)
 		if err != nil {
 			if err.Error() == "DisableScan" {
 				disableScanAcknowledge <- true
@@ -52,6 +79,15 @@ func scannerDaemon(rootDirectoryName string, cacheDirectoryName string,
 			}
 			logger.Printf("Error scanning: %s\n", err)
 		} else {
+			if dirtyInodes == nil {
+				lastFullScanTime = startTime
+			} else {
+				// Limit incremental scans to half of the time.
+				if t := time.Now().Add(time.Since(startTime)); t.After(
+					sleepUntil) {
+					sleepUntil = t
+				}
+			}
 			oldFS.InodeTable = fs.InodeTable
 			oldFS.DirectoryInode = fs.DirectoryInode
 			fsChannel <- fs
@@ -65,6 +101,35 @@ func scannerDaemon(rootDirectoryName string, cacheDirectoryName string,
 	}
 }
 
+// waitForChanges waits until changes are reported, the metadata scan interval
+// has elapsed or a request to disable scanning is received. It returns the
+// dirty inodes, or nil if a full scan is required.
+func waitForChanges(tracker *changeTracker,
+	fullScanTime time.Time) map[uint64]struct{} {
+	timeout := time.Until(fullScanTime)
+	if timeout <= 0 {
+		tracker.takeDirtyInodes()
+		return nil
+	}
+	if timeout > metadataScanInterval {
+		timeout = metadataScanInterval
+	}
+	timer := time.NewTimer(timeout)
+	select {
+	case <-tracker.notifyChannel:
+	case <-disableScanRequest:
+		disableScanAcknowledge <- true
+		<-disableScanAcknowledge
+	case <-timer.C:
+	}
+	timer.Stop()
+	if time.Now().After(fullScanTime) {
+		tracker.takeDirtyInodes()
+		return nil
+	}
+	return tracker.takeDirtyInodes()
+}
+
 func doDisableScanner(disableScanner bool) {
 	if disableScanner {
 		disableScanRequest <- true
@@ -81,3 +146,10 @@ func checkScanDisableRequest() bool {
 	}
 	return false
 }
+
+func (configuration *Configuration) fullScanInterval() time.Duration {
+	if configuration.FullScanInterval > 0 {
+		return configuration.FullScanInterval
+	}
+	return defaultFullScanInterval
+}
@@ -0,0 +1,97 @@
+package scanner
+
+import (
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+type changeTracker struct {
+	configuration     *Configuration
+	dev               uint64
+	logger            log.Logger
+	mechanism         string
+	notifyChannel     chan struct{}
+	rootDirectoryName string
+	mutex             sync.Mutex          // Protect everything below.
+	dirtyInodes       map[uint64]struct{} // Key: inode number.
+	overflowed        bool
+}
+
+func newTracker(rootDirectoryName string, configuration *Configuration,
+	logger log.Logger) *changeTracker {
+	return &changeTracker{
+		configuration:     configuration,
+		dirtyInodes:       make(map[uint64]struct{}),
+		logger:            logger,
+		notifyChannel:     make(chan struct{}, 1),
+		rootDirectoryName: rootDirectoryName,
+	}
+}
+
+// isExcluded returns true if changes to the specified path (relative to the
+// root directory) should be ignored.
+func (t *changeTracker) isExcluded(pathName string) bool {
+	if pathName == "/.subd" || strings.HasPrefix(pathName, "/.subd/") {
+		return true
+	}
+	scanFilter := t.configuration.ScanFilter
+	if scanFilter != nil && scanFilter.Match(pathName) {
+		return true
+	}
+	return false
+}
+
+func (t *changeTracker) markDirty(inodeNumber uint64) {
+	t.mutex.Lock()
+	t.dirtyInodes[inodeNumber] = struct{}{}
+	t.mutex.Unlock()
+	t.notify()
+}
+
+func (t *changeTracker) markOverflowed() {
+	t.mutex.Lock()
+	t.overflowed = true
+	t.mutex.Unlock()
+	t.notify()
+}
+
+func (t *changeTracker) notify() {
+	select {
+	case t.notifyChannel <- struct{}{}:
+	default:
+	}
+}
+
+// relativePath converts an absolute path name to a path name relative to the
+// root directory. If the path name is not below the root directory it is
+// returned unchanged.
+func (t *changeTracker) relativePath(pathName string) string {
+	if t.rootDirectoryName == "/" {
+		return pathName
+	}
+	if pathName == t.rootDirectoryName {
+		return "/"
+	}
+	if strings.HasPrefix(pathName, t.rootDirectoryName+"/") {
+		return path.Clean(pathName[len(t.rootDirectoryName):])
+	}
+	return pathName
+}
+
+// takeDirtyInodes returns the inodes which have been reported as changed
+// since the previous call and resets the set. If events may have been lost it
+// returns nil, indicating that a full scan is required.
+func (t *changeTracker) takeDirtyInodes() map[uint64]struct{} {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	dirtyInodes := t.dirtyInodes
+	t.dirtyInodes = make(map[uint64]struct{})
+	if t.overflowed {
+		t.overflowed = false
+		return nil
+	}
+	return dirtyInodes
+}
@@ -0,0 +1,465 @@
+package scanner
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/wsyscall"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	fanotifyDirentMask = unix.FAN_CREATE | unix.FAN_DELETE |
+		unix.FAN_MOVED_FROM | unix.FAN_MOVED_TO
+	fanotifyMask = unix.FAN_MODIFY | unix.FAN_CLOSE_WRITE | unix.FAN_ATTRIB |
+		fanotifyDirentMask | unix.FAN_ONDIR
+	maxFanotifyDirCache = 4096
+	inotifyMask         = syscall.IN_MODIFY | syscall.IN_ATTRIB |
+		syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE |
+		syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DONT_FOLLOW |
+		syscall.IN_ONLYDIR | syscall.IN_EXCL_UNLINK
+)
+
+// fanotifyInfo contains the information records for a fanotify event.
+type fanotifyInfo struct {
+	dirHandle  unix.FileHandle // Directory containing the object.
+	handle     unix.FileHandle // The object.
+	haveDir    bool
+	haveHandle bool
+	name       string // Name of the object in the directory.
+}
+
+type fanotifyState struct {
+	dirCache map[string]string // Key: directory file handle, value: path.
+	fd       int
+	mountFd  int // Used to open file handles.
+}
+
+type inotifyState struct {
+	fd      int
+	moves   map[uint32]string // Key: cookie, value: old path.
+	watches map[int]string    // Key: watch descriptor, value: path.
+}
+
+func startChangeTracker(rootDirectoryName string,
+	configuration *Configuration, logger log.Logger) (*changeTracker, error) {
+	var stat wsyscall.Stat_t
+	if err := wsyscall.Lstat(rootDirectoryName, &stat); err != nil {
+		return nil, err
+	}
+	t := newTracker(rootDirectoryName, configuration, logger)
+	t.dev = stat.Dev
+	if state, err := t.startFanotify(); err != nil {
+		logger.Printf("Unable to use fanotify: %s, trying inotify\n", err)
+	} else {
+		t.mechanism = "fanotify"
+		go t.readFanotify(state)
+		return t, nil
+	}
+	state, err := t.startInotify()
+	if err != nil {
+		return nil, err
+	}
+	t.mechanism = "inotify"
+	go t.readInotify(state)
+	return t, nil
+}
+
+// parseFanotifyFileHandle returns the file handle in a fanotify file
+// identifier information record and the remainder of the record.
+func parseFanotifyFileHandle(record []byte) (unix.FileHandle, []byte, bool) {
+	// Header (4 bytes), file-system ID (8 bytes), handle size and type.
+	const handleOffset = 12
+	if len(record) < handleOffset+8 {
+		return unix.FileHandle{}, nil, false
+	}
+	handleBytes := *(*uint32)(unsafe.Pointer(&record[handleOffset]))
+	handleType := *(*int32)(unsafe.Pointer(&record[handleOffset+4]))
+	end := uint64(handleOffset+8) + uint64(handleBytes)
+	if uint64(len(record)) < end {
+		return unix.FileHandle{}, nil, false
+	}
+	return unix.NewFileHandle(handleType, record[handleOffset+8:end]),
+		record[end:], true
+}
+
+// parseFanotifyInfo returns the file handles and name in the information
+// records of a fanotify event.
+func parseFanotifyInfo(info []byte) fanotifyInfo {
+	var result fanotifyInfo
+	for len(info) >= 4 {
+		infoType := info[0]
+		length := int(*(*uint16)(unsafe.Pointer(&info[2])))
+		if length < 4 || length > len(info) {
+			break
+		}
+		record := info[:length]
+		info = info[length:]
+		handle, remainder, ok := parseFanotifyFileHandle(record)
+		if !ok {
+			continue
+		}
+		switch infoType {
+		case unix.FAN_EVENT_INFO_TYPE_FID:
+			result.handle = handle
+			result.haveHandle = true
+		case unix.FAN_EVENT_INFO_TYPE_DFID:
+			result.dirHandle = handle
+			result.haveDir = true
+		case unix.FAN_EVENT_INFO_TYPE_DFID_NAME:
+			result.dirHandle = handle
+			result.haveDir = true
+			if index := bytes.IndexByte(remainder, 0); index >= 0 {
+				remainder = remainder[:index]
+			}
+			result.name = string(remainder)
+		}
+	}
+	return result
+}
+
+func (t *changeTracker) startFanotify() (*fanotifyState, error) {
+	// Events for directory entries (create, delete and rename) are only
+	// available when reporting file handles rather than file descriptors.
+	// Reporting the directory and name (Linux 5.9 and later) as well allows
+	// changes to excluded paths to be ignored without opening the file.
+	const flags = unix.FAN_CLASS_NOTIF | unix.FAN_CLOEXEC
+	const eventFlags = unix.O_RDONLY | unix.O_LARGEFILE | unix.O_CLOEXEC
+	fd, err := unix.FanotifyInit(
+		flags|unix.FAN_REPORT_FID|unix.FAN_REPORT_DFID_NAME, eventFlags)
+	if err == unix.EINVAL {
+		fd, err = unix.FanotifyInit(flags|unix.FAN_REPORT_FID, eventFlags)
+	}
+	if err != nil {
+		return nil, os.NewSyscallError("fanotify_init", err)
+	}
+	// Mark the whole file-system rather than the mount, since the root
+	// directory is a bind mount in a private mount namespace and changes are
+	// made via other mounts.
+	err = unix.FanotifyMark(fd, unix.FAN_MARK_ADD|unix.FAN_MARK_FILESYSTEM,
+		fanotifyMask, unix.AT_FDCWD, t.rootDirectoryName)
+	if err != nil {
+		unix.Close(fd)
+		return nil, os.NewSyscallError("fanotify_mark", err)
+	}
+	mountFd, err := unix.Open(t.rootDirectoryName,
+		unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	return &fanotifyState{
+		dirCache: make(map[string]string),
+		fd:       fd,
+		mountFd:  mountFd,
+	}, nil
+}
+
+func (t *changeTracker) readFanotify(state *fanotifyState) {
+	metadataSize := int(unsafe.Sizeof(unix.FanotifyEventMetadata{}))
+	buffer := make([]byte, 64<<10)
+	for {
+		nRead, err := unix.Read(state.fd, buffer)
+		if err != nil {
+			if err == unix.EINTR || err == unix.EAGAIN {
+				continue
+			}
+			t.logger.Printf("Error reading fanotify events: %s\n", err)
+			t.markOverflowed()
+			return
+		}
+		for offset := 0; offset+metadataSize <= nRead; {
+			event := (*unix.FanotifyEventMetadata)(
+				unsafe.Pointer(&buffer[offset]))
+			if event.Vers != unix.FANOTIFY_METADATA_VERSION ||
+				event.Event_len < uint32(metadataSize) ||
+				uint32(event.Metadata_len) > event.Event_len ||
+				offset+int(event.Event_len) > nRead {
+				t.logger.Printf("Unsupported fanotify event version: %d\n",
+					event.Vers)
+				t.markOverflowed()
+				return
+			}
+			infoStart := offset + int(event.Metadata_len)
+			offset += int(event.Event_len)
+			info := buffer[infoStart:offset]
+			if event.Mask&unix.FAN_Q_OVERFLOW != 0 {
+				t.markOverflowed()
+				continue
+			}
+			t.processFanotifyEvent(state, event.Mask, info)
+		}
+	}
+}
+
+// getFanotifyDirPath returns the path (relative to the root directory) of the
+// directory with the specified file handle. Paths are cached, so that events
+// for busy directories do not require the directory to be opened each time.
+func (t *changeTracker) getFanotifyDirPath(state *fanotifyState,
+	handle unix.FileHandle) (string, bool) {
+	key := strconv.Itoa(int(handle.Type())) + ":" + string(handle.Bytes())
+	if pathName, ok := state.dirCache[key]; ok {
+		return pathName, true
+	}
+	fd, err := unix.OpenByHandleAt(state.mountFd, handle, unix.O_PATH)
+	if err != nil { // Probably deleted.
+		return "", false
+	}
+	pathName, err := t.getFdPath(fd)
+	unix.Close(fd)
+	if err != nil {
+		return "", false
+	}
+	if len(state.dirCache) >= maxFanotifyDirCache {
+		state.dirCache = make(map[string]string)
+	}
+	state.dirCache[key] = pathName
+	return pathName, true
+}
+
+// getFdPath returns the path (relative to the root directory) of the open
+// file. It returns an error if the file is not below the root directory.
+func (t *changeTracker) getFdPath(fd int) (string, error) {
+	pathName, err := os.Readlink("/proc/self/fd/" + strconv.Itoa(fd))
+	if err != nil {
+		return "", err
+	}
+	if t.rootDirectoryName != "/" && pathName != t.rootDirectoryName &&
+		!strings.HasPrefix(pathName, t.rootDirectoryName+"/") {
+		return "", errors.New(pathName + ": not below root directory")
+	}
+	return t.relativePath(pathName), nil
+}
+
+// processFanotifyEvent handles an event. Events for excluded paths are
+// ignored. Changes to directory entries and attributes are picked up by the
+// metadata scan, so they only need to trigger a scan. Files which were written
+// to are marked dirty so that they are rehashed.
+// If the kernel does not report the directory and name, directory entry
+// events are checked using the path of the directory.
+func (t *changeTracker) processFanotifyEvent(state *fanotifyState,
+	mask uint64, rawInfo []byte) {
+	if mask&unix.FAN_ONDIR != 0 &&
+		mask&(unix.FAN_DELETE|unix.FAN_MOVED_FROM|unix.FAN_MOVED_TO) != 0 {
+		// Cached paths below the directory may no longer be valid.
+		state.dirCache = make(map[string]string)
+	}
+	info := parseFanotifyInfo(rawInfo)
+	fd := -1
+	var pathName string
+	if info.haveDir {
+		dirName, ok := t.getFanotifyDirPath(state, info.dirHandle)
+		if !ok { // Deleted or not below the root directory.
+			return
+		}
+		pathName = path.Join(dirName, info.name)
+	} else if info.haveHandle {
+		var err error
+		fd, err = unix.OpenByHandleAt(state.mountFd, info.handle,
+			unix.O_PATH)
+		if err != nil { // Deleted: the parent directory has an event.
+			return
+		}
+		defer unix.Close(fd)
+		if pathName, err = t.getFdPath(fd); err != nil {
+			return
+		}
+	} else {
+		t.notify()
+		return
+	}
+	if t.isExcluded(pathName) {
+		return
+	}
+	if mask&(unix.FAN_MODIFY|unix.FAN_CLOSE_WRITE) == 0 ||
+		mask&unix.FAN_ONDIR != 0 || !info.haveHandle {
+		t.notify()
+		return
+	}
+	if fd < 0 {
+		var err error
+		fd, err = unix.OpenByHandleAt(state.mountFd, info.handle,
+			unix.O_PATH)
+		if err != nil { // Deleted: the parent directory has an event.
+			return
+		}
+		defer unix.Close(fd)
+	}
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		return
+	}
+	if uint64(stat.Dev) != t.dev || stat.Mode&unix.S_IFMT != unix.S_IFREG {
+		return
+	}
+	t.markDirty(stat.Ino)
+}
+
+func (t *changeTracker) startInotify() (*inotifyState, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	state := &inotifyState{
+		fd:      fd,
+		moves:   make(map[uint32]string),
+		watches: make(map[int]string),
+	}
+	if err := t.addInotifyWatches(state, "/"); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return state, nil
+}
+
+// addInotifyWatches adds watches for the specified directory (relative to the
+// root directory) and all directories below it.
+func (t *changeTracker) addInotifyWatches(state *inotifyState,
+	dirName string) error {
+	if dirName != "/" && t.isExcluded(dirName) {
+		return nil
+	}
+	pathName := path.Join(t.rootDirectoryName, dirName)
+	var stat wsyscall.Stat_t
+	if err := wsyscall.Lstat(pathName, &stat); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if stat.Dev != t.dev || stat.Mode&syscall.S_IFMT != syscall.S_IFDIR {
+		return nil
+	}
+	wd, err := syscall.InotifyAddWatch(state.fd, pathName, inotifyMask)
+	if err != nil {
+		if err == syscall.ENOENT || err == syscall.ENOTDIR {
+			return nil
+		}
+		if err == syscall.ENOSPC {
+			return errors.New("inotify watch limit reached")
+		}
+		return os.NewSyscallError("inotify_add_watch", err)
+	}
+	state.watches[wd] = dirName
+	file, err := os.Open(pathName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	names, err := file.Readdirnames(-1)
+	file.Close()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		err := t.addInotifyWatches(state, path.Join(dirName, name))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *changeTracker) readInotify(state *inotifyState) {
+	buffer := make([]byte, 64<<10)
+	for {
+		nRead, err := syscall.Read(state.fd, buffer)
+		if err != nil {
+			if err == syscall.EINTR || err == syscall.EAGAIN {
+				continue
+			}
+			t.logger.Printf("Error reading inotify events: %s\n", err)
+			t.markOverflowed()
+			return
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= nRead; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			offset = nameStart + int(event.Len)
+			if offset > nRead {
+				break
+			}
+			name := string(bytes.TrimRight(buffer[nameStart:offset], "\x00"))
+			t.processInotifyEvent(state, event, name)
+		}
+	}
+}
+
+func (t *changeTracker) processInotifyEvent(state *inotifyState,
+	event *syscall.InotifyEvent, name string) {
+	if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+		t.markOverflowed()
+		return
+	}
+	if len(state.moves) > 0 {
+		_, ok := state.moves[event.Cookie]
+		if !ok || event.Mask&syscall.IN_MOVED_TO == 0 {
+			// A directory was moved out of the tree: the paths for watches
+			// below it are no longer valid, so do a full scan.
+			state.moves = make(map[uint32]string)
+			t.markOverflowed()
+		}
+	}
+	dirName, ok := state.watches[int(event.Wd)]
+	if !ok {
+		return
+	}
+	if event.Mask&syscall.IN_IGNORED != 0 {
+		delete(state.watches, int(event.Wd))
+		return
+	}
+	pathName := path.Join(dirName, name)
+	if t.isExcluded(pathName) {
+		return
+	}
+	if event.Mask&syscall.IN_ISDIR != 0 {
+		switch {
+		case event.Mask&syscall.IN_MOVED_FROM != 0:
+			state.moves[event.Cookie] = pathName
+		case event.Mask&syscall.IN_MOVED_TO != 0:
+			if oldPathName, ok := state.moves[event.Cookie]; ok {
+				delete(state.moves, event.Cookie)
+				state.renameWatches(oldPathName, pathName)
+			} else if err := t.addInotifyWatches(state, pathName); err != nil {
+				t.logger.Printf("Error adding inotify watches: %s\n", err)
+				t.markOverflowed()
+				return
+			}
+		case event.Mask&syscall.IN_CREATE != 0:
+			if err := t.addInotifyWatches(state, pathName); err != nil {
+				t.logger.Printf("Error adding inotify watches: %s\n", err)
+				t.markOverflowed()
+				return
+			}
+		}
+		t.notify()
+		return
+	}
+	var stat wsyscall.Stat_t
+	err := wsyscall.Lstat(path.Join(t.rootDirectoryName, pathName), &stat)
+	if err != nil || stat.Dev != t.dev {
+		t.notify()
+		return
+	}
+	t.markDirty(stat.Ino)
+}
+
+func (state *inotifyState) renameWatches(oldPathName, newPathName string) {
+	for wd, dirName := range state.watches {
+		if dirName == oldPathName {
+			state.watches[wd] = newPathName
+		} else if len(dirName) > len(oldPathName) &&
+			dirName[:len(oldPathName)+1] == oldPathName+"/" {
+			state.watches[wd] = newPathName + dirName[len(oldPathName):]
+		}
+	}
+}
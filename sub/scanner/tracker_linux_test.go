@@ -0,0 +1,162 @@
+package scanner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log/nulllogger"
+	"github.com/Cloud-Foundations/Dominator/lib/wsyscall"
+	"golang.org/x/sys/unix"
+)
+
+func makeFanotifyRecord(infoType byte, handle []byte, name string) []byte {
+	record := make([]byte, 20+len(handle)+len(name)+1)
+	record[0] = infoType
+	*(*uint16)(unsafe.Pointer(&record[2])) = uint16(len(record))
+	*(*uint32)(unsafe.Pointer(&record[12])) = uint32(len(handle))
+	*(*int32)(unsafe.Pointer(&record[16])) = 1
+	copy(record[20:], handle)
+	copy(record[20+len(handle):], name)
+	return record
+}
+
+func TestParseFanotifyInfo(t *testing.T) {
+	info := append(makeFanotifyRecord(unix.FAN_EVENT_INFO_TYPE_DFID_NAME,
+		[]byte{1, 2, 3, 4}, "file"),
+		makeFanotifyRecord(unix.FAN_EVENT_INFO_TYPE_FID,
+			[]byte{5, 6}, "")...)
+	result := parseFanotifyInfo(info)
+	if !result.haveDir || !result.haveHandle {
+		t.Fatalf("haveDir: %v, haveHandle: %v",
+			result.haveDir, result.haveHandle)
+	}
+	if result.dirHandle.Type() != 1 ||
+		string(result.dirHandle.Bytes()) != "\x01\x02\x03\x04" {
+		t.Errorf("directory handle type: %d, bytes: %v",
+			result.dirHandle.Type(), result.dirHandle.Bytes())
+	}
+	if result.name != "file" {
+		t.Errorf("name: %s != file", result.name)
+	}
+	if string(result.handle.Bytes()) != "\x05\x06" {
+		t.Errorf("handle bytes: %v", result.handle.Bytes())
+	}
+	result = parseFanotifyInfo(info[:22])
+	if result.haveDir || result.haveHandle {
+		t.Error("truncated record parsed")
+	}
+	info[0] = 0
+	if result := parseFanotifyInfo(info[:27]); result.haveDir {
+		t.Error("unsupported information type parsed")
+	}
+}
+
+func waitForDirty(t *testing.T, tracker *changeTracker,
+	inodeNumber uint64) {
+	timer := time.NewTimer(5 * time.Second)
+	defer timer.Stop()
+	for {
+		select {
+		case <-tracker.notifyChannel:
+		case <-timer.C:
+			t.Fatalf("inode: %d not reported", inodeNumber)
+		}
+		if _, ok := tracker.takeDirtyInodes()[inodeNumber]; ok {
+			return
+		}
+	}
+}
+
+func TestInotifyTracker(t *testing.T) {
+	dirname, err := ioutil.TempDir("", "tracker-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dirname)
+	var stat wsyscall.Stat_t
+	if err := wsyscall.Lstat(dirname, &stat); err != nil {
+		t.Fatal(err)
+	}
+	tracker := makeTestTracker(t)
+	// The reader goroutine may outlive the test.
+	tracker.logger = nulllogger.New()
+	tracker.rootDirectoryName = dirname
+	tracker.dev = stat.Dev
+	state, err := tracker.startInotify()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer unix.Close(state.fd)
+	go tracker.readInotify(state)
+	subdir := filepath.Join(dirname, "subdir")
+	if err := os.Mkdir(subdir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	select { // Wait for the watch on the new directory.
+	case <-tracker.notifyChannel:
+	case <-time.After(5 * time.Second):
+		t.Fatal("directory creation not reported")
+	}
+	filename := filepath.Join(subdir, "file")
+	if err := ioutil.WriteFile(filename, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := wsyscall.Lstat(filename, &stat); err != nil {
+		t.Fatal(err)
+	}
+	waitForDirty(t, tracker, stat.Ino)
+}
+
+func TestFanotifyExcluded(t *testing.T) {
+	dirname, err := ioutil.TempDir("", "tracker-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dirname)
+	var stat wsyscall.Stat_t
+	if err := wsyscall.Lstat(dirname, &stat); err != nil {
+		t.Fatal(err)
+	}
+	tracker := makeTestTracker(t)
+	tracker.logger = nulllogger.New()
+	tracker.rootDirectoryName = dirname
+	tracker.dev = stat.Dev
+	state, err := tracker.startFanotify()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer unix.Close(state.fd)
+	excludedDir := filepath.Join(dirname, "tmp")
+	if err := os.Mkdir(excludedDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	go tracker.readFanotify(state)
+	time.Sleep(100 * time.Millisecond)
+	for len(tracker.notifyChannel) > 0 {
+		<-tracker.notifyChannel
+	}
+	filename := filepath.Join(excludedDir, "file")
+	if err := ioutil.WriteFile(filename, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filename); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-tracker.notifyChannel:
+		t.Error("change to excluded path reported")
+	case <-time.After(200 * time.Millisecond):
+	}
+	filename = filepath.Join(dirname, "file")
+	if err := ioutil.WriteFile(filename, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := wsyscall.Lstat(filename, &stat); err != nil {
+		t.Fatal(err)
+	}
+	waitForDirty(t, tracker, stat.Ino)
+}
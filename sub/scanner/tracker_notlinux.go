@@ -0,0 +1,14 @@
+//go:build !linux
+
+package scanner
+
+import (
+	"errors"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func startChangeTracker(rootDirectoryName string,
+	configuration *Configuration, logger log.Logger) (*changeTracker, error) {
+	return nil, errors.New("change tracking not supported on this platform")
+}
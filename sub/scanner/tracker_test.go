@@ -0,0 +1,93 @@
+package scanner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filter"
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+)
+
+func makeTestTracker(t *testing.T) *changeTracker {
+	scanFilter, err := filter.New([]string{"/tmp/.*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newTracker("/root", &Configuration{ScanFilter: scanFilter},
+		testlogger.New(t))
+}
+
+func TestIsExcluded(t *testing.T) {
+	tracker := makeTestTracker(t)
+	for pathName, want := range map[string]bool{
+		"/.subd":       true,
+		"/.subd/state": true,
+		"/.subdir":     false,
+		"/etc/passwd":  false,
+		"/tmp/file":    true,
+	} {
+		if got := tracker.isExcluded(pathName); got != want {
+			t.Errorf("isExcluded(%s) = %v, want %v", pathName, got, want)
+		}
+	}
+}
+
+func TestRelativePath(t *testing.T) {
+	tracker := makeTestTracker(t)
+	for pathName, want := range map[string]string{
+		"/root":          "/",
+		"/root/etc/file": "/etc/file",
+		"/rootfs/file":   "/rootfs/file",
+	} {
+		if got := tracker.relativePath(pathName); got != want {
+			t.Errorf("relativePath(%s) = %s, want %s", pathName, got, want)
+		}
+	}
+}
+
+func TestTakeDirtyInodes(t *testing.T) {
+	tracker := makeTestTracker(t)
+	tracker.markDirty(1)
+	tracker.markDirty(2)
+	if dirtyInodes := tracker.takeDirtyInodes(); len(dirtyInodes) != 2 {
+		t.Errorf("dirty inodes: %v, want 2", dirtyInodes)
+	}
+	if dirtyInodes := tracker.takeDirtyInodes(); len(dirtyInodes) != 0 {
+		t.Errorf("dirty inodes: %v, want none", dirtyInodes)
+	}
+	tracker.markDirty(3)
+	tracker.markOverflowed()
+	if dirtyInodes := tracker.takeDirtyInodes(); dirtyInodes != nil {
+		t.Errorf("dirty inodes after overflow: %v, want nil", dirtyInodes)
+	}
+	if dirtyInodes := tracker.takeDirtyInodes(); dirtyInodes == nil {
+		t.Error("overflow not reset")
+	}
+}
+
+func TestWaitForChanges(t *testing.T) {
+	disableScanRequest = make(chan bool, 1)
+	disableScanAcknowledge = make(chan bool)
+	tracker := makeTestTracker(t)
+	if dirtyInodes := waitForChanges(tracker, time.Now()); dirtyInodes != nil {
+		t.Errorf("dirty inodes at full scan time: %v, want nil", dirtyInodes)
+	}
+	tracker.markDirty(1)
+	dirtyInodes := waitForChanges(tracker, time.Now().Add(time.Hour))
+	if _, ok := dirtyInodes[1]; !ok || len(dirtyInodes) != 1 {
+		t.Errorf("dirty inodes: %v, want [1]", dirtyInodes)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		tracker.markDirty(2)
+	}()
+	dirtyInodes = waitForChanges(tracker, time.Now().Add(time.Hour))
+	if _, ok := dirtyInodes[2]; !ok {
+		t.Errorf("dirty inodes: %v, want [2]", dirtyInodes)
+	}
+	dirtyInodes = waitForChanges(tracker,
+		time.Now().Add(20*time.Millisecond))
+	if dirtyInodes != nil {
+		t.Errorf("dirty inodes after timeout: %v, want nil", dirtyInodes)
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/objectcache"
 )
 
+// scanFileSystem scans the file-system. If dirtyInodes is not nil, only the
+// regular files which are dirty or have changed size or modification time are
+// rehashed.
 func scanFileSystem(rootDirectoryName string, cacheDirectoryName string,
-	configuration *Configuration, oldFS *FileSystem) (*FileSystem, error) {
+	configuration *Configuration, oldFS *FileSystem,
+	dirtyInodes map[uint64]struct{}) (*FileSystem, error) {
 	var fileSystem FileSystem
 	fileSystem.configuration = configuration
 	fileSystem.rootDirectoryName = rootDirectoryName
@@ -15,9 +19,21 @@ func scanFileSystem(rootDirectoryName string, cacheDirectoryName string,
 	if configuration.CpuLimiter != nil {
 		hasher = scanner.NewCpuLimitedHasher(configuration.CpuLimiter, hasher)
 	}
-	fs, err := scanner.ScanFileSystem(rootDirectoryName,
-		configuration.FsScanContext, configuration.ScanFilter,
-		checkScanDisableRequest, hasher, &oldFS.FileSystem)
+	var fs *scanner.FileSystem
+	var err error
+	if dirtyInodes == nil {
+		fs, err = scanner.ScanFileSystem(rootDirectoryName,
+			configuration.FsScanContext, configuration.ScanFilter,
+			checkScanDisableRequest, hasher, &oldFS.FileSystem)
+	} else {
+		fs, err = scanner.ScanFileSystemIncremental(rootDirectoryName,
+			configuration.FsScanContext, configuration.ScanFilter,
+			checkScanDisableRequest, hasher, &oldFS.FileSystem,
+			func(inodeNumber uint64) bool {
+				_, ok := dirtyInodes[inodeNumber]
+				return ok
+			})
+	}
 	if err != nil {
 		return nil, err
 	}